@@ -1,32 +1,33 @@
 package api
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 	db "vk-film/db/sqlc"
+	"vk-film/token"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // createActorRequest represents the request body for creating an actor.
-// swagger:parameters createActor
 type createActorRequest struct {
 	// The name of the actor.
 	// Required: true
-	Name string `json:"name" binding:"required"`
+	Name string `json:"name" binding:"required,max=255"`
 
 	// The gender of the actor.
 	// Required: true
-	Gender string `json:"gender" binding:"required"`
+	Gender string `json:"gender" binding:"required,oneof=male female other"`
 
-	// The birthday of the actor.
+	// The birthday of the actor, which cannot be in the future.
 	// Required: true
-	Birthday time.Time `json:"birthday" binding:"required"`
+	Birthday time.Time `json:"birthday" binding:"required,notfuture"`
 }
 
 // actorResponse represents the response body for an actor.
-// swagger:response actorResponse
 type actorResponse struct {
 	// The ID of the actor.
 	// Example: 1
@@ -43,6 +44,10 @@ type actorResponse struct {
 	// The birthday of the actor.
 	// Example: 2000-01-01T00:00:00Z
 	Birthday time.Time `json:"birthday"`
+
+	// The version of the actor, also sent as the ETag header and expected in If-Match to modify it.
+	// Example: 3
+	Version int32 `json:"version"`
 }
 
 // newActorResponse creates a new actorResponse from a db.Actor.
@@ -52,151 +57,325 @@ func newActorResponse(actor db.Actor) actorResponse {
 		Name:     actor.Name,
 		Gender:   actor.Gender,
 		Birthday: actor.Birthday,
+		Version:  actor.Version,
 	}
 }
 
+// createActorResponse represents the response body for a created actor.
+type createActorResponse struct {
+	actorResponse
+
+	// Existing actors with a similar name and the same birthday, the new actor may be one of them.
+	PossibleDuplicates []actorResponse `json:"possible_duplicates"`
+}
+
 // createActor creates a new actor.
-// swagger:route POST /actors actors createActor
-// Creates a new actor.
-// responses:
-//
-//	200: actorResponse
-//	400: errorResponse
-//	401: errorResponse
-//	500: errorResponse
 func (server *Server) createActor(ctx *gin.Context) {
 	var req createActorRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	err := checkAdminPermissions(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	rsp, ok := server.createActorResource(ctx, req)
+	if !ok {
 		return
 	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// createActorResource creates an actor for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) createActorResource(ctx *gin.Context, req createActorRequest) (createActorResponse, bool) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	duplicates, err := server.findDuplicateActors(ctx, req.Name, req.Birthday)
+	if err != nil {
+		writeError(ctx, err)
+		return createActorResponse{}, false
+	}
 	arg := db.CreateActorParams{
 		Name:     req.Name,
 		Gender:   req.Gender,
 		Birthday: req.Birthday,
 	}
-	actor, err := server.store.CreateActor(ctx, arg)
+	var actor db.Actor
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		var err error
+		actor, err = store.CreateActorTx(ctx, db.CreateActorTxParams{
+			CreateActorParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityActor, actor.ID, nil, newActorResponse(actor))
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		writeError(ctx, err)
+		return createActorResponse{}, false
 	}
-	rsp := newActorResponse(actor)
-	ctx.JSON(http.StatusOK, rsp)
+	ctx.Header(etagHeader, versionETag(actor.Version))
+	rsp := createActorResponse{
+		actorResponse:      newActorResponse(actor),
+		PossibleDuplicates: duplicates,
+	}
+	return rsp, true
 }
 
-// updateActorRequest represents the request body for updating an actor.
-// swagger:parameters updateActor
+// updateActorRequest represents the patchable document of an actor.
+// It is modified either by a JSON Merge Patch (RFC 7386), sent as application/merge-patch+json or application/json,
+// or by a JSON Patch (RFC 6902), sent as application/json-patch+json. Only the fields touched by the patch change.
 type updateActorRequest struct {
-	// The ID of the actor to update.
-	// Required: true
-	ID int32 `json:"id" binding:"required"`
+	// The ID of the actor to update, can also be sent as the id query parameter.
+	ID *int32 `json:"id"`
 
 	// The name of the actor.
 	// Example: John Doe
-	Name string `json:"name"`
+	Name *string `json:"name" binding:"omitnil,min=1,max=255"`
 
 	// The gender of the actor.
 	// Example: male
-	Gender string `json:"gender"`
+	Gender *string `json:"gender" binding:"omitnil,oneof=male female other"`
 
-	// The birthday of the actor.
+	// The birthday of the actor, which cannot be in the future.
 	// Example: 2000-01-01T00:00:00Z
-	Birthday time.Time `json:"birthday"`
+	Birthday *time.Time `json:"birthday" binding:"omitnil,notfuture"`
 }
 
-// updateActor updates an existing actor.
-// swagger:route PUT /actors actors updateActor
-// Updates an existing actor.
-// responses:
-//
-//		'200': actorResponse
-//		'400':
-//		  description: Bad request. The request body is missing or invalid.
-//		'403':
-//		  description: Forbidden. Only admins have permission to update actors.
-//		'404':
-//		  description: Not found. The actor with the provided ID does not exist.
-//	 '500':
-//		  description: Internal server error. Something went wrong while processing the request.
-func (server *Server) updateActor(ctx *gin.Context) {
-	var req updateActorRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
+// newUpdateActorParams validates a patched actor and sets the fields changed by the patch,
+// the untouched ones keep their current value. id is the ID the request asked for, an old ID of a merged actor
+// redirecting to before.
+func newUpdateActorParams(id int32, before db.Actor, patched updateActorRequest) (arg db.UpdateActorParams, changed bool, err error) {
+	arg.ID = before.ID
+	if patched.ID == nil || (*patched.ID != before.ID && *patched.ID != id) {
+		return arg, false, errPatchChangesID
+	}
+	if err := binding.Validator.ValidateStruct(patched); err != nil {
+		return arg, false, err
+	}
+	if patched.Name == nil {
+		return arg, false, errors.New("name cannot be null")
+	}
+	if *patched.Name != before.Name {
+		arg.Name = sql.NullString{String: *patched.Name, Valid: true}
+	}
+	if patched.Gender == nil {
+		return arg, false, errors.New("gender cannot be null")
 	}
+	if *patched.Gender != before.Gender {
+		arg.Gender = sql.NullString{String: *patched.Gender, Valid: true}
+	}
+	if patched.Birthday == nil {
+		return arg, false, errors.New("birthday cannot be null")
+	}
+	if !patched.Birthday.Equal(before.Birthday) {
+		arg.Birthday = sql.NullTime{Time: *patched.Birthday, Valid: true}
+	}
+	changed = arg.Name.Valid || arg.Gender.Valid || arg.Birthday.Valid
+	return arg, changed, nil
+}
 
-	err := checkAdminPermissions(ctx)
+// updateActor partially updates an existing actor.
+func (server *Server) updateActor(ctx *gin.Context) {
+	body, err := ctx.GetRawData()
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	arg := db.UpdateActorParams{
-		ID:       req.ID,
-		Name:     req.Name,
-		Gender:   req.Gender,
-		Birthday: req.Birthday,
-	}
-	actor, err := server.store.UpdateActor(ctx, arg)
+	id, err := patchTargetID(ctx, body)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "no_data_found":
-				ctx.JSON(http.StatusNotFound, errorResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.updateActorResource(ctx, id, body)
+	if !ok {
 		return
 	}
 	ctx.JSON(http.StatusOK, actor)
 }
 
+// updateActorResource applies a patch to an actor for the legacy and the v1 routes, the errors are written to the response.
+// The actor is read and locked in the transaction of the update, so that the patch applies to its current version.
+func (server *Server) updateActorResource(ctx *gin.Context, id int32, body []byte) (db.Actor, bool) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	var actor db.Actor
+	// the errors of the patch itself are bad requests, those of the store keep their own status
+	var patchErr error
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockActor(ctx, id)
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(ctx, before.Version)
+		if err != nil {
+			return err
+		}
+		current := updateActorRequest{
+			ID:       &before.ID,
+			Name:     &before.Name,
+			Gender:   &before.Gender,
+			Birthday: &before.Birthday,
+		}
+		var patched updateActorRequest
+		patchErr = applyPatch(ctx.ContentType(), body, current, &patched)
+		if patchErr != nil {
+			return patchErr
+		}
+		var arg db.UpdateActorParams
+		var changed bool
+		arg, changed, patchErr = newUpdateActorParams(id, before, patched)
+		if patchErr != nil {
+			return patchErr
+		}
+		if !changed {
+			actor = before
+			return nil
+		}
+		arg.ExpectedVersion = expected
+		actor, err = store.UpdateActorTx(ctx, db.UpdateActorTxParams{
+			UpdateActorParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityActor, actor.ID, newActorResponse(before), newActorResponse(actor))
+	})
+	if patchErr != nil {
+		writeStatusError(ctx, http.StatusBadRequest, patchErr)
+		return db.Actor{}, false
+	}
+	if err != nil {
+		writeError(ctx, err)
+		return db.Actor{}, false
+	}
+	ctx.Header(etagHeader, versionETag(actor.Version))
+	return actor, true
+}
+
 // deleteActorRequest represents the request body for deleting an actor.
-// swagger:parameters deleteActor
 type deleteActorRequest struct {
 	// The ID of the actor to delete.
-	// in: body
 	// required: true
 	ID int32 `form:"id" binding:"required"`
 }
 
 // deleteActor deletes an actor by ID.
-// swagger:route DELETE /actors actors deleteActor
-// Deletes an actor by ID.
-// responses:
-//
-//	204: noContentResponse
-//	400: errorResponse
-//	403: errorResponse
-//	404: errorResponse
 func (server *Server) deleteActor(ctx *gin.Context) {
 	var req deleteActorRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if !server.deleteActorResource(ctx, req.ID) {
 		return
 	}
-	err := checkAdminPermissions(ctx)
+	ctx.Status(http.StatusNoContent)
+}
+
+// deleteActorResource deletes an actor for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) deleteActorResource(ctx *gin.Context, id int32) bool {
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockActor(ctx, id)
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(ctx, before.Version)
+		if err != nil {
+			return err
+		}
+		deleted, err := store.DeleteActor(ctx, db.DeleteActorParams{
+			ID:              before.ID,
+			ExpectedVersion: expected,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errPreconditionFailed
+		}
+		return recordAudit(ctx, store, db.AuditActionDelete, db.AuditEntityActor, before.ID, newActorResponse(before), nil)
+	})
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		writeError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// getActorRequest represents the query parameters for retrieving an actor.
+type getActorRequest struct {
+	// The ID of the actor, IDs of actors merged into another one resolve to the survivor.
+	// required: true
+	ID int32 `form:"id" binding:"required,min=1"`
+}
+
+// getActor retrieves an actor by ID.
+func (server *Server) getActor(ctx *gin.Context) {
+	var req getActorRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.getActorResource(ctx, req.ID)
+	if !ok {
 		return
 	}
-	err = server.store.DeleteActor(ctx, req.ID)
+	ctx.JSON(http.StatusOK, newActorResponse(actor))
+}
+
+// getActorResource reads an actor for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) getActorResource(ctx *gin.Context, id int32) (db.Actor, bool) {
+	actor, err := server.store.GetActor(ctx, id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		writeError(ctx, err)
+		return db.Actor{}, false
+	}
+	ctx.Header(etagHeader, versionETag(actor.Version))
+	return actor, true
+}
+
+// mergeActors merges a duplicate actor into a survivor.
+func (server *Server) mergeActors(ctx *gin.Context) {
+	var req mergeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.mergeActorResources(ctx, req.SurvivorID, req.DuplicateID)
+	if !ok {
 		return
 	}
-	ctx.JSON(http.StatusNoContent, req.ID)
+	ctx.JSON(http.StatusOK, newActorResponse(actor))
+}
+
+// mergeActorResources merges two actors for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) mergeActorResources(ctx *gin.Context, survivorID, duplicateID int32) (db.Actor, bool) {
+	arg := db.MergeTxParams{
+		SurvivorID:  survivorID,
+		DuplicateID: duplicateID,
+	}
+	var actor db.Actor
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		duplicate, err := store.GetActor(ctx, duplicateID)
+		if err != nil {
+			return err
+		}
+		actor, err = store.MergeActorsTx(ctx, arg)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionMerge, db.AuditEntityActor, duplicate.ID, newActorResponse(duplicate), gin.H{"merged_into": actor.ID})
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return db.Actor{}, false
+	}
+	return actor, true
 }
 
 func (server *Server) actorsWithMovies(ctx *gin.Context) {
 	actorsWithMovies, err := server.store.GetActorMoviesList(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, actorsWithMovies)
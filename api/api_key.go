@@ -0,0 +1,236 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errAPIKeyNotAllowed = errors.New("an API key cannot act on a user account, log in as the user")
+	errPastExpiry       = errors.New("the expiry of the API key must be in the future")
+)
+
+// apiKeyURI represents the path of an API key in the v1 API.
+type apiKeyURI struct {
+	// required: true
+	ID int32 `uri:"id" binding:"required,min=1"`
+}
+
+// createAPIKeyRequest represents the request body for issuing an API key.
+type createAPIKeyRequest struct {
+	// The name of the client using the key, recorded as apikey:<name> in the audit log.
+	// Required: true
+	// Example: nightly-import
+	Name string `json:"name" binding:"required,min=3,max=40"`
+
+	// The permissions granted to the key, listed by GET /v1/permissions.
+	// Example: ["movies:write", "actors:write"]
+	Scopes []string `json:"scopes" binding:"max=50"`
+
+	// The time the key expires, it never expires without it.
+	// Example: "2023-03-17T09:00:00Z"
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// apiKeyResponse represents an API key, without the key itself.
+type apiKeyResponse struct {
+	// Example: 1
+	ID int32 `json:"id"`
+
+	// Example: nightly-import
+	Name string `json:"name"`
+
+	// The start of the key, telling the keys apart.
+	// Example: vkf_5f2b9c1e
+	Prefix string `json:"prefix"`
+
+	// Example: ["actors:write", "movies:write"]
+	Scopes []string `json:"scopes"`
+
+	// The user who issued the key.
+	// Example: vk-admin
+	CreatedBy string `json:"created_by"`
+
+	// Example: "2022-03-17T09:00:00Z"
+	CreatedAt time.Time `json:"created_at"`
+
+	// The time the key expires, absent for the keys that never expire.
+	// Example: "2023-03-17T09:00:00Z"
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// The time the key last authenticated a request, absent for the unused keys.
+	// Example: "2022-03-18T02:00:00Z"
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// The time the key was revoked, absent for the active keys.
+	// Example: "2022-03-19T09:00:00Z"
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// createAPIKeyResponse represents an API key just issued, the only response holding the key.
+type createAPIKeyResponse struct {
+	apiKeyResponse
+
+	// The key, sent in the X-API-Key header or as an ApiKey authorization. It is not stored and cannot be shown again.
+	// Example: vkf_5f2b9c1e_Qm9yaXMgS3VzdG9kaWV2IGFuZCB0aGUgbmlnaHRseSBpbXBvcnQ
+	Key string `json:"key"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	rsp := apiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		CreatedBy: apiKey.CreatedBy,
+		CreatedAt: apiKey.CreatedAt,
+	}
+	if rsp.Scopes == nil {
+		rsp.Scopes = []string{}
+	}
+	if apiKey.ExpiresAt.Valid {
+		rsp.ExpiresAt = &apiKey.ExpiresAt.Time
+	}
+	if apiKey.LastUsedAt.Valid {
+		rsp.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	if apiKey.RevokedAt.Valid {
+		rsp.RevokedAt = &apiKey.RevokedAt.Time
+	}
+	return rsp
+}
+
+// createAPIKeyV1 issues an API key, requires the api_keys:manage permission.
+// Issues an API key granting a set of permissions, for the service-to-service clients. Only a hash of the key is stored,
+// the response is the only one holding the key. The Location header holds its URL.
+// The issuer can only grant the permissions it holds itself, through its role or the scopes of its own key.
+func (server *Server) createAPIKeyV1(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			writeStatusError(ctx, http.StatusUnprocessableEntity, errPastExpiry)
+			return
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	scopes, ok := server.checkPermissionNames(ctx, req.Scopes)
+	if !ok {
+		return
+	}
+	for _, scope := range scopes {
+		err := server.checkPermission(ctx, scope)
+		if err == authz.ErrPermissionDenied || err == authz.ErrScopeDenied || err == authz.ErrIssuerDenied {
+			writeStatusError(ctx, http.StatusForbidden, fmt.Errorf("cannot grant %q, a permission the issuer does not hold", scope))
+			return
+		}
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+	key, prefix, hash, err := token.NewAPIKey()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	var rsp apiKeyResponse
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		apiKey, err := store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+			Name:      req.Name,
+			Prefix:    prefix,
+			KeyHash:   hash,
+			Scopes:    scopes,
+			CreatedBy: authPayload.Username,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		rsp = newAPIKeyResponse(apiKey)
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityAPIKey, apiKey.ID, nil, rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Header(locationHeader, fmt.Sprintf("/v1/api-keys/%d", rsp.ID))
+	ctx.JSON(http.StatusCreated, createAPIKeyResponse{apiKeyResponse: rsp, Key: key})
+}
+
+// listAPIKeysV1 lists the API keys, requires the api_keys:manage permission.
+func (server *Server) listAPIKeysV1(ctx *gin.Context) {
+	apiKeys, err := server.store.ListAPIKeys(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		rsp = append(rsp, newAPIKeyResponse(apiKey))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getAPIKeyV1 retrieves an API key, requires the api_keys:manage permission.
+func (server *Server) getAPIKeyV1(ctx *gin.Context) {
+	var uri apiKeyURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	apiKey, err := server.store.GetAPIKey(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
+
+// revokeAPIKeyV1 revokes an API key, requires the api_keys:manage permission.
+func (server *Server) revokeAPIKeyV1(ctx *gin.Context) {
+	var uri apiKeyURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	before, err := server.store.GetAPIKey(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if before.RevokedAt.Valid {
+		ctx.JSON(http.StatusOK, newAPIKeyResponse(before))
+		return
+	}
+	var rsp apiKeyResponse
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		apiKey, err := store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+			ID:        before.ID,
+			RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		rsp = newAPIKeyResponse(apiKey)
+		return recordAudit(ctx, store, db.AuditActionRevokeAPIKey, db.AuditEntityAPIKey, apiKey.ID, newAPIKeyResponse(before), rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The events of the security log recorded by the API, besides the lockouts recorded by the login guard.
+const (
+	securityEventTwoFactorEnabled  = "two_factor_enabled"
+	securityEventTwoFactorDisabled = "two_factor_disabled"
+	securityEventRecoveryCodeUsed  = "recovery_code_used"
+)
+
+// recordAudit appends an entry to the audit log for a mutation made by the authenticated user.
+// before and after are the states of the entity around the mutation, nil when it did not exist.
+// store is the one of the transaction applying the mutation, so that no mutation is committed without its entry.
+func recordAudit(ctx *gin.Context, store db.Store, action string, entity string, entityID int32, before, after interface{}) error {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	return db.RecordAudit(ctx, store, db.RecordAuditParams{
+		Username:  authPayload.Username,
+		Action:    action,
+		Entity:    entity,
+		EntityID:  entityID,
+		Before:    before,
+		After:     after,
+		RequestID: ctx.GetString(requestIDKey),
+		ClientIP:  ctx.ClientIP(),
+	})
+}
+
+// recordSecurityEvent appends an entry to the security log about a user. A failure to record is logged and does not
+// fail the request.
+func (server *Server) recordSecurityEvent(ctx *gin.Context, event string, username string, details string) {
+	log.Printf("security: %s, username %q, client IP %s: %s", event, username, ctx.ClientIP(), details)
+	err := server.store.CreateSecurityEvent(ctx, db.CreateSecurityEventParams{
+		Event:    event,
+		Username: sql.NullString{String: username, Valid: true},
+		ClientIp: ctx.ClientIP(),
+		Details:  details,
+	})
+	if err != nil {
+		log.Printf("cannot record security event %s of user %q: %v", event, username, err)
+	}
+}
+
+// auditEntryResponse represents an entry of the audit log.
+type auditEntryResponse struct {
+	// Example: 42
+	ID int64 `json:"id"`
+
+	// The user who made the modification.
+	// Example: vk-admin
+	Username string `json:"username"`
+
+	// The kind of modification, can be: ["create", "update", "delete", "merge", "revoke_tokens", "assign_role", "disable", "enable", "require_password_reset", "revoke_api_key"].
+	// Example: update
+	Action string `json:"action"`
+
+	// The kind of entity that was modified.
+	// Example: movie
+	Entity string `json:"entity"`
+
+	// The ID of the entity that was modified.
+	// Example: 3
+	EntityID int32 `json:"entity_id,omitempty"`
+
+	// The modified fields with their values before and after the modification.
+	// Example: {"rating":{"before":"9.2","after":"9.1"}}
+	Diff json.RawMessage `json:"diff"`
+
+	// The ID of the request that made the modification.
+	RequestID string `json:"request_id"`
+
+	// The IP address of the client that made the modification.
+	// Example: 127.0.0.1
+	ClientIP string `json:"client_ip"`
+
+	// Example: "2024-03-17T10:00:00Z"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newAuditEntryResponse(entry db.AuditLog) auditEntryResponse {
+	return auditEntryResponse{
+		ID:        entry.ID,
+		Username:  entry.Username,
+		Action:    entry.Action,
+		Entity:    entry.Entity,
+		EntityID:  entry.EntityID.Int32,
+		Diff:      entry.Diff,
+		RequestID: entry.RequestID,
+		ClientIP:  entry.ClientIp,
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+// listAuditEntriesRequest represents the query parameters for searching the audit log.
+type listAuditEntriesRequest struct {
+	// The kind of entity that was modified.
+	// Example: movie
+	Entity string `form:"entity"`
+
+	// The ID of the entity that was modified.
+	EntityID int32 `form:"entity_id" binding:"omitempty,min=1"`
+
+	// The user who made the modification.
+	Username string `form:"username"`
+
+	// Only entries recorded at or after this time.
+	// Example: 2024-03-01T00:00:00Z
+	From time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+
+	// Only entries recorded before this time.
+	// Example: 2024-04-01T00:00:00Z
+	To time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+
+	// The page number, starting at 1.
+	// required: true
+	PageID int32 `form:"page_id" binding:"required,min=1"`
+
+	// The number of entries per page.
+	// required: true
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAuditEntries searches the audit log, requires the audit:read permission.
+func (server *Server) listAuditEntries(ctx *gin.Context) {
+	var req listAuditEntriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	arg := db.ListAuditEntriesParams{
+		Entity:     sql.NullString{String: req.Entity, Valid: req.Entity != ""},
+		EntityID:   sql.NullInt32{Int32: req.EntityID, Valid: req.EntityID != 0},
+		Username:   sql.NullString{String: req.Username, Valid: req.Username != ""},
+		FromTime:   sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		ToTime:     sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		PageLimit:  req.PageSize,
+		PageOffset: (req.PageID - 1) * req.PageSize,
+	}
+	entries, err := server.store.ListAuditEntries(ctx, arg)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]auditEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		rsp = append(rsp, newAuditEntryResponse(entry))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listSecurityEventsRequest represents the query parameters for searching the security log.
+type listSecurityEventsRequest struct {
+	// The username the event is about, for the IP lockouts the username of the last failed login.
+	Username string `form:"username" binding:"max=50"`
+
+	// The kind of event.
+	Event string `form:"event" binding:"omitempty,oneof=account_locked ip_locked two_factor_enabled two_factor_disabled recovery_code_used"`
+
+	// The page number, starting at 1.
+	PageID int32 `form:"page_id,default=1" binding:"min=1"`
+
+	// The number of events per page.
+	PageSize int32 `form:"page_size,default=20" binding:"min=5,max=100"`
+}
+
+// securityEventResponse represents an entry of the security log.
+type securityEventResponse struct {
+	// Example: 42
+	ID int64 `json:"id"`
+
+	// The kind of event, can be: ["account_locked", "ip_locked", "two_factor_enabled", "two_factor_disabled", "recovery_code_used"].
+	// Example: account_locked
+	Event string `json:"event"`
+
+	// Example: vk-user
+	Username string `json:"username,omitempty"`
+
+	// The IP address of the client that caused the event.
+	// Example: 127.0.0.1
+	ClientIP string `json:"client_ip"`
+
+	// Example: 5 failed logins within 15m0s, locked until 2024-03-17T10:15:00Z
+	Details string `json:"details"`
+
+	// Example: "2024-03-17T10:00:00Z"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// listSecurityEventsV1 searches the security log, requires the audit:read permission.
+func (server *Server) listSecurityEventsV1(ctx *gin.Context) {
+	var req listSecurityEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	events, err := server.store.ListSecurityEvents(ctx, db.ListSecurityEventsParams{
+		Username:   sql.NullString{String: req.Username, Valid: req.Username != ""},
+		Event:      sql.NullString{String: req.Event, Valid: req.Event != ""},
+		PageLimit:  req.PageSize,
+		PageOffset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]securityEventResponse, 0, len(events))
+	for _, event := range events {
+		rsp = append(rsp, securityEventResponse{
+			ID:        event.ID,
+			Event:     event.Event,
+			Username:  event.Username.String,
+			ClientIP:  event.ClientIp,
+			Details:   event.Details,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// auditStore keeps the audit entries created, the other methods of the store are not used by recordAudit.
+type auditStore struct {
+	db.Store
+	entries []db.CreateAuditEntryParams
+	err     error
+}
+
+func (store *auditStore) CreateAuditEntry(ctx context.Context, arg db.CreateAuditEntryParams) (db.AuditLog, error) {
+	if store.err != nil {
+		return db.AuditLog{}, store.err
+	}
+	store.entries = append(store.entries, arg)
+	return db.AuditLog{}, nil
+}
+
+// newAuditContext returns the context of a request made by username from 10.0.0.1.
+func newAuditContext(username string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPatch, "/v1/movies/3", nil)
+	ctx.Request.RemoteAddr = "10.0.0.1:4242"
+	ctx.Set(authorizationPayload, &token.Payload{Username: username})
+	ctx.Set(requestIDKey, "request-1")
+	return ctx
+}
+
+func TestRecordAudit(t *testing.T) {
+	store := &auditStore{}
+	ctx := newAuditContext("vk-admin")
+	before := db.Movie{ID: 3, Name: "Alien", Rating: "8.4", Version: 1}
+	after := db.Movie{ID: 3, Name: "Alien", Rating: "8.5", Version: 2}
+
+	err := recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityMovie, 3, before, after)
+	require.NoError(t, err)
+	require.Len(t, store.entries, 1)
+	entry := store.entries[0]
+	require.Equal(t, "vk-admin", entry.Username)
+	require.Equal(t, db.AuditActionUpdate, entry.Action)
+	require.Equal(t, db.AuditEntityMovie, entry.Entity)
+	require.Equal(t, int32(3), entry.EntityID.Int32)
+	require.Equal(t, "request-1", entry.RequestID)
+	require.Equal(t, "10.0.0.1", entry.ClientIp)
+
+	// only the modified fields are recorded
+	var diff map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(entry.Diff, &diff))
+	require.Equal(t, map[string]map[string]interface{}{
+		"rating":  {"before": "8.4", "after": "8.5"},
+		"version": {"before": float64(1), "after": float64(2)},
+	}, diff)
+}
+
+func TestRecordAuditOfCreation(t *testing.T) {
+	store := &auditStore{}
+	ctx := newAuditContext(token.APIKeyPrincipal + "billing")
+
+	err := recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityActor, 7, nil, db.Actor{ID: 7, Name: "Sigourney Weaver"})
+	require.NoError(t, err)
+	require.Equal(t, token.APIKeyPrincipal+"billing", store.entries[0].Username)
+	var diff map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(store.entries[0].Diff, &diff))
+	require.Equal(t, map[string]interface{}{"before": nil, "after": "Sigourney Weaver"}, diff["name"])
+}
+
+// TestRecordAuditFailureFailsMutation checks that the error of the entry is returned, so that the transaction of the
+// mutation is rolled back rather than committed without its entry.
+func TestRecordAuditFailureFailsMutation(t *testing.T) {
+	errInsert := errors.New("cannot insert")
+	store := &auditStore{err: errInsert}
+
+	err := recordAudit(newAuditContext("vk-admin"), store, db.AuditActionDelete, db.AuditEntityMovie, 3, db.Movie{ID: 3}, nil)
+	require.ErrorIs(t, err, errInsert)
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	db "vk-film/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheStatsProvider is implemented by stores with a read cache.
+type cacheStatsProvider interface {
+	Stats() db.CacheStats
+}
+
+// getCacheStats returns the read cache counters, requires the cache:read permission.
+func (server *Server) getCacheStats(ctx *gin.Context) {
+	cached, ok := server.store.(cacheStatsProvider)
+	if !ok {
+		writeStatusError(ctx, http.StatusNotFound, errors.New("the read cache is disabled"))
+		return
+	}
+	ctx.JSON(http.StatusOK, cached.Stats())
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cacheControlHeader    = "Cache-Control"
+	lastModifiedHeader    = "Last-Modified"
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+)
+
+// catalogCacheMiddleware adds validators derived from the catalog change counter to the read routes of the catalog
+// and answers 304 Not Modified when the client already holds the current representation.
+// The counter is read before the handler runs, so a concurrent change can only make the validators older than
+// the body, which costs the client one extra full response and never serves it stale data.
+func (server *Server) catalogCacheMiddleware(cacheControl string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+			ctx.Next()
+			return
+		}
+		state, err := server.store.GetCatalogState(ctx)
+		if err != nil {
+			// without the counter the response is simply not cacheable
+			ctx.Header(cacheControlHeader, "no-store")
+			ctx.Next()
+			return
+		}
+		etag := catalogETag(state.Version, ctx.Request.URL.RequestURI())
+		lastModified := state.UpdatedAt.UTC().Truncate(time.Second)
+		headers := http.Header{}
+		headers.Set(etagHeader, etag)
+		headers.Set(lastModifiedHeader, lastModified.Format(http.TimeFormat))
+		if len(cacheControl) > 0 {
+			headers.Set(cacheControlHeader, cacheControl)
+		}
+		if notModified(ctx.Request, etag, lastModified) {
+			for name, values := range headers {
+				ctx.Writer.Header()[name] = values
+			}
+			ctx.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+		ctx.Writer = &validatorWriter{ResponseWriter: ctx.Writer, headers: headers}
+		ctx.Next()
+	}
+}
+
+// validatorWriter adds the validators and the Cache-Control header of a catalog read to its response once the handler
+// writes it, and only to the successful responses: the errors must be neither cached nor revalidated.
+type validatorWriter struct {
+	gin.ResponseWriter
+	headers http.Header
+}
+
+func (w *validatorWriter) WriteHeaderNow() {
+	w.addHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *validatorWriter) Write(data []byte) (int, error) {
+	w.addHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *validatorWriter) WriteString(s string) (int, error) {
+	w.addHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *validatorWriter) addHeaders() {
+	if w.headers == nil || w.Written() {
+		return
+	}
+	if status := w.Status(); status >= 200 && status < 300 {
+		for name, values := range w.headers {
+			w.Header()[name] = values
+		}
+	}
+	w.headers = nil
+}
+
+// catalogETag builds a strong entity tag for a read of the catalog, unique per catalog version and request URI.
+func catalogETag(version int64, requestURI string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(requestURI))
+	return fmt.Sprintf(`"c%d-%x"`, version, hash.Sum32())
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since preconditions of a request,
+// If-Modified-Since is ignored when If-None-Match is present, as required by RFC 9110.
+func notModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := req.Header.Get(ifNoneMatchHeader); len(ifNoneMatch) > 0 {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ifModifiedSince := req.Header.Get(ifModifiedSinceHeader); len(ifModifiedSince) > 0 {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		return !lastModified.After(since)
+	}
+	return false
+}
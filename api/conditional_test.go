@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// catalogStateStore returns the catalog state, the other methods of the store are not used by the cache middleware.
+type catalogStateStore struct {
+	db.Store
+	state db.CatalogState
+	err   error
+}
+
+func (store *catalogStateStore) GetCatalogState(ctx context.Context) (db.CatalogState, error) {
+	return store.state, store.err
+}
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	etag := catalogETag(7, "/v1/movies")
+
+	testCases := []struct {
+		name     string
+		header   map[string]string
+		expected bool
+	}{
+		{
+			name:     "NoPrecondition",
+			expected: false,
+		},
+		{
+			name:     "SameETag",
+			header:   map[string]string{ifNoneMatchHeader: etag},
+			expected: true,
+		},
+		{
+			name:     "ETagInList",
+			header:   map[string]string{ifNoneMatchHeader: `"c6-0", ` + etag},
+			expected: true,
+		},
+		{
+			// If-None-Match uses the weak comparison
+			name:     "WeakETag",
+			header:   map[string]string{ifNoneMatchHeader: "W/" + etag},
+			expected: true,
+		},
+		{
+			name:     "Any",
+			header:   map[string]string{ifNoneMatchHeader: "*"},
+			expected: true,
+		},
+		{
+			name:     "OtherETag",
+			header:   map[string]string{ifNoneMatchHeader: catalogETag(6, "/v1/movies")},
+			expected: false,
+		},
+		{
+			name: "OtherETagIgnoresDate",
+			header: map[string]string{
+				ifNoneMatchHeader:     catalogETag(6, "/v1/movies"),
+				ifModifiedSinceHeader: lastModified.Format(http.TimeFormat),
+			},
+			expected: false,
+		},
+		{
+			name:     "UnmodifiedSince",
+			header:   map[string]string{ifModifiedSinceHeader: lastModified.Format(http.TimeFormat)},
+			expected: true,
+		},
+		{
+			name:     "ModifiedSince",
+			header:   map[string]string{ifModifiedSinceHeader: lastModified.Add(-time.Second).Format(http.TimeFormat)},
+			expected: false,
+		},
+		{
+			name:     "InvalidDate",
+			header:   map[string]string{ifModifiedSinceHeader: "yesterday"},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+			for name, value := range tc.header {
+				req.Header.Set(name, value)
+			}
+			require.Equal(t, tc.expected, notModified(req, etag, lastModified))
+		})
+	}
+}
+
+func TestCatalogETag(t *testing.T) {
+	etag := catalogETag(7, "/v1/movies?page=1")
+	require.Equal(t, etag, catalogETag(7, "/v1/movies?page=1"))
+	require.NotEqual(t, etag, catalogETag(8, "/v1/movies?page=1"))
+	require.NotEqual(t, etag, catalogETag(7, "/v1/movies?page=2"))
+}
+
+// newCatalogCacheRouter returns a router serving status on /v1/movies behind the catalog cache middleware.
+func newCatalogCacheRouter(store db.Store, status int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := &Server{store: store}
+	router := gin.New()
+	router.GET("/v1/movies", server.catalogCacheMiddleware("max-age=60"), func(ctx *gin.Context) {
+		ctx.JSON(status, gin.H{})
+	})
+	return router
+}
+
+func TestCatalogCacheMiddleware(t *testing.T) {
+	updatedAt := time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+	store := &catalogStateStore{state: db.CatalogState{Version: 7, UpdatedAt: updatedAt}}
+	router := newCatalogCacheRouter(store, http.StatusOK)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/movies", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	etag := recorder.Header().Get(etagHeader)
+	require.Equal(t, catalogETag(7, "/v1/movies"), etag)
+	require.Equal(t, "Fri, 01 Mar 2024 12:00:00 GMT", recorder.Header().Get(lastModifiedHeader))
+	require.Equal(t, "max-age=60", recorder.Header().Get(cacheControlHeader))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+	req.Header.Set(ifNoneMatchHeader, etag)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotModified, recorder.Code)
+	require.Empty(t, recorder.Body.String())
+	require.Equal(t, etag, recorder.Header().Get(etagHeader))
+
+	// a change of the catalog changes the entity tag
+	store.state.Version = 8
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestCatalogCacheMiddlewareError(t *testing.T) {
+	router := newCatalogCacheRouter(&catalogStateStore{state: db.CatalogState{Version: 7}}, http.StatusNotFound)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/movies", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Empty(t, recorder.Header().Get(etagHeader))
+	require.Empty(t, recorder.Header().Get(cacheControlHeader))
+
+	router = newCatalogCacheRouter(&catalogStateStore{err: errors.New("connection refused")}, http.StatusOK)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/movies", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "no-store", recorder.Header().Get(cacheControlHeader))
+	require.Empty(t, recorder.Header().Get(etagHeader))
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	locationHeader    = "Location"
+	deprecationHeader = "Deprecation"
+	sunsetHeader      = "Sunset"
+	linkHeader        = "Link"
+	// legacyDateLayout is the layout of LEGACY_API_DEPRECATED_AT and LEGACY_API_SUNSET.
+	legacyDateLayout = "2006-01-02"
+)
+
+// legacyAPI holds the deprecation schedule announced on the unversioned routes.
+type legacyAPI struct {
+	deprecatedAt time.Time
+	sunset       time.Time
+}
+
+// newLegacyAPI parses the deprecation schedule, an empty date leaves the matching header out.
+func newLegacyAPI(deprecatedAt, sunset string) (legacyAPI, error) {
+	var legacy legacyAPI
+	var err error
+	if len(deprecatedAt) > 0 {
+		legacy.deprecatedAt, err = time.Parse(legacyDateLayout, deprecatedAt)
+		if err != nil {
+			return legacy, fmt.Errorf("invalid LEGACY_API_DEPRECATED_AT: %v", err)
+		}
+	}
+	if len(sunset) > 0 {
+		legacy.sunset, err = time.Parse(legacyDateLayout, sunset)
+		if err != nil {
+			return legacy, fmt.Errorf("invalid LEGACY_API_SUNSET: %v", err)
+		}
+	}
+	return legacy, nil
+}
+
+// deprecated marks the responses of a legacy route with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// and links the v1 route replacing it.
+func (server *Server) deprecated(successor string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !server.legacyAPI.deprecatedAt.IsZero() {
+			ctx.Header(deprecationHeader, fmt.Sprintf("@%d", server.legacyAPI.deprecatedAt.Unix()))
+		}
+		if !server.legacyAPI.sunset.IsZero() {
+			ctx.Header(sunsetHeader, server.legacyAPI.sunset.UTC().Format(http.TimeFormat))
+		}
+		ctx.Header(linkHeader, fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		ctx.Next()
+	}
+}
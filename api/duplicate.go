@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/util"
+)
+
+// findDuplicateActors returns the existing actors born on the same day whose name is likely the same.
+func (server *Server) findDuplicateActors(ctx context.Context, name string, birthday time.Time) ([]actorResponse, error) {
+	actors, err := server.likelyDuplicateActors(ctx, name, birthday)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := make([]actorResponse, 0, len(actors))
+	for _, actor := range actors {
+		duplicates = append(duplicates, newActorResponse(actor))
+	}
+	return duplicates, nil
+}
+
+func (server *Server) likelyDuplicateActors(ctx context.Context, name string, birthday time.Time) ([]db.Actor, error) {
+	candidates, err := server.store.ListActorsByBirthday(ctx, birthday)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := []db.Actor{}
+	for _, candidate := range candidates {
+		if util.IsLikelyDuplicateName(name, candidate.Name) {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+	return duplicates, nil
+}
+
+// findDuplicateMovies returns the existing movies released in the same year whose name is likely the same.
+func (server *Server) findDuplicateMovies(ctx context.Context, name string, releaseDate time.Time) ([]movieResponse, error) {
+	movies, err := server.likelyDuplicateMovies(ctx, name, releaseDate)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := make([]movieResponse, 0, len(movies))
+	for _, movie := range movies {
+		duplicates = append(duplicates, newMovieResponse(movie))
+	}
+	return duplicates, nil
+}
+
+func (server *Server) likelyDuplicateMovies(ctx context.Context, name string, releaseDate time.Time) ([]db.Movie, error) {
+	candidates, err := server.store.ListMoviesByReleaseYear(ctx, releaseDate)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := []db.Movie{}
+	for _, candidate := range candidates {
+		if util.IsLikelyDuplicateName(name, candidate.Name) {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+	return duplicates, nil
+}
+
+// mergeRequest represents the request body for merging a duplicate record into a survivor, requires the movies:delete or actors:delete permission.
+type mergeRequest struct {
+	// The ID of the record that is kept.
+	// Required: true
+	// Example: 5
+	SurvivorID int32 `json:"survivor_id" binding:"required,min=1"`
+
+	// The ID of the record that is merged into the survivor and removed.
+	// Required: true
+	// Example: 16
+	DuplicateID int32 `json:"duplicate_id" binding:"required,min=1"`
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/mail"
+	"vk-film/token"
+	"vk-film/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errNoEmail              = errors.New("the user has no email")
+	errEmailAlreadyVerified = errors.New("the email of the user has already been verified")
+)
+
+// updateEmailRequest represents the request body for changing the email of the authenticated user.
+type updateEmailRequest struct {
+	// The new email, a link verifying it is sent to it.
+	// Required: true
+	// Example: vk-user@example.com
+	Email string `json:"email" binding:"required,email,max=254"`
+}
+
+// userTokenRequest represents the request body carrying a token received by email.
+type userTokenRequest struct {
+	// The token of the link received by email.
+	// Required: true
+	Token string `json:"token" binding:"required"`
+}
+
+// forgotPasswordRequest represents the request body for requesting a password reset link.
+type forgotPasswordRequest struct {
+	// The verified email of the user.
+	// Required: true
+	// Example: vk-user@example.com
+	Email string `json:"email" binding:"required,email,max=254"`
+}
+
+// resetPasswordRequest represents the request body for choosing a new password with a reset token.
+type resetPasswordRequest struct {
+	// The token of the password reset link received by email.
+	// Required: true
+	Token string `json:"token" binding:"required"`
+
+	// The new password.
+	// Required: true
+	// Minimum: 6
+	// Example: password456
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// updateEmailV1 changes the email of the authenticated user.
+func (server *Server) updateEmailV1(ctx *gin.Context) {
+	var req updateEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	user, err := server.store.UpdateUserEmail(ctx, db.UpdateUserEmailParams{
+		Username: authPayload.Username,
+		Email:    sql.NullString{String: req.Email, Valid: true},
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	err = server.sendEmailVerification(ctx, user)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// sendEmailVerificationV1 sends the verification link of the email of the authenticated user again.
+func (server *Server) sendEmailVerificationV1(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if !user.Email.Valid {
+		writeError(ctx, errNoEmail)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		writeError(ctx, errEmailAlreadyVerified)
+		return
+	}
+	err = server.sendEmailVerification(ctx, user)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+// verifyEmailV1 verifies an email with the token of its verification link.
+func (server *Server) verifyEmailV1(ctx *gin.Context) {
+	var req userTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		TokenHash:  token.HashOpaqueToken(req.Token),
+		VerifiedAt: time.Now(),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// forgotPasswordV1 sends a password reset link to a verified email.
+// Sends a password reset link to a verified email. The response is the same whether a user has the email or not,
+// so that it does not reveal which emails are registered.
+func (server *Server) forgotPasswordV1(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err == nil && user.EmailVerifiedAt.Valid && !user.DisabledAt.Valid {
+		err = server.sendUserToken(ctx, user, db.UserTokenResetPassword, server.config.PasswordResetURL, server.config.PasswordResetTokenDuration,
+			"Reset your password",
+			"Hello %s,\n\nChoose a new password by opening the link below, it expires in %s:\n\n%s\n\n"+
+				"If you did not ask to reset your password, ignore this email, your password does not change.\n")
+	}
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("cannot send the password reset of %s: %v", req.Email, err)
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+// resetPasswordV1 replaces the password of a user with the token of a password reset link.
+// Replaces the password of a user with the token of a password reset link, a token works once.
+// The tokens and the sessions issued before are rejected.
+func (server *Server) resetPasswordV1(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	user, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      token.HashOpaqueToken(req.Token),
+		HashedPassword: hashedPassword,
+		ChangedAt:      time.Now(),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.revocations.ForgetUser(user.Username)
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// sendEmailVerification sends a link verifying the email of a user.
+func (server *Server) sendEmailVerification(ctx *gin.Context, user db.User) error {
+	return server.sendUserToken(ctx, user, db.UserTokenVerifyEmail, server.config.EmailVerificationURL, server.config.EmailVerificationTokenDuration,
+		"Verify your email",
+		"Hello %s,\n\nConfirm your email by opening the link below, it expires in %s:\n\n%s\n")
+}
+
+// sendUserToken stores a single-use token for a purpose and emails a link carrying it to the user.
+// The body is formatted with the username, the duration of the token and the link.
+func (server *Server) sendUserToken(ctx *gin.Context, user db.User, purpose string, linkURL string, duration time.Duration, subject string, body string) error {
+	secret, _, err := createUserToken(ctx, server.store, user, purpose, duration)
+	if err != nil {
+		return err
+	}
+	return server.mailUserToken(ctx, user, secret, linkURL, duration, subject, body)
+}
+
+// createUserToken stores a single-use token for a purpose, the tokens of the same user and purpose not used yet
+// stop working. It returns the token and the time it expires.
+func createUserToken(ctx *gin.Context, store db.Store, user db.User, purpose string, duration time.Duration) (string, time.Time, error) {
+	secret, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(duration)
+	err = store.CreateUserTokenTx(ctx, db.CreateUserTokenParams{
+		TokenHash: hash,
+		Username:  user.Username,
+		Purpose:   purpose,
+		Email:     user.Email.String,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return secret, expiresAt, nil
+}
+
+// mailUserToken emails a link carrying a single-use token to the user.
+func (server *Server) mailUserToken(ctx *gin.Context, user db.User, secret string, linkURL string, duration time.Duration, subject string, body string) error {
+	link, err := url.Parse(linkURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", secret)
+	link.RawQuery = query.Encode()
+	return server.mailer.Send(ctx, mail.Message{
+		To:      user.Email.String,
+		Subject: subject,
+		Body:    fmt.Sprintf(body, user.Username, duration, link.String()),
+	})
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+	"vk-film/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func (store *userStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	for _, user := range store.users {
+		if user.Email.Valid && user.Email.String == email {
+			return user, nil
+		}
+	}
+	return db.User{}, sql.ErrNoRows
+}
+
+// ResetPasswordTx uses a reset token stored by CreateUserTokenTx like the SQL store: the token works once,
+// and the other reset tokens of its user stop working.
+func (store *userStore) ResetPasswordTx(ctx context.Context, arg db.ResetPasswordTxParams) (db.User, error) {
+	for _, userToken := range store.userTokens {
+		if userToken.Purpose == db.UserTokenResetPassword && bytes.Equal(userToken.TokenHash, arg.TokenHash) &&
+			userToken.ExpiresAt.After(arg.ChangedAt) {
+			remaining := store.userTokens[:0]
+			for _, other := range store.userTokens {
+				if other.Username != userToken.Username || other.Purpose != db.UserTokenResetPassword {
+					remaining = append(remaining, other)
+				}
+			}
+			store.userTokens = remaining
+			return store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+				Username:          userToken.Username,
+				HashedPassword:    arg.HashedPassword,
+				PasswordChangedAt: arg.ChangedAt,
+			})
+		}
+	}
+	return db.User{}, db.ErrInvalidUserToken
+}
+
+// postJSON serves a POST of body to path through router.
+func postJSON(router *gin.Engine, path string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return recorder
+}
+
+func TestResetPasswordV1(t *testing.T) {
+	user := newTestUser(t, "alice", "secret1")
+	user.PasswordResetRequired = true
+	user.Email = sql.NullString{String: "alice@example.com", Valid: true}
+	user.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	store := newUserStore(user)
+	mailer := &testMailer{}
+	server := newUserServer(t, store, mailer)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/users/password/forgot", server.forgotPasswordV1)
+	router.POST("/v1/users/password/reset", server.resetPasswordV1)
+
+	recorder := postJSON(router, "/v1/users/password/forgot", `{"email": "alice@example.com"}`)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	require.Len(t, mailer.messages, 1)
+	resetToken := resetLinkToken(t, mailer.messages[0].Body)
+
+	recorder = postJSON(router, "/v1/users/password/reset", `{"token": "`+resetToken+`x", "new_password": "secret2"}`)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	var got problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Equal(t, "invalid_token", got.Code)
+
+	// the reset replaces the password and completes the forced reset
+	recorder = postJSON(router, "/v1/users/password/reset", `{"token": "`+resetToken+`", "new_password": "secret2"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	reset := store.users["alice"]
+	require.False(t, reset.PasswordResetRequired)
+	require.NoError(t, util.CheckPassword("secret2", reset.HashedPassword))
+	require.NoError(t, checkUserCanLogIn(reset))
+
+	// a token works once
+	recorder = postJSON(router, "/v1/users/password/reset", `{"token": "`+resetToken+`", "new_password": "secret3"}`)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.NoError(t, util.CheckPassword("secret2", store.users["alice"].HashedPassword))
+}
+
+func TestResetPasswordV1ReplacedToken(t *testing.T) {
+	store := newUserStore(newTestUser(t, "alice", "secret1"))
+	server := newUserServer(t, store, &testMailer{})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/users/password/reset", server.resetPasswordV1)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	first, _, err := createUserToken(ctx, store, store.users["alice"], db.UserTokenResetPassword, time.Hour)
+	require.NoError(t, err)
+	second, _, err := createUserToken(ctx, store, store.users["alice"], db.UserTokenResetPassword, time.Hour)
+	require.NoError(t, err)
+
+	// only the last token issued to a user works
+	recorder := postJSON(router, "/v1/users/password/reset", `{"token": "`+first+`", "new_password": "secret2"}`)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = postJSON(router, "/v1/users/password/reset", `{"token": "`+second+`", "new_password": "secret2"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestResetPasswordV1InvalidPassword(t *testing.T) {
+	store := newUserStore(newTestUser(t, "alice", "secret1"))
+	server := newUserServer(t, store, &testMailer{})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/users/password/reset", server.resetPasswordV1)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	resetToken, _, err := createUserToken(ctx, store, store.users["alice"], db.UserTokenResetPassword, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, token.HashOpaqueToken(resetToken), store.userTokens[0].TokenHash)
+
+	// a rejected password does not use the token
+	recorder := postJSON(router, "/v1/users/password/reset", `{"token": "`+resetToken+`", "new_password": "short"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	require.Len(t, store.userTokens, 1)
+}
+
+func TestForgotPasswordV1(t *testing.T) {
+	verified := sql.NullTime{Time: time.Now(), Valid: true}
+	testCases := []struct {
+		name   string
+		user   func(user *db.User)
+		mailed bool
+	}{
+		{
+			name:   "VerifiedEmail",
+			user:   func(user *db.User) { user.EmailVerifiedAt = verified },
+			mailed: true,
+		},
+		{
+			name: "UnverifiedEmail",
+			user: func(user *db.User) {},
+		},
+		{
+			name: "DisabledUser",
+			user: func(user *db.User) {
+				user.EmailVerifiedAt = verified
+				user.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := newTestUser(t, "alice", "secret1")
+			user.Email = sql.NullString{String: "alice@example.com", Valid: true}
+			tc.user(&user)
+			store := newUserStore(user)
+			mailer := &testMailer{}
+			server := newUserServer(t, store, mailer)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/v1/users/password/forgot", server.forgotPasswordV1)
+
+			// the response never reveals whether the email is registered
+			require.Equal(t, http.StatusAccepted, postJSON(router, "/v1/users/password/forgot", `{"email": "alice@example.com"}`).Code)
+			require.Equal(t, http.StatusAccepted, postJSON(router, "/v1/users/password/forgot", `{"email": "bob@example.com"}`).Code)
+			if tc.mailed {
+				require.Len(t, mailer.messages, 1)
+				require.Len(t, store.userTokens, 1)
+			} else {
+				require.Empty(t, mailer.messages)
+				require.Empty(t, store.userTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	etagHeader    = "ETag"
+	ifMatchHeader = "If-Match"
+)
+
+var (
+	errPreconditionRequired = errors.New("the If-Match header is required to modify this resource")
+	errPreconditionFailed   = errors.New("the resource has been modified since it was read, fetch it again")
+	errInvalidIfMatch       = errors.New("the If-Match header must be * or a list of entity tags")
+)
+
+// versionETag builds the strong entity tag of a resource version.
+func versionETag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// expectedVersion reads the If-Match header of a write on a resource whose current version is known.
+// The returned version is invalid when the client accepts any version of the resource.
+func (server *Server) expectedVersion(ctx *gin.Context, current int32) (sql.NullInt32, error) {
+	ifMatch := strings.TrimSpace(strings.Join(ctx.Request.Header.Values(ifMatchHeader), ","))
+	if len(ifMatch) == 0 {
+		if server.config.RequireIfMatch {
+			return sql.NullInt32{}, errPreconditionRequired
+		}
+		return sql.NullInt32{}, nil
+	}
+	if ifMatch == "*" {
+		return sql.NullInt32{}, nil
+	}
+	// If-Match uses the strong comparison of RFC 9110, a weak tag never matches
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			return sql.NullInt32{}, errInvalidIfMatch
+		}
+		if weak {
+			continue
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 32)
+		if err == nil && int32(version) == current {
+			return sql.NullInt32{Int32: current, Valid: true}, nil
+		}
+	}
+	return sql.NullInt32{}, errPreconditionFailed
+}
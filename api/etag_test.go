@@ -0,0 +1,101 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"vk-film/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// newIfMatchContext returns the context of a write whose If-Match header lines are ifMatch.
+func newIfMatchContext(ifMatch ...string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPut, "/v1/movies/3", nil)
+	for _, value := range ifMatch {
+		ctx.Request.Header.Add(ifMatchHeader, value)
+	}
+	return ctx
+}
+
+func TestVersionETag(t *testing.T) {
+	require.Equal(t, `"7"`, versionETag(7))
+}
+
+func TestExpectedVersion(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ifMatch         []string
+		requireIfMatch  bool
+		expectedVersion sql.NullInt32
+		expectedErr     error
+	}{
+		{
+			name: "NoHeader",
+		},
+		{
+			name:           "NoHeaderRequired",
+			requireIfMatch: true,
+			expectedErr:    errPreconditionRequired,
+		},
+		{
+			name:           "Any",
+			ifMatch:        []string{"*"},
+			requireIfMatch: true,
+		},
+		{
+			name:            "Current",
+			ifMatch:         []string{`"4"`},
+			expectedVersion: sql.NullInt32{Int32: 4, Valid: true},
+		},
+		{
+			name:            "CurrentInList",
+			ifMatch:         []string{`"2", "4"`},
+			expectedVersion: sql.NullInt32{Int32: 4, Valid: true},
+		},
+		{
+			name:            "CurrentOnAnotherLine",
+			ifMatch:         []string{`"2"`, `"4"`},
+			expectedVersion: sql.NullInt32{Int32: 4, Valid: true},
+		},
+		{
+			name:        "Stale",
+			ifMatch:     []string{`"3"`},
+			expectedErr: errPreconditionFailed,
+		},
+		{
+			name:        "Weak",
+			ifMatch:     []string{`W/"4"`},
+			expectedErr: errPreconditionFailed,
+		},
+		{
+			name:        "NotAVersion",
+			ifMatch:     []string{`"abc"`},
+			expectedErr: errPreconditionFailed,
+		},
+		{
+			name:        "Unquoted",
+			ifMatch:     []string{"4"},
+			expectedErr: errInvalidIfMatch,
+		},
+		{
+			name:        "Empty",
+			ifMatch:     []string{`"3",`},
+			expectedErr: errInvalidIfMatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &Server{config: util.Config{RequireIfMatch: tc.requireIfMatch}}
+
+			version, err := server.expectedVersion(newIfMatchContext(tc.ifMatch...), 4)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedVersion, version)
+		})
+	}
+}
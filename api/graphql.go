@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	// graphqlDefaultLimit is the page size of the list queries when no limit is given.
+	graphqlDefaultLimit = 20
+	// graphqlMaxLimit is the largest page size of the list queries.
+	graphqlMaxLimit = 100
+	// graphqlRelationCost is the estimated number of items of a nested list, used to compute the complexity.
+	graphqlRelationCost = 10
+)
+
+// graphqlListCosts are the estimated numbers of items returned by the list fields without a limit argument.
+var graphqlListCosts = map[string]int{
+	"movies":      graphqlDefaultLimit,
+	"actors":      graphqlDefaultLimit,
+	"cast":        graphqlRelationCost,
+	"filmography": graphqlRelationCost,
+}
+
+// graphqlRequest represents a GraphQL query or mutation.
+type graphqlRequest struct {
+	// The GraphQL document.
+	// Required: true
+	// Example: { movies(limit: 5) { name cast { name } } }
+	Query string `json:"query" binding:"required"`
+
+	// The operation to execute when the document contains several.
+	OperationName string `json:"operationName"`
+
+	// The values of the variables of the operation.
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// graphqlContext is the per-request state available to the resolvers.
+type graphqlContext struct {
+	ctx     *gin.Context
+	loaders *graphqlLoaders
+}
+
+type graphqlContextKey struct{}
+
+// requestContext returns the state of the request a resolver runs for.
+func requestContext(p graphql.ResolveParams) *graphqlContext {
+	return p.Context.Value(graphqlContextKey{}).(*graphqlContext)
+}
+
+// graphql executes a GraphQL query or mutation over the catalog. Queries deeper or more complex than the configured
+// limits are rejected, mutations require the same permissions as their REST counterparts.
+func (server *Server) graphql(ctx *gin.Context) {
+	var req graphqlRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	document, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	validation := graphql.ValidateDocument(&server.graphqlSchema, document, nil)
+	if !validation.IsValid {
+		ctx.JSON(http.StatusBadRequest, graphql.Result{Errors: validation.Errors})
+		return
+	}
+	if err := server.checkQueryLimits(document, req.OperationName, req.Variables); err != nil {
+		ctx.JSON(http.StatusBadRequest, graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	requestCtx := context.WithValue(ctx.Request.Context(), graphqlContextKey{}, &graphqlContext{
+		ctx:     ctx,
+		loaders: server.newGraphQLLoaders(ctx),
+	})
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        server.graphqlSchema,
+		AST:           document,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       requestCtx,
+	})
+	for i, formatted := range result.Errors {
+		result.Errors[i] = graphqlError(ctx, formatted)
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// graphqlError maps the error returned by a resolver through the problem layer, like the errors of the REST handlers:
+// its message is the detail of the problem, so that the internal errors are reported without the SQL details,
+// and the code, the status and the invalid fields of the problem are added to its extensions.
+// The errors raised by the GraphQL engine itself are kept as they are.
+func graphqlError(ctx *gin.Context, formatted gqlerrors.FormattedError) gqlerrors.FormattedError {
+	located, ok := formatted.OriginalError().(*gqlerrors.Error)
+	if !ok || located.OriginalError == nil {
+		return formatted
+	}
+	p := newProblem(ctx, http.StatusInternalServerError, located.OriginalError)
+	extensions := map[string]interface{}{
+		"code":   p.Code,
+		"status": p.Status,
+	}
+	if len(p.Errors) > 0 {
+		extensions["errors"] = p.Errors
+	}
+	for name, value := range formatted.Extensions {
+		extensions[name] = value
+	}
+	return gqlerrors.FormattedError{
+		Message:    p.Detail,
+		Locations:  formatted.Locations,
+		Path:       formatted.Path,
+		Extensions: extensions,
+	}
+}
+
+// checkQueryLimits rejects operations nested deeper than GRAPHQL_MAX_DEPTH or whose estimated
+// number of resolved fields exceeds GRAPHQL_MAX_COMPLEXITY. Introspection fields are not counted.
+func (server *Server) checkQueryLimits(document *ast.Document, operationName string, variables map[string]interface{}) error {
+	measure := queryMeasure{
+		variables: variables,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		measured:  make(map[string][2]int),
+	}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			measure.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return fmt.Errorf("unknown operation %q", operationName)
+	}
+	depth, complexity := measure.selectionSet(operation.SelectionSet)
+	if depth > server.config.GraphQLMaxDepth {
+		return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, server.config.GraphQLMaxDepth)
+	}
+	if complexity > server.config.GraphQLMaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, server.config.GraphQLMaxComplexity)
+	}
+	return nil
+}
+
+type queryMeasure struct {
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	// measured caches the depth and complexity of the fragments already visited
+	measured map[string][2]int
+}
+
+// selectionSet returns the depth and the complexity of a selection set. The complexity of a field is 1 plus the
+// complexity of its selection multiplied by the number of items it is expected to return.
+func (measure *queryMeasure) selectionSet(set *ast.SelectionSet) (depth int, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var selectionDepth, selectionComplexity int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			childDepth, childComplexity := measure.selectionSet(selection.SelectionSet)
+			selectionDepth = childDepth + 1
+			selectionComplexity = 1 + measure.listSize(selection)*childComplexity
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			measured, ok := measure.measured[name]
+			if !ok {
+				if fragment, found := measure.fragments[name]; found {
+					measured[0], measured[1] = measure.selectionSet(fragment.SelectionSet)
+				}
+				measure.measured[name] = measured
+			}
+			selectionDepth, selectionComplexity = measured[0], measured[1]
+		case *ast.InlineFragment:
+			selectionDepth, selectionComplexity = measure.selectionSet(selection.SelectionSet)
+		}
+		depth = max(depth, selectionDepth)
+		complexity += selectionComplexity
+	}
+	return depth, complexity
+}
+
+// listSize returns the number of items a field is expected to return, taken from its limit argument when set.
+func (measure *queryMeasure) listSize(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if limit, err := strconv.Atoi(value.Value); err == nil {
+				return clampLimit(limit)
+			}
+		case *ast.Variable:
+			switch limit := measure.variables[value.Name.Value].(type) {
+			case float64:
+				return clampLimit(int(limit))
+			case int:
+				return clampLimit(limit)
+			}
+		}
+	}
+	if size, ok := graphqlListCosts[field.Name.Value]; ok {
+		return size
+	}
+	return 1
+}
+
+// clampLimit keeps out of range limits, rejected later by the resolvers, from lowering the complexity.
+func clampLimit(limit int) int {
+	return min(max(limit, 1), graphqlMaxLimit)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"sync"
+	db "vk-film/db/sqlc"
+)
+
+// batchLoader collects the keys requested while resolving one level of a GraphQL query and fetches them all
+// with a single query when the first of their values is needed, instead of one query per parent object.
+// It relies on the executor resolving every field of a level before calling the thunks it returned.
+type batchLoader[K comparable, V any] struct {
+	mu      sync.Mutex
+	fetch   func(keys []K) (map[K]V, error)
+	pending []K
+	queued  map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:  fetch,
+		queued: make(map[K]bool),
+		values: make(map[K]V),
+		errs:   make(map[K]error),
+	}
+}
+
+// load queues a key and returns a thunk resolving to its value, in the form expected by graphql-go.
+func (loader *batchLoader[K, V]) load(key K) func() (interface{}, error) {
+	loader.mu.Lock()
+	if !loader.loaded(key) && !loader.queued[key] {
+		loader.queued[key] = true
+		loader.pending = append(loader.pending, key)
+	}
+	loader.mu.Unlock()
+
+	return func() (interface{}, error) {
+		loader.mu.Lock()
+		defer loader.mu.Unlock()
+		if !loader.loaded(key) {
+			loader.dispatch()
+		}
+		if err := loader.errs[key]; err != nil {
+			return nil, err
+		}
+		return loader.values[key], nil
+	}
+}
+
+// loaded must be called with the lock held.
+func (loader *batchLoader[K, V]) loaded(key K) bool {
+	_, done := loader.values[key]
+	_, failed := loader.errs[key]
+	return done || failed
+}
+
+// dispatch fetches every pending key, it must be called with the lock held.
+func (loader *batchLoader[K, V]) dispatch() {
+	keys := loader.pending
+	loader.pending = nil
+	values, err := loader.fetch(keys)
+	for _, key := range keys {
+		delete(loader.queued, key)
+		if err != nil {
+			loader.errs[key] = err
+			continue
+		}
+		loader.values[key] = values[key]
+	}
+}
+
+// graphqlLoaders holds the batch loaders of a single GraphQL request, values are never shared between requests.
+type graphqlLoaders struct {
+	cast        *batchLoader[int32, []db.Actor]
+	filmography *batchLoader[int32, []db.Movie]
+}
+
+func (server *Server) newGraphQLLoaders(ctx context.Context) *graphqlLoaders {
+	return &graphqlLoaders{
+		cast: newBatchLoader(func(movieIDs []int32) (map[int32][]db.Actor, error) {
+			rows, err := server.store.ListMovieCast(ctx, movieIDs)
+			if err != nil {
+				return nil, err
+			}
+			cast := make(map[int32][]db.Actor, len(movieIDs))
+			for _, id := range movieIDs {
+				cast[id] = []db.Actor{}
+			}
+			for _, row := range rows {
+				cast[row.MovieID] = append(cast[row.MovieID], db.Actor{
+					ID:       row.ID,
+					Name:     row.Name,
+					Gender:   row.Gender,
+					Birthday: row.Birthday,
+					Version:  row.Version,
+				})
+			}
+			return cast, nil
+		}),
+		filmography: newBatchLoader(func(actorIDs []int32) (map[int32][]db.Movie, error) {
+			rows, err := server.store.ListActorFilmography(ctx, actorIDs)
+			if err != nil {
+				return nil, err
+			}
+			filmography := make(map[int32][]db.Movie, len(actorIDs))
+			for _, id := range actorIDs {
+				filmography[id] = []db.Movie{}
+			}
+			for _, row := range rows {
+				filmography[row.ActorID] = append(filmography[row.ActorID], db.Movie{
+					ID:          row.ID,
+					Name:        row.Name,
+					Description: row.Description,
+					ReleaseDate: row.ReleaseDate,
+					Rating:      row.Rating,
+					Version:     row.Version,
+				})
+			}
+			return filmography, nil
+		}),
+	}
+}
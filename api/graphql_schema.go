@@ -0,0 +1,684 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const graphqlDateLayout = "2006-01-02"
+
+var (
+	errExpectedVersionRequired = errors.New("expectedVersion is required to modify this resource")
+	errInvalidLimit            = fmt.Errorf("limit must be between 1 and %d", graphqlMaxLimit)
+	errInvalidOffset           = errors.New("offset cannot be negative")
+	errNullDescription         = errors.New("description cannot be null")
+)
+
+// graphqlDate is a calendar date, formatted as YYYY-MM-DD like the dates of the REST API requests.
+var graphqlDate = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Date",
+	Description: "A calendar date formatted as YYYY-MM-DD.",
+	Serialize: func(value interface{}) interface{} {
+		switch value := value.(type) {
+		case time.Time:
+			return value.Format(graphqlDateLayout)
+		case *time.Time:
+			if value != nil {
+				return value.Format(graphqlDateLayout)
+			}
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if value, ok := value.(string); ok {
+			if date, err := time.Parse(graphqlDateLayout, value); err == nil {
+				return date
+			}
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		if value, ok := valueAST.(*ast.StringValue); ok {
+			if date, err := time.Parse(graphqlDateLayout, value.Value); err == nil {
+				return date
+			}
+		}
+		return nil
+	},
+})
+
+var movieSortEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "MovieSort",
+	Values: graphql.EnumValueConfigMap{
+		"RATING":       &graphql.EnumValueConfig{Value: "rating", Description: "Highest rated first."},
+		"NAME":         &graphql.EnumValueConfig{Value: "name", Description: "Alphabetical order."},
+		"RELEASE_DATE": &graphql.EnumValueConfig{Value: "release_date", Description: "Most recent first."},
+	},
+})
+
+var genderEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Gender",
+	Values: graphql.EnumValueConfigMap{
+		"male":   &graphql.EnumValueConfig{Value: "male"},
+		"female": &graphql.EnumValueConfig{Value: "female"},
+		"other":  &graphql.EnumValueConfig{Value: "other"},
+	},
+})
+
+// newGraphQLSchema builds the schema of the /graphql endpoint, its resolvers use the server store.
+func (server *Server) newGraphQLSchema() (graphql.Schema, error) {
+	movieType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Movie",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: movieField(func(m db.Movie) interface{} { return m.ID })},
+			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: movieField(func(m db.Movie) interface{} { return m.Name })},
+			"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: movieField(func(m db.Movie) interface{} { return m.Description })},
+			"releaseDate": &graphql.Field{Type: graphql.NewNonNull(graphqlDate), Resolve: movieField(func(m db.Movie) interface{} { return m.ReleaseDate })},
+			"rating":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: movieField(func(m db.Movie) interface{} { return m.Rating })},
+			"version":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: movieField(func(m db.Movie) interface{} { return m.Version })},
+		},
+	})
+	actorType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Actor",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: actorField(func(a db.Actor) interface{} { return a.ID })},
+			"name":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: actorField(func(a db.Actor) interface{} { return a.Name })},
+			"gender":   &graphql.Field{Type: graphql.NewNonNull(genderEnum), Resolve: actorField(func(a db.Actor) interface{} { return a.Gender })},
+			"birthday": &graphql.Field{Type: graphql.NewNonNull(graphqlDate), Resolve: actorField(func(a db.Actor) interface{} { return a.Birthday })},
+			"version":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: actorField(func(a db.Actor) interface{} { return a.Version })},
+			"filmography": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(movieType))),
+				Description: "The movies the actor played in, most recent first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return requestContext(p).loaders.filmography.load(p.Source.(db.Actor).ID), nil
+				},
+			},
+		},
+	})
+	movieType.AddFieldConfig("cast", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(actorType))),
+		Description: "The actors playing in the movie.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return requestContext(p).loaders.cast.load(p.Source.(db.Movie).ID), nil
+		},
+	})
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"username":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: userField(func(u db.User) interface{} { return u.Username })},
+			"role":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: userField(func(u db.User) interface{} { return u.Role })},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: userField(func(u db.User) interface{} { return u.CreatedAt })},
+		},
+	})
+	createMoviePayloadType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CreateMoviePayload",
+		Fields: graphql.Fields{
+			"movie":              &graphql.Field{Type: graphql.NewNonNull(movieType)},
+			"possibleDuplicates": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(movieType)))},
+		},
+	})
+	createActorPayloadType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CreateActorPayload",
+		Fields: graphql.Fields{
+			"actor":              &graphql.Field{Type: graphql.NewNonNull(actorType)},
+			"possibleDuplicates": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(actorType)))},
+		},
+	})
+
+	withPageArgs := func(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args["limit"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphqlDefaultLimit}
+		args["offset"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0}
+		return args
+	}
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"movie": &graphql.Field{
+				Type:        movieType,
+				Description: "A movie by ID, IDs of movies merged into another one resolve to the survivor.",
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve:     server.resolveMovie,
+			},
+			"movies": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(movieType))),
+				Description: "Movies matching the filters, one page at a time.",
+				Args: withPageArgs(graphql.FieldConfigArgument{
+					"name":           &graphql.ArgumentConfig{Type: graphql.String, Description: "A fragment of the name, case insensitive."},
+					"releasedAfter":  &graphql.ArgumentConfig{Type: graphqlDate},
+					"releasedBefore": &graphql.ArgumentConfig{Type: graphqlDate},
+					"minRating":      &graphql.ArgumentConfig{Type: graphql.Float},
+					"sortBy":         &graphql.ArgumentConfig{Type: movieSortEnum, DefaultValue: "rating"},
+				}),
+				Resolve: server.resolveMovies,
+			},
+			"actor": &graphql.Field{
+				Type:        actorType,
+				Description: "An actor by ID, IDs of actors merged into another one resolve to the survivor.",
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve:     server.resolveActor,
+			},
+			"actors": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(actorType))),
+				Description: "Actors matching the filters in alphabetical order, one page at a time.",
+				Args: withPageArgs(graphql.FieldConfigArgument{
+					"name":       &graphql.ArgumentConfig{Type: graphql.String, Description: "A fragment of the name, case insensitive."},
+					"gender":     &graphql.ArgumentConfig{Type: genderEnum},
+					"bornAfter":  &graphql.ArgumentConfig{Type: graphqlDate},
+					"bornBefore": &graphql.ArgumentConfig{Type: graphqlDate},
+				}),
+				Resolve: server.resolveActors,
+			},
+			"me": &graphql.Field{
+				Type:        graphql.NewNonNull(userType),
+				Description: "The authenticated user.",
+				Resolve:     server.resolveMe,
+			},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "A user by username, requires the users:manage permission.",
+				Args:        graphql.FieldConfigArgument{"username": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve:     server.resolveUser,
+			},
+		},
+	})
+
+	movieInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "MovieInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"releaseDate": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphqlDate)},
+			"rating":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	movieUpdateInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "MovieUpdateInput",
+		Description: "The fields to change, the omitted ones keep their value.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"releaseDate": &graphql.InputObjectFieldConfig{Type: graphqlDate},
+			"rating":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	actorInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ActorInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"gender":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(genderEnum)},
+			"birthday": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphqlDate)},
+		},
+	})
+	actorUpdateInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ActorUpdateInput",
+		Description: "The fields to change, the omitted ones keep their value.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"gender":   &graphql.InputObjectFieldConfig{Type: genderEnum},
+			"birthday": &graphql.InputObjectFieldConfig{Type: graphqlDate},
+		},
+	})
+	writeArgs := func(input graphql.Input) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{
+			"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"input":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)},
+			"expectedVersion": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Fails the mutation when the current version differs."},
+		}
+	}
+	deleteArgs := graphql.FieldConfigArgument{
+		"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"expectedVersion": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Fails the mutation when the current version differs."},
+	}
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createMovie": &graphql.Field{
+				Type:    graphql.NewNonNull(createMoviePayloadType),
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieInput)}},
+				Resolve: server.resolveCreateMovie,
+			},
+			"updateMovie": &graphql.Field{
+				Type:    graphql.NewNonNull(movieType),
+				Args:    writeArgs(movieUpdateInput),
+				Resolve: server.resolveUpdateMovie,
+			},
+			"deleteMovie": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Deletes a movie and returns its ID.",
+				Args:        deleteArgs,
+				Resolve:     server.resolveDeleteMovie,
+			},
+			"createActor": &graphql.Field{
+				Type:    graphql.NewNonNull(createActorPayloadType),
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(actorInput)}},
+				Resolve: server.resolveCreateActor,
+			},
+			"updateActor": &graphql.Field{
+				Type:    graphql.NewNonNull(actorType),
+				Args:    writeArgs(actorUpdateInput),
+				Resolve: server.resolveUpdateActor,
+			},
+			"deleteActor": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Deletes an actor and returns its ID.",
+				Args:        deleteArgs,
+				Resolve:     server.resolveDeleteActor,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType,
+		Mutation: mutationType,
+	})
+}
+
+func movieField(get func(db.Movie) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(db.Movie)), nil
+	}
+}
+
+func actorField(get func(db.Actor) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(db.Actor)), nil
+	}
+}
+
+func userField(get func(db.User) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(db.User)), nil
+	}
+}
+
+// pageArgs reads the limit and offset arguments of a list query.
+func pageArgs(args map[string]interface{}) (limit int32, offset int32, err error) {
+	limitArg, _ := args["limit"].(int)
+	offsetArg, _ := args["offset"].(int)
+	if limitArg < 1 || limitArg > graphqlMaxLimit {
+		return 0, 0, errInvalidLimit
+	}
+	if offsetArg < 0 {
+		return 0, 0, errInvalidOffset
+	}
+	return int32(limitArg), int32(offsetArg), nil
+}
+
+func nullString(args map[string]interface{}, name string) sql.NullString {
+	value, ok := args[name].(string)
+	return sql.NullString{String: value, Valid: ok}
+}
+
+func nullTime(args map[string]interface{}, name string) sql.NullTime {
+	value, ok := args[name].(time.Time)
+	return sql.NullTime{Time: value, Valid: ok}
+}
+
+// graphqlExpectedVersion is the GraphQL counterpart of expectedVersion, the version is sent as the expectedVersion argument.
+func (server *Server) graphqlExpectedVersion(args map[string]interface{}, current int32) (sql.NullInt32, error) {
+	version, ok := args["expectedVersion"].(int)
+	if !ok {
+		if server.config.RequireIfMatch {
+			return sql.NullInt32{}, errExpectedVersionRequired
+		}
+		return sql.NullInt32{}, nil
+	}
+	if int32(version) != current {
+		return sql.NullInt32{}, errPreconditionFailed
+	}
+	return sql.NullInt32{Int32: int32(version), Valid: true}, nil
+}
+
+func (server *Server) resolveMovie(p graphql.ResolveParams) (interface{}, error) {
+	movie, err := server.store.GetMovie(p.Context, int32(p.Args["id"].(int)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return movie, nil
+}
+
+func (server *Server) resolveMovies(p graphql.ResolveParams) (interface{}, error) {
+	limit, offset, err := pageArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	arg := db.ListMoviesParams{
+		Name:           nullString(p.Args, "name"),
+		ReleasedAfter:  nullTime(p.Args, "releasedAfter"),
+		ReleasedBefore: nullTime(p.Args, "releasedBefore"),
+		SortBy:         p.Args["sortBy"].(string),
+		PageLimit:      limit,
+		PageOffset:     offset,
+	}
+	if minRating, ok := p.Args["minRating"].(float64); ok {
+		arg.MinRating = sql.NullString{String: strconv.FormatFloat(minRating, 'f', -1, 64), Valid: true}
+	}
+	return server.store.ListMovies(p.Context, arg)
+}
+
+func (server *Server) resolveActor(p graphql.ResolveParams) (interface{}, error) {
+	actor, err := server.store.GetActor(p.Context, int32(p.Args["id"].(int)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return actor, nil
+}
+
+func (server *Server) resolveActors(p graphql.ResolveParams) (interface{}, error) {
+	limit, offset, err := pageArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	arg := db.ListActorsParams{
+		Name:       nullString(p.Args, "name"),
+		Gender:     nullString(p.Args, "gender"),
+		BornAfter:  nullTime(p.Args, "bornAfter"),
+		BornBefore: nullTime(p.Args, "bornBefore"),
+		PageLimit:  limit,
+		PageOffset: offset,
+	}
+	return server.store.ListActors(p.Context, arg)
+}
+
+func (server *Server) resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	authPayload := requestContext(p).ctx.MustGet(authorizationPayload).(*token.Payload)
+	return server.store.GetUser(p.Context, authPayload.Username)
+}
+
+func (server *Server) resolveUser(p graphql.ResolveParams) (interface{}, error) {
+	if err := server.checkPermission(requestContext(p).ctx, authz.UsersManage); err != nil {
+		return nil, err
+	}
+	user, err := server.store.GetUser(p.Context, p.Args["username"].(string))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (server *Server) resolveCreateMovie(p graphql.ResolveParams) (interface{}, error) {
+	ctx := requestContext(p).ctx
+	if err := server.checkPermission(ctx, authz.MoviesWrite); err != nil {
+		return nil, err
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	input := p.Args["input"].(map[string]interface{})
+	arg := db.CreateMovieParams{
+		Name:        input["name"].(string),
+		Description: input["description"].(string),
+		ReleaseDate: input["releaseDate"].(time.Time),
+		Rating:      input["rating"].(string),
+	}
+	err := binding.Validator.ValidateStruct(createMovieRequest{
+		Name:        arg.Name,
+		Description: arg.Description,
+		ReleaseDate: arg.ReleaseDate,
+		Rating:      arg.Rating,
+	})
+	if err != nil {
+		return nil, err
+	}
+	duplicates, err := server.likelyDuplicateMovies(p.Context, arg.Name, arg.ReleaseDate)
+	if err != nil {
+		return nil, err
+	}
+	var movie db.Movie
+	err = server.store.ExecTx(p.Context, func(store db.Store) error {
+		var err error
+		movie, err = store.CreateMovieTx(p.Context, db.CreateMovieTxParams{
+			CreateMovieParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityMovie, movie.ID, nil, newMovieResponse(movie))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"movie": movie, "possibleDuplicates": duplicates}, nil
+}
+
+func (server *Server) resolveUpdateMovie(p graphql.ResolveParams) (interface{}, error) {
+	ctx := requestContext(p).ctx
+	if err := server.checkPermission(ctx, authz.MoviesWrite); err != nil {
+		return nil, err
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	input := p.Args["input"].(map[string]interface{})
+	arg := db.UpdateMovieParams{
+		Name:        nullString(input, "name"),
+		Description: nullString(input, "description"),
+		Rating:      nullString(input, "rating"),
+		ReleaseDate: nullTime(input, "releaseDate"),
+	}
+	if description, ok := input["description"]; ok && description == nil {
+		return nil, errNullDescription
+	}
+	patch := updateMovieRequest{}
+	if arg.Name.Valid {
+		patch.Name = &arg.Name.String
+	}
+	if arg.Description.Valid {
+		patch.Description = &arg.Description.String
+	}
+	if arg.Rating.Valid {
+		patch.Rating = &arg.Rating.String
+	}
+	if err := binding.Validator.ValidateStruct(patch); err != nil {
+		return nil, err
+	}
+	var movie db.Movie
+	err := server.store.ExecTx(p.Context, func(store db.Store) error {
+		before, err := store.LockMovie(p.Context, int32(p.Args["id"].(int)))
+		if err != nil {
+			return err
+		}
+		arg.ID = before.ID
+		arg.ExpectedVersion, err = server.graphqlExpectedVersion(p.Args, before.Version)
+		if err != nil {
+			return err
+		}
+		if !arg.Name.Valid && !arg.Description.Valid && !arg.Rating.Valid && !arg.ReleaseDate.Valid {
+			movie = before
+			return nil
+		}
+		movie, err = store.UpdateMovieTx(p.Context, db.UpdateMovieTxParams{
+			UpdateMovieParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityMovie, movie.ID, newMovieResponse(before), newMovieResponse(movie))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+func (server *Server) resolveDeleteMovie(p graphql.ResolveParams) (interface{}, error) {
+	ctx := requestContext(p).ctx
+	if err := server.checkPermission(ctx, authz.MoviesDelete); err != nil {
+		return nil, err
+	}
+	var before db.Movie
+	err := server.store.ExecTx(p.Context, func(store db.Store) error {
+		var err error
+		before, err = store.LockMovie(p.Context, int32(p.Args["id"].(int)))
+		if err != nil {
+			return err
+		}
+		expected, err := server.graphqlExpectedVersion(p.Args, before.Version)
+		if err != nil {
+			return err
+		}
+		deleted, err := store.DeleteMovie(p.Context, db.DeleteMovieParams{
+			ID:              before.ID,
+			ExpectedVersion: expected,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errPreconditionFailed
+		}
+		return recordAudit(ctx, store, db.AuditActionDelete, db.AuditEntityMovie, before.ID, newMovieResponse(before), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return before.ID, nil
+}
+
+func (server *Server) resolveCreateActor(p graphql.ResolveParams) (interface{}, error) {
+	ctx := requestContext(p).ctx
+	if err := server.checkPermission(ctx, authz.ActorsWrite); err != nil {
+		return nil, err
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	input := p.Args["input"].(map[string]interface{})
+	arg := db.CreateActorParams{
+		Name:     input["name"].(string),
+		Gender:   input["gender"].(string),
+		Birthday: input["birthday"].(time.Time),
+	}
+	err := binding.Validator.ValidateStruct(createActorRequest{
+		Name:     arg.Name,
+		Gender:   arg.Gender,
+		Birthday: arg.Birthday,
+	})
+	if err != nil {
+		return nil, err
+	}
+	duplicates, err := server.likelyDuplicateActors(p.Context, arg.Name, arg.Birthday)
+	if err != nil {
+		return nil, err
+	}
+	var actor db.Actor
+	err = server.store.ExecTx(p.Context, func(store db.Store) error {
+		var err error
+		actor, err = store.CreateActorTx(p.Context, db.CreateActorTxParams{
+			CreateActorParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityActor, actor.ID, nil, newActorResponse(actor))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"actor": actor, "possibleDuplicates": duplicates}, nil
+}
+
+func (server *Server) resolveUpdateActor(p graphql.ResolveParams) (interface{}, error) {
+	ctx := requestContext(p).ctx
+	if err := server.checkPermission(ctx, authz.ActorsWrite); err != nil {
+		return nil, err
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	input := p.Args["input"].(map[string]interface{})
+	arg := db.UpdateActorParams{
+		Name:     nullString(input, "name"),
+		Gender:   nullString(input, "gender"),
+		Birthday: nullTime(input, "birthday"),
+	}
+	patch := updateActorRequest{}
+	if arg.Name.Valid {
+		patch.Name = &arg.Name.String
+	}
+	if arg.Gender.Valid {
+		patch.Gender = &arg.Gender.String
+	}
+	if arg.Birthday.Valid {
+		patch.Birthday = &arg.Birthday.Time
+	}
+	if err := binding.Validator.ValidateStruct(patch); err != nil {
+		return nil, err
+	}
+	var actor db.Actor
+	err := server.store.ExecTx(p.Context, func(store db.Store) error {
+		before, err := store.LockActor(p.Context, int32(p.Args["id"].(int)))
+		if err != nil {
+			return err
+		}
+		arg.ID = before.ID
+		arg.ExpectedVersion, err = server.graphqlExpectedVersion(p.Args, before.Version)
+		if err != nil {
+			return err
+		}
+		if !arg.Name.Valid && !arg.Gender.Valid && !arg.Birthday.Valid {
+			actor = before
+			return nil
+		}
+		actor, err = store.UpdateActorTx(p.Context, db.UpdateActorTxParams{
+			UpdateActorParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityActor, actor.ID, newActorResponse(before), newActorResponse(actor))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+func (server *Server) resolveDeleteActor(p graphql.ResolveParams) (interface{}, error) {
+	ctx := requestContext(p).ctx
+	if err := server.checkPermission(ctx, authz.ActorsDelete); err != nil {
+		return nil, err
+	}
+	var before db.Actor
+	err := server.store.ExecTx(p.Context, func(store db.Store) error {
+		var err error
+		before, err = store.LockActor(p.Context, int32(p.Args["id"].(int)))
+		if err != nil {
+			return err
+		}
+		expected, err := server.graphqlExpectedVersion(p.Args, before.Version)
+		if err != nil {
+			return err
+		}
+		deleted, err := store.DeleteActor(p.Context, db.DeleteActorParams{
+			ID:              before.ID,
+			ExpectedVersion: expected,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errPreconditionFailed
+		}
+		return recordAudit(ctx, store, db.AuditActionDelete, db.AuditEntityActor, before.ID, newActorResponse(before), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return before.ID, nil
+}
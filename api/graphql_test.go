@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"vk-film/util"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckQueryLimits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]interface{}
+		depth         int
+		complexity    int
+	}{
+		{
+			name:       "SingleObject",
+			query:      `{ movie(id: 1) { name } }`,
+			depth:      2,
+			complexity: 2,
+		},
+		{
+			name:       "DefaultListSizes",
+			query:      `{ movies { name cast { name } } }`,
+			depth:      3,
+			complexity: 1 + graphqlDefaultLimit*(1+1+graphqlRelationCost),
+		},
+		{
+			name:       "LimitArgument",
+			query:      `{ movies(limit: 5) { name } }`,
+			depth:      2,
+			complexity: 6,
+		},
+		{
+			name:       "LimitVariable",
+			query:      `query Movies($limit: Int) { movies(limit: $limit) { name } }`,
+			variables:  map[string]interface{}{"limit": float64(50)},
+			depth:      2,
+			complexity: 51,
+		},
+		{
+			name:       "LimitAboveMaximum",
+			query:      `{ movies(limit: 1000) { name } }`,
+			depth:      2,
+			complexity: 1 + graphqlMaxLimit,
+		},
+		{
+			name:       "LimitBelowMinimum",
+			query:      `{ movies(limit: 0) { name } }`,
+			depth:      2,
+			complexity: 2,
+		},
+		{
+			name: "ReusedFragment",
+			query: `{ a: movie(id: 1) { ...Details } b: movie(id: 2) { ...Details } }
+				fragment Details on Movie { name cast { name } }`,
+			depth:      3,
+			complexity: 2 * (1 + 1 + 1 + graphqlRelationCost),
+		},
+		{
+			name:       "InlineFragment",
+			query:      `{ movie(id: 1) { ... on Movie { name } } }`,
+			depth:      2,
+			complexity: 2,
+		},
+		{
+			name:       "IntrospectionNotCounted",
+			query:      `{ __schema { types { name } } movie(id: 1) { name } }`,
+			depth:      2,
+			complexity: 2,
+		},
+		{
+			name:          "NamedOperation",
+			query:         `query Small { movie(id: 1) { name } } query Large { movies { cast { filmography { name } } } }`,
+			operationName: "Small",
+			depth:         2,
+			complexity:    2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			document, err := parser.Parse(parser.ParseParams{Source: tc.query})
+			require.NoError(t, err)
+			check := func(maxDepth, maxComplexity int) error {
+				server := &Server{config: util.Config{GraphQLMaxDepth: maxDepth, GraphQLMaxComplexity: maxComplexity}}
+				return server.checkQueryLimits(document, tc.operationName, tc.variables)
+			}
+
+			require.NoError(t, check(tc.depth, tc.complexity))
+			require.ErrorContains(t, check(tc.depth-1, tc.complexity), "query depth")
+			require.ErrorContains(t, check(tc.depth, tc.complexity-1), "query complexity")
+		})
+	}
+}
+
+func TestCheckQueryLimitsUnknownOperation(t *testing.T) {
+	document, err := parser.Parse(parser.ParseParams{Source: `query Movies { movies { name } }`})
+	require.NoError(t, err)
+	server := &Server{config: util.Config{GraphQLMaxDepth: 10, GraphQLMaxComplexity: 1000}}
+	require.ErrorContains(t, server.checkQueryLimits(document, "Actors", nil), "unknown operation")
+}
+
+func TestBatchLoader(t *testing.T) {
+	var batches [][]int32
+	loader := newBatchLoader(func(keys []int32) (map[int32]string, error) {
+		batch := append([]int32(nil), keys...)
+		sort.Slice(batch, func(i, j int) bool { return batch[i] < batch[j] })
+		batches = append(batches, batch)
+		values := make(map[int32]string, len(keys))
+		for _, key := range keys {
+			values[key] = string(rune('a' + key))
+		}
+		return values, nil
+	})
+
+	// the keys of one level are fetched together, once each
+	thunks := []func() (interface{}, error){loader.load(1), loader.load(2), loader.load(1)}
+	for i, want := range []string{"b", "c", "b"} {
+		value, err := thunks[i]()
+		require.NoError(t, err)
+		require.Equal(t, want, value)
+	}
+	require.Equal(t, [][]int32{{1, 2}}, batches)
+
+	// the values loaded before are not fetched again
+	value, err := loader.load(2)()
+	require.NoError(t, err)
+	require.Equal(t, "c", value)
+	require.Len(t, batches, 1)
+
+	value, err = loader.load(3)()
+	require.NoError(t, err)
+	require.Equal(t, "d", value)
+	require.Equal(t, [][]int32{{1, 2}, {3}}, batches)
+}
+
+func TestBatchLoaderError(t *testing.T) {
+	errFetch := errors.New("cannot fetch")
+	fetches := 0
+	loader := newBatchLoader(func(keys []int32) (map[int32]string, error) {
+		fetches++
+		return nil, errFetch
+	})
+
+	first, second := loader.load(1), loader.load(2)
+	_, err := first()
+	require.ErrorIs(t, err, errFetch)
+	_, err = second()
+	require.ErrorIs(t, err, errFetch)
+	require.Equal(t, 1, fetches)
+}
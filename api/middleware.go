@@ -5,49 +5,137 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"vk-film/authz"
 	"vk-film/token"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
 	authorizationHeader     = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
+	apiKeyHeader            = "X-API-Key"
 	authorizationPayload    = "authorization_payload"
+	apiKeyPayloadKey        = "api_key_payload"
 	authorizationHeaderKey  = "authorization"
+	requestIDHeader         = "X-Request-ID"
+	requestIDKey            = "request_id"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// requestIDMiddleware tags every request with an ID, reusing the one sent by the client if any.
+func requestIDMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if len(requestID) == 0 || len(requestID) > 64 {
+			requestID = uuid.NewString()
+		}
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeader, requestID)
+		ctx.Next()
+	}
+}
+
+// authMiddleware authenticates a request by its bearer access token, rejecting it if it has been revoked,
+// or by its API key, sent in the X-API-Key header or as an ApiKey authorization.
+func authMiddleware(tokenMaker token.Maker, revocations *token.RevocationList, apiKeys *token.APIKeyVerifier) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		if apiKey := ctx.GetHeader(apiKeyHeader); len(apiKey) > 0 {
+			authenticateAPIKey(ctx, apiKeys, apiKey)
+			return
+		}
 
 		authorizationHeader := ctx.GetHeader(authorizationHeader)
 		if len(authorizationHeader) == 0 {
 			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, http.StatusUnauthorized, err)
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
 			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, http.StatusUnauthorized, err)
 			return
 		}
 
 		authorizationType := strings.ToLower(fields[0])
+		if authorizationType == authorizationTypeAPIKey {
+			authenticateAPIKey(ctx, apiKeys, fields[1])
+			return
+		}
 		if authorizationType != authorizationTypeBearer {
 			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, http.StatusUnauthorized, err)
 			return
 		}
 
 		accessToken := fields[1]
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, http.StatusUnauthorized, err)
+			return
 		}
+		err = revocations.Check(ctx, payload)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err == token.ErrRevokedToken {
+				status = http.StatusUnauthorized
+			} else if err == token.ErrUserDisabled {
+				status = http.StatusForbidden
+			}
+			abortWithError(ctx, status, err)
+			return
+		}
+		ctx.Set(authorizationPayload, payload)
+		ctx.Next()
+
+	}
+}
+
+// authenticateAPIKey sets the payload of a valid API key, the unknown, expired and revoked keys are rejected.
+// The key verified by rateLimitMiddleware is not looked up again.
+func authenticateAPIKey(ctx *gin.Context, apiKeys *token.APIKeyVerifier, apiKey string) {
+	if payload, ok := ctx.Get(apiKeyPayloadKey); ok {
 		ctx.Set(authorizationPayload, payload)
 		ctx.Next()
+		return
+	}
+	payload, err := apiKeys.Verify(ctx, apiKey)
+	if err != nil {
+		abortWithError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Set(authorizationPayload, payload)
+	ctx.Next()
+}
+
+// requireUser rejects the API keys on the routes acting on the account of the authenticated user, a key has none.
+func requireUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+		if authPayload.IsAPIKey() {
+			abortWithError(ctx, http.StatusForbidden, errAPIKeyNotAllowed)
+			return
+		}
+		ctx.Next()
+	}
+}
 
+// requirePermission rejects the requests of users whose role does not grant a permission, after authMiddleware.
+func (server *Server) requirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		err := server.checkPermission(ctx, permission)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err == authz.ErrPermissionDenied || err == authz.ErrScopeDenied || err == authz.ErrIssuerDenied {
+				status = http.StatusForbidden
+			}
+			abortWithError(ctx, status, err)
+			return
+		}
+		ctx.Next()
 	}
 }
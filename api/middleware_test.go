@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// authStore keeps an API key and the permissions of the roles, the other methods of the store are not used
+// by the authentication and the authorization.
+type authStore struct {
+	db.Store
+	key         db.GetUsableAPIKeyRow
+	permissions []db.RolePermission
+	rolesErr    error
+	lookups     int
+}
+
+func (store *authStore) GetUsableAPIKey(ctx context.Context, arg db.GetUsableAPIKeyParams) (db.GetUsableAPIKeyRow, error) {
+	store.lookups++
+	if string(arg.KeyHash) != string(store.key.KeyHash) {
+		return db.GetUsableAPIKeyRow{}, sql.ErrNoRows
+	}
+	return store.key, nil
+}
+
+func (store *authStore) TouchAPIKey(ctx context.Context, arg db.TouchAPIKeyParams) error {
+	return nil
+}
+
+func (store *authStore) ListRolePermissions(ctx context.Context) ([]db.RolePermission, error) {
+	return store.permissions, store.rolesErr
+}
+
+// newAuthRouter serves GET /movies to the clients granted movies:write.
+func newAuthRouter(t *testing.T, store *authStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := &Server{
+		store:      store,
+		authorizer: authz.NewAuthorizer(store, time.Minute),
+		apiKeys:    token.NewAPIKeyVerifier(store),
+	}
+	router := gin.New()
+	router.GET("/movies", authMiddleware(nil, nil, server.apiKeys), server.requirePermission(authz.MoviesWrite), func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+		ctx.JSON(http.StatusOK, gin.H{"username": authPayload.Username})
+	})
+	return router
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	key, prefix, hash, err := token.NewAPIKey()
+	require.NoError(t, err)
+	usableKey := db.GetUsableAPIKeyRow{
+		ID:         3,
+		Name:       "billing",
+		Prefix:     prefix,
+		KeyHash:    hash,
+		Scopes:     []string{authz.MoviesWrite},
+		CreatedBy:  "alice",
+		CreatedAt:  time.Now(),
+		IssuerRole: "editor",
+	}
+	editor := []db.RolePermission{{Role: "editor", Permission: authz.MoviesWrite}}
+
+	testCases := []struct {
+		name        string
+		setHeader   func(request *http.Request)
+		scopes      []string
+		permissions []db.RolePermission
+		status      int
+		code        string
+	}{
+		{
+			name:        "XAPIKeyHeader",
+			setHeader:   func(request *http.Request) { request.Header.Set(apiKeyHeader, key) },
+			permissions: editor,
+			status:      http.StatusOK,
+		},
+		{
+			name:        "AuthorizationHeader",
+			setHeader:   func(request *http.Request) { request.Header.Set(authorizationHeader, "ApiKey "+key) },
+			permissions: editor,
+			status:      http.StatusOK,
+		},
+		{
+			name:        "UnknownKey",
+			setHeader:   func(request *http.Request) { request.Header.Set(apiKeyHeader, key+"x") },
+			permissions: editor,
+			status:      http.StatusUnauthorized,
+			code:        "invalid_api_key",
+		},
+		{
+			name:        "MissingScope",
+			setHeader:   func(request *http.Request) { request.Header.Set(apiKeyHeader, key) },
+			scopes:      []string{authz.ActorsWrite},
+			permissions: editor,
+			status:      http.StatusForbidden,
+			code:        "insufficient_scopes",
+		},
+		{
+			name:        "IssuerDemoted",
+			setHeader:   func(request *http.Request) { request.Header.Set(apiKeyHeader, key) },
+			permissions: []db.RolePermission{{Role: "editor", Permission: authz.ActorsWrite}},
+			status:      http.StatusForbidden,
+			code:        "insufficient_permissions",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &authStore{key: usableKey, permissions: tc.permissions}
+			if tc.scopes != nil {
+				store.key.Scopes = tc.scopes
+			}
+			router := newAuthRouter(t, store)
+
+			request := httptest.NewRequest(http.MethodGet, "/movies", nil)
+			tc.setHeader(request)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			require.Equal(t, tc.status, recorder.Code)
+			if tc.code != "" {
+				var got problem
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, tc.code, got.Code)
+			}
+		})
+	}
+}
+
+// TestAuthMiddlewareReusesRateLimitedKey checks that the key verified by rateLimitMiddleware is not looked up again.
+func TestAuthMiddlewareReusesRateLimitedKey(t *testing.T) {
+	store := &authStore{}
+	payload := &token.Payload{Username: token.APIKeyPrincipal + "billing", Role: "editor", APIKeyID: 3, Scopes: []string{authz.MoviesWrite}}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set(apiKeyPayloadKey, payload)
+	})
+	router.GET("/movies", authMiddleware(nil, nil, token.NewAPIKeyVerifier(store)), func(ctx *gin.Context) {
+		require.Same(t, payload, ctx.MustGet(authorizationPayload))
+		ctx.Status(http.StatusOK)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/movies", nil)
+	request.Header.Set(apiKeyHeader, "vkf_00000000_secret")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Zero(t, store.lookups)
+}
+
+func TestRequirePermission(t *testing.T) {
+	permissions := []db.RolePermission{
+		{Role: authz.RoleAdministrator, Permission: authz.RolesManage},
+		{Role: "editor", Permission: authz.MoviesWrite},
+	}
+
+	testCases := []struct {
+		name     string
+		role     string
+		rolesErr error
+		status   int
+		code     string
+	}{
+		{
+			name:   "Granted",
+			role:   "editor",
+			status: http.StatusOK,
+		},
+		{
+			name:   "NotGranted",
+			role:   authz.RoleClient,
+			status: http.StatusForbidden,
+			code:   "insufficient_permissions",
+		},
+		{
+			// the administrator role holds only the permissions granted to it in the store
+			name:   "Administrator",
+			role:   authz.RoleAdministrator,
+			status: http.StatusForbidden,
+			code:   "insufficient_permissions",
+		},
+		{
+			name:     "StoreError",
+			role:     "editor",
+			rolesErr: errors.New("connection refused"),
+			status:   http.StatusInternalServerError,
+			code:     "internal",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &authStore{permissions: permissions, rolesErr: tc.rolesErr}
+			server := &Server{store: store, authorizer: authz.NewAuthorizer(store, time.Minute)}
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(ctx *gin.Context) {
+				ctx.Set(authorizationPayload, &token.Payload{Username: "alice", Role: tc.role})
+			})
+			router.GET("/movies", server.requirePermission(authz.MoviesWrite), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/movies", nil))
+
+			require.Equal(t, tc.status, recorder.Code)
+			if tc.code != "" {
+				var got problem
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, tc.code, got.Code)
+			}
+		})
+	}
+}
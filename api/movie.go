@@ -2,26 +2,27 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 	db "vk-film/db/sqlc"
+	"vk-film/token"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/gin-gonic/gin/binding"
 )
 
-// createMovieRequest represents a request to create a movie, ONLY FOR ADMINS.
-// swagger:parameters createMovieRequest
+// createMovieRequest represents a request to create a movie, requires the movies:write permission.
 type createMovieRequest struct {
 	// The name of the movie.
 	// Required: true
 	// example: Inception
-	Name string `json:"name" binding:"required"`
+	Name string `json:"name" binding:"required,max=150"`
 
 	// The description of the movie.
 	// Required: true
 	// example: A mind-bending action thriller
-	Description string `json:"description" binding:"required"`
+	Description string `json:"description" binding:"required,max=1000"`
 
 	// The release date of the movie.
 	// Required: true
@@ -32,11 +33,10 @@ type createMovieRequest struct {
 	// The rating of the movie.
 	// Required: true
 	// example: 8.8
-	Rating string `json:"rating" binding:"required"`
+	Rating string `json:"rating" binding:"required,rating"`
 }
 
 // movieResponse represents the response for a movie.
-// swagger:response movieResponse
 type movieResponse struct {
 	// The ID of the movie.
 	// Example: 123
@@ -62,6 +62,11 @@ type movieResponse struct {
 	// Example: 8.7
 	// required: true
 	Rating string `json:"rating"`
+
+	// The version of the movie, also sent as the ETag header and expected in If-Match to modify it.
+	// Example: 3
+	// required: true
+	Version int32 `json:"version"`
 }
 
 // newMovieResponse creates a new Movie Response from a db.Movie.
@@ -72,140 +77,209 @@ func newMovieResponse(movie db.Movie) movieResponse {
 		Description: movie.Description,
 		ReleaseDate: movie.ReleaseDate,
 		Rating:      movie.Rating,
+		Version:     movie.Version,
 	}
 }
 
+// createMovieResponse represents the response for a created movie.
+type createMovieResponse struct {
+	movieResponse
+
+	// Existing movies with a similar name released the same year, the new movie may be one of them.
+	PossibleDuplicates []movieResponse `json:"possible_duplicates"`
+}
+
 // createMovie creates a new movie.
-// swagger:operation POST /movie/create movies createMovie
-//
-// Creates a new movie with the provided details.
-//
-// ---
-// produces:
-// - application/json
-// parameters:
-//   - name: body
-//     in: body
-//     description: Request body containing the movie information.
-//     required: true
-//     schema:
-//     "$ref": "#/definitions/createMovieRequest"
-//
-// responses:
-//
-//	'200':
-//	  description: Successfully created the movie.
-//	  schema:
-//	    "$ref": "#/definitions/movieResponse"
-//	'400':
-//	  description: Bad request. The request body is missing or invalid.
-//	'403':
-//	  description: Forbidden. Only admins have permission to update movies.
-//	'404':
-//	  description: Not found. The movie with the provided ID does not exist.
-//	'500':
-//	  description: Internal server error. Something went wrong while processing the request.
 func (server *Server) createMovie(ctx *gin.Context) {
 	var req createMovieRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	err := checkAdminPermissions(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	rsp, ok := server.createMovieResource(ctx, req)
+	if !ok {
 		return
 	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// createMovieResource creates a movie for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) createMovieResource(ctx *gin.Context, req createMovieRequest) (createMovieResponse, bool) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	duplicates, err := server.findDuplicateMovies(ctx, req.Name, req.ReleaseDate)
+	if err != nil {
+		writeError(ctx, err)
+		return createMovieResponse{}, false
+	}
 	arg := db.CreateMovieParams{
 		Name:        req.Name,
 		Description: req.Description,
 		ReleaseDate: req.ReleaseDate,
 		Rating:      req.Rating,
 	}
-	movie, err := server.store.CreateMovie(ctx, arg)
+	var movie db.Movie
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		var err error
+		movie, err = store.CreateMovieTx(ctx, db.CreateMovieTxParams{
+			CreateMovieParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityMovie, movie.ID, nil, newMovieResponse(movie))
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		writeError(ctx, err)
+		return createMovieResponse{}, false
 	}
-	rsp := newMovieResponse(movie)
-	ctx.JSON(http.StatusOK, rsp)
+	ctx.Header(etagHeader, versionETag(movie.Version))
+	rsp := createMovieResponse{
+		movieResponse:      newMovieResponse(movie),
+		PossibleDuplicates: duplicates,
+	}
+	return rsp, true
 }
 
-// updateMovieRequest represents the request body for updating a movie.
-// swagger:parameters updateMovie
+// updateMovieRequest represents the patchable document of a movie.
+// It is modified either by a JSON Merge Patch (RFC 7386), sent as application/merge-patch+json or application/json,
+// or by a JSON Patch (RFC 6902), sent as application/json-patch+json. Only the fields touched by the patch change.
 type updateMovieRequest struct {
-	// ID of the movie to be updated.
-	// required: true
-	// in: body
-	ID int32 `json:"id" binding:"required"`
+	// ID of the movie to be updated, can also be sent as the id query parameter.
+	ID *int32 `json:"id"`
 
 	// New name of the movie.
-	// in: body
-	Name string `json:"name"`
+	Name *string `json:"name" binding:"omitnil,min=1,max=150"`
 
 	// New description of the movie.
-	// in: body
-	Description string `json:"description"`
+	Description *string `json:"description" binding:"omitnil,max=1000"`
 
 	// New rating of the movie.
-	// in: body
-	Rating string `json:"rating"`
+	Rating *string `json:"rating" binding:"omitnil,rating"`
 
 	// New release date of the movie.
-	// in: body
-	ReleaseDate time.Time `json:"release_date"`
+	ReleaseDate *time.Time `json:"release_date"`
 }
 
-// updateMovie updates a movie based on the provided request body.
-// swagger:route PATCH /movie/update movies updateMovie
-// responses:
-//
-//	'200': movieResponse
-//	'400':
-//	  description: Bad request. The request body is missing or invalid.
-//	'403':
-//	  description: Forbidden. Only admins have permission to update movies.
-//	'404':
-//	  description: Not found. The movie with the provided ID does not exist.
-//	'500':
-//	  description: Internal server error. Something went wrong while processing the request.
-func (server *Server) updateMovie(ctx *gin.Context) {
-	var req updateMovieRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
+// newUpdateMovieParams validates a patched movie and sets the fields changed by the patch,
+// the untouched ones keep their current value. id is the ID the request asked for, an old ID of a merged movie
+// redirecting to before.
+func newUpdateMovieParams(id int32, before db.Movie, patched updateMovieRequest) (arg db.UpdateMovieParams, changed bool, err error) {
+	arg.ID = before.ID
+	if patched.ID == nil || (*patched.ID != before.ID && *patched.ID != id) {
+		return arg, false, errPatchChangesID
+	}
+	if err := binding.Validator.ValidateStruct(patched); err != nil {
+		return arg, false, err
+	}
+	if patched.Name == nil {
+		return arg, false, errors.New("name cannot be null")
+	}
+	if *patched.Name != before.Name {
+		arg.Name = sql.NullString{String: *patched.Name, Valid: true}
+	}
+	if patched.Description == nil {
+		return arg, false, errNullDescription
+	}
+	if *patched.Description != before.Description {
+		arg.Description = sql.NullString{String: *patched.Description, Valid: true}
 	}
+	if patched.Rating == nil {
+		return arg, false, errors.New("rating cannot be null")
+	}
+	if *patched.Rating != before.Rating {
+		arg.Rating = sql.NullString{String: *patched.Rating, Valid: true}
+	}
+	if patched.ReleaseDate == nil {
+		return arg, false, errors.New("release_date cannot be null")
+	}
+	if !patched.ReleaseDate.Equal(before.ReleaseDate) {
+		arg.ReleaseDate = sql.NullTime{Time: *patched.ReleaseDate, Valid: true}
+	}
+	changed = arg.Name.Valid || arg.Description.Valid || arg.Rating.Valid || arg.ReleaseDate.Valid
+	return arg, changed, nil
+}
 
-	err := checkAdminPermissions(ctx)
+// updateMovie partially updates a movie based on the provided patch.
+func (server *Server) updateMovie(ctx *gin.Context) {
+	body, err := ctx.GetRawData()
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	arg := db.UpdateMovieParams{
-		ID:          req.ID,
-		Name:        req.Name,
-		Description: req.Description,
-		Rating:      req.Rating,
-		ReleaseDate: req.ReleaseDate,
-	}
-	movie, err := server.store.UpdateMovie(ctx, arg)
+	id, err := patchTargetID(ctx, body)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "no_data_found":
-				ctx.JSON(http.StatusNotFound, errorResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	movie, ok := server.updateMovieResource(ctx, id, body)
+	if !ok {
 		return
 	}
 	ctx.JSON(http.StatusOK, movie)
+}
 
+// updateMovieResource applies a patch to a movie for the legacy and the v1 routes, the errors are written to the response.
+// The movie is read and locked in the transaction of the update, so that the patch applies to its current version.
+func (server *Server) updateMovieResource(ctx *gin.Context, id int32, body []byte) (db.Movie, bool) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	var movie db.Movie
+	// the errors of the patch itself are bad requests, those of the store keep their own status
+	var patchErr error
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockMovie(ctx, id)
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(ctx, before.Version)
+		if err != nil {
+			return err
+		}
+		current := updateMovieRequest{
+			ID:          &before.ID,
+			Name:        &before.Name,
+			Description: &before.Description,
+			Rating:      &before.Rating,
+			ReleaseDate: &before.ReleaseDate,
+		}
+		var patched updateMovieRequest
+		patchErr = applyPatch(ctx.ContentType(), body, current, &patched)
+		if patchErr != nil {
+			return patchErr
+		}
+		var arg db.UpdateMovieParams
+		var changed bool
+		arg, changed, patchErr = newUpdateMovieParams(id, before, patched)
+		if patchErr != nil {
+			return patchErr
+		}
+		if !changed {
+			movie = before
+			return nil
+		}
+		arg.ExpectedVersion = expected
+		movie, err = store.UpdateMovieTx(ctx, db.UpdateMovieTxParams{
+			UpdateMovieParams: arg,
+			Username:          authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityMovie, movie.ID, newMovieResponse(before), newMovieResponse(movie))
+	})
+	if patchErr != nil {
+		writeStatusError(ctx, http.StatusBadRequest, patchErr)
+		return db.Movie{}, false
+	}
+	if err != nil {
+		writeError(ctx, err)
+		return db.Movie{}, false
+	}
+	ctx.Header(etagHeader, versionETag(movie.Version))
+	return movie, true
 }
 
 // deleteMovieRequest represents the request payload for deleting a movie.
-// swagger:model
 type deleteMovieRequest struct {
 	// The ID of the movie to delete.
 	//
@@ -215,120 +289,144 @@ type deleteMovieRequest struct {
 }
 
 // deleteMovie deletes a movie based on the provided ID.
-// swagger:operation DELETE /movie/delete movies deleteMovie
-//
-// Delete a movie with the provided ID.
-//
-// ---
-// produces:
-// - application/json
-// parameters:
-//   - name: body
-//     in: body
-//     description: Request body containing the ID of the movie to delete.
-//     required: true
-//     schema:
-//     "$ref": "#/definitions/deleteMovieRequest"
-//
-// responses:
-//
-//	'200':
-//	  description: Successfully deleted the movie.
-//	  schema:
-//	    type: integer
-//	    format: int32
-//	'400':
-//	  description: Bad request. The request body is missing or invalid.
-//	'403':
-//	  description: Forbidden. The user does not have permission to delete movies.
-//	'404':
-//	  description: Not found. The movie with the provided ID does not exist.
 func (server *Server) deleteMovie(ctx *gin.Context) {
 	var req deleteMovieRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if !server.deleteMovieResource(ctx, req.ID) {
 		return
 	}
-	err := checkAdminPermissions(ctx)
+	ctx.JSON(http.StatusOK, req.ID)
+}
+
+// deleteMovieResource deletes a movie for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) deleteMovieResource(ctx *gin.Context, id int32) bool {
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockMovie(ctx, id)
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(ctx, before.Version)
+		if err != nil {
+			return err
+		}
+		deleted, err := store.DeleteMovie(ctx, db.DeleteMovieParams{
+			ID:              before.ID,
+			ExpectedVersion: expected,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errPreconditionFailed
+		}
+		return recordAudit(ctx, store, db.AuditActionDelete, db.AuditEntityMovie, before.ID, newMovieResponse(before), nil)
+	})
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		writeError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// getMovieRequest represents the query parameters for retrieving a movie.
+type getMovieRequest struct {
+	// The ID of the movie, IDs of movies merged into another one resolve to the survivor.
+	// required: true
+	ID int32 `form:"id" binding:"required,min=1"`
+}
+
+// getMovie retrieves a movie by ID.
+func (server *Server) getMovie(ctx *gin.Context) {
+	var req getMovieRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	err = server.store.DeleteMovie(ctx, req.ID)
+	movie, ok := server.getMovieResource(ctx, req.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newMovieResponse(movie))
+}
+
+// getMovieResource reads a movie for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) getMovieResource(ctx *gin.Context, id int32) (db.Movie, bool) {
+	movie, err := server.store.GetMovie(ctx, id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		writeError(ctx, err)
+		return db.Movie{}, false
+	}
+	ctx.Header(etagHeader, versionETag(movie.Version))
+	return movie, true
+}
+
+// mergeMovies merges a duplicate movie into a survivor.
+func (server *Server) mergeMovies(ctx *gin.Context) {
+	var req mergeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, req.ID)
+	movie, ok := server.mergeMovieResources(ctx, req.SurvivorID, req.DuplicateID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newMovieResponse(movie))
+}
+
+// mergeMovieResources merges two movies for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) mergeMovieResources(ctx *gin.Context, survivorID, duplicateID int32) (db.Movie, bool) {
+	arg := db.MergeTxParams{
+		SurvivorID:  survivorID,
+		DuplicateID: duplicateID,
+	}
+	var movie db.Movie
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		duplicate, err := store.GetMovie(ctx, duplicateID)
+		if err != nil {
+			return err
+		}
+		movie, err = store.MergeMoviesTx(ctx, arg)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionMerge, db.AuditEntityMovie, duplicate.ID, newMovieResponse(duplicate), gin.H{"merged_into": movie.ID})
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return db.Movie{}, false
+	}
+	return movie, true
 }
 
 // moviesSortedByRating retrieves movies sorted by rating.
-// swagger:operation GET /movies movies moviesSortedByRating
-//
-// Retrieves movies sorted by rating.
-// ---
-// responses:
-//
-//	200:
-//	  description: Successfully retrieved movies sorted by rating.
-//	  schema:
-//	    type: array
-//	    items:
-//	      $ref: "#/definitions/MovieResponse"
-//	500:
-//	  description: Internal server error.
 func (server *Server) moviesSortedByRating(ctx *gin.Context) {
 	movies, err := server.store.GetMoviesSortedByRating(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, movies)
 }
 
 // moviesSortedByName retrieves movies sorted by name.
-// swagger:operation GET /movies/by-name movies moviesSortedByName
-//
-// Retrieves movies sorted by name.
-// ---
-// responses:
-//
-//	200:
-//	  description: Successfully retrieved movies sorted by name.
-//	  schema:
-//	    type: array
-//	    items:
-//	      $ref: "#/definitions/MovieResponse"
-//	500:
-//	  description: Internal server error.
 func (server *Server) moviesSortedByName(ctx *gin.Context) {
 	movies, err := server.store.GetMoviesSortedByName(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, movies)
 }
 
 // moviesSortedByReleaseDate retrieves movies sorted by release date.
-// swagger:operation GET /movies/by-date movies moviesSortedByReleaseDate
-//
-// Retrieves movies sorted by release date.
-// ---
-// responses:
-//
-//	200:
-//	  description: Successfully retrieved movies sorted by release date.
-//	  schema:
-//	    type: array
-//	    items:
-//	      $ref: "#/definitions/MovieResponse"
-//	500:
-//	  description: Internal server error.
 func (server *Server) moviesSortedByReleaseDate(ctx *gin.Context) {
 	movies, err := server.store.GetMoviesByReleaseDate(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, movies)
@@ -339,37 +437,10 @@ type movieFragmentRequest struct {
 }
 
 // moviesByNameFragment retrieves movies by name fragment.
-// swagger:operation GET /movies/by-name-fragment movies moviesByNameFragment
-//
-// Retrieves movies by name fragment.
-//
-// ---
-// consumes:
-// - application/json
-// produces:
-// - application/json
-// parameters:
-//   - name: body
-//     in: body
-//     description: The name fragment to search for.
-//     required: true
-//     schema:
-//     $ref: "#/definitions/MovieFragmentRequest"
-//
-// responses:
-//
-//	200:
-//	  description: Successfully retrieved movies by name fragment.
-//	  schema:
-//	    type: array
-//	    items:
-//	      $ref: "#/definitions/MovieResponse"
-//	400:
-//	  description: Bad request. Invalid JSON payload.
 func (server *Server) moviesByNameFragment(ctx *gin.Context) {
 	var req movieFragmentRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
 	fragment := req.Fragment
@@ -379,7 +450,7 @@ func (server *Server) moviesByNameFragment(ctx *gin.Context) {
 	}
 	movies, err := server.store.GetMoviesByNameFragment(ctx, sqlStr)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, movies)
@@ -390,43 +461,10 @@ type actorFragmentRequest struct {
 }
 
 // moviesByActorFragment retrieves movies by actor fragment.
-// swagger:operation GET /movies/by-actor-fragment movies moviesByActorFragment
-//
-// Retrieves movies by actor fragment.
-//
-// ---
-// consumes:
-// - application/json
-// produces:
-// - application/json
-// parameters:
-//   - name: body
-//     in: body
-//     description: The name fragment to search for.
-//     required: true
-//     schema:
-//     $ref: "#/definitions/ActorFragmentRequest"
-//
-// responses:
-//
-//	200:
-//	  description: Successful response with an array of movie objects
-//	  schema:
-//	    type: array
-//	    items:
-//	      $ref: "#/definitions/MovieResponse"
-//	400:
-//	  description: Bad request response
-//	  schema:
-//	    $ref: "#/definitions/ErrorResponse"
-//	500:
-//	  description: Internal server error response
-//	  schema:
-//	    $ref: "#/definitions/ErrorResponse"
 func (server *Server) moviesByActorFragment(ctx *gin.Context) {
 	var req actorFragmentRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
 		return
 	}
 	fragment := req.Fragment
@@ -436,7 +474,7 @@ func (server *Server) moviesByActorFragment(ctx *gin.Context) {
 	}
 	movies, err := server.store.GetMoviesByActorFragment(ctx, sqlStr)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, movies)
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/oidc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errOIDCDisabled       = errors.New("the login with an identity provider is not configured")
+	errInvalidOIDCState   = errors.New("the login is unknown or has expired, start it again")
+	errIdentityNotLinked  = errors.New("no user is linked to this identity, log in with a password and link it first")
+	errIdentityLinkedElse = errors.New("this identity is linked to another user")
+)
+
+// oidcLoginResponse represents a login started with the identity provider.
+type oidcLoginResponse struct {
+	// The URL of the identity provider to send the browser to, it comes back to the callback with the authorization code.
+	// Example: https://id.example.com/authorize?response_type=code&client_id=vk-film&code_challenge_method=S256&...
+	AuthorizationURL string `json:"authorization_url"`
+
+	// The time the login must be completed by.
+	// Example: "2022-03-17T09:10:00Z"
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// oidcCallbackRequest represents the query parameters the identity provider redirects the browser back with.
+type oidcCallbackRequest struct {
+	// The state of the login, sent to the identity provider when it started.
+	// required: true
+	State string `form:"state" binding:"required,max=100"`
+
+	// The authorization code, absent when the identity provider reports an error.
+	Code string `form:"code" binding:"max=2048"`
+
+	Error string `form:"error" binding:"max=100"`
+
+	ErrorDescription string `form:"error_description" binding:"max=1000"`
+}
+
+// identityURI represents the path of a linked identity in the v1 API.
+type identityURI struct {
+	// required: true
+	ID int32 `uri:"id" binding:"required,min=1"`
+}
+
+// identityResponse represents an account of the user at the identity provider, linked to the user.
+type identityResponse struct {
+	// Example: 1
+	ID int32 `json:"id"`
+
+	// Example: https://id.example.com
+	Issuer string `json:"issuer"`
+
+	// The ID of the account at the identity provider.
+	// Example: 248289761001
+	Subject string `json:"subject"`
+
+	// Example: vk-user@example.com
+	Email string `json:"email,omitempty"`
+
+	// Example: "2022-03-17T09:00:00Z"
+	CreatedAt time.Time `json:"created_at"`
+
+	// The time the user last logged in with the identity, absent before the first login.
+	// Example: "2022-03-18T09:00:00Z"
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+func newIdentityResponse(identity db.UserIdentity) identityResponse {
+	rsp := identityResponse{
+		ID:        identity.ID,
+		Issuer:    identity.Issuer,
+		Subject:   identity.Subject,
+		Email:     identity.Email.String,
+		CreatedAt: identity.CreatedAt,
+	}
+	if identity.LastLoginAt.Valid {
+		rsp.LastLoginAt = &identity.LastLoginAt.Time
+	}
+	return rsp
+}
+
+// startOIDCLoginV1 starts a login with the identity provider.
+// Starts a login with the identity provider by the authorization code flow with PKCE. The browser is sent to the URL returned,
+// the identity provider redirects it to the callback, which logs in the user linked to the identity.
+func (server *Server) startOIDCLoginV1(ctx *gin.Context) {
+	server.startOIDCLogin(ctx, sql.NullString{})
+}
+
+// linkIdentityV1 starts a login with the identity provider linking the identity to the authenticated user.
+// Starts a login with the identity provider like startOIDCLoginV1, the callback links the identity to the authenticated user
+// and logs the user in. The identity can then log in without a password.
+func (server *Server) linkIdentityV1(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	server.startOIDCLogin(ctx, sql.NullString{String: authPayload.Username, Valid: true})
+}
+
+// startOIDCLogin stores the secrets of a login, to be checked by the callback, and returns the URL of the identity provider.
+// The state and the PKCE code verifier never leave the server but through the identity provider, which only sees the challenge.
+func (server *Server) startOIDCLogin(ctx *gin.Context, linkTo sql.NullString) {
+	if server.oidcProvider == nil {
+		writeError(ctx, errOIDCDisabled)
+		return
+	}
+	state, stateHash, err := token.NewOpaqueToken()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	authorizationURL, err := server.oidcProvider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		writeStatusError(ctx, http.StatusBadGateway, err)
+		return
+	}
+	now := time.Now()
+	expiresAt := now.Add(server.config.OIDCLoginDuration)
+	err = server.store.CreateOIDCLogin(ctx, db.CreateOIDCLoginParams{
+		StateHash:    stateHash,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		Username:     linkTo,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := server.store.DeleteExpiredOIDCLogins(ctx, now); err != nil {
+		log.Printf("cannot delete the expired logins with the identity provider: %v", err)
+	}
+	ctx.JSON(http.StatusOK, oidcLoginResponse{AuthorizationURL: authorizationURL, ExpiresAt: expiresAt})
+}
+
+// oidcCallbackV1 completes a login with the identity provider.
+// Completes a login with the identity provider: the authorization code is exchanged for an ID token, and the user linked to
+// its identity is logged in like with a password, the users with two-factor authentication getting a challenge. An identity
+// is linked by linkIdentityV1, or at its first login to the user whose verified email the identity provider verified too.
+// The groups mapped to a role by OIDC_GROUP_ROLES set the role of the user.
+func (server *Server) oidcCallbackV1(ctx *gin.Context) {
+	if server.oidcProvider == nil {
+		writeError(ctx, errOIDCDisabled)
+		return
+	}
+	var req oidcCallbackRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	// the login is used once, whatever its outcome
+	login, err := server.store.TakeOIDCLogin(ctx, db.TakeOIDCLoginParams{
+		StateHash: token.HashOpaqueToken(req.State),
+		Now:       time.Now(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, errInvalidOIDCState)
+			return
+		}
+		writeError(ctx, err)
+		return
+	}
+	if req.Error != "" {
+		writeStatusError(ctx, http.StatusUnauthorized, &oidc.Error{Code: req.Error, Description: req.ErrorDescription})
+		return
+	}
+	if req.Code == "" {
+		writeStatusError(ctx, http.StatusBadRequest, errors.New("the authorization code is missing"))
+		return
+	}
+	identity, err := server.oidcProvider.Exchange(ctx, req.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		var providerErr *oidc.Error
+		if errors.As(err, &providerErr) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			writeStatusError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+		writeStatusError(ctx, http.StatusBadGateway, err)
+		return
+	}
+	user, err := server.identityUser(ctx, identity, login.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if user.DisabledAt.Valid {
+		writeError(ctx, token.ErrUserDisabled)
+		return
+	}
+	user, err = server.syncGroupRole(ctx, user, identity.Groups)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.completeLogin(ctx, user)
+}
+
+// identityUser returns the user linked to an identity, linking it when the login links it to a user or when the identity
+// provider verified the email of a user who verified it here too. The identities of nobody are rejected, they are never
+// made into users.
+func (server *Server) identityUser(ctx *gin.Context, identity oidc.Identity, linkTo sql.NullString) (db.User, error) {
+	email := sql.NullString{String: identity.Email, Valid: identity.Email != ""}
+	lastLoginAt := sql.NullTime{Time: time.Now(), Valid: true}
+	linked, err := server.store.GetUserIdentity(ctx, db.GetUserIdentityParams{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+	})
+	if err == nil {
+		if linkTo.Valid && linkTo.String != linked.Username {
+			return db.User{}, errIdentityLinkedElse
+		}
+		_, err = server.store.UpdateUserIdentityLogin(ctx, db.UpdateUserIdentityLoginParams{
+			ID:          linked.ID,
+			Email:       email,
+			LastLoginAt: lastLoginAt,
+		})
+		if err != nil {
+			return db.User{}, err
+		}
+		return server.store.GetUser(ctx, linked.Username)
+	}
+	if err != sql.ErrNoRows {
+		return db.User{}, err
+	}
+	var user db.User
+	if linkTo.Valid {
+		user, err = server.store.GetUser(ctx, linkTo.String)
+	} else if identity.EmailVerified && identity.Email != "" {
+		user, err = server.store.GetUserByEmail(ctx, identity.Email)
+		if err == nil && !user.EmailVerifiedAt.Valid {
+			err = errIdentityNotLinked
+		}
+	} else {
+		err = errIdentityNotLinked
+	}
+	if err == sql.ErrNoRows {
+		err = errIdentityNotLinked
+	}
+	if err != nil {
+		return db.User{}, err
+	}
+	_, err = server.store.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		Issuer:      identity.Issuer,
+		Subject:     identity.Subject,
+		Username:    user.Username,
+		Email:       email,
+		LastLoginAt: lastLoginAt,
+	})
+	if err != nil {
+		return db.User{}, err
+	}
+	log.Printf("identity %s of %s linked to user %q", identity.Subject, identity.Issuer, user.Username)
+	return user, nil
+}
+
+// syncGroupRole gives a user the role mapped to the first of its groups in OIDC_GROUP_ROLES. The users in none of the mapped
+// groups keep their role, so that the roles assigned here hold for them. A changed role revokes the tokens of the previous one.
+func (server *Server) syncGroupRole(ctx *gin.Context, user db.User, groups []string) (db.User, error) {
+	role, ok := oidc.RoleForGroups(server.oidcGroupRoles, groups)
+	if !ok || role == user.Role {
+		return user, nil
+	}
+	updated, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Username: user.Username,
+		Role:     role,
+	})
+	if err != nil {
+		return db.User{}, err
+	}
+	_, err = server.revocations.RevokeUser(ctx, user.Username)
+	if err != nil {
+		return db.User{}, err
+	}
+	log.Printf("role of user %q changed from %q to %q by the groups of the identity provider", user.Username, user.Role, role)
+	return updated, nil
+}
+
+// listIdentitiesV1 lists the identities linked to the authenticated user.
+func (server *Server) listIdentitiesV1(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	identities, err := server.store.ListUserIdentities(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]identityResponse, 0, len(identities))
+	for _, identity := range identities {
+		rsp = append(rsp, newIdentityResponse(identity))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// unlinkIdentityV1 unlinks an identity from the authenticated user.
+func (server *Server) unlinkIdentityV1(ctx *gin.Context) {
+	var uri identityURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	// the identities of the other users are reported as missing
+	rows, err := server.store.DeleteUserIdentity(ctx, db.DeleteUserIdentityParams{
+		ID:       uri.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if rows == 0 {
+		writeError(ctx, sql.ErrNoRows)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	openapiBearerScheme = "bearer"
+	openapiAPIKeyScheme = "apiKey"
+)
+
+//go:embed docs/index.html
+var docsPage []byte
+
+// jsonPatchOperation is an operation of a JSON Patch (RFC 6902) document.
+type jsonPatchOperation struct {
+	Op    string      `json:"op" binding:"required,oneof=add remove replace move copy test"`
+	Path  string      `json:"path" binding:"required"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// openapiOperation describes a route of the v1 API in the generated OpenAPI document.
+// The parameters, bodies and responses are generated from the same types the handlers bind and write.
+type openapiOperation struct {
+	method  string
+	path    string
+	id      string
+	tag     string
+	summary string
+	// public operations do not require an access token
+	public bool
+	// the permission the role of the user must grant, if any
+	permission string
+	// params is a struct whose uri and form fields are the path and query parameters
+	params []interface{}
+	body   interface{}
+	// patch operations accept a JSON Merge Patch of body or a JSON Patch
+	patch    bool
+	status   int
+	response interface{}
+	// accepted is the response of the operations that may answer 202 Accepted instead, such as the logins waiting for a second factor
+	accepted interface{}
+	// cacheable operations answer 304 Not Modified to conditional requests
+	cacheable bool
+}
+
+var openapiOperations = []openapiOperation{
+	{method: http.MethodPost, path: "/v1/users", id: "createUser", tag: "users", summary: "Creates a new user.", public: true, body: createUserRequest{}, status: http.StatusCreated, response: userResponse{}},
+	{method: http.MethodPost, path: "/v1/users/login", id: "loginUser", tag: "users", summary: "Logs in a user, the users with two-factor authentication get a challenge completed with a TOTP code.", public: true, body: loginUserRequest{}, status: http.StatusOK, response: loginUserResponse{}, accepted: loginChallengeResponse{}},
+	{method: http.MethodPost, path: "/v1/users/login/verify", id: "verifyLogin", tag: "users", summary: "Completes a login answered by a challenge with a code of the authenticator or a recovery code.", public: true, body: verifyLoginRequest{}, status: http.StatusOK, response: loginUserResponse{}},
+	{method: http.MethodPost, path: "/v1/users/login/totp", id: "enrollLoginTOTP", tag: "users", summary: "Enrolls the authenticator required by the role of the user during a login, its first code completes the login.", public: true, body: loginChallengeRequest{}, status: http.StatusCreated, response: totpEnrollmentResponse{}},
+	{method: http.MethodPost, path: "/v1/users/oidc/authorize", id: "startOIDCLogin", tag: "users", summary: "Starts a login with the identity provider by the authorization code flow with PKCE.", public: true, status: http.StatusOK, response: oidcLoginResponse{}},
+	{method: http.MethodGet, path: "/v1/users/oidc/callback", id: "oidcCallback", tag: "users", summary: "Completes a login with the identity provider, logging in the user linked to the identity.", public: true, params: []interface{}{oidcCallbackRequest{}}, status: http.StatusOK, response: loginUserResponse{}, accepted: loginChallengeResponse{}},
+	{method: http.MethodPost, path: "/v1/tokens/renew", id: "renewAccessToken", tag: "users", summary: "Exchanges a refresh token for a new access token and a new refresh token, reusing a refresh token revokes its session.", public: true, body: renewAccessTokenRequest{}, status: http.StatusOK, response: renewAccessTokenResponse{}},
+	{method: http.MethodPost, path: "/v1/users/password", id: "changePassword", tag: "users", summary: "Changes the password of a user, authenticated by the current password, the forced password resets are completed with a reset token.", public: true, body: changePasswordRequest{}, status: http.StatusOK, response: userResponse{}},
+	{method: http.MethodPost, path: "/v1/users/password/forgot", id: "forgotPassword", tag: "users", summary: "Sends a password reset link to a verified email, answering the same whether a user has the email or not.", public: true, body: forgotPasswordRequest{}, status: http.StatusAccepted},
+	{method: http.MethodPost, path: "/v1/users/password/reset", id: "resetPassword", tag: "users", summary: "Replaces the password of a user with the token of a password reset link.", public: true, body: resetPasswordRequest{}, status: http.StatusOK, response: userResponse{}},
+	{method: http.MethodPut, path: "/v1/users/email", id: "updateEmail", tag: "users", summary: "Changes the email of the authenticated user and sends a link verifying it.", body: updateEmailRequest{}, status: http.StatusOK, response: userResponse{}},
+	{method: http.MethodPost, path: "/v1/users/email/verification", id: "sendEmailVerification", tag: "users", summary: "Sends the verification link of the email of the authenticated user again.", status: http.StatusAccepted},
+	{method: http.MethodPost, path: "/v1/users/email/verify", id: "verifyEmail", tag: "users", summary: "Verifies an email with the token of its verification link.", public: true, body: userTokenRequest{}, status: http.StatusOK, response: userResponse{}},
+	{method: http.MethodPost, path: "/v1/users/logout", id: "logoutUser", tag: "users", summary: "Logs out, revoking the access token and ending the session of the refresh token sent.", body: logoutUserRequest{}, status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/v1/users", id: "listUsers", tag: "users", summary: "Lists a page of users filtered by username, role and status, sorted by username.", permission: authz.UsersManage, params: []interface{}{listUsersRequest{}}, status: http.StatusOK, response: []userDetailsResponse{}},
+	{method: http.MethodGet, path: "/v1/users/:username", id: "getUser", tag: "users", summary: "Retrieves a user with the state of the account.", permission: authz.UsersManage, params: []interface{}{userURI{}}, status: http.StatusOK, response: userDetailsResponse{}},
+	{method: http.MethodPost, path: "/v1/users/:username/disable", id: "disableUser", tag: "users", summary: "Disables a user, revoking the tokens and the sessions of the user.", permission: authz.UsersManage, params: []interface{}{userURI{}}, status: http.StatusOK, response: userDetailsResponse{}},
+	{method: http.MethodPost, path: "/v1/users/:username/enable", id: "enableUser", tag: "users", summary: "Enables a disabled user.", permission: authz.UsersManage, params: []interface{}{userURI{}}, status: http.StatusOK, response: userDetailsResponse{}},
+	{method: http.MethodPost, path: "/v1/users/:username/password-reset", id: "requirePasswordReset", tag: "users", summary: "Forces a user to choose a new password with a reset token before logging in again, revoking the tokens and the sessions of the user. The token is emailed, or returned when the user has no verified email.", permission: authz.UsersManage, params: []interface{}{userURI{}}, status: http.StatusOK, response: passwordResetResponse{}},
+	{method: http.MethodPost, path: "/v1/users/:username/tokens/revoke", id: "revokeUserTokens", tag: "users", summary: "Revokes the access tokens issued to a user until now and ends every session of the user.", permission: authz.UsersManage, params: []interface{}{userURI{}}, status: http.StatusOK, response: tokenRevocationResponse{}},
+	{method: http.MethodPut, path: "/v1/users/:username/role", id: "assignUserRole", tag: "users", summary: "Assigns a role to a user, revoking the tokens and the sessions of the user.", permission: authz.RolesManage, params: []interface{}{userURI{}}, body: assignRoleRequest{}, status: http.StatusOK, response: userResponse{}},
+	{method: http.MethodPost, path: "/v1/users/identities", id: "linkIdentity", tag: "users", summary: "Starts a login with the identity provider linking the identity to the authenticated user.", status: http.StatusOK, response: oidcLoginResponse{}},
+	{method: http.MethodGet, path: "/v1/users/identities", id: "listIdentities", tag: "users", summary: "Lists the accounts at the identity provider linked to the authenticated user.", status: http.StatusOK, response: []identityResponse{}},
+	{method: http.MethodDelete, path: "/v1/users/identities/:id", id: "unlinkIdentity", tag: "users", summary: "Unlinks an account at the identity provider from the authenticated user.", params: []interface{}{identityURI{}}, status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/v1/users/totp", id: "getTwoFactor", tag: "users", summary: "Returns the state of the two-factor authentication of the authenticated user.", status: http.StatusOK, response: twoFactorResponse{}},
+	{method: http.MethodPost, path: "/v1/users/totp", id: "enrollTOTP", tag: "users", summary: "Enrolls an authenticator for the authenticated user, confirmed by its first code.", status: http.StatusCreated, response: totpEnrollmentResponse{}},
+	{method: http.MethodPost, path: "/v1/users/totp/confirm", id: "confirmTOTP", tag: "users", summary: "Confirms the enrolled authenticator with its first code, enabling two-factor authentication and returning the recovery codes.", body: confirmTOTPRequest{}, status: http.StatusOK, response: recoveryCodesResponse{}},
+	{method: http.MethodPost, path: "/v1/users/totp/recovery-codes", id: "regenerateRecoveryCodes", tag: "users", summary: "Replaces the recovery codes of the authenticated user, checked by a code of the authenticator or a recovery code.", body: secondFactorRequest{}, status: http.StatusOK, response: recoveryCodesResponse{}},
+	{method: http.MethodPost, path: "/v1/users/totp/disable", id: "disableTOTP", tag: "users", summary: "Disables two-factor authentication for the authenticated user, unless the role of the user requires it.", body: secondFactorRequest{}, status: http.StatusNoContent},
+	{method: http.MethodPost, path: "/v1/users/:username/totp/reset", id: "resetTwoFactor", tag: "users", summary: "Disables two-factor authentication for a user who lost the authenticator and the recovery codes.", permission: authz.UsersManage, params: []interface{}{userURI{}}, status: http.StatusOK, response: twoFactorResponse{}},
+	{method: http.MethodGet, path: "/v1/sessions", id: "listSessions", tag: "users", summary: "Lists the active sessions of the authenticated user, most recently used first.", status: http.StatusOK, response: []sessionResponse{}},
+	{method: http.MethodDelete, path: "/v1/sessions/:id", id: "revokeSession", tag: "users", summary: "Revokes a session of the authenticated user.", params: []interface{}{sessionURI{}}, status: http.StatusNoContent},
+
+	{method: http.MethodGet, path: "/v1/permissions", id: "listPermissions", tag: "roles", summary: "Lists the permissions a role can grant.", permission: authz.RolesManage, status: http.StatusOK, response: []permissionResponse{}},
+	{method: http.MethodGet, path: "/v1/roles", id: "listRoles", tag: "roles", summary: "Lists the roles with their permissions, sorted by name.", permission: authz.RolesManage, status: http.StatusOK, response: []roleResponse{}},
+	{method: http.MethodPost, path: "/v1/roles", id: "createRole", tag: "roles", summary: "Creates a role granting a set of permissions.", permission: authz.RolesManage, body: createRoleRequest{}, status: http.StatusCreated, response: roleResponse{}},
+	{method: http.MethodGet, path: "/v1/roles/:name", id: "getRole", tag: "roles", summary: "Retrieves a role with its permissions.", permission: authz.RolesManage, params: []interface{}{roleURI{}}, status: http.StatusOK, response: roleResponse{}},
+	{method: http.MethodPut, path: "/v1/roles/:name", id: "updateRole", tag: "roles", summary: "Replaces the description and the permissions of a role.", permission: authz.RolesManage, params: []interface{}{roleURI{}}, body: updateRoleRequest{}, status: http.StatusOK, response: roleResponse{}},
+	{method: http.MethodDelete, path: "/v1/roles/:name", id: "deleteRole", tag: "roles", summary: "Deletes a role no user has.", permission: authz.RolesManage, params: []interface{}{roleURI{}}, status: http.StatusNoContent},
+
+	{method: http.MethodPost, path: "/v1/api-keys", id: "createAPIKey", tag: "api-keys", summary: "Issues an API key granting a set of permissions, the response is the only one holding the key.", permission: authz.APIKeysManage, body: createAPIKeyRequest{}, status: http.StatusCreated, response: createAPIKeyResponse{}},
+	{method: http.MethodGet, path: "/v1/api-keys", id: "listAPIKeys", tag: "api-keys", summary: "Lists the API keys, the revoked ones included.", permission: authz.APIKeysManage, status: http.StatusOK, response: []apiKeyResponse{}},
+	{method: http.MethodGet, path: "/v1/api-keys/:id", id: "getAPIKey", tag: "api-keys", summary: "Retrieves an API key, with the time it was last used.", permission: authz.APIKeysManage, params: []interface{}{apiKeyURI{}}, status: http.StatusOK, response: apiKeyResponse{}},
+	{method: http.MethodPost, path: "/v1/api-keys/:id/revoke", id: "revokeAPIKey", tag: "api-keys", summary: "Revokes an API key, it is rejected from the next request on.", permission: authz.APIKeysManage, params: []interface{}{apiKeyURI{}}, status: http.StatusOK, response: apiKeyResponse{}},
+
+	{method: http.MethodPost, path: "/v1/movies", id: "createMovie", tag: "movies", summary: "Creates a new movie, warning about existing movies that are likely the same title.", permission: authz.MoviesWrite, body: createMovieRequest{}, status: http.StatusCreated, response: createMovieResponse{}},
+	{method: http.MethodGet, path: "/v1/movies", id: "listMovies", tag: "movies", summary: "Lists a page of movies filtered by name, release date and rating.", params: []interface{}{listMoviesRequest{}}, status: http.StatusOK, response: []movieResponse{}, cacheable: true},
+	{method: http.MethodGet, path: "/v1/movies/:id", id: "getMovie", tag: "movies", summary: "Retrieves a movie, its version is sent as the ETag header.", params: []interface{}{resourceURI{}}, status: http.StatusOK, response: movieResponse{}},
+	{method: http.MethodPatch, path: "/v1/movies/:id", id: "updateMovie", tag: "movies", summary: "Updates the fields of a movie touched by a JSON Merge Patch or a JSON Patch.", permission: authz.MoviesWrite, params: []interface{}{resourceURI{}}, body: updateMovieRequest{}, patch: true, status: http.StatusOK, response: movieResponse{}},
+	{method: http.MethodDelete, path: "/v1/movies/:id", id: "deleteMovie", tag: "movies", summary: "Deletes a movie.", permission: authz.MoviesDelete, params: []interface{}{resourceURI{}}, status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/v1/movies/:id/actors", id: "listMovieCast", tag: "movies", summary: "Lists the actors starring in a movie.", params: []interface{}{resourceURI{}}, status: http.StatusOK, response: []actorResponse{}, cacheable: true},
+	{method: http.MethodPost, path: "/v1/movies/:id/merge", id: "mergeMovie", tag: "movies", summary: "Merges a duplicate movie into this one.", permission: authz.MoviesDelete, params: []interface{}{resourceURI{}}, body: mergeIntoRequest{}, status: http.StatusOK, response: movieResponse{}},
+	{method: http.MethodGet, path: "/v1/movies/:id/revisions", id: "listMovieRevisions", tag: "movies", summary: "Lists the revisions of a movie, newest first.", permission: authz.RevisionsRead, params: []interface{}{resourceURI{}}, status: http.StatusOK, response: []revisionResponse{}},
+	{method: http.MethodGet, path: "/v1/movies/:id/revisions/diff", id: "diffMovieRevisions", tag: "movies", summary: "Lists the fields that differ between two revisions of a movie.", permission: authz.RevisionsRead, params: []interface{}{resourceURI{}, revisionRangeRequest{}}, status: http.StatusOK, response: revisionDiffResponse{}},
+	{method: http.MethodPost, path: "/v1/movies/:id/revisions/:revision/restore", id: "restoreMovieRevision", tag: "movies", summary: "Restores a movie to an earlier revision.", permission: authz.MoviesWrite, params: []interface{}{revisionURI{}}, status: http.StatusOK, response: movieResponse{}},
+
+	{method: http.MethodPost, path: "/v1/actors", id: "createActor", tag: "actors", summary: "Creates a new actor, warning about existing actors that are likely the same person.", permission: authz.ActorsWrite, body: createActorRequest{}, status: http.StatusCreated, response: createActorResponse{}},
+	{method: http.MethodGet, path: "/v1/actors", id: "listActors", tag: "actors", summary: "Lists a page of actors filtered by name and gender.", params: []interface{}{listActorsRequest{}}, status: http.StatusOK, response: []actorResponse{}, cacheable: true},
+	{method: http.MethodGet, path: "/v1/actors/:id", id: "getActor", tag: "actors", summary: "Retrieves an actor, its version is sent as the ETag header.", params: []interface{}{resourceURI{}}, status: http.StatusOK, response: actorResponse{}},
+	{method: http.MethodPatch, path: "/v1/actors/:id", id: "updateActor", tag: "actors", summary: "Updates the fields of an actor touched by a JSON Merge Patch or a JSON Patch.", permission: authz.ActorsWrite, params: []interface{}{resourceURI{}}, body: updateActorRequest{}, patch: true, status: http.StatusOK, response: actorResponse{}},
+	{method: http.MethodDelete, path: "/v1/actors/:id", id: "deleteActor", tag: "actors", summary: "Deletes an actor.", permission: authz.ActorsDelete, params: []interface{}{resourceURI{}}, status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/v1/actors/:id/movies", id: "listActorMovies", tag: "actors", summary: "Lists the movies an actor starred in.", params: []interface{}{resourceURI{}}, status: http.StatusOK, response: []movieResponse{}, cacheable: true},
+	{method: http.MethodPost, path: "/v1/actors/:id/merge", id: "mergeActor", tag: "actors", summary: "Merges a duplicate actor into this one.", permission: authz.ActorsDelete, params: []interface{}{resourceURI{}}, body: mergeIntoRequest{}, status: http.StatusOK, response: actorResponse{}},
+	{method: http.MethodGet, path: "/v1/actors/:id/revisions", id: "listActorRevisions", tag: "actors", summary: "Lists the revisions of an actor, newest first.", permission: authz.RevisionsRead, params: []interface{}{resourceURI{}}, status: http.StatusOK, response: []revisionResponse{}},
+	{method: http.MethodGet, path: "/v1/actors/:id/revisions/diff", id: "diffActorRevisions", tag: "actors", summary: "Lists the fields that differ between two revisions of an actor.", permission: authz.RevisionsRead, params: []interface{}{resourceURI{}, revisionRangeRequest{}}, status: http.StatusOK, response: revisionDiffResponse{}},
+	{method: http.MethodPost, path: "/v1/actors/:id/revisions/:revision/restore", id: "restoreActorRevision", tag: "actors", summary: "Restores an actor to an earlier revision.", permission: authz.ActorsWrite, params: []interface{}{revisionURI{}}, status: http.StatusOK, response: actorResponse{}},
+
+	{method: http.MethodGet, path: "/v1/audit-entries", id: "listAuditEntries", tag: "audit", summary: "Lists audit log entries filtered by entity, user and time range, newest first.", permission: authz.AuditRead, params: []interface{}{listAuditEntriesRequest{}}, status: http.StatusOK, response: []auditEntryResponse{}},
+	{method: http.MethodGet, path: "/v1/security-events", id: "listSecurityEvents", tag: "audit", summary: "Lists the security log entries, such as the lockouts after too many failed logins, newest first.", permission: authz.AuditRead, params: []interface{}{listSecurityEventsRequest{}}, status: http.StatusOK, response: []securityEventResponse{}},
+	{method: http.MethodGet, path: "/v1/cache/stats", id: "getCacheStats", tag: "cache", summary: "Returns the counters of the read cache.", permission: authz.CacheRead, status: http.StatusOK, response: db.CacheStats{}},
+}
+
+// newOpenAPIDocument generates the OpenAPI 3 document of the v1 API from openapiOperations.
+func newOpenAPIDocument() (*openapi3.T, error) {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "VK Film API",
+			Description: "This API provides endpoints for managing movies and actors.",
+			Version:     "1.0.0",
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: make(openapi3.Schemas),
+			SecuritySchemes: openapi3.SecuritySchemes{
+				openapiBearerScheme: &openapi3.SecuritySchemeRef{
+					Value: openapi3.NewJWTSecurityScheme(),
+				},
+				openapiAPIKeyScheme: &openapi3.SecuritySchemeRef{
+					Value: openapi3.NewSecurityScheme().WithType("apiKey").WithIn(openapi3.ParameterInHeader).WithName(apiKeyHeader),
+				},
+			},
+		},
+	}
+	spec := openapiSpec{doc: doc}
+	errorSchema, err := spec.schema(problem{})
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range openapiOperations {
+		operation := openapi3.NewOperation()
+		operation.OperationID = op.id
+		operation.Tags = []string{op.tag}
+		operation.Summary = op.summary
+		if !op.public {
+			operation.Security = &openapi3.SecurityRequirements{
+				openapi3.NewSecurityRequirement().Authenticate(openapiBearerScheme),
+				openapi3.NewSecurityRequirement().Authenticate(openapiAPIKeyScheme),
+			}
+		}
+		if op.permission != "" {
+			operation.Description = fmt.Sprintf("Requires the %s permission.", op.permission)
+		}
+		for _, params := range op.params {
+			parameters, err := spec.parameters(params)
+			if err != nil {
+				return nil, fmt.Errorf("cannot describe the parameters of %s: %v", op.id, err)
+			}
+			operation.Parameters = append(operation.Parameters, parameters...)
+		}
+		if op.body != nil {
+			body, err := spec.requestBody(op.body, op.patch)
+			if err != nil {
+				return nil, fmt.Errorf("cannot describe the body of %s: %v", op.id, err)
+			}
+			operation.RequestBody = &openapi3.RequestBodyRef{Value: body}
+		}
+
+		response := openapi3.NewResponse().WithDescription(http.StatusText(op.status))
+		if op.response != nil {
+			schema, err := spec.schema(op.response)
+			if err != nil {
+				return nil, fmt.Errorf("cannot describe the response of %s: %v", op.id, err)
+			}
+			response.WithJSONSchemaRef(schema)
+		}
+		operation.AddResponse(op.status, response)
+		if op.accepted != nil {
+			schema, err := spec.schema(op.accepted)
+			if err != nil {
+				return nil, fmt.Errorf("cannot describe the accepted response of %s: %v", op.id, err)
+			}
+			operation.AddResponse(http.StatusAccepted, openapi3.NewResponse().WithDescription(http.StatusText(http.StatusAccepted)).WithJSONSchemaRef(schema))
+		}
+		if op.cacheable {
+			operation.AddResponse(http.StatusNotModified, openapi3.NewResponse().WithDescription(http.StatusText(http.StatusNotModified)))
+		}
+		operation.Responses.Set("default", &openapi3.ResponseRef{
+			Value: openapi3.NewResponse().WithDescription("Error").
+				WithContent(openapi3.NewContentWithSchemaRef(errorSchema, []string{problemMediaType})),
+		})
+		doc.AddOperation(openapiPath(op.path), op.method, operation)
+	}
+	return doc, doc.Validate(context.Background())
+}
+
+// openapiPath converts the parameters of a gin path to the OpenAPI syntax.
+func openapiPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+type openapiSpec struct {
+	doc *openapi3.T
+}
+
+// schema generates the schema of a value, structs are added to the components and referenced by name.
+func (spec openapiSpec) schema(value interface{}) (*openapi3.SchemaRef, error) {
+	t := reflect.TypeOf(value)
+	if t.Kind() == reflect.Slice {
+		items, err := spec.schema(reflect.Zero(t.Elem()).Interface())
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewArraySchema()
+		schema.Items = items
+		return openapi3.NewSchemaRef("", schema), nil
+	}
+	ref, err := openapi3gen.NewSchemaRefForValue(value, nil, openapi3gen.SchemaCustomizer(customizeSchema))
+	if err != nil {
+		return nil, err
+	}
+	if t.Kind() != reflect.Struct {
+		return ref, nil
+	}
+	spec.doc.Components.Schemas[t.Name()] = openapi3.NewSchemaRef("", ref.Value)
+	return openapi3.NewSchemaRef("#/components/schemas/"+t.Name(), ref.Value), nil
+}
+
+// requestBody describes a JSON body, patch bodies are a JSON Merge Patch of the value or a JSON Patch.
+func (spec openapiSpec) requestBody(value interface{}, patch bool) (*openapi3.RequestBody, error) {
+	schema, err := spec.schema(value)
+	if err != nil {
+		return nil, err
+	}
+	body := openapi3.NewRequestBody().WithRequired(true)
+	if !patch {
+		return body.WithJSONSchemaRef(schema), nil
+	}
+	operations, err := spec.schema([]jsonPatchOperation{})
+	if err != nil {
+		return nil, err
+	}
+	content := openapi3.NewContentWithSchemaRef(schema, []string{contentTypeMergePatch, gin.MIMEJSON})
+	content[contentTypeJSONPatch] = openapi3.NewMediaType().WithSchemaRef(operations)
+	return body.WithContent(content), nil
+}
+
+// parameters describes the uri fields of a struct as path parameters and its form fields as query parameters.
+func (spec openapiSpec) parameters(value interface{}) (openapi3.Parameters, error) {
+	t := reflect.TypeOf(value)
+	var parameters openapi3.Parameters
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		var parameter *openapi3.Parameter
+		if name, ok := field.Tag.Lookup("uri"); ok {
+			parameter = openapi3.NewPathParameter(name)
+		} else if name, ok := field.Tag.Lookup("form"); ok {
+			parameter = openapi3.NewQueryParameter(strings.Split(name, ",")[0])
+			parameter.Required = hasBindingRule(field.Tag, "required")
+		} else {
+			continue
+		}
+		ref, err := openapi3gen.NewSchemaRefForValue(reflect.Zero(field.Type).Interface(), nil)
+		if err != nil {
+			return nil, err
+		}
+		schema := ref.Value
+		customizeSchema(field.Name, field.Type, field.Tag, schema)
+		parameters = append(parameters, &openapi3.ParameterRef{Value: parameter.WithSchema(schema)})
+	}
+	return parameters, nil
+}
+
+// customizeSchema adds the rules of the binding tags to the generated schemas, so that the document
+// requires exactly what the handlers validate.
+func customizeSchema(name string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	if t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) {
+		schema.Required = requiredFields(t)
+		return nil
+	}
+	if layout := tag.Get("time_format"); layout == "2006-01-02" {
+		schema.Format = "date"
+	}
+	if form := tag.Get("form"); strings.Contains(form, "default=") {
+		schema.Default = defaultValue(schema, form[strings.Index(form, "default=")+len("default="):])
+	}
+	for _, rule := range strings.Split(tag.Get("binding"), ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "min", "max":
+			limit, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s rule of %s: %v", key, name, err)
+			}
+			setLimit(schema, key, limit)
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, option)
+			}
+		case "uuid":
+			schema.Format = "uuid"
+		case "email":
+			schema.Format = "email"
+		}
+	}
+	return nil
+}
+
+// requiredFields lists the JSON names of the fields of a struct, embedded ones included, bound as required.
+func requiredFields(t reflect.Type) []string {
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			required = append(required, requiredFields(field.Type)...)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(name) > 0 && hasBindingRule(field.Tag, "required") {
+			required = append(required, name)
+		}
+	}
+	return required
+}
+
+func hasBindingRule(tag reflect.StructTag, rule string) bool {
+	for _, r := range strings.Split(tag.Get("binding"), ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// setLimit applies a min or max rule, which bounds the length of strings and arrays and the value of numbers.
+func setLimit(schema *openapi3.Schema, key string, limit float64) {
+	if schema.Type.Is(openapi3.TypeArray) {
+		items := uint64(limit)
+		if key == "min" {
+			schema.MinItems = items
+		} else {
+			schema.MaxItems = &items
+		}
+		return
+	}
+	if schema.Type.Is(openapi3.TypeString) {
+		length := uint64(limit)
+		if key == "min" {
+			schema.MinLength = length
+		} else {
+			schema.MaxLength = &length
+		}
+		return
+	}
+	if key == "min" {
+		schema.Min = &limit
+	} else {
+		schema.Max = &limit
+	}
+}
+
+func defaultValue(schema *openapi3.Schema, value string) interface{} {
+	if schema.Type.Is(openapi3.TypeInteger) {
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
+	}
+	return value
+}
+
+// getOpenAPIDocument serves the OpenAPI 3 document of the v1 API.
+func (server *Server) getOpenAPIDocument(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, server.openapi)
+}
+
+// getDocs serves the interactive documentation of the v1 API.
+func (server *Server) getDocs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAPIOperationsMatchRoutes keeps the operations of the OpenAPI document in step with the routes of the v1 API,
+// a route added to setupV1Routes without its operation, or the other way round, fails the test.
+func TestOpenAPIOperationsMatchRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	server := &Server{}
+	server.setupV1Routes(router)
+
+	routes := []string{}
+	for _, route := range router.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	operations := []string{}
+	for _, op := range openapiOperations {
+		operations = append(operations, op.method+" "+op.path)
+	}
+	require.ElementsMatch(t, routes, operations)
+}
+
+// handlerTypes are the types a handler binds and writes, gathered from its source and from the functions it calls.
+type handlerTypes struct {
+	bodies    map[string]bool
+	params    map[string]bool
+	responses map[int]map[string]bool
+}
+
+// TestOpenAPIOperationsMatchHandlerTypes keeps the bodies, parameters and responses of the OpenAPI operations in step
+// with the types their handlers bind and write, so a handler changing its request or response type fails the test.
+func TestOpenAPIOperationsMatchHandlerTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	server := &Server{}
+	server.setupV1Routes(router)
+	handlers := map[string]string{}
+	for _, route := range router.Routes() {
+		handlers[route.Method+" "+route.Path] = handlerMethod(route.Handler)
+	}
+
+	analyzer := newHandlerAnalyzer(t)
+	for _, op := range openapiOperations {
+		t.Run(op.id, func(t *testing.T) {
+			name, ok := handlers[op.method+" "+op.path]
+			require.True(t, ok, "no route for %s %s", op.method, op.path)
+			found := analyzer.types(name)
+
+			bodies := []string{}
+			if op.body != nil {
+				bodies = append(bodies, typeName(op.body))
+			}
+			require.ElementsMatch(t, bodies, keys(found.bodies), "bodies of %s", name)
+			for _, param := range op.params {
+				require.Contains(t, found.params, typeName(param), "parameters of %s", name)
+			}
+
+			responses := []string{}
+			if op.response != nil {
+				responses = append(responses, typeName(op.response))
+			}
+			require.ElementsMatch(t, responses, keys(found.responses[op.status]), "responses of %s", name)
+			accepted := []string{}
+			if op.accepted != nil {
+				accepted = append(accepted, typeName(op.accepted))
+			}
+			if op.status != http.StatusAccepted {
+				require.ElementsMatch(t, accepted, keys(found.responses[http.StatusAccepted]), "accepted responses of %s", name)
+			}
+		})
+	}
+}
+
+// handlerMethod returns the name of the function or method gin reports for a handler, like createUserV1 for
+// vk-film/api.(*Server).createUserV1-fm.
+func handlerMethod(handler string) string {
+	name := handler[strings.LastIndex(handler, "/")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	name = strings.TrimPrefix(name, "api.")
+	name = strings.TrimPrefix(name, "(*Server).")
+	return strings.Split(name, ".")[0]
+}
+
+func typeName(value interface{}) string {
+	return reflect.TypeOf(value).String()
+}
+
+func keys[T comparable](set map[T]bool) []T {
+	list := make([]T, 0, len(set))
+	for key := range set {
+		list = append(list, key)
+	}
+	return list
+}
+
+// handlerAnalyzer reads the types bound and written by the handlers from the type-checked source of the package.
+type handlerAnalyzer struct {
+	info  *types.Info
+	decls map[string]*ast.FuncDecl
+}
+
+func newHandlerAnalyzer(t *testing.T) *handlerAnalyzer {
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+	files := []*ast.File{}
+	for _, file := range packages["api"].Files {
+		files = append(files, file)
+	}
+	analyzer := &handlerAnalyzer{
+		info: &types.Info{
+			Types: map[ast.Expr]types.TypeAndValue{},
+			Uses:  map[*ast.Ident]types.Object{},
+		},
+		decls: map[string]*ast.FuncDecl{},
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "gc", exportLookup(t))}
+	_, err = config.Check("vk-film/api", fset, files, analyzer.info)
+	require.NoError(t, err)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				analyzer.decls[funcKey(fn.Recv != nil, fn.Name.Name)] = fn
+			}
+		}
+	}
+	return analyzer
+}
+
+// exportLookup opens the export data the go command compiled for the dependencies of the package.
+func exportLookup(t *testing.T) importer.Lookup {
+	output, err := exec.Command("go", "list", "-export", "-deps", "-f", "{{.ImportPath}} {{.Export}}", ".").Output()
+	require.NoError(t, err)
+	exports := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		path, export, _ := strings.Cut(line, " ")
+		exports[path] = export
+	}
+	return func(path string) (io.ReadCloser, error) {
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	}
+}
+
+func funcKey(method bool, name string) string {
+	if method {
+		return "method " + name
+	}
+	return "func " + name
+}
+
+// types gathers the types bound and written by a handler and by the functions of the package it calls.
+func (analyzer *handlerAnalyzer) types(handler string) handlerTypes {
+	found := handlerTypes{
+		bodies:    map[string]bool{},
+		params:    map[string]bool{},
+		responses: map[int]map[string]bool{},
+	}
+	decl := analyzer.decls[funcKey(true, handler)]
+	if decl == nil {
+		decl = analyzer.decls[funcKey(false, handler)]
+	}
+	visited := map[*ast.FuncDecl]bool{}
+	var visit func(decl *ast.FuncDecl)
+	visit = func(decl *ast.FuncDecl) {
+		if decl == nil || visited[decl] {
+			return
+		}
+		visited[decl] = true
+		ast.Inspect(decl, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			var ident *ast.Ident
+			switch fun := call.Fun.(type) {
+			case *ast.Ident:
+				ident = fun
+			case *ast.SelectorExpr:
+				ident = fun.Sel
+			default:
+				return true
+			}
+			fn, ok := analyzer.info.Uses[ident].(*types.Func)
+			if !ok {
+				return true
+			}
+			if fn.Pkg() != nil && fn.Pkg().Path() == "vk-film/api" {
+				signature := fn.Type().(*types.Signature)
+				visit(analyzer.decls[funcKey(signature.Recv() != nil, fn.Name())])
+				if fn.Name() == "applyPatch" {
+					found.bodies[analyzer.pointee(call.Args[3])] = true
+				}
+				return true
+			}
+			if fn.Pkg() == nil || fn.Pkg().Path() != "github.com/gin-gonic/gin" {
+				return true
+			}
+			switch fn.Name() {
+			case "ShouldBindJSON":
+				found.bodies[analyzer.pointee(call.Args[0])] = true
+			case "ShouldBindUri", "ShouldBindQuery":
+				found.params[analyzer.pointee(call.Args[0])] = true
+			case "JSON":
+				status := analyzer.info.Types[call.Args[0]].Value
+				if status == nil || status.Kind() != constant.Int {
+					return true
+				}
+				code, _ := constant.Int64Val(status)
+				if found.responses[int(code)] == nil {
+					found.responses[int(code)] = map[string]bool{}
+				}
+				found.responses[int(code)][analyzer.typeString(call.Args[1])] = true
+			}
+			return true
+		})
+	}
+	visit(decl)
+	return found
+}
+
+// pointee returns the type pointed to by the argument of a binding, like createUserRequest for &req.
+func (analyzer *handlerAnalyzer) pointee(expr ast.Expr) string {
+	typ := analyzer.info.Types[expr].Type
+	if pointer, ok := typ.(*types.Pointer); ok {
+		typ = pointer.Elem()
+	}
+	return types.TypeString(typ, qualifyByName)
+}
+
+func (analyzer *handlerAnalyzer) typeString(expr ast.Expr) string {
+	return types.TypeString(analyzer.info.Types[expr].Type, qualifyByName)
+}
+
+func qualifyByName(pkg *types.Package) string {
+	return pkg.Name()
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc, err := newOpenAPIDocument()
+	require.NoError(t, err)
+	require.Len(t, doc.Paths.Map(), countPaths(openapiOperations))
+}
+
+func countPaths(operations []openapiOperation) int {
+	paths := map[string]bool{}
+	for _, op := range operations {
+		paths[op.path] = true
+	}
+	return len(paths)
+}
+
+// TestOpenAPIValidationRejectsSchemaViolations checks that a body breaking the document is rejected before its handler,
+// with all its invalid fields listed.
+func TestOpenAPIValidationRejectsSchemaViolations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc, err := newOpenAPIDocument()
+	require.NoError(t, err)
+	openapiRouter, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+	router := gin.New()
+	server := &Server{}
+	router.Use(server.openapiValidationMiddleware(openapiRouter))
+	called := false
+	router.POST("/v1/movies", func(ctx *gin.Context) {
+		called = true
+		ctx.Status(http.StatusCreated)
+	})
+
+	body := `{"name":"` + strings.Repeat("a", 151) + `","release_date":"2010-07-16T00:00:00Z","rating":"8.8"}`
+	request := httptest.NewRequest(http.MethodPost, "/v1/movies", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.False(t, called)
+	var got problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Equal(t, "schema_violation", got.Code)
+	require.ElementsMatch(t, []string{"name maxLength", "description required"}, fieldCodes(got.Errors))
+}
+
+func fieldCodes(fields []fieldError) []string {
+	codes := make([]string, 0, len(fields))
+	for _, field := range fields {
+		codes = append(codes, field.Field+" "+field.Code)
+	}
+	return codes
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contentTypeJSONPatch  = "application/json-patch+json"
+	contentTypeMergePatch = "application/merge-patch+json"
+)
+
+var (
+	errMissingPatchID = errors.New("the id of the record to update is required, as the id query parameter or in the body")
+	errPatchChangesID = errors.New("the id of a record cannot be changed")
+)
+
+// patchTargetRequest represents the ways to identify the record targeted by a PATCH request.
+type patchTargetRequest struct {
+	ID int32 `form:"id" json:"id"`
+}
+
+// patchTargetID returns the ID of the record targeted by a PATCH request, taken from the id query parameter,
+// or from the body when it is a merge patch object, as sent by clients of the former full updates.
+func patchTargetID(ctx *gin.Context, body []byte) (int32, error) {
+	var req patchTargetRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		return 0, err
+	}
+	if req.ID == 0 && ctx.ContentType() != contentTypeJSONPatch {
+		// a body that is not an object has no id, it is rejected when the patch is applied
+		_ = json.Unmarshal(body, &req)
+	}
+	if req.ID <= 0 {
+		return 0, errMissingPatchID
+	}
+	return req.ID, nil
+}
+
+// applyPatch applies a PATCH body to the current document and decodes the result into patched.
+// The body is a JSON Patch (RFC 6902) when sent as application/json-patch+json,
+// and a JSON Merge Patch (RFC 7386) otherwise. Members removed by the patch, or set to null,
+// are left nil in patched, and members unknown to the document are rejected.
+func applyPatch(contentType string, body []byte, current interface{}, patched interface{}) error {
+	document, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var result []byte
+	if contentType == contentTypeJSONPatch {
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return fmt.Errorf("invalid JSON patch: %w", err)
+		}
+		result, err = patch.Apply(document)
+		if err != nil {
+			return fmt.Errorf("cannot apply JSON patch: %w", err)
+		}
+	} else {
+		if !json.Valid(body) || !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+			return errors.New("invalid merge patch: the body must be a JSON object")
+		}
+		result, err = jsonpatch.MergePatch(document, body)
+		if err != nil {
+			return fmt.Errorf("cannot apply merge patch: %w", err)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(patched)
+}
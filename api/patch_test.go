@@ -0,0 +1,213 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// newPatchContext returns the context of a PATCH request to target sending body as contentType.
+func newPatchContext(target string, contentType string, body string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPatch, target, strings.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", contentType)
+	return ctx
+}
+
+func TestPatchTargetID(t *testing.T) {
+	testCases := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		expectedID  int32
+		expectedErr error
+	}{
+		{
+			name:        "Query",
+			target:      "/movies?id=3",
+			contentType: contentTypeMergePatch,
+			body:        `{"id": 5}`,
+			expectedID:  3,
+		},
+		{
+			name:        "MergePatchBody",
+			target:      "/movies",
+			contentType: contentTypeMergePatch,
+			body:        `{"id": 5, "rating": "8.5"}`,
+			expectedID:  5,
+		},
+		{
+			name:        "JSONBody",
+			target:      "/movies",
+			contentType: "application/json",
+			body:        `{"id": 5}`,
+			expectedID:  5,
+		},
+		{
+			// the id of a JSON Patch is an operation, not the target
+			name:        "JSONPatchBody",
+			target:      "/movies",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op": "replace", "path": "/id", "value": 5}]`,
+			expectedErr: errMissingPatchID,
+		},
+		{
+			name:        "JSONPatchQuery",
+			target:      "/movies?id=3",
+			contentType: contentTypeJSONPatch,
+			body:        `[]`,
+			expectedID:  3,
+		},
+		{
+			name:        "NotAnObject",
+			target:      "/movies",
+			contentType: contentTypeMergePatch,
+			body:        `[5]`,
+			expectedErr: errMissingPatchID,
+		},
+		{
+			name:        "Negative",
+			target:      "/movies?id=-3",
+			contentType: contentTypeMergePatch,
+			body:        `{}`,
+			expectedErr: errMissingPatchID,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newPatchContext(tc.target, tc.contentType, tc.body)
+
+			id, err := patchTargetID(ctx, []byte(tc.body))
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedID, id)
+		})
+	}
+}
+
+func TestPatchTargetIDInvalidQuery(t *testing.T) {
+	ctx := newPatchContext("/movies?id=abc", contentTypeMergePatch, `{}`)
+
+	_, err := patchTargetID(ctx, []byte(`{}`))
+	require.Error(t, err)
+}
+
+func TestApplyPatch(t *testing.T) {
+	id := int32(3)
+	name := "Alien"
+	description := "In space"
+	rating := "8.4"
+	releaseDate := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+	current := updateMovieRequest{ID: &id, Name: &name, Description: &description, Rating: &rating, ReleaseDate: &releaseDate}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		check       func(t *testing.T, patched updateMovieRequest, err error)
+	}{
+		{
+			name:        "MergePatch",
+			contentType: contentTypeMergePatch,
+			body:        `{"rating": "8.5"}`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "8.5", *patched.Rating)
+				require.Equal(t, name, *patched.Name)
+				require.Equal(t, id, *patched.ID)
+				require.True(t, releaseDate.Equal(*patched.ReleaseDate))
+			},
+		},
+		{
+			name:        "MergePatchNull",
+			contentType: "application/json",
+			body:        `{"description": null}`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.NoError(t, err)
+				require.Nil(t, patched.Description)
+				require.Equal(t, rating, *patched.Rating)
+			},
+		},
+		{
+			name:        "MergePatchUnknownField",
+			contentType: contentTypeMergePatch,
+			body:        `{"director": "Ridley Scott"}`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.ErrorContains(t, err, "unknown field")
+			},
+		},
+		{
+			name:        "MergePatchNotAnObject",
+			contentType: contentTypeMergePatch,
+			body:        `["rating"]`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.ErrorContains(t, err, "invalid merge patch")
+			},
+		},
+		{
+			name:        "JSONPatch",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op": "test", "path": "/rating", "value": "8.4"}, {"op": "replace", "path": "/name", "value": "Aliens"}]`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "Aliens", *patched.Name)
+				require.Equal(t, rating, *patched.Rating)
+			},
+		},
+		{
+			name:        "JSONPatchRemove",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op": "remove", "path": "/description"}]`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.NoError(t, err)
+				require.Nil(t, patched.Description)
+			},
+		},
+		{
+			name:        "JSONPatchFailedTest",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op": "test", "path": "/rating", "value": "9"}]`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.ErrorContains(t, err, "cannot apply JSON patch")
+			},
+		},
+		{
+			name:        "JSONPatchInvalid",
+			contentType: contentTypeJSONPatch,
+			body:        `{"op": "replace"}`,
+			check: func(t *testing.T, patched updateMovieRequest, err error) {
+				require.ErrorContains(t, err, "invalid JSON patch")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var patched updateMovieRequest
+			err := applyPatch(tc.contentType, []byte(tc.body), current, &patched)
+			tc.check(t, patched, err)
+		})
+	}
+}
+
+func TestNewUpdateMovieParamsKeepsID(t *testing.T) {
+	before := db.Movie{ID: 3, Name: "Alien", Description: "In space", Rating: "8.4", ReleaseDate: time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)}
+	otherID := before.ID + 1
+	patched := updateMovieRequest{ID: &otherID, Name: &before.Name, Description: &before.Description, Rating: &before.Rating, ReleaseDate: &before.ReleaseDate}
+
+	_, _, err := newUpdateMovieParams(before.ID, before, patched)
+	require.ErrorIs(t, err, errPatchChangesID)
+
+	// the old id of a merged movie still targets the movie it was merged into
+	_, changed, err := newUpdateMovieParams(otherID, before, patched)
+	require.NoError(t, err)
+	require.False(t, changed)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	problemMediaType = "application/problem+json"
+	// problemTypePrefix prefixes the code of a problem to build its type URI.
+	problemTypePrefix = "/problems/"
+)
+
+var (
+	// errRecordNotFound replaces sql.ErrNoRows in the problems.
+	errRecordNotFound = errors.New("the requested record does not exist")
+)
+
+// problem is the body of every error response, an RFC 7807 problem details object.
+// Clients should branch on the code, which never changes, and not on the title or the detail.
+type problem struct {
+	// A URI reference identifying the problem type, built from the code.
+	// Example: /problems/not_found
+	Type string `json:"type"`
+
+	// A short summary of the problem type.
+	// Example: Not Found
+	Title string `json:"title"`
+
+	// The HTTP status code.
+	// Example: 404
+	Status int `json:"status"`
+
+	// The machine-readable code of the problem.
+	// Example: not_found
+	Code string `json:"code"`
+
+	// An explanation specific to this occurrence of the problem.
+	// Example: the requested record does not exist
+	Detail string `json:"detail,omitempty"`
+
+	// The path of the request that caused the problem.
+	// Example: /v1/movies/42
+	Instance string `json:"instance,omitempty"`
+
+	// The ID of the request, to be quoted when reporting the problem.
+	RequestID string `json:"request_id,omitempty"`
+
+	// The invalid fields of the request, all of them reported at once.
+	Errors []fieldError `json:"errors,omitempty"`
+}
+
+// problemKind is the status and the code of a class of errors.
+type problemKind struct {
+	status int
+	code   string
+}
+
+// knownErrors maps the errors returned by the handlers and the store to their problem.
+var knownErrors = map[error]problemKind{
+	sql.ErrNoRows:              {http.StatusNotFound, "not_found"},
+	authz.ErrPermissionDenied:  {http.StatusForbidden, "insufficient_permissions"},
+	authz.ErrScopeDenied:       {http.StatusForbidden, "insufficient_scopes"},
+	authz.ErrIssuerDenied:      {http.StatusForbidden, "insufficient_permissions"},
+	errPreconditionRequired:    {http.StatusPreconditionRequired, "if_match_required"},
+	errPreconditionFailed:      {http.StatusPreconditionFailed, "version_mismatch"},
+	errInvalidIfMatch:          {http.StatusBadRequest, "invalid_if_match"},
+	errMissingPatchID:          {http.StatusBadRequest, "missing_id"},
+	errPatchChangesID:          {http.StatusBadRequest, "immutable_id"},
+	db.ErrMergeSameRecord:      {http.StatusBadRequest, "merge_same_record"},
+	db.ErrRefreshTokenReused:   {http.StatusUnauthorized, "refresh_token_reused"},
+	errBuiltInRole:             {http.StatusConflict, "built_in_role"},
+	token.ErrUserDisabled:      {http.StatusForbidden, "user_disabled"},
+	errPasswordResetRequired:   {http.StatusForbidden, "password_reset_required"},
+	errDisableSelf:             {http.StatusConflict, "self_disable"},
+	errNoEmail:                 {http.StatusConflict, "no_email"},
+	errEmailAlreadyVerified:    {http.StatusConflict, "email_already_verified"},
+	db.ErrInvalidUserToken:     {http.StatusBadRequest, "invalid_token"},
+	errInvalidCredentials:      {http.StatusUnauthorized, "invalid_credentials"},
+	errSamePassword:            {http.StatusUnprocessableEntity, "same_password"},
+	token.ErrInvalidAPIKey:     {http.StatusUnauthorized, "invalid_api_key"},
+	errAPIKeyNotAllowed:        {http.StatusForbidden, "api_key_not_allowed"},
+	errOIDCDisabled:            {http.StatusNotFound, "oidc_disabled"},
+	errInvalidOIDCState:        {http.StatusBadRequest, "invalid_state"},
+	errIdentityNotLinked:       {http.StatusForbidden, "identity_not_linked"},
+	errIdentityLinkedElse:      {http.StatusConflict, "identity_linked"},
+	errInvalidLoginChallenge:   {http.StatusUnauthorized, "invalid_challenge"},
+	errInvalidSecondFactor:     {http.StatusUnauthorized, "invalid_code"},
+	errTOTPEnabled:             {http.StatusConflict, "totp_enabled"},
+	errTOTPNotEnrolled:         {http.StatusConflict, "totp_not_enrolled"},
+	errTOTPNotEnabled:          {http.StatusConflict, "totp_not_enabled"},
+	errTOTPRequired:            {http.StatusForbidden, "totp_required"},
+	errExpectedVersionRequired: {http.StatusPreconditionRequired, "if_match_required"},
+	errInvalidLimit:            {http.StatusBadRequest, "invalid_request"},
+	errInvalidOffset:           {http.StatusBadRequest, "invalid_request"},
+	errNullDescription:         {http.StatusBadRequest, "invalid_request"},
+}
+
+// postgresErrors maps the Postgres error conditions raised by the store to their problem.
+var postgresErrors = map[string]problemKind{
+	"check_violation":       {http.StatusUnprocessableEntity, "constraint_violation"},
+	"not_null_violation":    {http.StatusUnprocessableEntity, "constraint_violation"},
+	"foreign_key_violation": {http.StatusConflict, "reference_conflict"},
+	"unique_violation":      {http.StatusConflict, "already_exists"},
+	"no_data_found":         {http.StatusNotFound, "not_found"},
+}
+
+// statusCodes are the codes of the errors written with an explicit status.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:           "invalid_request",
+	http.StatusUnauthorized:         "unauthenticated",
+	http.StatusForbidden:            "forbidden",
+	http.StatusNotFound:             "not_found",
+	http.StatusConflict:             "conflict",
+	http.StatusPreconditionFailed:   "version_mismatch",
+	http.StatusUnprocessableEntity:  "unprocessable",
+	http.StatusPreconditionRequired: "if_match_required",
+	http.StatusTooManyRequests:      "too_many_requests",
+	http.StatusInternalServerError:  "internal",
+}
+
+// newProblem builds the problem of an error. The statuses of the known, the Postgres and the validation errors
+// take precedence over the given one, the internal errors are reported without their message, which may reveal SQL details.
+func newProblem(ctx *gin.Context, status int, err error) problem {
+	kind, detail := problemKind{status, statusCodes[status]}, err.Error()
+	fields := fieldErrors(err)
+	var pqErr *pq.Error
+	if len(fields) > 0 {
+		kind = problemKind{http.StatusUnprocessableEntity, "validation_failed"}
+		detail = "the request has invalid fields, listed in errors"
+	} else if fields = schemaFieldErrors(err); len(fields) > 0 {
+		kind = problemKind{http.StatusBadRequest, "schema_violation"}
+		detail = "the request does not match the API document, its invalid fields are listed in errors"
+	} else if known, ok := knownErrors[err]; ok {
+		kind = known
+		if err == sql.ErrNoRows {
+			detail = errRecordNotFound.Error()
+		}
+	} else if errors.As(err, &pqErr) {
+		if mapped, ok := postgresErrors[pqErr.Code.Name()]; ok {
+			kind, detail = mapped, postgresDetail(pqErr)
+		}
+	}
+	if kind.code == "" {
+		kind.code = strings.ReplaceAll(strings.ToLower(http.StatusText(kind.status)), " ", "_")
+	}
+	if kind.status >= http.StatusInternalServerError {
+		log.Printf("request %s %s failed: %v", ctx.GetString(requestIDKey), ctx.Request.URL.Path, err)
+		detail = "the request could not be processed, try again later"
+	}
+	return problem{
+		Type:      problemTypePrefix + kind.code,
+		Title:     http.StatusText(kind.status),
+		Status:    kind.status,
+		Code:      kind.code,
+		Detail:    detail,
+		Instance:  ctx.Request.URL.Path,
+		RequestID: ctx.GetString(requestIDKey),
+		Errors:    fields,
+	}
+}
+
+// postgresDetail describes a Postgres error by the constraint it violates, without the SQL statement.
+func postgresDetail(pqErr *pq.Error) string {
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return fmt.Sprintf("a record with the same key already exists (%s)", pqErr.Constraint)
+	case "foreign_key_violation":
+		return fmt.Sprintf("the record references, or is referenced by, another record (%s)", pqErr.Constraint)
+	case "not_null_violation":
+		return fmt.Sprintf("the %s is required", pqErr.Column)
+	case "no_data_found":
+		return errRecordNotFound.Error()
+	}
+	return fmt.Sprintf("the record violates the %s constraint", pqErr.Constraint)
+}
+
+// writeError writes the problem of an error whose status follows from the error itself,
+// the errors unknown to knownErrors and postgresErrors are internal.
+func writeError(ctx *gin.Context, err error) {
+	writeStatusError(ctx, http.StatusInternalServerError, err)
+}
+
+// writeStatusError writes the problem of an error with the status chosen by the caller,
+// unless the error is one of knownErrors or postgresErrors.
+func writeStatusError(ctx *gin.Context, status int, err error) {
+	p := newProblem(ctx, status, err)
+	ctx.Header("Content-Type", problemMediaType)
+	ctx.JSON(p.Status, p)
+}
+
+// abortWithError writes the problem of an error like writeStatusError and stops the handler chain.
+func abortWithError(ctx *gin.Context, status int, err error) {
+	p := newProblem(ctx, status, err)
+	ctx.Header("Content-Type", problemMediaType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
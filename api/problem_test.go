@@ -0,0 +1,197 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"vk-film/authz"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// newProblemContext returns the context of a request to /v1/movies/42 with the ID request-1.
+func newProblemContext() (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/movies/42", nil)
+	ctx.Set(requestIDKey, "request-1")
+	return ctx, recorder
+}
+
+func TestNewProblem(t *testing.T) {
+	empty := ""
+	validationErr := binding.Validator.ValidateStruct(updateMovieRequest{Name: &empty})
+	require.Error(t, validationErr)
+
+	testCases := []struct {
+		name           string
+		status         int
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedDetail string
+	}{
+		{
+			name:           "Validation",
+			status:         http.StatusBadRequest,
+			err:            validationErr,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "validation_failed",
+			expectedDetail: "the request has invalid fields, listed in errors",
+		},
+		{
+			name:           "NoRows",
+			status:         http.StatusInternalServerError,
+			err:            sql.ErrNoRows,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "not_found",
+			expectedDetail: errRecordNotFound.Error(),
+		},
+		{
+			name:           "Known",
+			status:         http.StatusInternalServerError,
+			err:            authz.ErrPermissionDenied,
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "insufficient_permissions",
+			expectedDetail: authz.ErrPermissionDenied.Error(),
+		},
+		{
+			name:           "KnownOverridesStatus",
+			status:         http.StatusBadRequest,
+			err:            errPreconditionFailed,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "version_mismatch",
+			expectedDetail: errPreconditionFailed.Error(),
+		},
+		{
+			name:           "UniqueViolation",
+			status:         http.StatusInternalServerError,
+			err:            &pq.Error{Code: "23505", Constraint: "users_email_key", Message: "duplicate key value violates unique constraint"},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "already_exists",
+			expectedDetail: "a record with the same key already exists (users_email_key)",
+		},
+		{
+			name:           "ForeignKeyViolation",
+			status:         http.StatusInternalServerError,
+			err:            &pq.Error{Code: "23503", Constraint: "movie_actors_actor_id_fkey"},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "reference_conflict",
+			expectedDetail: "the record references, or is referenced by, another record (movie_actors_actor_id_fkey)",
+		},
+		{
+			name:           "NotNullViolation",
+			status:         http.StatusInternalServerError,
+			err:            &pq.Error{Code: "23502", Column: "description"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "constraint_violation",
+			expectedDetail: "the description is required",
+		},
+		{
+			name:           "CheckViolation",
+			status:         http.StatusInternalServerError,
+			err:            &pq.Error{Code: "23514", Constraint: "movies_rating_check"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "constraint_violation",
+			expectedDetail: "the record violates the movies_rating_check constraint",
+		},
+		{
+			name:           "NoDataFound",
+			status:         http.StatusInternalServerError,
+			err:            &pq.Error{Code: "P0002", Message: "movie 42 does not exist"},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "not_found",
+			expectedDetail: errRecordNotFound.Error(),
+		},
+		{
+			// the message of an unmapped Postgres error may reveal the SQL statement
+			name:           "UnmappedPostgres",
+			status:         http.StatusInternalServerError,
+			err:            &pq.Error{Code: "42P01", Message: `relation "movies" does not exist`},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal",
+			expectedDetail: "the request could not be processed, try again later",
+		},
+		{
+			name:           "Internal",
+			status:         http.StatusInternalServerError,
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal",
+			expectedDetail: "the request could not be processed, try again later",
+		},
+		{
+			name:           "ExplicitStatus",
+			status:         http.StatusBadRequest,
+			err:            errors.New("invalid character"),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_request",
+			expectedDetail: "invalid character",
+		},
+		{
+			name:           "StatusWithoutCode",
+			status:         http.StatusMethodNotAllowed,
+			err:            errors.New("use GET"),
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
+			expectedDetail: "use GET",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := newProblemContext()
+
+			p := newProblem(ctx, tc.status, tc.err)
+			require.Equal(t, tc.expectedStatus, p.Status)
+			require.Equal(t, tc.expectedCode, p.Code)
+			require.Equal(t, problemTypePrefix+tc.expectedCode, p.Type)
+			require.Equal(t, http.StatusText(tc.expectedStatus), p.Title)
+			require.Equal(t, tc.expectedDetail, p.Detail)
+			require.Equal(t, "/v1/movies/42", p.Instance)
+			require.Equal(t, "request-1", p.RequestID)
+		})
+	}
+}
+
+func TestNewProblemListsFields(t *testing.T) {
+	empty := ""
+	long := string(make([]byte, 1001))
+	ctx, _ := newProblemContext()
+
+	p := newProblem(ctx, http.StatusBadRequest, binding.Validator.ValidateStruct(updateMovieRequest{Name: &empty, Description: &long}))
+	require.Equal(t, []fieldError{
+		{Field: "name", Code: "min", Message: "name must be at least 1 characters"},
+		{Field: "description", Code: "max", Message: "description must be at most 1000 characters"},
+	}, p.Errors)
+}
+
+func TestKnownErrorsAreClientErrors(t *testing.T) {
+	for err, kind := range knownErrors {
+		require.NotEmpty(t, kind.code, err.Error())
+		require.GreaterOrEqual(t, kind.status, http.StatusBadRequest, err.Error())
+		require.Less(t, kind.status, http.StatusInternalServerError, err.Error())
+	}
+	for name, kind := range postgresErrors {
+		require.Less(t, kind.status, http.StatusInternalServerError, name)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	ctx, recorder := newProblemContext()
+
+	writeError(ctx, sql.ErrNoRows)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Equal(t, problemMediaType, recorder.Header().Get("Content-Type"))
+	var p problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &p))
+	require.Equal(t, "not_found", p.Code)
+	require.Equal(t, "request-1", p.RequestID)
+}
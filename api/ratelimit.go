@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vk-film/ratelimit"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	rateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// The route groups sharing a rate limit.
+const (
+	rateLimitGroupAuth    = "auth"
+	rateLimitGroupCatalog = "catalog"
+	rateLimitGroupDefault = "default"
+)
+
+// rateLimitGroups maps the first segment of the routes, past /v1, to their group. The other routes are in the default group.
+var rateLimitGroups = map[string]string{
+	"users":         rateLimitGroupAuth,
+	"tokens":        rateLimitGroupAuth,
+	"movies":        rateLimitGroupCatalog,
+	"movie":         rateLimitGroupCatalog,
+	"actors":        rateLimitGroupCatalog,
+	"actor":         rateLimitGroupCatalog,
+	"actors-movies": rateLimitGroupCatalog,
+	"graphql":       rateLimitGroupCatalog,
+}
+
+// rateLimiter holds the limits of the route groups and the backend keeping their buckets.
+type rateLimiter struct {
+	backend ratelimit.Backend
+	limits  map[string]ratelimit.Limit
+}
+
+// newRateLimiter parses the limits of the route groups, an empty limit leaves its group unlimited.
+func newRateLimiter(backend ratelimit.Backend, auth, catalog, defaultLimit string) (*rateLimiter, error) {
+	limiter := &rateLimiter{backend: backend, limits: make(map[string]ratelimit.Limit)}
+	for group, value := range map[string]string{
+		rateLimitGroupAuth:    auth,
+		rateLimitGroupCatalog: catalog,
+		rateLimitGroupDefault: defaultLimit,
+	} {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s rate limit: %v", group, err)
+		}
+		limiter.limits[group] = limit
+	}
+	return limiter, nil
+}
+
+// rateLimitMiddleware limits the requests of every client per route group, with a token bucket per client and group.
+// The clients are the API keys and the users authenticated by a valid bearer token, the other requests are limited per client IP.
+// The responses carry the RateLimit-* headers, the rejected ones a 429 status and the Retry-After header.
+// A failing backend lets the requests through, the rate limiting must not take the API down with it.
+func (server *Server) rateLimitMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		group := rateLimitGroup(ctx.FullPath())
+		limit := server.rateLimiter.limits[group]
+		if !limit.Enabled() {
+			ctx.Next()
+			return
+		}
+		result, err := server.rateLimiter.backend.Take(ctx, group+":"+server.rateLimitClient(ctx), limit)
+		if err != nil {
+			log.Printf("cannot check the rate limit: %v", err)
+			ctx.Next()
+			return
+		}
+		ctx.Header(rateLimitLimitHeader, strconv.Itoa(result.Limit))
+		ctx.Header(rateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		ctx.Header(rateLimitResetHeader, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		ctx.Header(rateLimitPolicyHeader, fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+		if !result.Allowed {
+			ctx.Header(retryAfterHeader, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			err := fmt.Errorf("too many requests, try again in %s", result.RetryAfter.Round(time.Second))
+			abortWithError(ctx, http.StatusTooManyRequests, err)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// rateLimitClient identifies the client of a request: the API key authenticating it, the username of a valid bearer token,
+// or else its IP, which is only read from the forwarding headers set by the trusted proxies.
+// The credentials are only verified here, the revoked tokens are rejected by authMiddleware,
+// which reuses the payload of the API key instead of looking the key up again.
+func (server *Server) rateLimitClient(ctx *gin.Context) string {
+	fields := strings.Fields(ctx.GetHeader(authorizationHeader))
+	apiKey := ctx.GetHeader(apiKeyHeader)
+	if len(apiKey) == 0 && len(fields) == 2 && strings.ToLower(fields[0]) == authorizationTypeAPIKey {
+		apiKey = fields[1]
+	}
+	if len(apiKey) > 0 {
+		payload, err := server.apiKeys.Verify(ctx, apiKey)
+		if err == nil {
+			ctx.Set(apiKeyPayloadKey, payload)
+			return fmt.Sprintf("%s%d", token.APIKeyPrincipal, payload.APIKeyID)
+		}
+	} else if len(fields) == 2 && strings.ToLower(fields[0]) == authorizationTypeBearer {
+		payload, err := server.tokenMaker.VerifyToken(fields[1])
+		if err == nil {
+			return "user:" + payload.Username
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+// rateLimitGroup returns the group of a route, the unmatched requests are in the default group.
+func rateLimitGroup(route string) string {
+	route = strings.TrimPrefix(route, "/v1")
+	segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+	if group, ok := rateLimitGroups[segment]; ok {
+		return group
+	}
+	return rateLimitGroupDefault
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
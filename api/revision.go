@@ -0,0 +1,318 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+	"vk-film/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revisionResponse represents a stored revision of a movie or an actor.
+type revisionResponse struct {
+	// The revision number, starting at 1 for the creation.
+	// Example: 3
+	Revision int32 `json:"revision"`
+
+	// The user who made the change.
+	// Example: vk-admin
+	Username string `json:"username"`
+
+	// Example: "2024-03-17T10:00:00Z"
+	CreatedAt time.Time `json:"created_at"`
+
+	// The full record as it was stored by this revision.
+	Data json.RawMessage `json:"data"`
+}
+
+func newMovieRevisionResponse(revision db.MovieRevision) revisionResponse {
+	return revisionResponse{
+		Revision:  revision.Revision,
+		Username:  revision.Username,
+		CreatedAt: revision.CreatedAt,
+		Data:      revision.Data,
+	}
+}
+
+func newActorRevisionResponse(revision db.ActorRevision) revisionResponse {
+	return revisionResponse{
+		Revision:  revision.Revision,
+		Username:  revision.Username,
+		CreatedAt: revision.CreatedAt,
+		Data:      revision.Data,
+	}
+}
+
+// revisionDiffResponse represents the differences between two revisions.
+type revisionDiffResponse struct {
+	// Example: 1
+	From int32 `json:"from"`
+
+	// Example: 3
+	To int32 `json:"to"`
+
+	// The changed fields with their values in both revisions.
+	// Example: {"rating":{"before":"9.2","after":"9.1"}}
+	Diff map[string]util.FieldChange `json:"diff"`
+}
+
+// listRevisionsRequest represents the query parameters for listing the revisions of a record.
+type listRevisionsRequest struct {
+	// required: true
+	ID int32 `form:"id" binding:"required,min=1"`
+}
+
+// diffRevisionsRequest represents the query parameters for comparing two revisions of a record.
+type diffRevisionsRequest struct {
+	// required: true
+	ID int32 `form:"id" binding:"required,min=1"`
+
+	// required: true
+	From int32 `form:"from" binding:"required,min=1"`
+
+	// required: true
+	To int32 `form:"to" binding:"required,min=1"`
+}
+
+// restoreRevisionRequest represents the request body for restoring an earlier revision of a record.
+type restoreRevisionRequest struct {
+	// Required: true
+	ID int32 `json:"id" binding:"required,min=1"`
+
+	// The revision to restore, the restored state is stored as a new revision.
+	// Required: true
+	Revision int32 `json:"revision" binding:"required,min=1"`
+}
+
+// listMovieRevisions lists the revisions of a movie, requires the revisions:read permission.
+func (server *Server) listMovieRevisions(ctx *gin.Context) {
+	var req listRevisionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.listMovieRevisionResources(ctx, req.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listMovieRevisionResources lists the revisions of a movie for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) listMovieRevisionResources(ctx *gin.Context, id int32) ([]revisionResponse, bool) {
+	revisions, err := server.store.ListMovieRevisions(ctx, id)
+	if err != nil {
+		writeError(ctx, err)
+		return nil, false
+	}
+	rsp := make([]revisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		rsp = append(rsp, newMovieRevisionResponse(revision))
+	}
+	return rsp, true
+}
+
+// diffMovieRevisions compares two revisions of a movie, requires the revisions:read permission.
+func (server *Server) diffMovieRevisions(ctx *gin.Context) {
+	var req diffRevisionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.diffMovieRevisionResources(ctx, req.ID, req.From, req.To)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// diffMovieRevisionResources compares two revisions of a movie for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) diffMovieRevisionResources(ctx *gin.Context, id, from, to int32) (revisionDiffResponse, bool) {
+	revisions := make([]db.MovieRevision, 0, 2)
+	for _, number := range []int32{from, to} {
+		revision, err := server.store.GetMovieRevision(ctx, db.GetMovieRevisionParams{
+			MovieID:  id,
+			Revision: number,
+		})
+		if err != nil {
+			writeError(ctx, err)
+			return revisionDiffResponse{}, false
+		}
+		revisions = append(revisions, revision)
+	}
+	diff, err := util.JSONDiff(revisions[0].Data, revisions[1].Data)
+	if err != nil {
+		writeError(ctx, err)
+		return revisionDiffResponse{}, false
+	}
+	rsp := revisionDiffResponse{
+		From: from,
+		To:   to,
+		Diff: diff,
+	}
+	return rsp, true
+}
+
+// restoreMovieRevision restores an earlier revision of a movie, requires the movies:write permission.
+func (server *Server) restoreMovieRevision(ctx *gin.Context) {
+	var req restoreRevisionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	movie, ok := server.restoreMovieRevisionResource(ctx, req.ID, req.Revision)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newMovieResponse(movie))
+}
+
+// restoreMovieRevisionResource restores a revision of a movie for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) restoreMovieRevisionResource(ctx *gin.Context, id, revision int32) (db.Movie, bool) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	var movie db.Movie
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockMovie(ctx, id)
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(ctx, before.Version)
+		if err != nil {
+			return err
+		}
+		movie, err = store.RestoreMovieRevisionTx(ctx, db.RestoreRevisionTxParams{
+			ID:              before.ID,
+			Revision:        revision,
+			ExpectedVersion: expected,
+			Username:        authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionRestore, db.AuditEntityMovie, movie.ID, newMovieResponse(before), newMovieResponse(movie))
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return db.Movie{}, false
+	}
+	ctx.Header(etagHeader, versionETag(movie.Version))
+	return movie, true
+}
+
+// listActorRevisions lists the revisions of an actor, requires the revisions:read permission.
+func (server *Server) listActorRevisions(ctx *gin.Context) {
+	var req listRevisionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.listActorRevisionResources(ctx, req.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listActorRevisionResources lists the revisions of an actor for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) listActorRevisionResources(ctx *gin.Context, id int32) ([]revisionResponse, bool) {
+	revisions, err := server.store.ListActorRevisions(ctx, id)
+	if err != nil {
+		writeError(ctx, err)
+		return nil, false
+	}
+	rsp := make([]revisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		rsp = append(rsp, newActorRevisionResponse(revision))
+	}
+	return rsp, true
+}
+
+// diffActorRevisions compares two revisions of an actor, requires the revisions:read permission.
+func (server *Server) diffActorRevisions(ctx *gin.Context) {
+	var req diffRevisionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.diffActorRevisionResources(ctx, req.ID, req.From, req.To)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// diffActorRevisionResources compares two revisions of an actor for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) diffActorRevisionResources(ctx *gin.Context, id, from, to int32) (revisionDiffResponse, bool) {
+	revisions := make([]db.ActorRevision, 0, 2)
+	for _, number := range []int32{from, to} {
+		revision, err := server.store.GetActorRevision(ctx, db.GetActorRevisionParams{
+			ActorID:  id,
+			Revision: number,
+		})
+		if err != nil {
+			writeError(ctx, err)
+			return revisionDiffResponse{}, false
+		}
+		revisions = append(revisions, revision)
+	}
+	diff, err := util.JSONDiff(revisions[0].Data, revisions[1].Data)
+	if err != nil {
+		writeError(ctx, err)
+		return revisionDiffResponse{}, false
+	}
+	rsp := revisionDiffResponse{
+		From: from,
+		To:   to,
+		Diff: diff,
+	}
+	return rsp, true
+}
+
+// restoreActorRevision restores an earlier revision of an actor, requires the actors:write permission.
+func (server *Server) restoreActorRevision(ctx *gin.Context) {
+	var req restoreRevisionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.restoreActorRevisionResource(ctx, req.ID, req.Revision)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newActorResponse(actor))
+}
+
+// restoreActorRevisionResource restores a revision of an actor for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) restoreActorRevisionResource(ctx *gin.Context, id, revision int32) (db.Actor, bool) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	var actor db.Actor
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockActor(ctx, id)
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(ctx, before.Version)
+		if err != nil {
+			return err
+		}
+		actor, err = store.RestoreActorRevisionTx(ctx, db.RestoreRevisionTxParams{
+			ID:              before.ID,
+			Revision:        revision,
+			ExpectedVersion: expected,
+			Username:        authPayload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionRestore, db.AuditEntityActor, actor.ID, newActorResponse(before), newActorResponse(actor))
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return db.Actor{}, false
+	}
+	ctx.Header(etagHeader, versionETag(actor.Version))
+	return actor, true
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errBuiltInRole = errors.New("the administrator and client roles cannot be deleted, and the permissions of the administrator role cannot be changed")
+
+// roleURI represents the path of a role in the v1 API.
+type roleURI struct {
+	// required: true
+	Name string `uri:"name" binding:"required,max=20"`
+}
+
+// createRoleRequest represents the request body for creating a role.
+type createRoleRequest struct {
+	// The name of the role, lowercase letters and digits.
+	// Required: true
+	// Example: moderator
+	Name string `json:"name" binding:"required,min=3,max=20,lowercase,alphanum"`
+
+	// What the role is for.
+	// Example: Moderates the catalog
+	Description string `json:"description" binding:"max=255"`
+
+	// The permissions granted by the role, listed by GET /v1/permissions.
+	// Example: ["movies:write", "actors:write"]
+	Permissions []string `json:"permissions" binding:"max=50"`
+}
+
+// updateRoleRequest represents the request body for replacing the description and the permissions of a role.
+type updateRoleRequest struct {
+	// What the role is for.
+	// Example: Moderates the catalog
+	Description string `json:"description" binding:"max=255"`
+
+	// The permissions granted by the role, replacing the ones granted before.
+	// Example: ["movies:write", "actors:write", "revisions:read"]
+	Permissions []string `json:"permissions" binding:"max=50"`
+}
+
+// assignRoleRequest represents the request body for assigning a role to a user.
+type assignRoleRequest struct {
+	// The name of the role.
+	// Required: true
+	// Example: editor
+	Role string `json:"role" binding:"required,max=20"`
+}
+
+// roleResponse represents a role with its permissions.
+type roleResponse struct {
+	// Example: editor
+	Name string `json:"name"`
+
+	// Example: Edits movies and actors, without deleting them
+	Description string `json:"description"`
+
+	// Example: ["actors:write", "movies:write", "revisions:read"]
+	Permissions []string `json:"permissions"`
+
+	// Example: "2022-03-17T09:00:00Z"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// permissionResponse represents a permission a role can grant.
+type permissionResponse struct {
+	// Example: movies:write
+	Name string `json:"name"`
+
+	// Example: Create, update and restore movies
+	Description string `json:"description"`
+}
+
+// listPermissionsV1 lists the permissions a role can grant, requires the roles:manage permission.
+func (server *Server) listPermissionsV1(ctx *gin.Context) {
+	permissions, err := server.store.ListPermissions(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]permissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		rsp = append(rsp, permissionResponse{Name: permission.Name, Description: permission.Description})
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listRolesV1 lists the roles, requires the roles:manage permission.
+func (server *Server) listRolesV1(ctx *gin.Context) {
+	roles, err := server.store.ListRoles(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	permissions, err := server.rolePermissions(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]roleResponse, 0, len(roles))
+	for _, role := range roles {
+		rsp = append(rsp, newRoleResponse(role, permissions[role.Name]))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getRoleV1 retrieves a role, requires the roles:manage permission.
+func (server *Server) getRoleV1(ctx *gin.Context) {
+	var uri roleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	role, err := server.store.GetRole(ctx, uri.Name)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	permissions, err := server.rolePermissions(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newRoleResponse(role, permissions[role.Name]))
+}
+
+// createRoleV1 creates a role, requires the roles:manage permission.
+func (server *Server) createRoleV1(ctx *gin.Context) {
+	var req createRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	permissions, ok := server.checkPermissionNames(ctx, req.Permissions)
+	if !ok {
+		return
+	}
+	var rsp roleResponse
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		role, err := store.CreateRoleTx(ctx, db.RoleTxParams{
+			Name:        req.Name,
+			Description: req.Description,
+			Permissions: permissions,
+		})
+		if err != nil {
+			return err
+		}
+		rsp = newRoleResponse(role, permissions)
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityRole, role.ID, nil, rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.authorizer.Invalidate()
+	ctx.Header(locationHeader, fmt.Sprintf("/v1/roles/%s", rsp.Name))
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// updateRoleV1 replaces the description and the permissions of a role, requires the roles:manage permission.
+func (server *Server) updateRoleV1(ctx *gin.Context) {
+	var uri roleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	var req updateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if uri.Name == authz.RoleAdministrator {
+		writeError(ctx, errBuiltInRole)
+		return
+	}
+	permissions, ok := server.checkPermissionNames(ctx, req.Permissions)
+	if !ok {
+		return
+	}
+	before, err := server.store.GetRole(ctx, uri.Name)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	permissionsBefore, err := server.rolePermissions(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	var rsp roleResponse
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		role, err := store.UpdateRoleTx(ctx, db.RoleTxParams{
+			Name:        uri.Name,
+			Description: req.Description,
+			Permissions: permissions,
+		})
+		if err != nil {
+			return err
+		}
+		rsp = newRoleResponse(role, permissions)
+		return recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityRole, role.ID, newRoleResponse(before, permissionsBefore[before.Name]), rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.authorizer.Invalidate()
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// deleteRoleV1 deletes a role, requires the roles:manage permission.
+func (server *Server) deleteRoleV1(ctx *gin.Context) {
+	var uri roleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if uri.Name == authz.RoleAdministrator || uri.Name == authz.RoleClient {
+		writeError(ctx, errBuiltInRole)
+		return
+	}
+	role, err := server.store.GetRole(ctx, uri.Name)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	permissions, err := server.rolePermissions(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	// the roles of users cannot be deleted, the users reference them
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		deleted, err := store.DeleteRole(ctx, role.Name)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return sql.ErrNoRows
+		}
+		return recordAudit(ctx, store, db.AuditActionDelete, db.AuditEntityRole, role.ID, newRoleResponse(role, permissions[role.Name]), nil)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.authorizer.Invalidate()
+	ctx.Status(http.StatusNoContent)
+}
+
+// assignUserRoleV1 assigns a role to a user, requires the roles:manage permission.
+func (server *Server) assignUserRoleV1(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	var req assignRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	role, err := server.store.GetRole(ctx, req.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeStatusError(ctx, http.StatusUnprocessableEntity, fmt.Errorf("unknown role %q", req.Role))
+			return
+		}
+		writeError(ctx, err)
+		return
+	}
+	before, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	var rsp userResponse
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		user, err := store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+			Username: before.Username,
+			Role:     role.Name,
+		})
+		if err != nil {
+			return err
+		}
+		// the access tokens carry the role, the ones issued with the previous role are rejected
+		err = store.RevokeUserTokensTx(ctx, db.RevokeUserTokensParams{
+			Username:         user.Username,
+			TokensValidAfter: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		rsp = newUserResponse(user)
+		return recordAudit(ctx, store, db.AuditActionAssign, db.AuditEntityUser, user.ID, newUserResponse(before), rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.revocations.ForgetUser(before.Username)
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func newRoleResponse(role db.Role, permissions []string) roleResponse {
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return roleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+	}
+}
+
+// rolePermissions returns the permissions of every role, by role name.
+func (server *Server) rolePermissions(ctx *gin.Context) (map[string][]string, error) {
+	rows, err := server.store.ListRolePermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissions := make(map[string][]string)
+	for _, row := range rows {
+		permissions[row.Role] = append(permissions[row.Role], row.Permission)
+	}
+	return permissions, nil
+}
+
+// checkPermissionNames returns the distinct permissions of a request, writing 422 Unprocessable Entity when one is unknown.
+func (server *Server) checkPermissionNames(ctx *gin.Context, names []string) ([]string, bool) {
+	known, err := server.store.ListPermissions(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return nil, false
+	}
+	exists := make(map[string]bool, len(known))
+	for _, permission := range known {
+		exists[permission.Name] = true
+	}
+	permissions := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !exists[name] {
+			writeStatusError(ctx, http.StatusUnprocessableEntity, fmt.Errorf("unknown permission %q", name))
+			return nil, false
+		}
+		if !seen[name] {
+			seen[name] = true
+			permissions = append(permissions, name)
+		}
+	}
+	sort.Strings(permissions)
+	return permissions, true
+}
@@ -1,25 +1,56 @@
 package api
 
 import (
-	"errors"
 	"fmt"
+	"strings"
+	"vk-film/authz"
 	db "vk-film/db/sqlc"
+	"vk-film/lockout"
+	"vk-film/mail"
+	"vk-film/oidc"
+	"vk-film/ratelimit"
 	"vk-film/token"
 	"vk-film/util"
 
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
 )
 
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config      util.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	revocations *token.RevocationList
+	apiKeys     *token.APIKeyVerifier
+	authorizer  *authz.Authorizer
+	mailer      mail.Mailer
+	loginGuard  *lockout.Guard
+	rateLimiter *rateLimiter
+	// oidcProvider is nil when OIDC_ISSUER is not set, the login with an identity provider is disabled
+	oidcProvider   *oidc.Provider
+	oidcGroupRoles []oidc.GroupRole
+	router         *gin.Engine
+	graphqlSchema  graphql.Schema
+	legacyAPI      legacyAPI
+	openapi        *openapi3.T
 }
 
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewJWTMaker(config.TokenSymmetricKey)
+	tokenMaker, err := token.NewMaker(token.MakerConfig{
+		Type:                config.TokenType,
+		SymmetricKey:        config.TokenSymmetricKey,
+		AsymmetricKey:       config.TokenAsymmetricKey,
+		Issuer:              config.TokenIssuer,
+		Audience:            config.TokenAudience,
+		Store:               store,
+		KeyRotationInterval: config.TokenKeyRotationInterval,
+		KeyOverlap:          config.TokenKeyOverlap,
+		KeySyncInterval:     config.TokenKeySyncInterval,
+		KeyEncryptionKey:    config.TokenKeyEncryptionKey,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %v", err)
 	}
@@ -28,56 +59,191 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 		store:      store,
 		tokenMaker: tokenMaker,
 	}
-	server.setupRouter()
+	server.revocations = token.NewRevocationList(store, config.RevocationSyncInterval)
+	server.apiKeys = token.NewAPIKeyVerifier(store)
+	server.authorizer = authz.NewAuthorizer(store, config.PermissionSyncInterval)
+	server.mailer, err = mail.NewMailer(mail.Config{
+		Type:         config.Mailer,
+		From:         config.MailFrom,
+		SMTPHost:     config.SMTPHost,
+		SMTPPort:     config.SMTPPort,
+		SMTPUsername: config.SMTPUsername,
+		SMTPPassword: config.SMTPPassword,
+		OutboxDir:    config.MailOutboxDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mailer: %v", err)
+	}
+	server.loginGuard, err = lockout.NewGuard(store, lockout.Config{
+		MaxFailures:     config.LoginMaxFailures,
+		MaxIPFailures:   config.LoginIPMaxFailures,
+		Window:          config.LoginFailureWindow,
+		Delay:           config.LoginDelay,
+		MaxDelay:        config.LoginMaxDelay,
+		LockoutDuration: config.LoginLockoutDuration,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create login guard: %v", err)
+	}
+	rateLimitBackend, err := ratelimit.NewBackend(ratelimit.Config{
+		Backend:       config.RateLimitBackend,
+		RedisAddress:  config.RedisAddress,
+		RedisPassword: config.RedisPassword,
+		RedisDB:       config.RedisDB,
+	}, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limit backend: %v", err)
+	}
+	server.rateLimiter, err = newRateLimiter(rateLimitBackend, config.RateLimitAuth, config.RateLimitCatalog, config.RateLimitDefault)
+	if err != nil {
+		return nil, err
+	}
+	if config.OIDCIssuer != "" {
+		server.oidcProvider, err = oidc.NewProvider(oidc.Config{
+			Issuer:       config.OIDCIssuer,
+			ClientID:     config.OIDCClientID,
+			ClientSecret: config.OIDCClientSecret,
+			RedirectURL:  config.OIDCRedirectURL,
+			Scopes:       strings.Fields(config.OIDCScopes),
+			GroupsClaim:  config.OIDCGroupsClaim,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot create identity provider: %v", err)
+		}
+	}
+	server.oidcGroupRoles, err = oidc.ParseGroupRoles(config.OIDCGroupRoles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC_GROUP_ROLES: %v", err)
+	}
+	server.legacyAPI, err = newLegacyAPI(config.LegacyAPIDeprecatedAt, config.LegacyAPISunset)
+	if err != nil {
+		return nil, err
+	}
+	server.openapi, err = newOpenAPIDocument()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create openapi document: %v", err)
+	}
+	server.graphqlSchema, err = server.newGraphQLSchema()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create graphql schema: %v", err)
+	}
+	err = server.setupRouter()
+	if err != nil {
+		return nil, err
+	}
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
+func (server *Server) setupRouter() error {
 	router := gin.Default()
+	// the client IP keys the rate limits, the login throttles and the logs, it is taken from X-Forwarded-For
+	// only when the request comes through one of the trusted proxies, none by default
+	err := router.SetTrustedProxies(strings.Fields(server.config.TrustedProxies))
+	if err != nil {
+		return fmt.Errorf("invalid trusted proxies: %v", err)
+	}
 	config := cors.DefaultConfig()
 	// I set the following to allow all origins and methods, only for testing purposes, In production, it shoud be set to specific origins
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	config.AllowCredentials = true
-	config.AllowHeaders = []string{"Content-Type", "Authorization", "accept"}
+	config.AllowHeaders = []string{"Content-Type", "Authorization", "accept", apiKeyHeader, requestIDHeader, ifMatchHeader, ifNoneMatchHeader, ifModifiedSinceHeader}
+	config.ExposeHeaders = []string{requestIDHeader, etagHeader, lastModifiedHeader, locationHeader, deprecationHeader, sunsetHeader, linkHeader, retryAfterHeader,
+		rateLimitLimitHeader, rateLimitRemainingHeader, rateLimitResetHeader, rateLimitPolicyHeader}
 	router.Use(cors.New(config))
-	router.POST("/users", server.createUser)
-	router.POST("/users/login", server.loginUser)
+	router.Use(requestIDMiddleware())
+	router.Use(server.rateLimitMiddleware())
+	if server.config.OpenAPIValidation {
+		openapiRouter, err := gorillamux.NewRouter(server.openapi)
+		if err != nil {
+			return fmt.Errorf("cannot create openapi router: %v", err)
+		}
+		router.Use(server.openapiValidationMiddleware(openapiRouter))
+	}
+	router.GET("/openapi.json", server.getOpenAPIDocument)
+	router.GET("/docs", server.getDocs)
+	router.GET("/.well-known/jwks.json", server.getJWKS)
+	server.setupV1Routes(router)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	// legacy routes, replaced by the v1 routes
+	router.POST("/users", server.deprecated("/v1/users"), server.createUser)
+	router.POST("/users/login", server.deprecated("/v1/users/login"), server.loginUser)
+
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations, server.apiKeys))
+	movieCatalogRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations, server.apiKeys), server.catalogCacheMiddleware(server.config.MoviesCacheControl))
+	actorCatalogRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations, server.apiKeys), server.catalogCacheMiddleware(server.config.ActorsCacheControl))
 	// movie routes
-	authRoutes.POST("/movie/create", server.createMovie)
-	authRoutes.PATCH("/movie/update", server.updateMovie)
-	authRoutes.DELETE("/movie/delete", server.deleteMovie)
-	authRoutes.GET("/movies", server.moviesSortedByRating)
-	authRoutes.GET("/movies/by-name", server.moviesSortedByName)
-	authRoutes.GET("/movies/by-date", server.moviesSortedByReleaseDate)
-	authRoutes.GET("/movies/by-name-fragment", server.moviesByNameFragment)
-	authRoutes.GET("/movies/by-actor-fragment", server.moviesByActorFragment)
+	authRoutes.POST("/movie/create", server.deprecated("/v1/movies"), server.requirePermission(authz.MoviesWrite), server.createMovie)
+	authRoutes.PATCH("/movie/update", server.deprecated("/v1/movies"), server.requirePermission(authz.MoviesWrite), server.updateMovie)
+	authRoutes.DELETE("/movie/delete", server.deprecated("/v1/movies"), server.requirePermission(authz.MoviesDelete), server.deleteMovie)
+	authRoutes.POST("/movie/merge", server.deprecated("/v1/movies"), server.requirePermission(authz.MoviesDelete), server.mergeMovies)
+	authRoutes.GET("/movie", server.deprecated("/v1/movies"), server.getMovie)
+	authRoutes.GET("/movie/revisions", server.deprecated("/v1/movies"), server.requirePermission(authz.RevisionsRead), server.listMovieRevisions)
+	authRoutes.GET("/movie/revisions/diff", server.deprecated("/v1/movies"), server.requirePermission(authz.RevisionsRead), server.diffMovieRevisions)
+	authRoutes.POST("/movie/revisions/restore", server.deprecated("/v1/movies"), server.requirePermission(authz.MoviesWrite), server.restoreMovieRevision)
+	movieCatalogRoutes.GET("/movies", server.deprecated("/v1/movies?sort=rating"), server.moviesSortedByRating)
+	movieCatalogRoutes.GET("/movies/by-name", server.deprecated("/v1/movies?sort=name"), server.moviesSortedByName)
+	movieCatalogRoutes.GET("/movies/by-date", server.deprecated("/v1/movies?sort=release_date"), server.moviesSortedByReleaseDate)
+	movieCatalogRoutes.GET("/movies/by-name-fragment", server.deprecated("/v1/movies"), server.moviesByNameFragment)
+	movieCatalogRoutes.GET("/movies/by-actor-fragment", server.deprecated("/v1/actors"), server.moviesByActorFragment)
 
 	// actor routes
 
-	authRoutes.POST("/actor/create", server.createActor)
-	authRoutes.PATCH("/actor/update", server.updateActor)
-	authRoutes.DELETE("/actor/delete", server.deleteActor)
-	authRoutes.GET("/actors-movies", server.actorsWithMovies)
+	authRoutes.POST("/actor/create", server.deprecated("/v1/actors"), server.requirePermission(authz.ActorsWrite), server.createActor)
+	authRoutes.PATCH("/actor/update", server.deprecated("/v1/actors"), server.requirePermission(authz.ActorsWrite), server.updateActor)
+	authRoutes.DELETE("/actor/delete", server.deprecated("/v1/actors"), server.requirePermission(authz.ActorsDelete), server.deleteActor)
+	authRoutes.POST("/actor/merge", server.deprecated("/v1/actors"), server.requirePermission(authz.ActorsDelete), server.mergeActors)
+	authRoutes.GET("/actor", server.deprecated("/v1/actors"), server.getActor)
+	authRoutes.GET("/actor/revisions", server.deprecated("/v1/actors"), server.requirePermission(authz.RevisionsRead), server.listActorRevisions)
+	authRoutes.GET("/actor/revisions/diff", server.deprecated("/v1/actors"), server.requirePermission(authz.RevisionsRead), server.diffActorRevisions)
+	authRoutes.POST("/actor/revisions/restore", server.deprecated("/v1/actors"), server.requirePermission(authz.ActorsWrite), server.restoreActorRevision)
+	actorCatalogRoutes.GET("/actors-movies", server.deprecated("/v1/actors"), server.actorsWithMovies)
+
+	// audit routes
+	authRoutes.GET("/audit", server.deprecated("/v1/audit-entries"), server.requirePermission(authz.AuditRead), server.listAuditEntries)
+
+	// graphql routes
+	authRoutes.POST("/graphql", server.graphql)
+
+	// cache routes
+	authRoutes.GET("/cache/stats", server.deprecated("/v1/cache/stats"), server.requirePermission(authz.CacheRead), server.getCacheStats)
 
 	server.router = router
+	return nil
 }
 
 func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// Auth is the authentication and authorization state of a server: the signing keys, the revoked tokens,
+// the permissions of the roles and the throttled logins.
+type Auth struct {
+	TokenMaker  token.Maker
+	Revocations *token.RevocationList
+	Authorizer  *authz.Authorizer
+	LoginGuard  *lockout.Guard
 }
 
-func checkAdminPermissions(ctx *gin.Context) error {
+// Auth returns the authentication and authorization state of the server, shared with the gRPC server
+// so that both see the same keys, revocations, permissions and login throttles.
+func (server *Server) Auth() Auth {
+	return Auth{
+		TokenMaker:  server.tokenMaker,
+		Revocations: server.revocations,
+		Authorizer:  server.authorizer,
+		LoginGuard:  server.loginGuard,
+	}
+}
+
+// checkPermission returns authz.ErrPermissionDenied if the role of the authenticated user does not grant a permission,
+// or authz.ErrScopeDenied if the scopes of the authenticating API key do not, and authz.ErrIssuerDenied if the role of
+// the user who issued the key no longer does.
+// The routes check their permission with requirePermission, the GraphQL resolvers, sharing a single route, call it.
+func (server *Server) checkPermission(ctx *gin.Context, permission string) error {
 	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
-	if authPayload.Role != "administrator" {
-		err := errors.New("Insufficient permissions, only admin can modify data")
-		return err
+	if authPayload.IsAPIKey() {
+		return server.authorizer.AuthorizeAPIKey(ctx, authPayload.Role, authPayload.Scopes, permission)
 	}
-	return nil
+	return server.authorizer.Authorize(ctx, authPayload.Role, permission)
 }
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errInvalidRefreshToken = errors.New("invalid refresh token")
+	errSessionExpired      = errors.New("session has expired or has been revoked")
+	errNoPublicKeys        = errors.New("the access tokens are not signed with public keys")
+)
+
+// sessionURI represents the path of a session in the v1 API.
+type sessionURI struct {
+	// required: true
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// renewAccessTokenRequest represents the request body for renewing an access token.
+type renewAccessTokenRequest struct {
+	// The refresh token returned by the login or by the last renewal, it can be used only once.
+	// Required: true
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// renewAccessTokenResponse represents the response body for renewing an access token.
+type renewAccessTokenResponse struct {
+	// The new access token.
+	AccessToken string `json:"access_token"`
+
+	// The refresh token replacing the one sent, which is no longer valid.
+	RefreshToken string `json:"refresh_token"`
+
+	// The time the session, and every refresh token of it, expires.
+	// Example: 2022-03-24T10:00:00Z
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// sessionResponse represents a session of the authenticated user.
+type sessionResponse struct {
+	// The ID of the session.
+	// Example: 2f0a7d0e-8b5c-4b8e-9d1c-8f4a0c1e2b3d
+	ID uuid.UUID `json:"id"`
+
+	// The user agent of the last login or renewal.
+	// Example: Mozilla/5.0
+	UserAgent string `json:"user_agent"`
+
+	// The IP address of the last login or renewal.
+	// Example: 203.0.113.7
+	ClientIP string `json:"client_ip"`
+
+	// The time of the login.
+	CreatedAt time.Time `json:"created_at"`
+
+	// The time of the last login or renewal.
+	LastUsedAt time.Time `json:"last_used_at"`
+
+	// The time the session expires, renewals do not extend it.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newSessionResponse(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+}
+
+// createSession starts a session for a user who just logged in and returns its first refresh token.
+func (server *Server) createSession(ctx *gin.Context, user db.User) (string, db.Session, error) {
+	refreshToken, refreshHash, err := token.NewRefreshToken()
+	if err != nil {
+		return "", db.Session{}, err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", db.Session{}, err
+	}
+	session, err := server.store.CreateSessionTx(ctx, db.CreateSessionTxParams{
+		CreateSessionParams: db.CreateSessionParams{
+			ID:        id,
+			Username:  user.Username,
+			UserAgent: ctx.Request.UserAgent(),
+			ClientIp:  ctx.ClientIP(),
+			ExpiresAt: time.Now().Add(server.config.RefreshTokenDuration),
+		},
+		RefreshTokenHash: refreshHash,
+	})
+	return refreshToken, session, err
+}
+
+// renewAccessToken exchanges a refresh token for a new access token and a new refresh token.
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rotatedHash := token.HashRefreshToken(req.RefreshToken)
+	refreshToken, err := server.store.GetRefreshToken(ctx, rotatedHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeStatusError(ctx, http.StatusUnauthorized, errInvalidRefreshToken)
+			return
+		}
+		writeError(ctx, err)
+		return
+	}
+	session, err := server.store.GetSession(ctx, refreshToken.SessionID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if session.IsRevoked || time.Now().After(session.ExpiresAt) {
+		writeStatusError(ctx, http.StatusUnauthorized, errSessionExpired)
+		return
+	}
+	if refreshToken.RotatedAt.Valid {
+		server.revokeReusedSession(ctx, session)
+		return
+	}
+	user, err := server.store.GetUser(ctx, session.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	err = checkUserCanLogIn(user)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	// the sessions started before the password changed end with the access tokens issued then
+	if session.CreatedAt.Before(user.PasswordChangedAt) || session.CreatedAt.Before(user.TokensValidAfter) {
+		writeStatusError(ctx, http.StatusUnauthorized, errSessionExpired)
+		return
+	}
+	newRefreshToken, refreshHash, err := token.NewRefreshToken()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	session, err = server.store.RenewSessionTx(ctx, db.RenewSessionTxParams{
+		SessionID:   session.ID,
+		UserAgent:   ctx.Request.UserAgent(),
+		ClientIp:    ctx.ClientIP(),
+		RotatedHash: rotatedHash,
+		RefreshHash: refreshHash,
+	})
+	if err != nil {
+		if err == db.ErrRefreshTokenReused {
+			server.revokeReusedSession(ctx, session)
+			return
+		}
+		writeError(ctx, err)
+		return
+	}
+	accessToken, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, renewAccessTokenResponse{
+		AccessToken:           accessToken,
+		RefreshToken:          newRefreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	})
+}
+
+// revokeReusedSession revokes a session whose rotated refresh token was sent again. Either the client or an attacker
+// holds a stolen token, the session cannot tell which one, so both have to log in again.
+func (server *Server) revokeReusedSession(ctx *gin.Context, session db.Session) {
+	_, err := server.store.RevokeSession(ctx, db.RevokeSessionParams{
+		ID:       session.ID,
+		Username: session.Username,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	log.Printf("request %s: refresh token reused, session %s of %s revoked", ctx.GetString(requestIDKey), session.ID, session.Username)
+	writeError(ctx, db.ErrRefreshTokenReused)
+}
+
+// listSessionsV1 lists the sessions of the authenticated user.
+func (server *Server) listSessionsV1(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	sessions, err := server.store.ListActiveSessions(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		rsp = append(rsp, newSessionResponse(session))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// revokeSessionV1 revokes a session of the authenticated user.
+func (server *Server) revokeSessionV1(ctx *gin.Context) {
+	var uri sessionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	// the sessions of the other users are reported as missing
+	_, err := server.store.RevokeSession(ctx, db.RevokeSessionParams{
+		ID:       uuid.MustParse(uri.ID),
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// logoutUserRequest represents the request body for logging out.
+type logoutUserRequest struct {
+	// The refresh token of the session to end, without it the session stays active.
+	RefreshToken string `json:"refresh_token"`
+}
+
+// userURI represents the path of a user in the v1 API.
+type userURI struct {
+	// required: true
+	Username string `uri:"username" binding:"required"`
+}
+
+// tokenRevocationResponse represents the state of the tokens of a user after their revocation.
+type tokenRevocationResponse struct {
+	// The access tokens of the user issued before this time are rejected.
+	TokensValidAfter time.Time `json:"tokens_valid_after"`
+}
+
+// logoutUser revokes the access token of the request and ends the session of the refresh token sent.
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	err := server.revocations.Revoke(ctx, authPayload)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if req.RefreshToken != "" {
+		refreshToken, err := server.store.GetRefreshToken(ctx, token.HashRefreshToken(req.RefreshToken))
+		if err != nil && err != sql.ErrNoRows {
+			writeError(ctx, err)
+			return
+		}
+		// unknown refresh tokens and the ones of the other users are ignored, there is no session of the user to end
+		if err == nil {
+			_, err = server.store.RevokeSession(ctx, db.RevokeSessionParams{
+				ID:       refreshToken.SessionID,
+				Username: authPayload.Username,
+			})
+			if err != nil && err != sql.ErrNoRows {
+				writeError(ctx, err)
+				return
+			}
+		}
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// revokeUserTokensV1 revokes every token of a user, requires the users:manage permission.
+func (server *Server) revokeUserTokensV1(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := tokenRevocationResponse{TokensValidAfter: time.Now()}
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		err := store.RevokeUserTokensTx(ctx, db.RevokeUserTokensParams{
+			Username:         user.Username,
+			TokensValidAfter: rsp.TokensValidAfter,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionRevoke, db.AuditEntityUser, user.ID, nil, rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.revocations.ForgetUser(user.Username)
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getJWKS serves the public keys verifying the access tokens, so that other services verify them without the signing keys.
+// Lists the public keys verifying the access tokens, by kid. The keys published before they sign and after they retire
+// are listed too, refresh the set at least as often as it may be cached.
+func (server *Server) getJWKS(ctx *gin.Context) {
+	maker, ok := server.tokenMaker.(token.KeySetMaker)
+	if !ok {
+		writeStatusError(ctx, http.StatusNotFound, errNoPublicKeys)
+		return
+	}
+	keys, err := maker.KeySet(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Header(cacheControlHeader, fmt.Sprintf("public, max-age=%d", int(server.config.TokenKeySyncInterval.Seconds())))
+	ctx.JSON(http.StatusOK, keys)
+}
@@ -0,0 +1,606 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/token"
+	"vk-film/totp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryCodeCount is the number of recovery codes issued with an authenticator.
+const recoveryCodeCount = 10
+
+var (
+	errInvalidLoginChallenge = errors.New("the login challenge is unknown or has expired, log in again")
+	errInvalidSecondFactor   = errors.New("incorrect or already used code")
+	errSecondFactorChoice    = errors.New("exactly one of code and recovery_code is required")
+	errTOTPEnabled           = errors.New("two-factor authentication is already enabled, disable it before enrolling another authenticator")
+	errTOTPNotEnrolled       = errors.New("no authenticator is being enrolled, start with POST /v1/users/totp")
+	errTOTPNotEnabled        = errors.New("two-factor authentication is not enabled for the user")
+	errTOTPRequired          = errors.New("two-factor authentication is required for the role of the user and cannot be disabled")
+)
+
+// loginChallengeResponse represents a login whose password was checked, waiting for the second factor of the user.
+type loginChallengeResponse struct {
+	// The challenge completing the login at /v1/users/login/verify.
+	// Example: 5mX0sV3nq1Vw0Gk8c0xk3TjQ2yH4hYdM9b6l1pR7eZs
+	Challenge string `json:"challenge"`
+
+	// The time the login must be completed by.
+	// Example: "2022-03-17T09:05:00Z"
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Whether the role of the user requires two-factor authentication while the user has no authenticator yet,
+	// which is enrolled with /v1/users/login/totp before the login is completed.
+	// Example: false
+	EnrollmentRequired bool `json:"enrollment_required"`
+}
+
+// loginChallengeRequest represents the request body for enrolling an authenticator during a login.
+type loginChallengeRequest struct {
+	// The challenge returned by the login.
+	// Required: true
+	Challenge string `json:"challenge" binding:"required,max=100"`
+}
+
+// verifyLoginRequest represents the request body for completing a login with the second factor.
+type verifyLoginRequest struct {
+	// The challenge returned by the login.
+	// Required: true
+	Challenge string `json:"challenge" binding:"required,max=100"`
+
+	// The code shown by the authenticator.
+	// Example: 123456
+	Code string `json:"code" binding:"max=10"`
+
+	// A recovery code, in place of the code of a lost authenticator.
+	// Example: ABCD-EFGH-IJKL-MNOP
+	RecoveryCode string `json:"recovery_code" binding:"max=30"`
+}
+
+// secondFactorRequest represents the request body of the changes to two-factor authentication checking the second factor.
+type secondFactorRequest struct {
+	// The code shown by the authenticator.
+	// Example: 123456
+	Code string `json:"code" binding:"max=10"`
+
+	// A recovery code, in place of the code of a lost authenticator.
+	// Example: ABCD-EFGH-IJKL-MNOP
+	RecoveryCode string `json:"recovery_code" binding:"max=30"`
+}
+
+// confirmTOTPRequest represents the request body for confirming the enrolled authenticator.
+type confirmTOTPRequest struct {
+	// The first code shown by the authenticator.
+	// Required: true
+	// Example: 123456
+	Code string `json:"code" binding:"required,max=10"`
+}
+
+// totpEnrollmentResponse represents an authenticator being enrolled, until its first code confirms it.
+type totpEnrollmentResponse struct {
+	// The secret shared with the authenticator in base32, for the authenticators that cannot scan a QR code.
+	// Example: JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	Secret string `json:"secret"`
+
+	// The otpauth URI of the secret, shown as a QR code for the authenticator to scan.
+	// Example: otpauth://totp/vk-film:vk-admin?algorithm=SHA1&digits=6&issuer=vk-film&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	URI string `json:"otpauth_uri"`
+}
+
+// recoveryCodesResponse represents the recovery codes of a user, shown only once.
+type recoveryCodesResponse struct {
+	// The single-use codes standing in for the codes of a lost authenticator, the server keeps only their hashes.
+	// Example: ["ABCD-EFGH-IJKL-MNOP","QRST-UVWX-YZ23-4567"]
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// twoFactorResponse represents the state of the two-factor authentication of a user.
+type twoFactorResponse struct {
+	// Example: true
+	Enabled bool `json:"enabled"`
+
+	// The time the authenticator was confirmed, absent while two-factor authentication is disabled.
+	// Example: "2022-03-17T09:00:00Z"
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+
+	// The number of recovery codes not used yet.
+	// Example: 10
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+
+	// Whether the role of the user requires two-factor authentication.
+	// Example: false
+	Required bool `json:"required"`
+}
+
+// completeLogin logs in a user whose password, or identity at the identity provider, was checked. The users with
+// two-factor authentication, or whose role requires it, get a challenge completed by verifyLoginV1 instead, and their
+// failed logins are kept until then, so that the password cannot reset them while the codes are guessed.
+func (server *Server) completeLogin(ctx *gin.Context, user db.User) {
+	secret, err := server.getTOTPSecret(ctx, user.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	enabled := secret.ConfirmedAt.Valid
+	if !enabled && !server.twoFactorRequired(user.Role) {
+		err = server.loginGuard.Succeed(ctx, user.Username)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		server.writeLogin(ctx, user)
+		return
+	}
+	challenge, challengeHash, err := token.NewOpaqueToken()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	now := time.Now()
+	expiresAt := now.Add(server.config.LoginChallengeDuration)
+	err = server.store.CreateLoginChallenge(ctx, db.CreateLoginChallengeParams{
+		ChallengeHash: challengeHash,
+		Username:      user.Username,
+		ExpiresAt:     expiresAt,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := server.store.DeleteExpiredLoginChallenges(ctx, now); err != nil {
+		log.Printf("cannot delete the expired login challenges: %v", err)
+	}
+	ctx.JSON(http.StatusAccepted, loginChallengeResponse{
+		Challenge:          challenge,
+		ExpiresAt:          expiresAt,
+		EnrollmentRequired: !enabled,
+	})
+}
+
+// verifyLoginV1 completes a login with the second factor of the user.
+// Completes a login answered by a challenge with the code of the authenticator or a recovery code, each accepted once.
+// The failures are throttled like the wrong passwords. When the role of the user requires an authenticator the user
+// has not enrolled yet, its first code confirms the one enrolled by enrollLoginTOTPV1 and the recovery codes are returned.
+func (server *Server) verifyLoginV1(ctx *gin.Context) {
+	var req verifyLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	challenge, user, ok := server.getLoginChallenge(ctx, req.Challenge)
+	if !ok {
+		return
+	}
+	secret, err := server.getTOTPSecret(ctx, user.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	var recoveryCodes []string
+	switch {
+	case secret.ConfirmedAt.Valid:
+		if !server.checkSecondFactor(ctx, secret, req.Code, req.RecoveryCode) {
+			return
+		}
+	case len(secret.Secret) > 0:
+		// the authenticator required by the role of the user is enrolled by the login
+		if req.Code == "" || req.RecoveryCode != "" {
+			writeStatusError(ctx, http.StatusBadRequest, errors.New("the code of the enrolled authenticator is required"))
+			return
+		}
+		recoveryCodes, ok = server.confirmTOTP(ctx, secret, req.Code)
+		if !ok {
+			return
+		}
+		err = server.loginGuard.Succeed(ctx, user.Username)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+	default:
+		writeError(ctx, errTOTPNotEnrolled)
+		return
+	}
+	// the challenge completes a single login, even when it is sent twice with valid codes
+	rows, err := server.store.DeleteLoginChallenge(ctx, challenge.ChallengeHash)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if rows == 0 {
+		writeError(ctx, errInvalidLoginChallenge)
+		return
+	}
+	rsp, err := server.newLogin(ctx, user)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp.RecoveryCodes = recoveryCodes
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// enrollLoginTOTPV1 enrolls an authenticator during a login, for the users whose role requires one.
+// Enrolls an authenticator for a login answered by a challenge requiring the enrollment, its first code completes
+// the login at verifyLoginV1. Enrolling again replaces the authenticator not confirmed yet.
+func (server *Server) enrollLoginTOTPV1(ctx *gin.Context) {
+	var req loginChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	_, user, ok := server.getLoginChallenge(ctx, req.Challenge)
+	if !ok {
+		return
+	}
+	server.enrollTOTP(ctx, user.Username)
+}
+
+// getTwoFactorV1 returns the state of the two-factor authentication of the authenticated user.
+func (server *Server) getTwoFactorV1(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	rsp, err := server.getTwoFactor(ctx, authPayload.Username, authPayload.Role)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// enrollTOTPV1 enrolls an authenticator for the authenticated user.
+// Enrolls an authenticator for the authenticated user, who scans the otpauth URI as a QR code and confirms it with
+// its first code at confirmTOTPV1. Enrolling again replaces the authenticator not confirmed yet.
+func (server *Server) enrollTOTPV1(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	server.enrollTOTP(ctx, authPayload.Username)
+}
+
+// enrollTOTP stores a new secret waiting for its confirmation and returns it for the authenticator.
+func (server *Server) enrollTOTP(ctx *gin.Context, username string) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	_, err = server.store.CreateTOTPSecret(ctx, db.CreateTOTPSecretParams{
+		Username: username,
+		Secret:   secret,
+	})
+	if err != nil {
+		// the confirmed secrets are not replaced
+		if err == sql.ErrNoRows {
+			writeError(ctx, errTOTPEnabled)
+			return
+		}
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, totpEnrollmentResponse{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(server.config.TOTPIssuer, username, secret),
+	})
+}
+
+// confirmTOTPV1 enables two-factor authentication for the authenticated user.
+// Confirms the authenticator enrolled by enrollTOTPV1 with its first code, enabling two-factor authentication.
+// The recovery codes are returned once.
+func (server *Server) confirmTOTPV1(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	secret, err := server.getTOTPSecret(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if secret.ConfirmedAt.Valid {
+		writeError(ctx, errTOTPEnabled)
+		return
+	}
+	if len(secret.Secret) == 0 {
+		writeError(ctx, errTOTPNotEnrolled)
+		return
+	}
+	recoveryCodes, ok := server.confirmTOTP(ctx, secret, req.Code)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// confirmTOTP enables an enrolled secret with its first code and returns the recovery codes issued with it.
+// The errors are written to the response.
+func (server *Server) confirmTOTP(ctx *gin.Context, secret db.UserTotpSecret, code string) ([]string, bool) {
+	now := time.Now()
+	step, ok := totp.Validate(secret.Secret, code, now)
+	if !ok {
+		writeError(ctx, errInvalidSecondFactor)
+		return nil, false
+	}
+	recoveryCodes, codeHashes, err := newRecoveryCodes()
+	if err != nil {
+		writeError(ctx, err)
+		return nil, false
+	}
+	_, err = server.store.ConfirmTOTPTx(ctx, db.ConfirmTOTPTxParams{
+		Username:           secret.Username,
+		Step:               step,
+		ConfirmedAt:        now,
+		RecoveryCodeHashes: codeHashes,
+	})
+	if err != nil {
+		// confirmed or replaced meanwhile
+		if err == sql.ErrNoRows {
+			writeError(ctx, errTOTPNotEnrolled)
+			return nil, false
+		}
+		writeError(ctx, err)
+		return nil, false
+	}
+	server.recordSecurityEvent(ctx, securityEventTwoFactorEnabled, secret.Username, "authenticator confirmed")
+	return recoveryCodes, true
+}
+
+// regenerateRecoveryCodesV1 replaces the recovery codes of the authenticated user.
+// Replaces the recovery codes of the authenticated user, checked by a code of the authenticator or a recovery code.
+// The codes left unused stop working, the new ones are returned once.
+func (server *Server) regenerateRecoveryCodesV1(ctx *gin.Context) {
+	var req secondFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	secret, ok := server.getEnabledTOTPSecret(ctx, authPayload.Username)
+	if !ok {
+		return
+	}
+	if !server.checkSecondFactor(ctx, secret, req.Code, req.RecoveryCode) {
+		return
+	}
+	recoveryCodes, codeHashes, err := newRecoveryCodes()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	err = server.store.ReplaceRecoveryCodesTx(ctx, db.ReplaceRecoveryCodesTxParams{
+		Username:   authPayload.Username,
+		CodeHashes: codeHashes,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// disableTOTPV1 disables two-factor authentication for the authenticated user.
+// Disables two-factor authentication for the authenticated user, checked by a code of the authenticator or a recovery
+// code, unless the role of the user requires it. The authenticator and the recovery codes stop working.
+func (server *Server) disableTOTPV1(ctx *gin.Context) {
+	var req secondFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	if server.twoFactorRequired(authPayload.Role) {
+		writeError(ctx, errTOTPRequired)
+		return
+	}
+	secret, ok := server.getEnabledTOTPSecret(ctx, authPayload.Username)
+	if !ok {
+		return
+	}
+	if !server.checkSecondFactor(ctx, secret, req.Code, req.RecoveryCode) {
+		return
+	}
+	_, err := server.store.DeleteTOTPSecret(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.recordSecurityEvent(ctx, securityEventTwoFactorDisabled, authPayload.Username, "disabled by the user")
+	ctx.Status(http.StatusNoContent)
+}
+
+// resetTwoFactorV1 disables two-factor authentication for a user, requires the users:manage permission.
+// Disables two-factor authentication for a user who lost both the authenticator and the recovery codes. The users whose
+// role requires it enroll a new authenticator at their next login.
+func (server *Server) resetTwoFactorV1(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	before, err := server.getTwoFactor(ctx, user.Username, user.Role)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := twoFactorResponse{Required: before.Required}
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		_, err := store.DeleteTOTPSecret(ctx, user.Username)
+		if err != nil || !before.Enabled {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionResetTwoFactor, db.AuditEntityUser, user.ID, before, rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if before.Enabled {
+		authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+		server.recordSecurityEvent(ctx, securityEventTwoFactorDisabled, user.Username, fmt.Sprintf("reset by %s", authPayload.Username))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// checkSecondFactor checks the code of the authenticator or the recovery code of a user, throttled by the login guard
+// like the passwords, and forgets the failed logins of the user once it succeeds. Every code is accepted once.
+// The errors are written to the response.
+func (server *Server) checkSecondFactor(ctx *gin.Context, secret db.UserTotpSecret, code string, recoveryCode string) bool {
+	if (code == "") == (recoveryCode == "") {
+		writeStatusError(ctx, http.StatusBadRequest, errSecondFactorChoice)
+		return false
+	}
+	err := server.loginGuard.Reserve(ctx, secret.Username, ctx.ClientIP())
+	if err != nil {
+		writeLoginError(ctx, err)
+		return false
+	}
+	now := time.Now()
+	var rows int64
+	if code != "" {
+		step, ok := totp.Validate(secret.Secret, code, now)
+		if ok {
+			// the steps up to the last one accepted are rejected, the code cannot be replayed
+			rows, err = server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+				Username: secret.Username,
+				LastStep: step,
+			})
+		}
+	} else {
+		rows, err = server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: secret.Username,
+			CodeHash: token.HashOpaqueToken(totp.NormalizeRecoveryCode(recoveryCode)),
+			UsedAt:   sql.NullTime{Time: now, Valid: true},
+		})
+	}
+	if err != nil {
+		server.releaseLogin(ctx, secret.Username)
+		writeError(ctx, err)
+		return false
+	}
+	if rows == 0 {
+		err = server.loginGuard.Fail(ctx, secret.Username, ctx.ClientIP())
+		if err != nil {
+			writeError(ctx, err)
+			return false
+		}
+		writeError(ctx, errInvalidSecondFactor)
+		return false
+	}
+	if recoveryCode != "" {
+		server.recordSecurityEvent(ctx, securityEventRecoveryCodeUsed, secret.Username, "recovery code used in place of the authenticator")
+	}
+	err = server.loginGuard.Release(ctx, secret.Username, ctx.ClientIP())
+	if err != nil {
+		writeError(ctx, err)
+		return false
+	}
+	err = server.loginGuard.Succeed(ctx, secret.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// getLoginChallenge returns a login challenge not expired yet and its user, who must still be allowed to log in.
+// The errors are written to the response.
+func (server *Server) getLoginChallenge(ctx *gin.Context, challenge string) (db.LoginChallenge, db.User, bool) {
+	loginChallenge, err := server.store.GetLoginChallenge(ctx, db.GetLoginChallengeParams{
+		ChallengeHash: token.HashOpaqueToken(challenge),
+		Now:           time.Now(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, errInvalidLoginChallenge)
+			return db.LoginChallenge{}, db.User{}, false
+		}
+		writeError(ctx, err)
+		return db.LoginChallenge{}, db.User{}, false
+	}
+	user, err := server.store.GetUser(ctx, loginChallenge.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return db.LoginChallenge{}, db.User{}, false
+	}
+	// the user may have been disabled since the password was checked
+	err = checkUserCanLogIn(user)
+	if err != nil {
+		writeError(ctx, err)
+		return db.LoginChallenge{}, db.User{}, false
+	}
+	return loginChallenge, user, true
+}
+
+// getTOTPSecret returns the TOTP secret of a user, enabled once confirmed, or the zero secret when the user has none.
+func (server *Server) getTOTPSecret(ctx *gin.Context, username string) (db.UserTotpSecret, error) {
+	secret, err := server.store.GetTOTPSecret(ctx, username)
+	if err == sql.ErrNoRows {
+		return db.UserTotpSecret{}, nil
+	}
+	return secret, err
+}
+
+// getEnabledTOTPSecret returns the confirmed TOTP secret of a user. The errors are written to the response.
+func (server *Server) getEnabledTOTPSecret(ctx *gin.Context, username string) (db.UserTotpSecret, bool) {
+	secret, err := server.getTOTPSecret(ctx, username)
+	if err != nil {
+		writeError(ctx, err)
+		return db.UserTotpSecret{}, false
+	}
+	if !secret.ConfirmedAt.Valid {
+		writeError(ctx, errTOTPNotEnabled)
+		return db.UserTotpSecret{}, false
+	}
+	return secret, true
+}
+
+func (server *Server) getTwoFactor(ctx *gin.Context, username string, role string) (twoFactorResponse, error) {
+	secret, err := server.getTOTPSecret(ctx, username)
+	if err != nil {
+		return twoFactorResponse{}, err
+	}
+	rsp := twoFactorResponse{
+		Enabled:  secret.ConfirmedAt.Valid,
+		Required: server.twoFactorRequired(role),
+	}
+	if rsp.Enabled {
+		rsp.EnabledAt = &secret.ConfirmedAt.Time
+		rsp.RecoveryCodesLeft, err = server.store.CountRecoveryCodes(ctx, username)
+		if err != nil {
+			return twoFactorResponse{}, err
+		}
+	}
+	return rsp, nil
+}
+
+// twoFactorRequired tells whether the users of a role must log in with a second factor, which TOTP_REQUIRED_FOR_ADMINISTRATORS
+// requires of the administrators.
+func (server *Server) twoFactorRequired(role string) bool {
+	return server.config.TOTPRequiredForAdministrators && role == authz.RoleAdministrator
+}
+
+// newRecoveryCodes generates the recovery codes of a user and the hashes to store in their place.
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	recoveryCodes, err := totp.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	codeHashes := make([][]byte, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		codeHashes[i] = token.HashOpaqueToken(totp.NormalizeRecoveryCode(code))
+	}
+	return recoveryCodes, codeHashes, nil
+}
@@ -2,31 +2,55 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 	db "vk-film/db/sqlc"
+	"vk-film/lockout"
+	"vk-film/token"
 	"vk-film/util"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/google/uuid"
+)
+
+const retryAfterHeader = "Retry-After"
+
+// The statuses filtering the user listing.
+const (
+	userStatusActive   = "active"
+	userStatusDisabled = "disabled"
+)
+
+var (
+	errPasswordResetRequired = errors.New("the password of the user must be reset with POST /v1/users/password/reset and the token sent by email or issued by an administrator")
+	errDisableSelf           = errors.New("users cannot disable themselves")
+	errSamePassword          = errors.New("the new password must differ from the current one")
+	errInvalidCredentials    = errors.New("incorrect username or password")
 )
 
 // createUserRequest represents the request body for creating a new user, the user's role cannot be setted through api, by default is 'client', if you want to check administrator endpoints you can create an user directly in the database, otherwise you can use the default administrator [username: 'admin', password: 'qwerty'].
-// swagger:parameters createUser
 type createUserRequest struct {
 	// The username of the user.
 	// Required: true
 	// Example: vk-qwerty
-	Username string `json:"username" binding:"required"`
+	Username string `json:"username" binding:"required,max=50"`
 
 	// The password of the user.
 	// Required: true
+	// Minimum: 6
 	// Example: password123
-	Password string `json:"password" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+
+	// The email of the user, optional, a link verifying it is sent to it.
+	// Example: vk-qwerty@example.com
+	Email string `json:"email" binding:"omitempty,email,max=254"`
 }
 
 // userResponse represents the response body for a user.
-// swagger:response userResponse
 type userResponse struct {
 	// The username of the user.
 	// Example: vk-user
@@ -43,6 +67,14 @@ type userResponse struct {
 	// The role of the user.
 	// Example: administrator
 	Role string `json:"role"`
+
+	// The email of the user, absent when the user has none.
+	// Example: vk-user@example.com
+	Email string `json:"email,omitempty"`
+
+	// Whether the email has been verified, only verified emails receive the password reset links.
+	// Example: true
+	EmailVerified bool `json:"email_verified"`
 }
 
 func newUserResponse(user db.User) userResponse {
@@ -51,77 +83,58 @@ func newUserResponse(user db.User) userResponse {
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 		Role:              user.Role,
+		Email:             user.Email.String,
+		EmailVerified:     user.EmailVerifiedAt.Valid,
 	}
 }
 
 // createUser creates a new user.
-// swagger:route POST /users users createUser
-// Creates a new user.
-//
-// Consumes:
-// - application/json
-//
-// Parameters:
-//   - In: body
-//     name: user
-//     description: User to create.
-//     schema:
-//     type: object
-//     required:
-//   - username
-//   - password
-//     properties:
-//     username:
-//     type: string
-//     description: The username of the user.
-//     password:
-//     type: string
-//
-// Responses:
-//
-//	200: userResponse
-//	400: errorResponse
-//	403: errorResponse
-//	500: errorResponse
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, ok := server.createUserResource(ctx, req)
+	if !ok {
 		return
 	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// createUserResource creates a user for the legacy and the v1 routes, the errors are written to the response.
+func (server *Server) createUserResource(ctx *gin.Context, req createUserRequest) (db.User, bool) {
 	hashedPassword, err := util.HashPassword(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		writeError(ctx, err)
+		return db.User{}, false
 	}
 	arg := db.CreateUserParams{
 		Username:       req.Username,
 		HashedPassword: hashedPassword,
+		Email:          sql.NullString{String: req.Email, Valid: req.Email != ""},
 	}
 	user, err := server.store.CreateUser(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
+		writeError(ctx, err)
+		return db.User{}, false
+	}
+	if user.Email.Valid {
+		// the user exists already, a verification email that could not be sent is sent again on request
+		err = server.sendEmailVerification(ctx, user)
+		if err != nil {
+			log.Printf("cannot send the email verification of %s: %v", user.Username, err)
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
 	}
-	rsp := newUserResponse(user)
-	ctx.JSON(http.StatusOK, rsp)
+	return user, true
 }
 
 // loginUserRequest represents the request body for logging in a user.
-// swagger:parameters loginUser
-// in: body
 type loginUserRequest struct {
 	// The username of the user.
 	// Required: true
 	// Example: john_doe
-	Username string `json:"username" binding:"required"`
+	Username string `json:"username" binding:"required,max=50"`
 
 	// The password of the user.
 	// Required: true
@@ -131,63 +144,498 @@ type loginUserRequest struct {
 }
 
 // loginUserResponse represents the response body for logging in a user.
-// swagger:response loginUser
 type loginUserResponse struct {
 	// The access token for the user.
 	// Example: eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VybmFtZSI6Im...
 	AccessToken string `json:"access_token"`
 
+	// The refresh token renewing the access token at /v1/tokens/renew, it can be used only once.
+	RefreshToken string `json:"refresh_token"`
+
+	// The ID of the session started by the login.
+	// Example: 2f0a7d0e-8b5c-4b8e-9d1c-8f4a0c1e2b3d
+	SessionID uuid.UUID `json:"session_id"`
+
+	// The time the session, and every refresh token of it, expires.
+	// Example: 2022-03-24T10:00:00Z
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+
 	// The user information.
 	// Example: {"username":"admin","password_changed_at":"2022-03-17T10:00:00Z","created_at":"2022-03-17T09:00:00Z","role":"admin"}
 	User userResponse `json:"user"`
+
+	// The recovery codes of the authenticator enrolled by the login, shown only once.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
 // loginUser logs in a user based on the provided request body.
-// swagger:route POST /users/login users loginUser
-//
-// Logs in a user.
-//
-// responses:
-//
-//	'200': loginUser
-//	'400':
-//	  description: Bad request. The request body is missing or invalid.
-//	'401':
-//	  description: Unauthorized. Username or password is incorrect.
-//	'500':
-//	  description: Internal server error. Something went wrong while processing the request.
+// Logs in a user, starting a session whose refresh token renews the short-lived access token. The users with
+// two-factor authentication get a challenge instead, completed with a TOTP code at /v1/users/login/verify.
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, ok := server.checkCredentials(ctx, req.Username, req.Password)
+	if !ok {
 		return
 	}
-	user, err := server.store.GetUser(ctx, req.Username)
+	err := checkUserCanLogIn(user)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	server.completeLogin(ctx, user)
+}
+
+// writeLogin issues the access token of a user who logged in and starts a session, whatever the way the user logged in.
+func (server *Server) writeLogin(ctx *gin.Context, user db.User) {
+	rsp, err := server.newLogin(ctx, user)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) newLogin(ctx *gin.Context, user db.User) (loginUserResponse, error) {
 	accessToken, err := server.tokenMaker.CreateToken(
 		user.Username,
 		user.Role,
 		server.config.AccessTokenDuration,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return loginUserResponse{}, err
+	}
+	refreshToken, session, err := server.createSession(ctx, user)
+	if err != nil {
+		return loginUserResponse{}, err
 	}
 	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		SessionID:             session.ID,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+		User:                  newUserResponse(user),
+	}
+	return rsp, nil
+}
+
+// userDetailsResponse represents a user as seen by the users managing the accounts.
+type userDetailsResponse struct {
+	// Example: vk-user
+	Username string `json:"username"`
+
+	// Example: editor
+	Role string `json:"role"`
+
+	// Example: "2022-03-17T10:00:00Z"
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+
+	// Example: "2022-03-17T09:00:00Z"
+	CreatedAt time.Time `json:"created_at"`
+
+	// Example: vk-user@example.com
+	Email string `json:"email,omitempty"`
+
+	// Example: true
+	EmailVerified bool `json:"email_verified"`
+
+	// The time the user was disabled, absent for the active users.
+	// Example: "2022-03-18T09:00:00Z"
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+
+	// Whether the user must choose a new password before logging in again.
+	// Example: false
+	PasswordResetRequired bool `json:"password_reset_required"`
+}
+
+func newUserDetailsResponse(user db.User) userDetailsResponse {
+	rsp := userDetailsResponse{
+		Username:              user.Username,
+		Role:                  user.Role,
+		PasswordChangedAt:     user.PasswordChangedAt,
+		CreatedAt:             user.CreatedAt,
+		Email:                 user.Email.String,
+		EmailVerified:         user.EmailVerifiedAt.Valid,
+		PasswordResetRequired: user.PasswordResetRequired,
+	}
+	if user.DisabledAt.Valid {
+		rsp.DisabledAt = &user.DisabledAt.Time
+	}
+	return rsp
+}
+
+// checkUserCanLogIn returns the reason a user whose password was checked cannot log in, if any.
+func checkUserCanLogIn(user db.User) error {
+	if user.DisabledAt.Valid {
+		return token.ErrUserDisabled
+	}
+	if user.PasswordResetRequired {
+		return errPasswordResetRequired
+	}
+	return nil
+}
+
+// listUsersRequest represents the query parameters for listing users in the v1 API.
+type listUsersRequest struct {
+	// Only users whose username contains this fragment, ignoring the case.
+	Query string `form:"q" binding:"max=50"`
+
+	// Only users of this role.
+	Role string `form:"role" binding:"max=20"`
+
+	// Only the active or only the disabled users.
+	Status string `form:"status" binding:"omitempty,oneof=active disabled"`
+
+	// The page number, starting at 1.
+	PageID int32 `form:"page_id,default=1" binding:"min=1"`
+
+	// The number of users per page.
+	PageSize int32 `form:"page_size,default=20" binding:"min=5,max=100"`
+}
+
+// changePasswordRequest represents the request body for changing the password of a user.
+type changePasswordRequest struct {
+	// Required: true
+	// Example: vk-user
+	Username string `json:"username" binding:"required,max=50"`
+
+	// The current password.
+	// Required: true
+	// Example: password123
+	Password string `json:"password" binding:"required"`
+
+	// The new password, different from the current one.
+	// Required: true
+	// Minimum: 6
+	// Example: password456
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+
+	// The code shown by the authenticator, required with two-factor authentication.
+	// Example: 123456
+	Code string `json:"code" binding:"max=10"`
+
+	// A recovery code, in place of the code of a lost authenticator.
+	// Example: ABCD-EFGH-IJKL-MNOP
+	RecoveryCode string `json:"recovery_code" binding:"max=30"`
+}
+
+// listUsersV1 lists the users matching the filters, requires the users:manage permission.
+func (server *Server) listUsersV1(ctx *gin.Context) {
+	var req listUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	arg := db.ListUsersParams{
+		Username:   sql.NullString{String: req.Query, Valid: req.Query != ""},
+		Role:       sql.NullString{String: req.Role, Valid: req.Role != ""},
+		Disabled:   sql.NullBool{Bool: req.Status == userStatusDisabled, Valid: req.Status != ""},
+		PageLimit:  req.PageSize,
+		PageOffset: (req.PageID - 1) * req.PageSize,
+	}
+	users, err := server.store.ListUsers(ctx, arg)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]userDetailsResponse, 0, len(users))
+	for _, user := range users {
+		rsp = append(rsp, newUserDetailsResponse(user))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getUserV1 retrieves a user, requires the users:manage permission.
+func (server *Server) getUserV1(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserDetailsResponse(user))
+}
+
+// disableUserV1 disables a user, requires the users:manage permission.
+func (server *Server) disableUserV1(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayload).(*token.Payload)
+	if uri.Username == authPayload.Username {
+		writeError(ctx, errDisableSelf)
+		return
+	}
+	before, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
 	}
+	if before.DisabledAt.Valid {
+		ctx.JSON(http.StatusOK, newUserDetailsResponse(before))
+		return
+	}
+	var rsp userDetailsResponse
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		user, err := store.SetUserDisabled(ctx, db.SetUserDisabledParams{
+			Username:   before.Username,
+			DisabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		err = store.RevokeUserTokensTx(ctx, db.RevokeUserTokensParams{
+			Username:         user.Username,
+			TokensValidAfter: user.DisabledAt.Time,
+		})
+		if err != nil {
+			return err
+		}
+		rsp = newUserDetailsResponse(user)
+		return recordAudit(ctx, store, db.AuditActionDisable, db.AuditEntityUser, user.ID, newUserDetailsResponse(before), rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.revocations.ForgetUser(before.Username)
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+// enableUserV1 enables a disabled user, requires the users:manage permission.
+func (server *Server) enableUserV1(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	before, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if !before.DisabledAt.Valid {
+		ctx.JSON(http.StatusOK, newUserDetailsResponse(before))
+		return
+	}
+	var rsp userDetailsResponse
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		user, err := store.SetUserDisabled(ctx, db.SetUserDisabledParams{Username: before.Username})
+		if err != nil {
+			return err
+		}
+		rsp = newUserDetailsResponse(user)
+		return recordAudit(ctx, store, db.AuditActionEnable, db.AuditEntityUser, user.ID, newUserDetailsResponse(before), rsp)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.revocations.ForgetUser(before.Username)
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// passwordResetResponse represents a user forced to reset the password, with the token completing the reset when it
+// could not be emailed.
+type passwordResetResponse struct {
+	userDetailsResponse
+
+	// Whether the reset link was emailed to the verified email of the user.
+	// Example: true
+	ResetLinkSent bool `json:"reset_link_sent"`
+
+	// The single-use token of POST /v1/users/password/reset, handed to the user by the administrator.
+	// Only returned when the link was not emailed.
+	ResetToken string `json:"reset_token,omitempty"`
+
+	// The time the reset token expires.
+	// Example: "2022-03-18T10:00:00Z"
+	ResetTokenExpiresAt time.Time `json:"reset_token_expires_at"`
+}
+
+// requirePasswordResetV1 forces a user to choose a new password, requires the users:manage permission.
+// Forces a user to choose a new password with a single-use token before logging in again. The token is emailed as a
+// reset link to the verified email of the user, or returned to the administrator when the user has none.
+// The tokens and the sessions of the user are revoked.
+func (server *Server) requirePasswordResetV1(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	before, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	var user db.User
+	var rsp passwordResetResponse
+	var resetToken string
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		var err error
+		user, err = store.RequirePasswordReset(ctx, before.Username)
+		if err != nil {
+			return err
+		}
+		err = store.RevokeUserTokensTx(ctx, db.RevokeUserTokensParams{
+			Username:         user.Username,
+			TokensValidAfter: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		resetToken, rsp.ResetTokenExpiresAt, err = createUserToken(ctx, store, user, db.UserTokenResetPassword, server.config.PasswordResetTokenDuration)
+		if err != nil {
+			return err
+		}
+		rsp.userDetailsResponse = newUserDetailsResponse(user)
+		return recordAudit(ctx, store, db.AuditActionResetPassword, db.AuditEntityUser, user.ID, newUserDetailsResponse(before), rsp.userDetailsResponse)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	server.revocations.ForgetUser(before.Username)
+	if user.EmailVerifiedAt.Valid {
+		err = server.mailUserToken(ctx, user, resetToken, server.config.PasswordResetURL, server.config.PasswordResetTokenDuration,
+			"Choose a new password",
+			"Hello %s,\n\nAn administrator requires you to choose a new password before logging in again. "+
+				"Choose it by opening the link below, it expires in %s:\n\n%s\n")
+		if err != nil {
+			log.Printf("cannot send the password reset of %s: %v", user.Username, err)
+		}
+		rsp.ResetLinkSent = err == nil
+	}
+	if !rsp.ResetLinkSent {
+		rsp.ResetToken = resetToken
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// changePasswordV1 changes the password of a user.
+// Changes the password of a user, authenticated by the current password and, with two-factor authentication,
+// the code of the authenticator or a recovery code. The users whose password must be reset
+// complete the reset with the token sent by email or issued by an administrator instead.
+// The tokens and the sessions issued before are rejected.
+func (server *Server) changePasswordV1(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, ok := server.checkCredentials(ctx, req.Username, req.Password)
+	if !ok {
+		return
+	}
+	if user.DisabledAt.Valid {
+		writeError(ctx, token.ErrUserDisabled)
+		return
+	}
+	if user.PasswordResetRequired {
+		writeError(ctx, errPasswordResetRequired)
+		return
+	}
+	if req.NewPassword == req.Password {
+		writeError(ctx, errSamePassword)
+		return
+	}
+	secret, err := server.getTOTPSecret(ctx, user.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if secret.ConfirmedAt.Valid {
+		if !server.checkSecondFactor(ctx, secret, req.Code, req.RecoveryCode) {
+			return
+		}
+	} else {
+		err = server.loginGuard.Succeed(ctx, user.Username)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	user, err = server.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: time.Now(),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	// the cached cutoff predates the change, the tokens issued before are rejected from now on
+	server.revocations.ForgetUser(user.Username)
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// checkCredentials checks the password of a user, throttled by the login guard. The unknown users are answered
+// like the wrong passwords, after as long a check, so that the response does not reveal which users exist.
+// The failed logins are forgotten by the caller, once the second factor of the user is checked too.
+// The errors are written to the response.
+func (server *Server) checkCredentials(ctx *gin.Context, username string, password string) (db.User, bool) {
+	err := server.loginGuard.Reserve(ctx, username, ctx.ClientIP())
+	if err != nil {
+		writeLoginError(ctx, err)
+		return db.User{}, false
+	}
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil && err != sql.ErrNoRows {
+		server.releaseLogin(ctx, username)
+		writeError(ctx, err)
+		return db.User{}, false
+	}
+	if err == sql.ErrNoRows {
+		err = util.CheckUnknownUserPassword(password)
+	} else {
+		err = util.CheckPassword(password, user.HashedPassword)
+	}
+	if err != nil {
+		err = server.loginGuard.Fail(ctx, username, ctx.ClientIP())
+		if err != nil {
+			writeError(ctx, err)
+			return db.User{}, false
+		}
+		writeError(ctx, errInvalidCredentials)
+		return db.User{}, false
+	}
+	err = server.loginGuard.Release(ctx, username, ctx.ClientIP())
+	if err != nil {
+		writeError(ctx, err)
+		return db.User{}, false
+	}
+	return user, true
+}
+
+// releaseLogin releases a login reserved with the login guard that could not be checked, a failure to release it is logged.
+func (server *Server) releaseLogin(ctx *gin.Context, username string) {
+	err := server.loginGuard.Release(ctx, username, ctx.ClientIP())
+	if err != nil {
+		log.Printf("cannot release the login of %q: %v", username, err)
+	}
+}
+
+// writeLoginError writes the error of the login guard, the throttled logins are told when to retry.
+func writeLoginError(ctx *gin.Context, err error) {
+	var throttled *lockout.ThrottledError
+	if errors.As(err, &throttled) {
+		ctx.Header(retryAfterHeader, strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		writeStatusError(ctx, http.StatusTooManyRequests, err)
+		return
+	}
+	writeError(ctx, err)
+}
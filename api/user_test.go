@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/lockout"
+	"vk-film/mail"
+	"vk-film/token"
+	"vk-film/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// userStore keeps the users, their single-use tokens and the audit entries in memory, the login throttles are
+// always clear. Its ExecTx runs the function on itself.
+type userStore struct {
+	db.Store
+	users        map[string]db.User
+	userTokens   []db.CreateUserTokenParams
+	revokedUsers []string
+	audit        []db.CreateAuditEntryParams
+}
+
+func newUserStore(users ...db.User) *userStore {
+	store := &userStore{users: make(map[string]db.User)}
+	for _, user := range users {
+		store.users[user.Username] = user
+	}
+	return store
+}
+
+func (store *userStore) ExecTx(ctx context.Context, fn func(db.Store) error) error {
+	return fn(store)
+}
+
+func (store *userStore) GetUser(ctx context.Context, username string) (db.User, error) {
+	user, ok := store.users[username]
+	if !ok {
+		return db.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (store *userStore) RequirePasswordReset(ctx context.Context, username string) (db.User, error) {
+	user, err := store.GetUser(ctx, username)
+	if err != nil {
+		return db.User{}, err
+	}
+	user.PasswordResetRequired = true
+	store.users[username] = user
+	return user, nil
+}
+
+func (store *userStore) UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) (db.User, error) {
+	user, err := store.GetUser(ctx, arg.Username)
+	if err != nil {
+		return db.User{}, err
+	}
+	user.HashedPassword = arg.HashedPassword
+	user.PasswordChangedAt = arg.PasswordChangedAt
+	user.PasswordResetRequired = false
+	store.users[arg.Username] = user
+	return user, nil
+}
+
+func (store *userStore) RevokeUserTokensTx(ctx context.Context, arg db.RevokeUserTokensParams) error {
+	store.revokedUsers = append(store.revokedUsers, arg.Username)
+	return nil
+}
+
+func (store *userStore) CreateUserTokenTx(ctx context.Context, arg db.CreateUserTokenParams) error {
+	// the tokens of the same user and purpose not used yet stop working
+	tokens := store.userTokens[:0]
+	for _, userToken := range store.userTokens {
+		if userToken.Username != arg.Username || userToken.Purpose != arg.Purpose {
+			tokens = append(tokens, userToken)
+		}
+	}
+	store.userTokens = append(tokens, arg)
+	return nil
+}
+
+func (store *userStore) CreateAuditEntry(ctx context.Context, arg db.CreateAuditEntryParams) (db.AuditLog, error) {
+	store.audit = append(store.audit, arg)
+	return db.AuditLog{}, nil
+}
+
+func (store *userStore) ListLoginThrottles(ctx context.Context, keys []string) ([]db.LoginThrottle, error) {
+	return nil, nil
+}
+
+func (store *userStore) ReserveLoginAttempt(ctx context.Context, arg db.ReserveLoginAttemptParams) (db.LoginThrottle, error) {
+	return db.LoginThrottle{Key: arg.Key, Pending: 1, ReservedAt: arg.ReservedAt}, nil
+}
+
+func (store *userStore) ReleaseLoginAttempts(ctx context.Context, keys []string) error {
+	return nil
+}
+
+// testMailer keeps the messages sent, or fails to send them with err.
+type testMailer struct {
+	messages []mail.Message
+	err      error
+}
+
+func (mailer *testMailer) Send(ctx context.Context, msg mail.Message) error {
+	if mailer.err != nil {
+		return mailer.err
+	}
+	mailer.messages = append(mailer.messages, msg)
+	return nil
+}
+
+// newUserServer returns a server of the user routes backed by store and mailer.
+func newUserServer(t *testing.T, store db.Store, mailer mail.Mailer) *Server {
+	guard, err := lockout.NewGuard(store, lockout.Config{
+		MaxFailures:     5,
+		MaxIPFailures:   20,
+		Window:          time.Minute,
+		LockoutDuration: time.Minute,
+	})
+	require.NoError(t, err)
+	return &Server{
+		config: util.Config{
+			PasswordResetURL:           "https://vk-film.example/reset",
+			PasswordResetTokenDuration: time.Hour,
+		},
+		store:       store,
+		revocations: token.NewRevocationList(store, time.Minute),
+		mailer:      mailer,
+		loginGuard:  guard,
+	}
+}
+
+// newTestUser returns an active user with password, without email.
+func newTestUser(t *testing.T, username string, password string) db.User {
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	return db.User{ID: 9, Username: username, HashedPassword: hashedPassword, Role: "client", CreatedAt: time.Now()}
+}
+
+func TestCheckUserCanLogIn(t *testing.T) {
+	require.NoError(t, checkUserCanLogIn(db.User{}))
+	require.ErrorIs(t, checkUserCanLogIn(db.User{PasswordResetRequired: true}), errPasswordResetRequired)
+	disabled := db.User{DisabledAt: sql.NullTime{Time: time.Now(), Valid: true}, PasswordResetRequired: true}
+	require.ErrorIs(t, checkUserCanLogIn(disabled), token.ErrUserDisabled)
+}
+
+func TestRequirePasswordResetV1(t *testing.T) {
+	verified := sql.NullTime{Time: time.Now(), Valid: true}
+	email := sql.NullString{String: "alice@example.com", Valid: true}
+
+	testCases := []struct {
+		name     string
+		email    sql.NullString
+		verified sql.NullTime
+		mailErr  error
+		// whether the reset link is emailed rather than the token returned
+		mailed bool
+	}{
+		{
+			name:     "VerifiedEmail",
+			email:    email,
+			verified: verified,
+			mailed:   true,
+		},
+		{
+			name: "NoEmail",
+		},
+		{
+			name:  "UnverifiedEmail",
+			email: email,
+		},
+		{
+			name:     "MailFailure",
+			email:    email,
+			verified: verified,
+			mailErr:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := newTestUser(t, "alice", "secret1")
+			user.Email = tc.email
+			user.EmailVerifiedAt = tc.verified
+			store := newUserStore(user)
+			mailer := &testMailer{err: tc.mailErr}
+			server := newUserServer(t, store, mailer)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(ctx *gin.Context) {
+				ctx.Set(authorizationPayload, &token.Payload{Username: "admin", Role: "administrator"})
+			})
+			router.POST("/v1/users/:username/password-reset", server.requirePasswordResetV1)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/users/alice/password-reset", nil))
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var rsp passwordResetResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.True(t, rsp.PasswordResetRequired)
+			require.True(t, store.users["alice"].PasswordResetRequired)
+			require.Equal(t, []string{"alice"}, store.revokedUsers)
+			require.Len(t, store.audit, 1)
+			require.Equal(t, db.AuditActionResetPassword, store.audit[0].Action)
+			require.Equal(t, "admin", store.audit[0].Username)
+
+			require.Len(t, store.userTokens, 1)
+			stored := store.userTokens[0]
+			require.Equal(t, db.UserTokenResetPassword, stored.Purpose)
+			require.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+			require.WithinDuration(t, stored.ExpiresAt, rsp.ResetTokenExpiresAt, time.Second)
+
+			require.Equal(t, tc.mailed, rsp.ResetLinkSent)
+			if tc.mailed {
+				// the token only travels in the link, never to the administrator
+				require.Empty(t, rsp.ResetToken)
+				require.Len(t, mailer.messages, 1)
+				require.Equal(t, "alice@example.com", mailer.messages[0].To)
+				linkToken := resetLinkToken(t, mailer.messages[0].Body)
+				require.Equal(t, stored.TokenHash, token.HashOpaqueToken(linkToken))
+			} else {
+				require.Empty(t, mailer.messages)
+				require.Equal(t, stored.TokenHash, token.HashOpaqueToken(rsp.ResetToken))
+			}
+		})
+	}
+}
+
+// resetLinkToken returns the token of the password reset link in the body of an email.
+func resetLinkToken(t *testing.T, body string) string {
+	for _, field := range strings.Fields(body) {
+		if strings.HasPrefix(field, "https://vk-film.example/reset?") {
+			link, err := url.Parse(field)
+			require.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	require.FailNow(t, "the email has no reset link", body)
+	return ""
+}
+
+func TestRequirePasswordResetV1UnknownUser(t *testing.T) {
+	store := newUserStore()
+	server := newUserServer(t, store, &testMailer{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/users/:username/password-reset", server.requirePasswordResetV1)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/users/bob/password-reset", nil))
+
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Empty(t, store.userTokens)
+}
+
+func TestChangePasswordV1PasswordResetRequired(t *testing.T) {
+	user := newTestUser(t, "alice", "secret1")
+	user.PasswordResetRequired = true
+	store := newUserStore(user)
+	server := newUserServer(t, store, &testMailer{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/users/password", server.changePasswordV1)
+	body := `{"username": "alice", "password": "secret1", "new_password": "secret2"}`
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/users/password", strings.NewReader(body)))
+
+	// the current password no longer completes a forced reset, only the reset token does
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	var got problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Equal(t, "password_reset_required", got.Code)
+	require.Equal(t, user.HashedPassword, store.users["alice"].HashedPassword)
+}
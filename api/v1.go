@@ -0,0 +1,551 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resourceURI represents the path of a single movie or actor in the v1 API.
+type resourceURI struct {
+	// IDs of records merged into another one resolve to the survivor.
+	// required: true
+	ID int32 `uri:"id" binding:"required,min=1"`
+}
+
+// revisionURI represents the path of a revision of a movie or an actor in the v1 API.
+type revisionURI struct {
+	// required: true
+	ID int32 `uri:"id" binding:"required,min=1"`
+
+	// required: true
+	Revision int32 `uri:"revision" binding:"required,min=1"`
+}
+
+// revisionRangeRequest represents the query parameters for comparing two revisions in the v1 API.
+type revisionRangeRequest struct {
+	// required: true
+	From int32 `form:"from" binding:"required,min=1"`
+
+	// required: true
+	To int32 `form:"to" binding:"required,min=1"`
+}
+
+// mergeIntoRequest represents the request body for merging a duplicate into the record of the path.
+type mergeIntoRequest struct {
+	// The ID of the record to merge into the one of the path and delete.
+	// Required: true
+	DuplicateID int32 `json:"duplicate_id" binding:"required,min=1"`
+}
+
+// listMoviesRequest represents the query parameters for listing movies in the v1 API.
+type listMoviesRequest struct {
+	// Only movies whose name contains this fragment.
+	Name string `form:"name"`
+
+	// Only movies released on or after this date.
+	ReleasedAfter time.Time `form:"released_after" time_format:"2006-01-02"`
+
+	// Only movies released on or before this date.
+	ReleasedBefore time.Time `form:"released_before" time_format:"2006-01-02"`
+
+	// Only movies rated at least this.
+	MinRating float64 `form:"min_rating" binding:"min=0,max=10"`
+
+	// The order of the movies, can be: ["rating", "name", "release_date"].
+	Sort string `form:"sort,default=rating" binding:"oneof=rating name release_date"`
+
+	// The page number, starting at 1.
+	PageID int32 `form:"page_id,default=1" binding:"min=1"`
+
+	// The number of movies per page.
+	PageSize int32 `form:"page_size,default=20" binding:"min=5,max=100"`
+}
+
+// listActorsRequest represents the query parameters for listing actors in the v1 API.
+type listActorsRequest struct {
+	// Only actors whose name contains this fragment.
+	Name string `form:"name"`
+
+	// Only actors of this gender.
+	Gender string `form:"gender"`
+
+	// The page number, starting at 1.
+	PageID int32 `form:"page_id,default=1" binding:"min=1"`
+
+	// The number of actors per page.
+	PageSize int32 `form:"page_size,default=20" binding:"min=5,max=100"`
+}
+
+// setupV1Routes registers the resource-oriented routes of the v1 API.
+func (server *Server) setupV1Routes(router *gin.Engine) {
+	v1 := router.Group("/v1")
+	v1.POST("/users", server.createUserV1)
+	v1.POST("/users/login", server.loginUser)
+	v1.POST("/users/login/verify", server.verifyLoginV1)
+	v1.POST("/users/login/totp", server.enrollLoginTOTPV1)
+	v1.POST("/users/password", server.changePasswordV1)
+	v1.POST("/users/password/forgot", server.forgotPasswordV1)
+	v1.POST("/users/password/reset", server.resetPasswordV1)
+	v1.POST("/users/email/verify", server.verifyEmailV1)
+	v1.POST("/users/oidc/authorize", server.startOIDCLoginV1)
+	v1.GET("/users/oidc/callback", server.oidcCallbackV1)
+	v1.POST("/tokens/renew", server.renewAccessToken)
+
+	authRoutes := v1.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations, server.apiKeys))
+	authRoutes.POST("/users/logout", requireUser(), server.logoutUser)
+	authRoutes.PUT("/users/email", requireUser(), server.updateEmailV1)
+	authRoutes.POST("/users/email/verification", requireUser(), server.sendEmailVerificationV1)
+	authRoutes.GET("/users", server.requirePermission(authz.UsersManage), server.listUsersV1)
+	authRoutes.GET("/users/:username", server.requirePermission(authz.UsersManage), server.getUserV1)
+	authRoutes.POST("/users/:username/disable", server.requirePermission(authz.UsersManage), server.disableUserV1)
+	authRoutes.POST("/users/:username/enable", server.requirePermission(authz.UsersManage), server.enableUserV1)
+	authRoutes.POST("/users/:username/password-reset", server.requirePermission(authz.UsersManage), server.requirePasswordResetV1)
+	authRoutes.POST("/users/:username/tokens/revoke", server.requirePermission(authz.UsersManage), server.revokeUserTokensV1)
+	authRoutes.POST("/users/identities", requireUser(), server.linkIdentityV1)
+	authRoutes.GET("/users/identities", requireUser(), server.listIdentitiesV1)
+	authRoutes.DELETE("/users/identities/:id", requireUser(), server.unlinkIdentityV1)
+	authRoutes.GET("/users/totp", requireUser(), server.getTwoFactorV1)
+	authRoutes.POST("/users/totp", requireUser(), server.enrollTOTPV1)
+	authRoutes.POST("/users/totp/confirm", requireUser(), server.confirmTOTPV1)
+	authRoutes.POST("/users/totp/recovery-codes", requireUser(), server.regenerateRecoveryCodesV1)
+	authRoutes.POST("/users/totp/disable", requireUser(), server.disableTOTPV1)
+	authRoutes.POST("/users/:username/totp/reset", server.requirePermission(authz.UsersManage), server.resetTwoFactorV1)
+	authRoutes.GET("/sessions", requireUser(), server.listSessionsV1)
+	authRoutes.DELETE("/sessions/:id", requireUser(), server.revokeSessionV1)
+
+	// role routes
+	authRoutes.GET("/permissions", server.requirePermission(authz.RolesManage), server.listPermissionsV1)
+	authRoutes.GET("/roles", server.requirePermission(authz.RolesManage), server.listRolesV1)
+	authRoutes.POST("/roles", server.requirePermission(authz.RolesManage), server.createRoleV1)
+	authRoutes.GET("/roles/:name", server.requirePermission(authz.RolesManage), server.getRoleV1)
+	authRoutes.PUT("/roles/:name", server.requirePermission(authz.RolesManage), server.updateRoleV1)
+	authRoutes.DELETE("/roles/:name", server.requirePermission(authz.RolesManage), server.deleteRoleV1)
+	authRoutes.PUT("/users/:username/role", server.requirePermission(authz.RolesManage), server.assignUserRoleV1)
+
+	// API key routes
+	authRoutes.POST("/api-keys", server.requirePermission(authz.APIKeysManage), server.createAPIKeyV1)
+	authRoutes.GET("/api-keys", server.requirePermission(authz.APIKeysManage), server.listAPIKeysV1)
+	authRoutes.GET("/api-keys/:id", server.requirePermission(authz.APIKeysManage), server.getAPIKeyV1)
+	authRoutes.POST("/api-keys/:id/revoke", server.requirePermission(authz.APIKeysManage), server.revokeAPIKeyV1)
+
+	movieCatalogRoutes := v1.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations, server.apiKeys), server.catalogCacheMiddleware(server.config.MoviesCacheControl))
+	actorCatalogRoutes := v1.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations, server.apiKeys), server.catalogCacheMiddleware(server.config.ActorsCacheControl))
+	// movie routes
+	authRoutes.POST("/movies", server.requirePermission(authz.MoviesWrite), server.createMovieV1)
+	authRoutes.GET("/movies/:id", server.getMovieV1)
+	authRoutes.PATCH("/movies/:id", server.requirePermission(authz.MoviesWrite), server.updateMovieV1)
+	authRoutes.DELETE("/movies/:id", server.requirePermission(authz.MoviesDelete), server.deleteMovieV1)
+	authRoutes.POST("/movies/:id/merge", server.requirePermission(authz.MoviesDelete), server.mergeMovieV1)
+	authRoutes.GET("/movies/:id/revisions", server.requirePermission(authz.RevisionsRead), server.listMovieRevisionsV1)
+	authRoutes.GET("/movies/:id/revisions/diff", server.requirePermission(authz.RevisionsRead), server.diffMovieRevisionsV1)
+	authRoutes.POST("/movies/:id/revisions/:revision/restore", server.requirePermission(authz.MoviesWrite), server.restoreMovieRevisionV1)
+	movieCatalogRoutes.GET("/movies", server.listMoviesV1)
+	movieCatalogRoutes.GET("/movies/:id/actors", server.listMovieCastV1)
+
+	// actor routes
+	authRoutes.POST("/actors", server.requirePermission(authz.ActorsWrite), server.createActorV1)
+	authRoutes.GET("/actors/:id", server.getActorV1)
+	authRoutes.PATCH("/actors/:id", server.requirePermission(authz.ActorsWrite), server.updateActorV1)
+	authRoutes.DELETE("/actors/:id", server.requirePermission(authz.ActorsDelete), server.deleteActorV1)
+	authRoutes.POST("/actors/:id/merge", server.requirePermission(authz.ActorsDelete), server.mergeActorV1)
+	authRoutes.GET("/actors/:id/revisions", server.requirePermission(authz.RevisionsRead), server.listActorRevisionsV1)
+	authRoutes.GET("/actors/:id/revisions/diff", server.requirePermission(authz.RevisionsRead), server.diffActorRevisionsV1)
+	authRoutes.POST("/actors/:id/revisions/:revision/restore", server.requirePermission(authz.ActorsWrite), server.restoreActorRevisionV1)
+	actorCatalogRoutes.GET("/actors", server.listActorsV1)
+	actorCatalogRoutes.GET("/actors/:id/movies", server.listActorMoviesV1)
+
+	// audit routes
+	authRoutes.GET("/audit-entries", server.requirePermission(authz.AuditRead), server.listAuditEntries)
+	authRoutes.GET("/security-events", server.requirePermission(authz.AuditRead), server.listSecurityEventsV1)
+
+	// cache routes
+	authRoutes.GET("/cache/stats", server.requirePermission(authz.CacheRead), server.getCacheStats)
+}
+
+// createUserV1 creates a new user.
+func (server *Server) createUserV1(ctx *gin.Context) {
+	var req createUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	user, ok := server.createUserResource(ctx, req)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusCreated, newUserResponse(user))
+}
+
+// createMovieV1 creates a new movie, requires the movies:write permission.
+func (server *Server) createMovieV1(ctx *gin.Context) {
+	var req createMovieRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.createMovieResource(ctx, req)
+	if !ok {
+		return
+	}
+	ctx.Header(locationHeader, fmt.Sprintf("/v1/movies/%d", rsp.ID))
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// listMoviesV1 lists the movies matching the filters.
+func (server *Server) listMoviesV1(ctx *gin.Context) {
+	var req listMoviesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	arg := db.ListMoviesParams{
+		Name:           sql.NullString{String: req.Name, Valid: req.Name != ""},
+		ReleasedAfter:  sql.NullTime{Time: req.ReleasedAfter, Valid: !req.ReleasedAfter.IsZero()},
+		ReleasedBefore: sql.NullTime{Time: req.ReleasedBefore, Valid: !req.ReleasedBefore.IsZero()},
+		MinRating:      sql.NullString{String: fmt.Sprint(req.MinRating), Valid: req.MinRating > 0},
+		SortBy:         req.Sort,
+		PageLimit:      req.PageSize,
+		PageOffset:     (req.PageID - 1) * req.PageSize,
+	}
+	movies, err := server.store.ListMovies(ctx, arg)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]movieResponse, 0, len(movies))
+	for _, movie := range movies {
+		rsp = append(rsp, newMovieResponse(movie))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getMovieV1 retrieves a movie.
+func (server *Server) getMovieV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	movie, ok := server.getMovieResource(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newMovieResponse(movie))
+}
+
+// updateMovieV1 partially updates a movie, requires the movies:write permission.
+func (server *Server) updateMovieV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ctx.GetRawData()
+	if err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	movie, ok := server.updateMovieResource(ctx, uri.ID, body)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newMovieResponse(movie))
+}
+
+// deleteMovieV1 deletes a movie, requires the movies:delete permission.
+func (server *Server) deleteMovieV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if !server.deleteMovieResource(ctx, uri.ID) {
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// listMovieCastV1 lists the actors of a movie.
+func (server *Server) listMovieCastV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	movie, err := server.store.GetMovie(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rows, err := server.store.ListMovieCast(ctx, []int32{movie.ID})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]actorResponse, 0, len(rows))
+	for _, row := range rows {
+		rsp = append(rsp, actorResponse{
+			ID:       row.ID,
+			Name:     row.Name,
+			Gender:   row.Gender,
+			Birthday: row.Birthday,
+			Version:  row.Version,
+		})
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// mergeMovieV1 merges a duplicate movie into the movie of the path, requires the movies:delete permission.
+func (server *Server) mergeMovieV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	var req mergeIntoRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	movie, ok := server.mergeMovieResources(ctx, uri.ID, req.DuplicateID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newMovieResponse(movie))
+}
+
+// listMovieRevisionsV1 lists the revisions of a movie, requires the revisions:read permission.
+func (server *Server) listMovieRevisionsV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.listMovieRevisionResources(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// diffMovieRevisionsV1 compares two revisions of a movie, requires the revisions:read permission.
+func (server *Server) diffMovieRevisionsV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	var req revisionRangeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.diffMovieRevisionResources(ctx, uri.ID, req.From, req.To)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// restoreMovieRevisionV1 restores an earlier revision of a movie, requires the movies:write permission.
+func (server *Server) restoreMovieRevisionV1(ctx *gin.Context) {
+	var uri revisionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	movie, ok := server.restoreMovieRevisionResource(ctx, uri.ID, uri.Revision)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newMovieResponse(movie))
+}
+
+// createActorV1 creates a new actor, requires the actors:write permission.
+func (server *Server) createActorV1(ctx *gin.Context) {
+	var req createActorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.createActorResource(ctx, req)
+	if !ok {
+		return
+	}
+	ctx.Header(locationHeader, fmt.Sprintf("/v1/actors/%d", rsp.ID))
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// listActorsV1 lists the actors matching the filters.
+func (server *Server) listActorsV1(ctx *gin.Context) {
+	var req listActorsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	arg := db.ListActorsParams{
+		Name:       sql.NullString{String: req.Name, Valid: req.Name != ""},
+		Gender:     sql.NullString{String: req.Gender, Valid: req.Gender != ""},
+		PageLimit:  req.PageSize,
+		PageOffset: (req.PageID - 1) * req.PageSize,
+	}
+	actors, err := server.store.ListActors(ctx, arg)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]actorResponse, 0, len(actors))
+	for _, actor := range actors {
+		rsp = append(rsp, newActorResponse(actor))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getActorV1 retrieves an actor.
+func (server *Server) getActorV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.getActorResource(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newActorResponse(actor))
+}
+
+// updateActorV1 partially updates an actor, requires the actors:write permission.
+func (server *Server) updateActorV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ctx.GetRawData()
+	if err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.updateActorResource(ctx, uri.ID, body)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newActorResponse(actor))
+}
+
+// deleteActorV1 deletes an actor, requires the actors:delete permission.
+func (server *Server) deleteActorV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if !server.deleteActorResource(ctx, uri.ID) {
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// listActorMoviesV1 lists the movies of an actor.
+func (server *Server) listActorMoviesV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, err := server.store.GetActor(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rows, err := server.store.ListActorFilmography(ctx, []int32{actor.ID})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	rsp := make([]movieResponse, 0, len(rows))
+	for _, row := range rows {
+		rsp = append(rsp, movieResponse{
+			ID:          row.ID,
+			Name:        row.Name,
+			Description: row.Description,
+			ReleaseDate: row.ReleaseDate,
+			Rating:      row.Rating,
+			Version:     row.Version,
+		})
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// mergeActorV1 merges a duplicate actor into the actor of the path, requires the actors:delete permission.
+func (server *Server) mergeActorV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	var req mergeIntoRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.mergeActorResources(ctx, uri.ID, req.DuplicateID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newActorResponse(actor))
+}
+
+// listActorRevisionsV1 lists the revisions of an actor, requires the revisions:read permission.
+func (server *Server) listActorRevisionsV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.listActorRevisionResources(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// diffActorRevisionsV1 compares two revisions of an actor, requires the revisions:read permission.
+func (server *Server) diffActorRevisionsV1(ctx *gin.Context) {
+	var uri resourceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	var req revisionRangeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	rsp, ok := server.diffActorRevisionResources(ctx, uri.ID, req.From, req.To)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// restoreActorRevisionV1 restores an earlier revision of an actor, requires the actors:write permission.
+func (server *Server) restoreActorRevisionV1(ctx *gin.Context) {
+	var uri revisionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeStatusError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	actor, ok := server.restoreActorRevisionResource(ctx, uri.ID, uri.Revision)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newActorResponse(actor))
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+const environmentDevelopment = "development"
+
+func init() {
+	// the errors name the invalid member without dumping its whole schema to the client
+	openapi3.SchemaErrorDetailsDisabled = true
+	openapi3filter.RegisterBodyDecoder(contentTypeMergePatch, openapi3filter.JSONBodyDecoder)
+}
+
+// openapiValidationMiddleware rejects requests that do not match the OpenAPI document with 400 Bad Request, listing
+// all their invalid fields at once.
+// In development it also checks the responses and logs the ones drifting from the document.
+// Requests to routes missing from the document, like the legacy ones, are not validated.
+func (server *Server) openapiValidationMiddleware(router routers.Router) gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		// the access token is verified by authMiddleware
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		MultiError:         true,
+	}
+	return func(ctx *gin.Context) {
+		route, pathParams, err := router.FindRoute(ctx.Request)
+		if err != nil {
+			ctx.Next()
+			return
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    ctx.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+			abortWithError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		if server.config.Environment != environmentDevelopment {
+			ctx.Next()
+			return
+		}
+		writer := &recordingWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+		output := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 writer.Status(),
+			Header:                 writer.Header(),
+			Options:                options,
+		}
+		output.SetBodyBytes(writer.body.Bytes())
+		if err := openapi3filter.ValidateResponse(ctx, output); err != nil {
+			log.Printf("response of %s %s does not match the OpenAPI document: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+		}
+	}
+}
+
+// schemaFieldErrors lists the fields rejected by the OpenAPI document, named by their parameter or by their path in the
+// body. It returns nil when the request was rejected for anything else than the values of its fields, like its media type.
+func schemaFieldErrors(err error) []fieldError {
+	requestErrors, ok := err.(openapi3.MultiError)
+	if !ok {
+		requestErrors = openapi3.MultiError{err}
+	}
+	var fields []fieldError
+	for _, err := range requestErrors {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(err, &requestErr) {
+			return nil
+		}
+		schemaErrors, ok := requestErr.Err.(openapi3.MultiError)
+		if !ok {
+			schemaErrors = openapi3.MultiError{requestErr.Err}
+		}
+		for _, err := range schemaErrors {
+			var schemaErr *openapi3.SchemaError
+			if !errors.As(err, &schemaErr) {
+				return nil
+			}
+			field := strings.Join(schemaErr.JSONPointer(), ".")
+			if requestErr.Parameter != nil {
+				field = requestErr.Parameter.Name
+			}
+			fields = append(fields, fieldError{
+				Field:   field,
+				Code:    schemaErr.SchemaField,
+				Message: fmt.Sprintf("%s: %s", field, schemaErr.Reason),
+			})
+		}
+	}
+	return fields
+}
+
+// recordingWriter keeps a copy of the response body for its validation.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (writer *recordingWriter) Write(data []byte) (int, error) {
+	writer.body.Write(data)
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *recordingWriter) WriteString(s string) (int, error) {
+	writer.body.WriteString(s)
+	return writer.ResponseWriter.WriteString(s)
+}
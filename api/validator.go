@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"vk-film/util"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		// the field errors are named like the fields of the request, not like the Go struct fields
+		v.RegisterTagNameFunc(requestFieldName)
+		v.RegisterValidation("rating", validRating)
+		v.RegisterValidation("notfuture", notFuture)
+	}
+}
+
+// fieldError describes one invalid field of a request.
+type fieldError struct {
+	// The name of the field in the body, the query or the path.
+	// Example: rating
+	Field string `json:"field"`
+
+	// The rule broken by the field.
+	// Example: rating
+	Code string `json:"code"`
+
+	// A message that can be shown next to the input of the field.
+	// Example: rating must be a number from 0 to 10 with at most one decimal
+	Message string `json:"message"`
+}
+
+// requestFieldName returns the name of a field in the request, taken from its json, form or uri tag.
+func requestFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form", "uri"} {
+		name := strings.Split(field.Tag.Get(key), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// validRating reports whether a rating fits the rating column, from 0 to 10 with at most one decimal.
+func validRating(fl validator.FieldLevel) bool {
+	return util.IsValidRating(fl.Field().String())
+}
+
+// notFuture reports whether a date is not after the current time.
+func notFuture(fl validator.FieldLevel) bool {
+	date, ok := fl.Field().Interface().(time.Time)
+	return ok && !date.After(time.Now())
+}
+
+// fieldErrors lists the invalid fields reported by the binding validator, it returns nil for the other errors.
+func fieldErrors(err error) []fieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]fieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, fieldError{
+				Field:   fe.Field(),
+				Code:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return fields
+	}
+	return nil
+}
+
+// fieldMessage describes the rule broken by a field.
+func fieldMessage(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s", fe.Field(), fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s%s", fe.Field(), fe.Param(), unit)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", fe.Field(), strings.Join(strings.Fields(fe.Param()), ", "))
+	case "rating":
+		return fmt.Sprintf("%s must be a number from 0 to 10 with at most one decimal", fe.Field())
+	case "uuid":
+		return fmt.Sprintf("%s must be a UUID", fe.Field())
+	case "alphanum":
+		return fmt.Sprintf("%s must contain only letters and digits", fe.Field())
+	case "lowercase":
+		return fmt.Sprintf("%s must be lowercase", fe.Field())
+	case "notfuture":
+		return fmt.Sprintf("%s must not be in the future", fe.Field())
+	case "email":
+		return fmt.Sprintf("%s must be an email address", fe.Field())
+	}
+	return fmt.Sprintf("%s is invalid", fe.Field())
+}
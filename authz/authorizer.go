@@ -0,0 +1,129 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	db "vk-film/db/sqlc"
+)
+
+// The permissions checked by the routes. A role grants any of them, the roles are managed in the store.
+const (
+	MoviesWrite   = "movies:write"
+	MoviesDelete  = "movies:delete"
+	ActorsWrite   = "actors:write"
+	ActorsDelete  = "actors:delete"
+	RevisionsRead = "revisions:read"
+	AuditRead     = "audit:read"
+	CacheRead     = "cache:read"
+	UsersManage   = "users:manage"
+	RolesManage   = "roles:manage"
+	APIKeysManage = "api_keys:manage"
+)
+
+// The roles every installation has: the administrator role keeps every permission, so that the roles can always be managed,
+// and the client role is the role of the new users.
+const (
+	RoleAdministrator = "administrator"
+	RoleClient        = "client"
+)
+
+var (
+	ErrPermissionDenied = errors.New("the role of the user does not grant the permission required")
+	ErrScopeDenied      = errors.New("the scopes of the API key do not grant the permission required")
+	ErrIssuerDenied     = errors.New("the role of the user who issued the API key no longer grants the permission required")
+)
+
+// Authorizer checks the permissions granted to the roles. The permissions of the roles are kept in memory,
+// so that checking a permission does not query the store on every request, and they are synced from the store
+// every syncInterval, to pick up the roles changed through the other servers.
+type Authorizer struct {
+	store        db.Store
+	syncInterval time.Duration
+
+	mu sync.Mutex
+	// the permissions of every role, by role name
+	roles    map[string]map[string]bool
+	syncedAt time.Time
+}
+
+// NewAuthorizer creates an authorizer syncing the permissions of the roles from the store at most every syncInterval.
+func NewAuthorizer(store db.Store, syncInterval time.Duration) *Authorizer {
+	return &Authorizer{
+		store:        store,
+		syncInterval: syncInterval,
+	}
+}
+
+// Authorize returns ErrPermissionDenied if a role does not grant a permission.
+// Unknown roles, like a role deleted after the token was issued, grant nothing.
+func (authorizer *Authorizer) Authorize(ctx context.Context, role string, permission string) error {
+	roles, err := authorizer.permissions(ctx)
+	if err != nil {
+		return err
+	}
+	if !roles[role][permission] {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// AuthorizeScopes returns ErrScopeDenied if the scopes of an API key do not include a permission.
+func AuthorizeScopes(scopes []string, permission string) error {
+	for _, scope := range scopes {
+		if scope == permission {
+			return nil
+		}
+	}
+	return ErrScopeDenied
+}
+
+// AuthorizeAPIKey returns ErrScopeDenied if the scopes of an API key do not include a permission, or ErrIssuerDenied if
+// the role of the user who issued the key no longer grants it, so that demoting a user narrows the keys of the user.
+func (authorizer *Authorizer) AuthorizeAPIKey(ctx context.Context, issuerRole string, scopes []string, permission string) error {
+	if err := AuthorizeScopes(scopes, permission); err != nil {
+		return err
+	}
+	err := authorizer.Authorize(ctx, issuerRole, permission)
+	if err == ErrPermissionDenied {
+		return ErrIssuerDenied
+	}
+	return err
+}
+
+// Invalidate makes the next check reload the permissions, after a role was changed through this server.
+func (authorizer *Authorizer) Invalidate() {
+	authorizer.mu.Lock()
+	authorizer.syncedAt = time.Time{}
+	authorizer.mu.Unlock()
+}
+
+// permissions returns the permissions of the roles, syncing them from the store when they are older than syncInterval.
+// When the store cannot be read, the permissions loaded before keep being used until the next sync.
+func (authorizer *Authorizer) permissions(ctx context.Context) (map[string]map[string]bool, error) {
+	authorizer.mu.Lock()
+	defer authorizer.mu.Unlock()
+	if time.Since(authorizer.syncedAt) < authorizer.syncInterval {
+		return authorizer.roles, nil
+	}
+	rows, err := authorizer.store.ListRolePermissions(ctx)
+	if err != nil {
+		if authorizer.roles == nil {
+			return nil, err
+		}
+		log.Printf("cannot sync the permissions of the roles: %v", err)
+	} else {
+		roles := make(map[string]map[string]bool)
+		for _, row := range rows {
+			if roles[row.Role] == nil {
+				roles[row.Role] = make(map[string]bool)
+			}
+			roles[row.Role][row.Permission] = true
+		}
+		authorizer.roles = roles
+	}
+	authorizer.syncedAt = time.Now()
+	return authorizer.roles, nil
+}
@@ -0,0 +1,142 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+// roleStore keeps the permissions of the roles and counts their reads, the other methods of the store are not used
+// by the authorizer.
+type roleStore struct {
+	db.Store
+	permissions []db.RolePermission
+	err         error
+	reads       int
+}
+
+func (store *roleStore) ListRolePermissions(ctx context.Context) ([]db.RolePermission, error) {
+	store.reads++
+	if store.err != nil {
+		return nil, store.err
+	}
+	return store.permissions, nil
+}
+
+func newRoleStore() *roleStore {
+	return &roleStore{permissions: []db.RolePermission{
+		{Role: RoleAdministrator, Permission: MoviesWrite},
+		{Role: RoleAdministrator, Permission: RolesManage},
+		{Role: "editor", Permission: MoviesWrite},
+	}}
+}
+
+func TestAuthorize(t *testing.T) {
+	testCases := []struct {
+		name        string
+		role        string
+		permission  string
+		expectedErr error
+	}{
+		{
+			name:       "Granted",
+			role:       "editor",
+			permission: MoviesWrite,
+		},
+		{
+			name:        "NotGranted",
+			role:        "editor",
+			permission:  RolesManage,
+			expectedErr: ErrPermissionDenied,
+		},
+		{
+			name:        "UnknownRole",
+			role:        "deleted",
+			permission:  MoviesWrite,
+			expectedErr: ErrPermissionDenied,
+		},
+		{
+			name:        "NoRole",
+			permission:  MoviesWrite,
+			expectedErr: ErrPermissionDenied,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authorizer := NewAuthorizer(newRoleStore(), time.Minute)
+
+			err := authorizer.Authorize(context.Background(), tc.role, tc.permission)
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestAuthorizeScopes(t *testing.T) {
+	require.NoError(t, AuthorizeScopes([]string{ActorsWrite, MoviesWrite}, MoviesWrite))
+	require.ErrorIs(t, AuthorizeScopes([]string{ActorsWrite}, MoviesWrite), ErrScopeDenied)
+	require.ErrorIs(t, AuthorizeScopes(nil, MoviesWrite), ErrScopeDenied)
+}
+
+func TestAuthorizeAPIKey(t *testing.T) {
+	authorizer := NewAuthorizer(newRoleStore(), time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, authorizer.AuthorizeAPIKey(ctx, "editor", []string{MoviesWrite}, MoviesWrite))
+	// the scopes are checked first, a key never grants more than its scopes
+	require.ErrorIs(t, authorizer.AuthorizeAPIKey(ctx, RoleAdministrator, []string{MoviesWrite}, RolesManage), ErrScopeDenied)
+	require.ErrorIs(t, authorizer.AuthorizeAPIKey(ctx, "editor", []string{RolesManage}, RolesManage), ErrIssuerDenied)
+}
+
+func TestAuthorizerSync(t *testing.T) {
+	store := newRoleStore()
+	authorizer := NewAuthorizer(store, time.Hour)
+	ctx := context.Background()
+	require.ErrorIs(t, authorizer.Authorize(ctx, "editor", ActorsWrite), ErrPermissionDenied)
+
+	// a role changed through another server is seen after the sync interval only
+	store.permissions = append(store.permissions, db.RolePermission{Role: "editor", Permission: ActorsWrite})
+	require.ErrorIs(t, authorizer.Authorize(ctx, "editor", ActorsWrite), ErrPermissionDenied)
+	require.Equal(t, 1, store.reads)
+
+	// a role changed through this server is seen at once
+	authorizer.Invalidate()
+	require.NoError(t, authorizer.Authorize(ctx, "editor", ActorsWrite))
+	require.Equal(t, 2, store.reads)
+}
+
+func TestAuthorizerSyncInterval(t *testing.T) {
+	syncInterval := 20 * time.Millisecond
+	store := newRoleStore()
+	authorizer := NewAuthorizer(store, syncInterval)
+	ctx := context.Background()
+	require.NoError(t, authorizer.Authorize(ctx, "editor", MoviesWrite))
+
+	store.permissions = store.permissions[:2]
+	time.Sleep(syncInterval)
+	require.ErrorIs(t, authorizer.Authorize(ctx, "editor", MoviesWrite), ErrPermissionDenied)
+	require.Equal(t, 2, store.reads)
+}
+
+func TestAuthorizerStoreError(t *testing.T) {
+	store := newRoleStore()
+	store.err = errors.New("connection refused")
+	authorizer := NewAuthorizer(store, time.Minute)
+	ctx := context.Background()
+
+	// without permissions loaded before, nothing can be authorized
+	require.ErrorIs(t, authorizer.Authorize(ctx, "editor", MoviesWrite), store.err)
+
+	store.err = nil
+	require.NoError(t, authorizer.Authorize(ctx, "editor", MoviesWrite))
+
+	// the permissions loaded before are kept when a later sync fails
+	store.err = errors.New("connection refused")
+	authorizer.Invalidate()
+	require.NoError(t, authorizer.Authorize(ctx, "editor", MoviesWrite))
+	require.Equal(t, 3, store.reads)
+}
@@ -0,0 +1,14 @@
+CREATE TABLE actor_redirects (
+    old_id INT PRIMARY KEY,
+    actor_id INT NOT NULL REFERENCES actors(id) ON DELETE CASCADE,
+    merged_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE movie_redirects (
+    old_id INT PRIMARY KEY,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    merged_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON actor_redirects (actor_id);
+CREATE INDEX ON movie_redirects (movie_id);
@@ -0,0 +1,26 @@
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    entity VARCHAR(50) NOT NULL,
+    entity_id INT,
+    diff JSONB NOT NULL DEFAULT '{}',
+    request_id VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON audit_log (entity, entity_id);
+CREATE INDEX ON audit_log (username);
+CREATE INDEX ON audit_log (created_at);
+
+-- the audit log is append-only
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH STATEMENT EXECUTE PROCEDURE audit_log_append_only();
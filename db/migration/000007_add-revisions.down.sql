@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS actor_revisions;
+DROP TABLE IF EXISTS movie_revisions;
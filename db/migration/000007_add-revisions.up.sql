@@ -0,0 +1,37 @@
+CREATE TABLE movie_revisions (
+    movie_id INT NOT NULL,
+    revision INT NOT NULL,
+    data JSONB NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now()),
+    PRIMARY KEY (movie_id, revision)
+);
+
+CREATE TABLE actor_revisions (
+    actor_id INT NOT NULL,
+    revision INT NOT NULL,
+    data JSONB NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now()),
+    PRIMARY KEY (actor_id, revision)
+);
+
+-- existing rows start their history at revision 1
+INSERT INTO movie_revisions (movie_id, revision, data, username)
+SELECT id, 1, jsonb_build_object(
+    'id', id,
+    'name', name,
+    'description', description,
+    'release_date', to_char(release_date, 'YYYY-MM-DD"T"00:00:00"Z"'),
+    'rating', rating::text
+), 'system'
+FROM movies;
+
+INSERT INTO actor_revisions (actor_id, revision, data, username)
+SELECT id, 1, jsonb_build_object(
+    'id', id,
+    'name', name,
+    'gender', gender,
+    'birthday', to_char(birthday, 'YYYY-MM-DD"T"00:00:00"Z"')
+), 'system'
+FROM actors;
@@ -0,0 +1,2 @@
+ALTER TABLE movies ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE actors ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS movie_actors_bump_catalog_version ON movie_actors;
+DROP TRIGGER IF EXISTS actors_bump_catalog_version ON actors;
+DROP TRIGGER IF EXISTS movies_bump_catalog_version ON movies;
+DROP FUNCTION IF EXISTS bump_catalog_version;
+DROP TABLE IF EXISTS catalog_state;
@@ -0,0 +1,27 @@
+CREATE TABLE catalog_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version BIGINT NOT NULL DEFAULT 1,
+    updated_at timestamp NOT NULL DEFAULT (now())
+);
+
+INSERT INTO catalog_state DEFAULT VALUES;
+
+-- every change to the catalog bumps its version
+CREATE FUNCTION bump_catalog_version() RETURNS trigger AS $$
+BEGIN
+    UPDATE catalog_state SET version = version + 1, updated_at = now();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER movies_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON movies
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE TRIGGER actors_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON actors
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE TRIGGER movie_actors_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON movie_actors
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
@@ -0,0 +1,22 @@
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY,
+    username VARCHAR(50) NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    user_agent VARCHAR(512) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    is_revoked BOOLEAN NOT NULL DEFAULT false,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now()),
+    last_used_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON sessions (username);
+
+-- every refresh token issued for a session, the rotated ones are kept to detect their reuse
+CREATE TABLE refresh_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+    rotated_at timestamp,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON refresh_tokens (session_id);
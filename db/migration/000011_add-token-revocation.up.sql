@@ -0,0 +1,12 @@
+-- the access tokens of a user issued before this time are rejected
+ALTER TABLE users ADD COLUMN tokens_valid_after timestamp NOT NULL DEFAULT '0001-01-01 00:00:00Z';
+
+-- the access tokens revoked before they expire, kept until then
+CREATE TABLE revoked_tokens (
+    id UUID PRIMARY KEY,
+    username VARCHAR(50) NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON revoked_tokens (expires_at);
@@ -0,0 +1,13 @@
+-- the keys signing the access tokens: a key is published before it signs, from not_before to not_after,
+-- and stays published until expires_at, so that the tokens it signed can be verified until they expire
+CREATE TABLE signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,
+    private_key BYTEA NOT NULL,
+    not_before timestamp NOT NULL,
+    not_after timestamp NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now()),
+    -- the servers rotating the keys at the same time create a single key
+    UNIQUE (algorithm, not_before)
+);
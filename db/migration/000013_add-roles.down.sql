@@ -0,0 +1,6 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+UPDATE users SET role = 'client' WHERE role NOT IN ('administrator', 'client');
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('administrator', 'client'));
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS permissions;
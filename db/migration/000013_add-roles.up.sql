@@ -0,0 +1,46 @@
+-- the permissions checked by the routes, a permission is added along with the code checking it
+CREATE TABLE permissions (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(20) UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE role_permissions (
+    role VARCHAR(20) NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('movies:write', 'Create, update and restore movies'),
+    ('movies:delete', 'Delete movies and merge duplicate movies'),
+    ('actors:write', 'Create, update and restore actors'),
+    ('actors:delete', 'Delete actors and merge duplicate actors'),
+    ('revisions:read', 'Read the revisions of movies and actors'),
+    ('audit:read', 'Read the audit log'),
+    ('cache:read', 'Read the statistics of the cache'),
+    ('users:manage', 'Read users and revoke their tokens'),
+    ('roles:manage', 'Manage roles and assign them to users');
+
+INSERT INTO roles (name, description) VALUES
+    ('administrator', 'Every permission'),
+    ('editor', 'Edits movies and actors, without deleting them'),
+    ('client', 'Reads the catalog');
+
+INSERT INTO role_permissions (role, permission)
+SELECT 'administrator', name FROM permissions;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('editor', 'movies:write'),
+    ('editor', 'actors:write'),
+    ('editor', 'revisions:read');
+
+-- the roles are data now, the users reference them instead of a fixed list
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles (name);
@@ -0,0 +1,4 @@
+-- disabled users cannot log in and their tokens are rejected, until an administrator enables them again
+ALTER TABLE users ADD COLUMN disabled_at timestamp;
+-- users required to reset their password cannot log in until they choose a new one
+ALTER TABLE users ADD COLUMN password_reset_required boolean NOT NULL DEFAULT false;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_tokens;
+
+DROP INDEX IF EXISTS users_email_key;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
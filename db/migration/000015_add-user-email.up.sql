@@ -0,0 +1,19 @@
+-- the email of a user is optional, it is verified before it can be used to reset the password
+ALTER TABLE users ADD COLUMN email VARCHAR(254);
+ALTER TABLE users ADD COLUMN email_verified_at timestamp;
+
+CREATE UNIQUE INDEX users_email_key ON users (lower(email));
+
+-- the single-use tokens sent by email, stored hashed like the refresh tokens
+CREATE TABLE user_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    username VARCHAR(50) NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    -- the address the token was sent to, a verification token does not verify an address changed since
+    email VARCHAR(254) NOT NULL,
+    expires_at timestamp NOT NULL,
+    used_at timestamp,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON user_tokens (username, purpose);
@@ -0,0 +1,21 @@
+-- the recent failed logins of an account ('user:<username>') or of a client IP ('ip:<address>'),
+-- tracked for the unknown usernames too, so that the throttling does not reveal which users exist
+CREATE TABLE login_throttles (
+    key VARCHAR(80) PRIMARY KEY,
+    failures INT NOT NULL,
+    last_failure_at timestamp NOT NULL,
+    locked_until timestamp
+);
+
+-- the security log, kept apart from the audit log of the mutations
+CREATE TABLE security_events (
+    id BIGSERIAL PRIMARY KEY,
+    event VARCHAR(30) NOT NULL,
+    username VARCHAR(50),
+    client_ip VARCHAR(64) NOT NULL,
+    details VARCHAR(255) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON security_events (username);
+CREATE INDEX ON security_events (created_at);
@@ -0,0 +1,10 @@
+-- the token buckets of the rate limiter when it is backed by Postgres, shared by the servers
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key VARCHAR(200) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    -- whether the last request took a token
+    allowed BOOLEAN NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE INDEX ON rate_limit_buckets (updated_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS api_keys;
+
+DELETE FROM permissions WHERE name = 'api_keys:manage';
@@ -0,0 +1,20 @@
+-- the keys of the service-to-service clients, stored hashed like the refresh tokens, with the prefix shown to tell them apart
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    prefix VARCHAR(20) UNIQUE NOT NULL,
+    key_hash BYTEA UNIQUE NOT NULL,
+    -- the permissions granted to the key, in place of a role
+    scopes VARCHAR(50)[] NOT NULL,
+    created_by VARCHAR(50) NOT NULL,
+    expires_at timestamp,
+    last_used_at timestamp,
+    revoked_at timestamp,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('api_keys:manage', 'Issue and revoke API keys');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('administrator', 'api_keys:manage');
@@ -0,0 +1,26 @@
+-- the accounts of the users at the OpenID Connect providers, a subject is unique within its issuer
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    username VARCHAR(50) NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    email VARCHAR(254),
+    last_login_at timestamp,
+    created_at timestamp NOT NULL DEFAULT (now()),
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX ON user_identities (username);
+
+-- the logins started with a provider, until the browser comes back with the authorization code,
+-- stored by the hash of their state; the username is set when the login links an identity to the user
+CREATE TABLE oidc_logins (
+    state_hash BYTEA PRIMARY KEY,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    username VARCHAR(50) REFERENCES users (username) ON DELETE CASCADE,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON oidc_logins (expires_at);
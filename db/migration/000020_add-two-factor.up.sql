@@ -0,0 +1,30 @@
+-- the TOTP secrets of the users (RFC 6238), enabled once confirmed by a first code;
+-- last_step is the time step of the last code accepted, so that every code is accepted once
+CREATE TABLE user_totp_secrets (
+    username VARCHAR(50) PRIMARY KEY REFERENCES users (username) ON DELETE CASCADE,
+    secret BYTEA NOT NULL,
+    confirmed_at timestamp,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+-- the single-use codes replacing the TOTP codes of a user who lost the authenticator, stored by their hash,
+-- they go with the secret they were issued for
+CREATE TABLE recovery_codes (
+    code_hash BYTEA PRIMARY KEY,
+    username VARCHAR(50) NOT NULL REFERENCES user_totp_secrets (username) ON DELETE CASCADE,
+    used_at timestamp,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON recovery_codes (username);
+
+-- the logins whose password was checked, waiting for the second factor, stored by the hash of their challenge
+CREATE TABLE login_challenges (
+    challenge_hash BYTEA PRIMARY KEY,
+    username VARCHAR(50) NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON login_challenges (expires_at);
@@ -0,0 +1,4 @@
+-- TRUNCATE does not fire the DELETE triggers, the audit log must refuse it too
+CREATE TRIGGER audit_log_no_truncate
+BEFORE TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE PROCEDURE audit_log_append_only();
@@ -0,0 +1,27 @@
+DROP TRIGGER IF EXISTS movie_actors_truncate_bump_catalog_version ON movie_actors;
+DROP TRIGGER IF EXISTS actors_truncate_bump_catalog_version ON actors;
+DROP TRIGGER IF EXISTS movies_truncate_bump_catalog_version ON movies;
+DROP TRIGGER IF EXISTS movie_actors_bump_catalog_version ON movie_actors;
+DROP TRIGGER IF EXISTS actors_bump_catalog_version ON actors;
+DROP TRIGGER IF EXISTS movies_bump_catalog_version ON movies;
+
+CREATE OR REPLACE FUNCTION bump_catalog_version() RETURNS trigger AS $$
+BEGIN
+    UPDATE catalog_state SET version = version + 1, updated_at = now();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER movies_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON movies
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE TRIGGER actors_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON actors
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE TRIGGER movie_actors_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON movie_actors
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
+
+ALTER TABLE catalog_state DROP COLUMN bumped_by;
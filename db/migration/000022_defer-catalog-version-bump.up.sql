@@ -0,0 +1,47 @@
+-- the statement triggers locked the catalog_state row from the first write of a transaction until its commit,
+-- serializing every write to the catalog. The deferred row triggers only lock it while committing, and the
+-- transaction bumping the version records its ID so that its other rows leave the version alone.
+ALTER TABLE catalog_state ADD COLUMN bumped_by BIGINT NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION bump_catalog_version() RETURNS trigger AS $$
+BEGIN
+    UPDATE catalog_state
+    SET version = version + 1,
+        updated_at = clock_timestamp(),
+        bumped_by = txid_current()
+    WHERE bumped_by <> txid_current();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER movies_bump_catalog_version ON movies;
+DROP TRIGGER actors_bump_catalog_version ON actors;
+DROP TRIGGER movie_actors_bump_catalog_version ON movie_actors;
+
+CREATE CONSTRAINT TRIGGER movies_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE ON movies
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE CONSTRAINT TRIGGER actors_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE ON actors
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE CONSTRAINT TRIGGER movie_actors_bump_catalog_version
+AFTER INSERT OR UPDATE OR DELETE ON movie_actors
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE PROCEDURE bump_catalog_version();
+
+-- the constraint triggers cannot fire on TRUNCATE, which locks the whole table anyway
+CREATE TRIGGER movies_truncate_bump_catalog_version
+AFTER TRUNCATE ON movies
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE TRIGGER actors_truncate_bump_catalog_version
+AFTER TRUNCATE ON actors
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
+
+CREATE TRIGGER movie_actors_truncate_bump_catalog_version
+AFTER TRUNCATE ON movie_actors
+FOR EACH STATEMENT EXECUTE PROCEDURE bump_catalog_version();
@@ -0,0 +1,70 @@
+ALTER TABLE users
+    ALTER COLUMN password_changed_at TYPE timestamp USING password_changed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN tokens_valid_after TYPE timestamp USING tokens_valid_after AT TIME ZONE 'UTC',
+    ALTER COLUMN disabled_at TYPE timestamp USING disabled_at AT TIME ZONE 'UTC',
+    ALTER COLUMN email_verified_at TYPE timestamp USING email_verified_at AT TIME ZONE 'UTC';
+ALTER TABLE actor_redirects
+    ALTER COLUMN merged_at TYPE timestamp USING merged_at AT TIME ZONE 'UTC';
+ALTER TABLE movie_redirects
+    ALTER COLUMN merged_at TYPE timestamp USING merged_at AT TIME ZONE 'UTC';
+ALTER TABLE audit_log
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE movie_revisions
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE actor_revisions
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE catalog_state
+    ALTER COLUMN updated_at TYPE timestamp USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE sessions
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE timestamp USING last_used_at AT TIME ZONE 'UTC';
+ALTER TABLE refresh_tokens
+    ALTER COLUMN rotated_at TYPE timestamp USING rotated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE revoked_tokens
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE timestamp USING revoked_at AT TIME ZONE 'UTC';
+ALTER TABLE signing_keys
+    ALTER COLUMN not_before TYPE timestamp USING not_before AT TIME ZONE 'UTC',
+    ALTER COLUMN not_after TYPE timestamp USING not_after AT TIME ZONE 'UTC',
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE roles
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE user_tokens
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN used_at TYPE timestamp USING used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE login_throttles
+    ALTER COLUMN last_failure_at TYPE timestamp USING last_failure_at AT TIME ZONE 'UTC',
+    ALTER COLUMN locked_until TYPE timestamp USING locked_until AT TIME ZONE 'UTC';
+ALTER TABLE security_events
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE rate_limit_buckets
+    ALTER COLUMN updated_at TYPE timestamp USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE api_keys
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE timestamp USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE timestamp USING revoked_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE user_identities
+    ALTER COLUMN last_login_at TYPE timestamp USING last_login_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE oidc_logins
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE user_totp_secrets
+    ALTER COLUMN confirmed_at TYPE timestamp USING confirmed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE recovery_codes
+    ALTER COLUMN used_at TYPE timestamp USING used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE login_challenges
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE users
+    ALTER COLUMN password_changed_at SET DEFAULT '0001-01-01 00:00:00Z',
+    ALTER COLUMN tokens_valid_after SET DEFAULT '0001-01-01 00:00:00Z';
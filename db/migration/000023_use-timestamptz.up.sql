@@ -0,0 +1,72 @@
+-- the columns without a time zone were compared with now(), which is in the time zone of the session, and the
+-- values written by the application, which are in UTC. The stored values are taken as UTC.
+ALTER TABLE users
+    ALTER COLUMN password_changed_at TYPE timestamptz USING password_changed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN tokens_valid_after TYPE timestamptz USING tokens_valid_after AT TIME ZONE 'UTC',
+    ALTER COLUMN disabled_at TYPE timestamptz USING disabled_at AT TIME ZONE 'UTC',
+    ALTER COLUMN email_verified_at TYPE timestamptz USING email_verified_at AT TIME ZONE 'UTC';
+ALTER TABLE actor_redirects
+    ALTER COLUMN merged_at TYPE timestamptz USING merged_at AT TIME ZONE 'UTC';
+ALTER TABLE movie_redirects
+    ALTER COLUMN merged_at TYPE timestamptz USING merged_at AT TIME ZONE 'UTC';
+ALTER TABLE audit_log
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE movie_revisions
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE actor_revisions
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE catalog_state
+    ALTER COLUMN updated_at TYPE timestamptz USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE sessions
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE timestamptz USING last_used_at AT TIME ZONE 'UTC';
+ALTER TABLE refresh_tokens
+    ALTER COLUMN rotated_at TYPE timestamptz USING rotated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE revoked_tokens
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE timestamptz USING revoked_at AT TIME ZONE 'UTC';
+ALTER TABLE signing_keys
+    ALTER COLUMN not_before TYPE timestamptz USING not_before AT TIME ZONE 'UTC',
+    ALTER COLUMN not_after TYPE timestamptz USING not_after AT TIME ZONE 'UTC',
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE roles
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE user_tokens
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN used_at TYPE timestamptz USING used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE login_throttles
+    ALTER COLUMN last_failure_at TYPE timestamptz USING last_failure_at AT TIME ZONE 'UTC',
+    ALTER COLUMN locked_until TYPE timestamptz USING locked_until AT TIME ZONE 'UTC';
+ALTER TABLE security_events
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE rate_limit_buckets
+    ALTER COLUMN updated_at TYPE timestamptz USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE api_keys
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE timestamptz USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE timestamptz USING revoked_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE user_identities
+    ALTER COLUMN last_login_at TYPE timestamptz USING last_login_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE oidc_logins
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE user_totp_secrets
+    ALTER COLUMN confirmed_at TYPE timestamptz USING confirmed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE recovery_codes
+    ALTER COLUMN used_at TYPE timestamptz USING used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE login_challenges
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE users
+    ALTER COLUMN password_changed_at SET DEFAULT '0001-01-01 00:00:00Z',
+    ALTER COLUMN tokens_valid_after SET DEFAULT '0001-01-01 00:00:00Z';
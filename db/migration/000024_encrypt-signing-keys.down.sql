@@ -0,0 +1,2 @@
+-- the encrypted keys cannot be read without the encryption key, the next sync creates a new one
+DELETE FROM signing_keys;
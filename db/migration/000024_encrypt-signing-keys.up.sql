@@ -0,0 +1,3 @@
+-- the private keys are stored encrypted from now on, the keys stored in clear are dropped and the next sync creates a new one.
+-- The access tokens they signed are rejected, the clients renew them with their refresh token.
+DELETE FROM signing_keys;
@@ -0,0 +1,3 @@
+ALTER TABLE login_throttles
+    DROP COLUMN reserved_at,
+    DROP COLUMN pending;
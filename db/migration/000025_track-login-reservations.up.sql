@@ -0,0 +1,5 @@
+-- the logins reserved while their password is checked, counted apart from the failures, so that a successful login
+-- does not move the window of the failures forward
+ALTER TABLE login_throttles
+    ADD COLUMN pending INT NOT NULL DEFAULT 0,
+    ADD COLUMN reserved_at timestamptz NOT NULL DEFAULT (now());
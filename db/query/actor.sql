@@ -8,15 +8,18 @@ INSERT INTO actors (
 
 -- name: UpdateActor :one
 UPDATE actors
-SET name = $2,
-  gender = $3,
-  birthday = $4
-WHERE id = $1
+SET name = COALESCE(sqlc.narg(name), name),
+  gender = COALESCE(sqlc.narg(gender), gender),
+  birthday = COALESCE(sqlc.narg(birthday), birthday),
+  version = version + 1
+WHERE id = sqlc.arg(id)
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version))
 RETURNING *;
 
--- name: DeleteActor :exec
+-- name: DeleteActor :execrows
 DELETE FROM actors
-WHERE id = $1;
+WHERE id = sqlc.arg(id)
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version));
 
 -- name: GetActorMoviesList :many
 SELECT
@@ -32,3 +35,71 @@ LEFT JOIN
     movies m ON ma.movie_id = m.id
 ORDER BY
     a.id, m.id;
+
+-- name: GetActor :one
+SELECT * FROM actors
+WHERE id = COALESCE(
+  (SELECT r.actor_id FROM actor_redirects r WHERE r.old_id = sqlc.arg(id)),
+  sqlc.arg(id)
+)
+LIMIT 1;
+
+-- name: ListActorsByBirthday :many
+SELECT * FROM actors
+WHERE birthday = $1
+ORDER BY id;
+
+-- name: ReassignActorMovies :exec
+INSERT INTO movie_actors (movie_id, actor_id)
+SELECT ma.movie_id, sqlc.arg(survivor_id)::int
+FROM movie_actors ma
+WHERE ma.actor_id = sqlc.arg(duplicate_id)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteActorMovies :exec
+DELETE FROM movie_actors
+WHERE actor_id = $1;
+
+-- name: RepointActorRedirects :exec
+UPDATE actor_redirects
+SET actor_id = sqlc.arg(survivor_id)
+WHERE actor_id = sqlc.arg(duplicate_id);
+
+-- name: CreateActorRedirect :exec
+INSERT INTO actor_redirects (
+  old_id,
+  actor_id
+) VALUES
+  ($1, $2);
+
+-- name: ListActors :many
+SELECT * FROM actors
+WHERE (sqlc.narg(name)::text IS NULL OR name ILIKE '%' || sqlc.narg(name) || '%')
+  AND (sqlc.narg(gender)::text IS NULL OR gender = sqlc.narg(gender))
+  AND (sqlc.narg(born_after)::date IS NULL OR birthday >= sqlc.narg(born_after))
+  AND (sqlc.narg(born_before)::date IS NULL OR birthday <= sqlc.narg(born_before))
+ORDER BY name, id
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListActorFilmography :many
+SELECT ma.actor_id, m.id, m.name, m.description, m.release_date, m.rating, m.version
+FROM movie_actors ma
+JOIN movies m ON m.id = ma.movie_id
+WHERE ma.actor_id = ANY(sqlc.arg(actor_ids)::int[])
+ORDER BY ma.actor_id, m.release_date DESC, m.id;
+
+-- name: LockActors :many
+SELECT * FROM actors
+WHERE id = ANY(sqlc.arg(ids)::int[])
+ORDER BY id
+FOR UPDATE;
+
+-- name: LockActor :one
+SELECT * FROM actors
+WHERE id = COALESCE(
+  (SELECT r.actor_id FROM actor_redirects r WHERE r.old_id = sqlc.arg(id)),
+  sqlc.arg(id)
+)
+LIMIT 1
+FOR UPDATE;
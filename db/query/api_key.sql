@@ -0,0 +1,58 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+  name,
+  prefix,
+  key_hash,
+  scopes,
+  created_by,
+  expires_at
+) VALUES
+  ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetUsableAPIKey :one
+-- issuers walks from the key up to the keys that issued it, named "apikey:<name>" in created_by, and ends at its user.
+-- The key is usable while none of them is revoked or expired and that user is not disabled, it grants no more than the
+-- role of that user.
+WITH RECURSIVE issuers AS (
+  SELECT a.created_by, a.revoked_at, a.expires_at, 0 AS depth
+  FROM api_keys a
+  WHERE a.key_hash = sqlc.arg(key_hash)
+  UNION
+  SELECT p.created_by, p.revoked_at, p.expires_at, i.depth + 1
+  FROM issuers i
+  JOIN api_keys p ON 'apikey:' || p.name = i.created_by
+)
+SELECT k.*, u.role AS issuer_role
+FROM api_keys k
+JOIN issuers i ON i.depth = (SELECT max(depth) FROM issuers)
+JOIN users u ON u.username = i.created_by
+WHERE k.key_hash = sqlc.arg(key_hash)
+  AND u.disabled_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM issuers
+    WHERE revoked_at IS NOT NULL
+       OR (expires_at IS NOT NULL AND expires_at <= sqlc.arg(now))
+  )
+LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+ORDER BY id;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = $2
+WHERE id = $1
+  AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = sqlc.arg(used_at)
+WHERE id = sqlc.arg(id)
+  AND (last_used_at IS NULL OR last_used_at < sqlc.arg(stale_before));
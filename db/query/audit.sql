@@ -0,0 +1,22 @@
+-- name: CreateAuditEntry :one
+INSERT INTO audit_log (
+  username,
+  action,
+  entity,
+  entity_id,
+  diff,
+  request_id,
+  client_ip
+) VALUES
+  ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: ListAuditEntries :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg(entity)::text IS NULL OR entity = sqlc.narg(entity))
+  AND (sqlc.narg(entity_id)::int IS NULL OR entity_id = sqlc.narg(entity_id))
+  AND (sqlc.narg(username)::text IS NULL OR username = sqlc.narg(username))
+  AND (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
@@ -0,0 +1,3 @@
+-- name: GetCatalogState :one
+SELECT * FROM catalog_state
+LIMIT 1;
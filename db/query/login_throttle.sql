@@ -0,0 +1,74 @@
+-- name: ListLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE key = ANY(sqlc.arg(keys)::varchar[]);
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (
+  key,
+  failures,
+  last_failure_at
+) VALUES
+  (sqlc.arg(key), 1, sqlc.arg(failed_at))
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+      WHEN login_throttles.last_failure_at < sqlc.arg(window_start) THEN 1
+      ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = sqlc.arg(failed_at),
+    pending = GREATEST(login_throttles.pending - 1, 0)
+RETURNING *;
+
+-- name: ReserveLoginAttempt :one
+INSERT INTO login_throttles (
+  key,
+  failures,
+  last_failure_at,
+  pending,
+  reserved_at
+) VALUES
+  (sqlc.arg(key), 0, sqlc.arg(reserved_at), 1, sqlc.arg(reserved_at))
+ON CONFLICT (key) DO UPDATE
+SET pending = CASE
+      WHEN login_throttles.reserved_at < sqlc.arg(window_start) THEN 1
+      ELSE login_throttles.pending + 1
+    END,
+    reserved_at = sqlc.arg(reserved_at)
+RETURNING *;
+
+-- name: ReleaseLoginAttempts :exec
+UPDATE login_throttles
+SET pending = GREATEST(pending - 1, 0)
+WHERE key = ANY(sqlc.arg(keys)::varchar[]);
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = $2,
+    failures = 0
+WHERE key = $1;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
+
+-- name: DeleteStaleLoginThrottles :exec
+DELETE FROM login_throttles
+WHERE last_failure_at < $1
+  AND reserved_at < $1
+  AND (locked_until IS NULL OR locked_until < $1);
+
+-- name: CreateSecurityEvent :exec
+INSERT INTO security_events (
+  event,
+  username,
+  client_ip,
+  details
+) VALUES
+  ($1, $2, $3, $4);
+
+-- name: ListSecurityEvents :many
+SELECT * FROM security_events
+WHERE (sqlc.narg(username)::varchar IS NULL OR username = sqlc.narg(username))
+  AND (sqlc.narg(event)::varchar IS NULL OR event = sqlc.narg(event))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
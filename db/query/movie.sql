@@ -9,16 +9,19 @@ INSERT INTO movies (
 
 -- name: UpdateMovie :one
 UPDATE movies
-SET name = $2,
-  description = $3,
-  rating = $4,
-  release_date = $5
-WHERE id = $1
+SET name = COALESCE(sqlc.narg(name), name),
+  description = COALESCE(sqlc.narg(description), description),
+  rating = COALESCE(sqlc.narg(rating), rating),
+  release_date = COALESCE(sqlc.narg(release_date), release_date),
+  version = version + 1
+WHERE id = sqlc.arg(id)
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version))
 RETURNING *;
 
--- name: DeleteMovie :exec
+-- name: DeleteMovie :execrows
 DELETE FROM movies
-WHERE id = $1;
+WHERE id = sqlc.arg(id)
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version));
 
 -- name: GetMoviesSortedByRating :many
 SELECT *
@@ -46,3 +49,75 @@ FROM movies m
 JOIN movie_actors ma ON m.id = ma.movie_id
 JOIN actors a ON ma.actor_id = a.id
 WHERE a.name LIKE '%' || $1 || '%';
+
+-- name: GetMovie :one
+SELECT * FROM movies
+WHERE id = COALESCE(
+  (SELECT r.movie_id FROM movie_redirects r WHERE r.old_id = sqlc.arg(id)),
+  sqlc.arg(id)
+)
+LIMIT 1;
+
+-- name: ListMoviesByReleaseYear :many
+SELECT * FROM movies
+WHERE EXTRACT(YEAR FROM release_date) = EXTRACT(YEAR FROM sqlc.arg(release_date)::date)
+ORDER BY id;
+
+-- name: ReassignMovieActors :exec
+INSERT INTO movie_actors (movie_id, actor_id)
+SELECT sqlc.arg(survivor_id)::int, ma.actor_id
+FROM movie_actors ma
+WHERE ma.movie_id = sqlc.arg(duplicate_id)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteMovieActors :exec
+DELETE FROM movie_actors
+WHERE movie_id = $1;
+
+-- name: RepointMovieRedirects :exec
+UPDATE movie_redirects
+SET movie_id = sqlc.arg(survivor_id)
+WHERE movie_id = sqlc.arg(duplicate_id);
+
+-- name: CreateMovieRedirect :exec
+INSERT INTO movie_redirects (
+  old_id,
+  movie_id
+) VALUES
+  ($1, $2);
+
+-- name: ListMovies :many
+SELECT * FROM movies
+WHERE (sqlc.narg(name)::text IS NULL OR name ILIKE '%' || sqlc.narg(name) || '%')
+  AND (sqlc.narg(released_after)::date IS NULL OR release_date >= sqlc.narg(released_after))
+  AND (sqlc.narg(released_before)::date IS NULL OR release_date <= sqlc.narg(released_before))
+  AND (sqlc.narg(min_rating)::numeric IS NULL OR rating >= sqlc.narg(min_rating))
+ORDER BY
+  CASE WHEN sqlc.arg(sort_by)::text = 'rating' THEN rating END DESC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'name' THEN name END,
+  CASE WHEN sqlc.arg(sort_by)::text = 'release_date' THEN release_date END DESC,
+  id
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListMovieCast :many
+SELECT ma.movie_id, a.id, a.name, a.gender, a.birthday, a.version
+FROM movie_actors ma
+JOIN actors a ON a.id = ma.actor_id
+WHERE ma.movie_id = ANY(sqlc.arg(movie_ids)::int[])
+ORDER BY ma.movie_id, a.name, a.id;
+
+-- name: LockMovies :many
+SELECT * FROM movies
+WHERE id = ANY(sqlc.arg(ids)::int[])
+ORDER BY id
+FOR UPDATE;
+
+-- name: LockMovie :one
+SELECT * FROM movies
+WHERE id = COALESCE(
+  (SELECT r.movie_id FROM movie_redirects r WHERE r.old_id = sqlc.arg(id)),
+  sqlc.arg(id)
+)
+LIMIT 1
+FOR UPDATE;
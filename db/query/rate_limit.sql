@@ -0,0 +1,21 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (
+  key,
+  tokens,
+  allowed,
+  updated_at
+) VALUES
+  (sqlc.arg(key), sqlc.arg(capacity)::float8 - 1, true, sqlc.arg(now)::timestamptz)
+ON CONFLICT (key) DO UPDATE
+SET tokens = CASE
+      WHEN LEAST(sqlc.arg(capacity)::float8, rate_limit_buckets.tokens + GREATEST(EXTRACT(EPOCH FROM sqlc.arg(now)::timestamptz - rate_limit_buckets.updated_at), 0) * sqlc.arg(rate)::float8) >= 1
+      THEN LEAST(sqlc.arg(capacity)::float8, rate_limit_buckets.tokens + GREATEST(EXTRACT(EPOCH FROM sqlc.arg(now)::timestamptz - rate_limit_buckets.updated_at), 0) * sqlc.arg(rate)::float8) - 1
+      ELSE LEAST(sqlc.arg(capacity)::float8, rate_limit_buckets.tokens + GREATEST(EXTRACT(EPOCH FROM sqlc.arg(now)::timestamptz - rate_limit_buckets.updated_at), 0) * sqlc.arg(rate)::float8)
+    END,
+    allowed = LEAST(sqlc.arg(capacity)::float8, rate_limit_buckets.tokens + GREATEST(EXTRACT(EPOCH FROM sqlc.arg(now)::timestamptz - rate_limit_buckets.updated_at), 0) * sqlc.arg(rate)::float8) >= 1,
+    updated_at = sqlc.arg(now)::timestamptz
+RETURNING *;
+
+-- name: DeleteIdleRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
@@ -0,0 +1,45 @@
+-- name: CreateMovieRevision :one
+INSERT INTO movie_revisions (
+  movie_id,
+  revision,
+  data,
+  username
+) VALUES (
+  sqlc.arg(movie_id),
+  (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM movie_revisions r WHERE r.movie_id = sqlc.arg(movie_id)),
+  sqlc.arg(data),
+  sqlc.arg(username)
+) RETURNING *;
+
+-- name: ListMovieRevisions :many
+SELECT * FROM movie_revisions
+WHERE movie_id = $1
+ORDER BY revision DESC;
+
+-- name: GetMovieRevision :one
+SELECT * FROM movie_revisions
+WHERE movie_id = $1 AND revision = $2
+LIMIT 1;
+
+-- name: CreateActorRevision :one
+INSERT INTO actor_revisions (
+  actor_id,
+  revision,
+  data,
+  username
+) VALUES (
+  sqlc.arg(actor_id),
+  (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM actor_revisions r WHERE r.actor_id = sqlc.arg(actor_id)),
+  sqlc.arg(data),
+  sqlc.arg(username)
+) RETURNING *;
+
+-- name: ListActorRevisions :many
+SELECT * FROM actor_revisions
+WHERE actor_id = $1
+ORDER BY revision DESC;
+
+-- name: GetActorRevision :one
+SELECT * FROM actor_revisions
+WHERE actor_id = $1 AND revision = $2
+LIMIT 1;
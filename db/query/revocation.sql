@@ -0,0 +1,26 @@
+-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens (
+  id,
+  username,
+  expires_at
+) VALUES
+  ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: ListRevokedTokens :many
+SELECT * FROM revoked_tokens
+WHERE expires_at > now();
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expires_at <= now();
+
+-- name: GetUserTokenCutoff :one
+SELECT GREATEST(password_changed_at, tokens_valid_after)::timestamptz AS cutoff, (disabled_at IS NOT NULL)::boolean AS disabled FROM users
+WHERE username = $1
+LIMIT 1;
+
+-- name: RevokeUserTokens :execrows
+UPDATE users
+SET tokens_valid_after = $2
+WHERE username = $1;
@@ -0,0 +1,41 @@
+-- name: CreateRole :one
+INSERT INTO roles (
+  name,
+  description
+) VALUES
+  ($1, $2) RETURNING *;
+
+-- name: GetRole :one
+SELECT * FROM roles
+WHERE name = $1
+LIMIT 1;
+
+-- name: ListRoles :many
+SELECT * FROM roles
+ORDER BY name;
+
+-- name: UpdateRole :one
+UPDATE roles
+SET description = $2
+WHERE name = $1
+RETURNING *;
+
+-- name: DeleteRole :execrows
+DELETE FROM roles
+WHERE name = $1;
+
+-- name: ListPermissions :many
+SELECT * FROM permissions
+ORDER BY name;
+
+-- name: ListRolePermissions :many
+SELECT * FROM role_permissions
+ORDER BY role, permission;
+
+-- name: AddRolePermissions :exec
+INSERT INTO role_permissions (role, permission)
+SELECT sqlc.arg(role), unnest(sqlc.arg(permissions)::varchar[]);
+
+-- name: DeleteRolePermissions :exec
+DELETE FROM role_permissions
+WHERE role = $1;
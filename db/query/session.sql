@@ -0,0 +1,57 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+  id,
+  username,
+  user_agent,
+  client_ip,
+  expires_at
+) VALUES
+  ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE username = $1
+  AND NOT is_revoked
+  AND expires_at > now()
+ORDER BY last_used_at DESC;
+
+-- name: TouchSession :one
+UPDATE sessions
+SET user_agent = $2,
+  client_ip = $3,
+  last_used_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: RevokeSession :one
+UPDATE sessions
+SET is_revoked = true
+WHERE id = $1 AND username = $2
+RETURNING *;
+
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens (
+  token_hash,
+  session_id
+) VALUES
+  ($1, $2);
+
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1
+LIMIT 1;
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET rotated_at = now()
+WHERE token_hash = $1 AND rotated_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET is_revoked = true
+WHERE username = $1 AND NOT is_revoked;
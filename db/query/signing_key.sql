@@ -0,0 +1,24 @@
+-- name: CreateSigningKey :exec
+INSERT INTO signing_keys (
+  kid,
+  algorithm,
+  private_key,
+  not_before,
+  not_after,
+  expires_at
+) VALUES
+  ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (algorithm, not_before) DO NOTHING;
+
+-- name: LockSigningKeys :exec
+-- held until the end of the transaction by the server creating the next key of the algorithm
+SELECT pg_advisory_xact_lock(hashtext('signing_keys:' || sqlc.arg(algorithm)::text));
+
+-- name: ListSigningKeys :many
+SELECT * FROM signing_keys
+WHERE algorithm = $1 AND expires_at > now()
+ORDER BY not_before;
+
+-- name: DeleteExpiredSigningKeys :exec
+DELETE FROM signing_keys
+WHERE expires_at <= now();
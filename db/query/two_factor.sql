@@ -0,0 +1,81 @@
+-- name: CreateTOTPSecret :one
+INSERT INTO user_totp_secrets (
+  username,
+  secret
+) VALUES
+  ($1, $2)
+ON CONFLICT (username) DO UPDATE
+SET secret = EXCLUDED.secret,
+    last_step = 0,
+    created_at = now()
+WHERE user_totp_secrets.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPSecret :one
+SELECT * FROM user_totp_secrets
+WHERE username = $1
+LIMIT 1;
+
+-- name: ConfirmTOTPSecret :one
+UPDATE user_totp_secrets
+SET confirmed_at = $2,
+    last_step = $3
+WHERE username = $1
+  AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp_secrets
+SET last_step = $2
+WHERE username = $1
+  AND confirmed_at IS NOT NULL
+  AND last_step < $2;
+
+-- name: DeleteTOTPSecret :execrows
+DELETE FROM user_totp_secrets
+WHERE username = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (
+  code_hash,
+  username
+) VALUES
+  ($1, $2);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = $3
+WHERE username = $1
+  AND code_hash = $2
+  AND used_at IS NULL;
+
+-- name: CountRecoveryCodes :one
+SELECT count(*) FROM recovery_codes
+WHERE username = $1
+  AND used_at IS NULL;
+
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges (
+  challenge_hash,
+  username,
+  expires_at
+) VALUES
+  ($1, $2, $3);
+
+-- name: GetLoginChallenge :one
+SELECT * FROM login_challenges
+WHERE challenge_hash = sqlc.arg(challenge_hash)
+  AND expires_at > sqlc.arg(now)
+LIMIT 1;
+
+-- name: DeleteLoginChallenge :execrows
+DELETE FROM login_challenges
+WHERE challenge_hash = $1;
+
+-- name: DeleteExpiredLoginChallenges :exec
+DELETE FROM login_challenges
+WHERE expires_at <= $1;
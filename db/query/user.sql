@@ -1,9 +1,10 @@
 -- name: CreateUser :one
 INSERT INTO users (
   username,
-  hashed_password
+  hashed_password,
+  email
 ) VALUES 
-  ($1, $2) RETURNING *;
+  ($1, $2, $3) RETURNING *;
 
 -- name: GetUser :one
 SELECT * FROM users
@@ -12,3 +13,57 @@ LIMIT 1;
 
 
 
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg(username)::text IS NULL OR username ILIKE '%' || sqlc.narg(username) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role))
+  AND (sqlc.narg(disabled)::boolean IS NULL OR (disabled_at IS NOT NULL) = sqlc.narg(disabled))
+ORDER BY username
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
+-- name: SetUserDisabled :one
+UPDATE users
+SET disabled_at = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: RequirePasswordReset :one
+UPDATE users
+SET password_reset_required = true
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2,
+    password_changed_at = $3,
+    password_reset_required = false
+WHERE username = $1
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE lower(email) = lower(sqlc.arg(email))
+LIMIT 1;
+
+-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2,
+    email_verified_at = NULL
+WHERE username = $1
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = $3
+WHERE username = $1
+  AND email = $2
+RETURNING *;
@@ -0,0 +1,52 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+  issuer,
+  subject,
+  username,
+  email,
+  last_login_at
+) VALUES
+  ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE issuer = $1
+  AND subject = $2
+LIMIT 1;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities
+WHERE username = $1
+ORDER BY id;
+
+-- name: UpdateUserIdentityLogin :one
+UPDATE user_identities
+SET email = $2,
+    last_login_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE id = $1
+  AND username = $2;
+
+-- name: CreateOIDCLogin :exec
+INSERT INTO oidc_logins (
+  state_hash,
+  code_verifier,
+  nonce,
+  username,
+  expires_at
+) VALUES
+  ($1, $2, $3, $4, $5);
+
+-- name: TakeOIDCLogin :one
+DELETE FROM oidc_logins
+WHERE state_hash = sqlc.arg(state_hash)
+  AND expires_at > sqlc.arg(now)
+RETURNING *;
+
+-- name: DeleteExpiredOIDCLogins :exec
+DELETE FROM oidc_logins
+WHERE expires_at <= $1;
@@ -0,0 +1,24 @@
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (
+  token_hash,
+  username,
+  purpose,
+  email,
+  expires_at
+) VALUES
+  ($1, $2, $3, $4, $5);
+
+-- name: UseUserToken :one
+UPDATE user_tokens
+SET used_at = sqlc.arg(used_at)
+WHERE token_hash = sqlc.arg(token_hash)
+  AND purpose = sqlc.arg(purpose)
+  AND used_at IS NULL
+  AND expires_at > sqlc.arg(used_at)
+RETURNING *;
+
+-- name: DeleteUserTokens :exec
+DELETE FROM user_tokens
+WHERE username = $1
+  AND purpose = $2
+  AND used_at IS NULL;
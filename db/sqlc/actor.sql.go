@@ -9,6 +9,8 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const createActor = `-- name: CreateActor :one
@@ -17,7 +19,7 @@ INSERT INTO actors (
   gender,
   birthday
 ) VALUES 
-  ($1, $2, $3) RETURNING id, name, gender, birthday
+  ($1, $2, $3) RETURNING id, name, gender, birthday, version
 `
 
 type CreateActorParams struct {
@@ -34,20 +36,80 @@ func (q *Queries) CreateActor(ctx context.Context, arg CreateActorParams) (Actor
 		&i.Name,
 		&i.Gender,
 		&i.Birthday,
+		&i.Version,
 	)
 	return i, err
 }
 
-const deleteActor = `-- name: DeleteActor :exec
+const createActorRedirect = `-- name: CreateActorRedirect :exec
+INSERT INTO actor_redirects (
+  old_id,
+  actor_id
+) VALUES
+  ($1, $2)
+`
+
+type CreateActorRedirectParams struct {
+	OldID   int32 `json:"old_id"`
+	ActorID int32 `json:"actor_id"`
+}
+
+func (q *Queries) CreateActorRedirect(ctx context.Context, arg CreateActorRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, createActorRedirect, arg.OldID, arg.ActorID)
+	return err
+}
+
+const deleteActor = `-- name: DeleteActor :execrows
 DELETE FROM actors
 WHERE id = $1
+  AND ($2::int IS NULL OR version = $2)
+`
+
+type DeleteActorParams struct {
+	ID              int32         `json:"id"`
+	ExpectedVersion sql.NullInt32 `json:"expected_version"`
+}
+
+func (q *Queries) DeleteActor(ctx context.Context, arg DeleteActorParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteActor, arg.ID, arg.ExpectedVersion)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteActorMovies = `-- name: DeleteActorMovies :exec
+DELETE FROM movie_actors
+WHERE actor_id = $1
 `
 
-func (q *Queries) DeleteActor(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteActor, id)
+func (q *Queries) DeleteActorMovies(ctx context.Context, actorID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteActorMovies, actorID)
 	return err
 }
 
+const getActor = `-- name: GetActor :one
+SELECT id, name, gender, birthday, version FROM actors
+WHERE id = COALESCE(
+  (SELECT r.actor_id FROM actor_redirects r WHERE r.old_id = $1),
+  $1
+)
+LIMIT 1
+`
+
+func (q *Queries) GetActor(ctx context.Context, id int32) (Actor, error) {
+	row := q.db.QueryRowContext(ctx, getActor, id)
+	var i Actor
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Gender,
+		&i.Birthday,
+		&i.Version,
+	)
+	return i, err
+}
+
 const getActorMoviesList = `-- name: GetActorMoviesList :many
 SELECT
     a.id AS actor_id,
@@ -99,28 +161,265 @@ func (q *Queries) GetActorMoviesList(ctx context.Context) ([]GetActorMoviesListR
 	return items, nil
 }
 
+const listActorFilmography = `-- name: ListActorFilmography :many
+SELECT ma.actor_id, m.id, m.name, m.description, m.release_date, m.rating, m.version
+FROM movie_actors ma
+JOIN movies m ON m.id = ma.movie_id
+WHERE ma.actor_id = ANY($1::int[])
+ORDER BY ma.actor_id, m.release_date DESC, m.id
+`
+
+type ListActorFilmographyRow struct {
+	ActorID     int32     `json:"actor_id"`
+	ID          int32     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ReleaseDate time.Time `json:"release_date"`
+	Rating      string    `json:"rating"`
+	Version     int32     `json:"version"`
+}
+
+func (q *Queries) ListActorFilmography(ctx context.Context, actorIds []int32) ([]ListActorFilmographyRow, error) {
+	rows, err := q.db.QueryContext(ctx, listActorFilmography, pq.Array(actorIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListActorFilmographyRow{}
+	for rows.Next() {
+		var i ListActorFilmographyRow
+		if err := rows.Scan(
+			&i.ActorID,
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.ReleaseDate,
+			&i.Rating,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActors = `-- name: ListActors :many
+SELECT id, name, gender, birthday, version FROM actors
+WHERE ($1::text IS NULL OR name ILIKE '%' || $1 || '%')
+  AND ($2::text IS NULL OR gender = $2)
+  AND ($3::date IS NULL OR birthday >= $3)
+  AND ($4::date IS NULL OR birthday <= $4)
+ORDER BY name, id
+LIMIT $5
+OFFSET $6
+`
+
+type ListActorsParams struct {
+	Name       sql.NullString `json:"name"`
+	Gender     sql.NullString `json:"gender"`
+	BornAfter  sql.NullTime   `json:"born_after"`
+	BornBefore sql.NullTime   `json:"born_before"`
+	PageLimit  int32          `json:"page_limit"`
+	PageOffset int32          `json:"page_offset"`
+}
+
+func (q *Queries) ListActors(ctx context.Context, arg ListActorsParams) ([]Actor, error) {
+	rows, err := q.db.QueryContext(ctx, listActors,
+		arg.Name,
+		arg.Gender,
+		arg.BornAfter,
+		arg.BornBefore,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Actor{}
+	for rows.Next() {
+		var i Actor
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Gender,
+			&i.Birthday,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActorsByBirthday = `-- name: ListActorsByBirthday :many
+SELECT id, name, gender, birthday, version FROM actors
+WHERE birthday = $1
+ORDER BY id
+`
+
+func (q *Queries) ListActorsByBirthday(ctx context.Context, birthday time.Time) ([]Actor, error) {
+	rows, err := q.db.QueryContext(ctx, listActorsByBirthday, birthday)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Actor{}
+	for rows.Next() {
+		var i Actor
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Gender,
+			&i.Birthday,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockActor = `-- name: LockActor :one
+SELECT id, name, gender, birthday, version FROM actors
+WHERE id = COALESCE(
+  (SELECT r.actor_id FROM actor_redirects r WHERE r.old_id = $1),
+  $1
+)
+LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) LockActor(ctx context.Context, id int32) (Actor, error) {
+	row := q.db.QueryRowContext(ctx, lockActor, id)
+	var i Actor
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Gender,
+		&i.Birthday,
+		&i.Version,
+	)
+	return i, err
+}
+
+const lockActors = `-- name: LockActors :many
+SELECT id, name, gender, birthday, version FROM actors
+WHERE id = ANY($1::int[])
+ORDER BY id
+FOR UPDATE
+`
+
+func (q *Queries) LockActors(ctx context.Context, ids []int32) ([]Actor, error) {
+	rows, err := q.db.QueryContext(ctx, lockActors, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Actor{}
+	for rows.Next() {
+		var i Actor
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Gender,
+			&i.Birthday,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reassignActorMovies = `-- name: ReassignActorMovies :exec
+INSERT INTO movie_actors (movie_id, actor_id)
+SELECT ma.movie_id, $1::int
+FROM movie_actors ma
+WHERE ma.actor_id = $2
+ON CONFLICT DO NOTHING
+`
+
+type ReassignActorMoviesParams struct {
+	SurvivorID  int32 `json:"survivor_id"`
+	DuplicateID int32 `json:"duplicate_id"`
+}
+
+func (q *Queries) ReassignActorMovies(ctx context.Context, arg ReassignActorMoviesParams) error {
+	_, err := q.db.ExecContext(ctx, reassignActorMovies, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
+const repointActorRedirects = `-- name: RepointActorRedirects :exec
+UPDATE actor_redirects
+SET actor_id = $1
+WHERE actor_id = $2
+`
+
+type RepointActorRedirectsParams struct {
+	SurvivorID  int32 `json:"survivor_id"`
+	DuplicateID int32 `json:"duplicate_id"`
+}
+
+func (q *Queries) RepointActorRedirects(ctx context.Context, arg RepointActorRedirectsParams) error {
+	_, err := q.db.ExecContext(ctx, repointActorRedirects, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
 const updateActor = `-- name: UpdateActor :one
 UPDATE actors
-SET name = $2,
-  gender = $3,
-  birthday = $4
-WHERE id = $1
-RETURNING id, name, gender, birthday
+SET name = COALESCE($1, name),
+  gender = COALESCE($2, gender),
+  birthday = COALESCE($3, birthday),
+  version = version + 1
+WHERE id = $4
+  AND ($5::int IS NULL OR version = $5)
+RETURNING id, name, gender, birthday, version
 `
 
 type UpdateActorParams struct {
-	ID       int32     `json:"id"`
-	Name     string    `json:"name"`
-	Gender   string    `json:"gender"`
-	Birthday time.Time `json:"birthday"`
+	Name            sql.NullString `json:"name"`
+	Gender          sql.NullString `json:"gender"`
+	Birthday        sql.NullTime   `json:"birthday"`
+	ID              int32          `json:"id"`
+	ExpectedVersion sql.NullInt32  `json:"expected_version"`
 }
 
 func (q *Queries) UpdateActor(ctx context.Context, arg UpdateActorParams) (Actor, error) {
 	row := q.db.QueryRowContext(ctx, updateActor,
-		arg.ID,
 		arg.Name,
 		arg.Gender,
 		arg.Birthday,
+		arg.ID,
+		arg.ExpectedVersion,
 	)
 	var i Actor
 	err := row.Scan(
@@ -128,6 +427,7 @@ func (q *Queries) UpdateActor(ctx context.Context, arg UpdateActorParams) (Actor
 		&i.Name,
 		&i.Gender,
 		&i.Birthday,
+		&i.Version,
 	)
 	return i, err
 }
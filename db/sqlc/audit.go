@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"vk-film/util"
+)
+
+// The actions recorded by the audit log.
+const (
+	AuditActionCreate         = "create"
+	AuditActionUpdate         = "update"
+	AuditActionDelete         = "delete"
+	AuditActionMerge          = "merge"
+	AuditActionRevoke         = "revoke_tokens"
+	AuditActionAssign         = "assign_role"
+	AuditActionDisable        = "disable"
+	AuditActionEnable         = "enable"
+	AuditActionResetPassword  = "require_password_reset"
+	AuditActionRevokeAPIKey   = "revoke_api_key"
+	AuditActionResetTwoFactor = "reset_two_factor"
+	AuditActionRestore        = "restore"
+)
+
+// The entities recorded by the audit log.
+const (
+	AuditEntityMovie  = "movie"
+	AuditEntityActor  = "actor"
+	AuditEntityUser   = "user"
+	AuditEntityRole   = "role"
+	AuditEntityAPIKey = "api_key"
+)
+
+// RecordAuditParams contains the input parameters of an audit log entry.
+// Before and After are the states of the entity around the mutation, nil when it did not exist.
+type RecordAuditParams struct {
+	Username  string
+	Action    string
+	Entity    string
+	EntityID  int32
+	Before    interface{}
+	After     interface{}
+	RequestID string
+	ClientIP  string
+}
+
+// RecordAudit appends an entry to the audit log with the diff of the states of the entity. The store is the one
+// of the transaction applying the mutation, so that no mutation is committed without its entry.
+func RecordAudit(ctx context.Context, store Store, arg RecordAuditParams) error {
+	diff, err := util.JSONDiff(arg.Before, arg.After)
+	if err != nil {
+		return fmt.Errorf("cannot diff audit entry for %s %d: %w", arg.Entity, arg.EntityID, err)
+	}
+	rawDiff, err := json.Marshal(diff)
+	if err != nil {
+		return fmt.Errorf("cannot encode audit entry for %s %d: %w", arg.Entity, arg.EntityID, err)
+	}
+	_, err = store.CreateAuditEntry(ctx, CreateAuditEntryParams{
+		Username:  arg.Username,
+		Action:    arg.Action,
+		Entity:    arg.Entity,
+		EntityID:  sql.NullInt32{Int32: arg.EntityID, Valid: true},
+		Diff:      rawDiff,
+		RequestID: arg.RequestID,
+		ClientIp:  arg.ClientIP,
+	})
+	return err
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache is a bounded cache evicting the least recently used entries, whose entries also expire after a TTL,
+// unless the TTL is 0.
+// Entries are labelled with tags, so that every entry derived from some data can be invalidated at once.
+type lruCache struct {
+	mu         sync.Mutex
+	size       int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	order      *list.List
+	tags       map[string]map[string]struct{}
+	generation uint64
+	evictions  uint64
+}
+
+type cacheEntry struct {
+	key       string
+	value     interface{}
+	tags      []string
+	expiresAt time.Time
+}
+
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		tags:    make(map[string]map[string]struct{}),
+	}
+}
+
+// get returns the value cached for a key, if it has not expired.
+func (cache *lruCache) get(key string) (interface{}, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		cache.remove(element)
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// currentGeneration returns a counter bumped by every invalidation, to be passed to set.
+func (cache *lruCache) currentGeneration() uint64 {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.generation
+}
+
+// set caches a value loaded while the cache was at the given generation. The value is dropped when an
+// invalidation happened since, because it may have been read before the write that caused it.
+func (cache *lruCache) set(key string, value interface{}, tags []string, generation uint64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if generation != cache.generation {
+		return
+	}
+	if element, ok := cache.entries[key]; ok {
+		cache.remove(element)
+	}
+	entry := &cacheEntry{
+		key:   key,
+		value: value,
+		tags:  tags,
+	}
+	if cache.ttl > 0 {
+		entry.expiresAt = time.Now().Add(cache.ttl)
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	for _, tag := range tags {
+		if cache.tags[tag] == nil {
+			cache.tags[tag] = make(map[string]struct{})
+		}
+		cache.tags[tag][key] = struct{}{}
+	}
+	for cache.order.Len() > cache.size {
+		cache.remove(cache.order.Back())
+		cache.evictions++
+	}
+}
+
+// invalidate removes every entry labelled with one of the tags.
+func (cache *lruCache) invalidate(tags ...string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.generation++
+	for _, tag := range tags {
+		for key := range cache.tags[tag] {
+			if element, ok := cache.entries[key]; ok {
+				cache.remove(element)
+			}
+		}
+	}
+}
+
+// purge removes every entry.
+func (cache *lruCache) purge() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.generation++
+	cache.entries = make(map[string]*list.Element)
+	cache.order.Init()
+	cache.tags = make(map[string]map[string]struct{})
+}
+
+func (cache *lruCache) len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+func (cache *lruCache) evicted() uint64 {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.evictions
+}
+
+// remove must be called with the lock held.
+func (cache *lruCache) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	cache.order.Remove(element)
+	delete(cache.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(cache.tags[tag], entry.key)
+		if len(cache.tags[tag]) == 0 {
+			delete(cache.tags, tag)
+		}
+	}
+}
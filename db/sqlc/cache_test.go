@@ -0,0 +1,95 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newLRUCache(2, 0)
+	cache.set("a", 1, nil, cache.currentGeneration())
+	cache.set("b", 2, nil, cache.currentGeneration())
+
+	// reading a makes b the least recently used entry
+	value, ok := cache.get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	cache.set("c", 3, nil, cache.currentGeneration())
+	require.Equal(t, 2, cache.len())
+	require.Equal(t, uint64(1), cache.evicted())
+	_, ok = cache.get("b")
+	require.False(t, ok)
+	_, ok = cache.get("a")
+	require.True(t, ok)
+	_, ok = cache.get("c")
+	require.True(t, ok)
+
+	// setting a key again replaces its entry
+	cache.set("c", 4, nil, cache.currentGeneration())
+	require.Equal(t, 2, cache.len())
+	value, _ = cache.get("c")
+	require.Equal(t, 4, value)
+}
+
+func TestLRUCacheExpires(t *testing.T) {
+	cache := newLRUCache(10, 10*time.Millisecond)
+	cache.set("a", 1, []string{tagMovies}, cache.currentGeneration())
+	_, ok := cache.get("a")
+	require.True(t, ok)
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok = cache.get("a")
+	require.False(t, ok)
+	require.Zero(t, cache.len())
+	require.Empty(t, cache.tags)
+}
+
+func TestLRUCacheInvalidatesTags(t *testing.T) {
+	cache := newLRUCache(10, 0)
+	generation := cache.currentGeneration()
+	cache.set("GetMovie:1", 1, []string{movieTag(1)}, generation)
+	cache.set("GetMovie:2", 2, []string{movieTag(2)}, generation)
+	cache.set("GetMoviesSortedByName", 3, []string{tagMovies}, generation)
+	cache.set("GetActorMoviesList", 4, []string{tagMovies, tagActors, tagCast}, generation)
+
+	cache.invalidate(tagMovies, movieTag(1))
+	_, ok := cache.get("GetMovie:1")
+	require.False(t, ok)
+	_, ok = cache.get("GetMoviesSortedByName")
+	require.False(t, ok)
+	_, ok = cache.get("GetActorMoviesList")
+	require.False(t, ok)
+	_, ok = cache.get("GetMovie:2")
+	require.True(t, ok)
+
+	// the tags of the removed entries are dropped with them
+	require.Equal(t, map[string]map[string]struct{}{movieTag(2): {"GetMovie:2": {}}}, cache.tags)
+
+	cache.purge()
+	require.Zero(t, cache.len())
+	require.Empty(t, cache.tags)
+}
+
+func TestLRUCacheDropsValuesLoadedBeforeAnInvalidation(t *testing.T) {
+	cache := newLRUCache(10, 0)
+
+	// the value was read before a write invalidated an unrelated tag, it may still predate the write
+	generation := cache.currentGeneration()
+	cache.invalidate(movieTag(42))
+	cache.set("GetMovie:1", 1, []string{movieTag(1)}, generation)
+	_, ok := cache.get("GetMovie:1")
+	require.False(t, ok)
+
+	generation = cache.currentGeneration()
+	cache.purge()
+	cache.set("GetMovie:1", 1, []string{movieTag(1)}, generation)
+	_, ok = cache.get("GetMovie:1")
+	require.False(t, ok)
+
+	cache.set("GetMovie:1", 1, []string{movieTag(1)}, cache.currentGeneration())
+	_, ok = cache.get("GetMovie:1")
+	require.True(t, ok)
+}
@@ -0,0 +1,320 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cache tags, every cached read is labelled with the data it was derived from
+const (
+	tagMovies = "movies"
+	tagActors = "actors"
+	tagCast   = "movie_actors"
+)
+
+func movieTag(id int32) string {
+	return fmt.Sprintf("movie:%d", id)
+}
+
+func actorTag(id int32) string {
+	return fmt.Sprintf("actor:%d", id)
+}
+
+// CacheStats reports the activity of a CachedStore.
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Coalesced uint64 `json:"coalesced"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// CachedStore is a Store decorator caching catalog reads in memory.
+// Writes made through it invalidate the cached reads they affect, and concurrent misses on the same read
+// share a single query. Every cached read checks the catalog version first, which every write to the catalog bumps
+// when it commits, so that the writes made by other processes are seen right away.
+// The reads of a store given by ExecTx are never cached, the writes read their rows there.
+type CachedStore struct {
+	Store
+	cache     *lruCache
+	group     singleflight.Group
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	coalesced atomic.Uint64
+	// the catalog version last read by GetCatalogState
+	catalogVersion atomic.Int64
+	// the tags invalidated by the writes of a store given by ExecTx, applied once its transaction is committed
+	pending *[]string
+}
+
+// NewCachedStore wraps a store with a cache holding at most size reads, each for at most ttl, or until the catalog
+// changes when ttl is 0.
+func NewCachedStore(store Store, size int, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		Store: store,
+		cache: newLRUCache(size, ttl),
+	}
+}
+
+// Stats returns the hit and miss counters of the cache.
+func (store *CachedStore) Stats() CacheStats {
+	return CacheStats{
+		Hits:      store.hits.Load(),
+		Misses:    store.misses.Load(),
+		Coalesced: store.coalesced.Load(),
+		Evictions: store.cache.evicted(),
+		Entries:   store.cache.len(),
+	}
+}
+
+// ExecTx executes a function within a database transaction, with a store invalidating the cached reads affected
+// by its writes only once they are committed. Its reads go to the database, they may see the uncommitted writes.
+func (store *CachedStore) ExecTx(ctx context.Context, fn func(Store) error) error {
+	if store.pending != nil {
+		return fn(store)
+	}
+	var pending []string
+	err := store.Store.ExecTx(ctx, func(tx Store) error {
+		return fn(&CachedStore{Store: tx, cache: store.cache, pending: &pending})
+	})
+	if err == nil && len(pending) > 0 {
+		store.cache.invalidate(pending...)
+	}
+	return err
+}
+
+// invalidate removes the cached reads labelled with the tags, at the commit of the transaction of the store
+// when it was given by ExecTx.
+func (store *CachedStore) invalidate(tags ...string) {
+	if store.pending != nil {
+		*store.pending = append(*store.pending, tags...)
+		return
+	}
+	store.cache.invalidate(tags...)
+}
+
+// cachedLoad returns the cached value of a key, or loads it once for all the concurrent callers and caches it
+// labelled with the tags computed from the loaded value. Errors are never cached. The catalog version is checked
+// before the cache, the value is loaded from the store when the version cannot be read.
+func cachedLoad[T any](store *CachedStore, ctx context.Context, key string, tags func(T) []string, load func(context.Context) (T, error)) (T, error) {
+	if store.pending != nil {
+		return load(ctx)
+	}
+	if _, err := store.GetCatalogState(ctx); err != nil {
+		return load(ctx)
+	}
+	if value, ok := store.cache.get(key); ok {
+		store.hits.Add(1)
+		return value.(T), nil
+	}
+	store.misses.Add(1)
+	leader := false
+	value, err, _ := store.group.Do(key, func() (interface{}, error) {
+		leader = true
+		generation := store.cache.currentGeneration()
+		// the query is shared by every waiting caller, it must not be canceled with the first one
+		value, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		store.cache.set(key, value, tags(value), generation)
+		return value, nil
+	})
+	if !leader {
+		store.coalesced.Add(1)
+	}
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+func staticTags[T any](tags ...string) func(T) []string {
+	return func(T) []string {
+		return tags
+	}
+}
+
+// GetCatalogState reads the catalog version, which is never cached, and drops every cached read when the version
+// changed since it was last read, so that the cached reads are never older than the version served with them.
+func (store *CachedStore) GetCatalogState(ctx context.Context) (CatalogState, error) {
+	state, err := store.Store.GetCatalogState(ctx)
+	if err != nil || store.pending != nil {
+		return state, err
+	}
+	if store.catalogVersion.Swap(state.Version) != state.Version {
+		store.cache.purge()
+	}
+	return state, nil
+}
+
+func (store *CachedStore) GetMovie(ctx context.Context, id int32) (Movie, error) {
+	key := fmt.Sprintf("GetMovie:%d", id)
+	tags := func(movie Movie) []string {
+		// an old ID resolves to its survivor, the entry must follow the survivor
+		return []string{movieTag(movie.ID)}
+	}
+	return cachedLoad(store, ctx, key, tags, func(ctx context.Context) (Movie, error) {
+		return store.Store.GetMovie(ctx, id)
+	})
+}
+
+func (store *CachedStore) GetActor(ctx context.Context, id int32) (Actor, error) {
+	key := fmt.Sprintf("GetActor:%d", id)
+	tags := func(actor Actor) []string {
+		return []string{actorTag(actor.ID)}
+	}
+	return cachedLoad(store, ctx, key, tags, func(ctx context.Context) (Actor, error) {
+		return store.Store.GetActor(ctx, id)
+	})
+}
+
+func (store *CachedStore) GetMoviesSortedByRating(ctx context.Context) ([]Movie, error) {
+	return cachedLoad(store, ctx, "GetMoviesSortedByRating", staticTags[[]Movie](tagMovies), store.Store.GetMoviesSortedByRating)
+}
+
+func (store *CachedStore) GetMoviesSortedByName(ctx context.Context) ([]Movie, error) {
+	return cachedLoad(store, ctx, "GetMoviesSortedByName", staticTags[[]Movie](tagMovies), store.Store.GetMoviesSortedByName)
+}
+
+func (store *CachedStore) GetMoviesByReleaseDate(ctx context.Context) ([]Movie, error) {
+	return cachedLoad(store, ctx, "GetMoviesByReleaseDate", staticTags[[]Movie](tagMovies), store.Store.GetMoviesByReleaseDate)
+}
+
+func (store *CachedStore) GetMoviesByNameFragment(ctx context.Context, dollar_1 sql.NullString) ([]Movie, error) {
+	key := fmt.Sprintf("GetMoviesByNameFragment:%v:%s", dollar_1.Valid, dollar_1.String)
+	return cachedLoad(store, ctx, key, staticTags[[]Movie](tagMovies), func(ctx context.Context) ([]Movie, error) {
+		return store.Store.GetMoviesByNameFragment(ctx, dollar_1)
+	})
+}
+
+func (store *CachedStore) GetMoviesByActorFragment(ctx context.Context, dollar_1 sql.NullString) ([]Movie, error) {
+	key := fmt.Sprintf("GetMoviesByActorFragment:%v:%s", dollar_1.Valid, dollar_1.String)
+	tags := staticTags[[]Movie](tagMovies, tagActors, tagCast)
+	return cachedLoad(store, ctx, key, tags, func(ctx context.Context) ([]Movie, error) {
+		return store.Store.GetMoviesByActorFragment(ctx, dollar_1)
+	})
+}
+
+func (store *CachedStore) GetActorMoviesList(ctx context.Context) ([]GetActorMoviesListRow, error) {
+	tags := staticTags[[]GetActorMoviesListRow](tagMovies, tagActors, tagCast)
+	return cachedLoad(store, ctx, "GetActorMoviesList", tags, store.Store.GetActorMoviesList)
+}
+
+func (store *CachedStore) CreateMovie(ctx context.Context, arg CreateMovieParams) (Movie, error) {
+	defer store.invalidate(tagMovies)
+	return store.Store.CreateMovie(ctx, arg)
+}
+
+func (store *CachedStore) CreateMovieTx(ctx context.Context, arg CreateMovieTxParams) (Movie, error) {
+	defer store.invalidate(tagMovies)
+	return store.Store.CreateMovieTx(ctx, arg)
+}
+
+func (store *CachedStore) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie, error) {
+	defer store.invalidate(tagMovies, movieTag(arg.ID))
+	return store.Store.UpdateMovie(ctx, arg)
+}
+
+func (store *CachedStore) UpdateMovieTx(ctx context.Context, arg UpdateMovieTxParams) (Movie, error) {
+	defer store.invalidate(tagMovies, movieTag(arg.ID))
+	return store.Store.UpdateMovieTx(ctx, arg)
+}
+
+func (store *CachedStore) RestoreMovieRevisionTx(ctx context.Context, arg RestoreRevisionTxParams) (Movie, error) {
+	defer store.invalidate(tagMovies, movieTag(arg.ID))
+	return store.Store.RestoreMovieRevisionTx(ctx, arg)
+}
+
+func (store *CachedStore) DeleteMovie(ctx context.Context, arg DeleteMovieParams) (int64, error) {
+	defer store.invalidate(tagMovies, movieTag(arg.ID))
+	return store.Store.DeleteMovie(ctx, arg)
+}
+
+func (store *CachedStore) MergeMoviesTx(ctx context.Context, arg MergeTxParams) (Movie, error) {
+	defer store.invalidate(tagMovies, tagCast, movieTag(arg.SurvivorID), movieTag(arg.DuplicateID))
+	return store.Store.MergeMoviesTx(ctx, arg)
+}
+
+func (store *CachedStore) ReassignMovieActors(ctx context.Context, arg ReassignMovieActorsParams) error {
+	defer store.invalidate(tagCast)
+	return store.Store.ReassignMovieActors(ctx, arg)
+}
+
+func (store *CachedStore) DeleteMovieActors(ctx context.Context, movieID int32) error {
+	defer store.invalidate(tagCast)
+	return store.Store.DeleteMovieActors(ctx, movieID)
+}
+
+func (store *CachedStore) CreateMovieRedirect(ctx context.Context, arg CreateMovieRedirectParams) error {
+	defer store.invalidate(movieTag(arg.OldID))
+	return store.Store.CreateMovieRedirect(ctx, arg)
+}
+
+func (store *CachedStore) RepointMovieRedirects(ctx context.Context, arg RepointMovieRedirectsParams) error {
+	defer store.invalidate(movieTag(arg.DuplicateID))
+	return store.Store.RepointMovieRedirects(ctx, arg)
+}
+
+func (store *CachedStore) CreateActor(ctx context.Context, arg CreateActorParams) (Actor, error) {
+	defer store.invalidate(tagActors)
+	return store.Store.CreateActor(ctx, arg)
+}
+
+func (store *CachedStore) CreateActorTx(ctx context.Context, arg CreateActorTxParams) (Actor, error) {
+	defer store.invalidate(tagActors)
+	return store.Store.CreateActorTx(ctx, arg)
+}
+
+func (store *CachedStore) UpdateActor(ctx context.Context, arg UpdateActorParams) (Actor, error) {
+	defer store.invalidate(tagActors, actorTag(arg.ID))
+	return store.Store.UpdateActor(ctx, arg)
+}
+
+func (store *CachedStore) UpdateActorTx(ctx context.Context, arg UpdateActorTxParams) (Actor, error) {
+	defer store.invalidate(tagActors, actorTag(arg.ID))
+	return store.Store.UpdateActorTx(ctx, arg)
+}
+
+func (store *CachedStore) RestoreActorRevisionTx(ctx context.Context, arg RestoreRevisionTxParams) (Actor, error) {
+	defer store.invalidate(tagActors, actorTag(arg.ID))
+	return store.Store.RestoreActorRevisionTx(ctx, arg)
+}
+
+func (store *CachedStore) DeleteActor(ctx context.Context, arg DeleteActorParams) (int64, error) {
+	defer store.invalidate(tagActors, actorTag(arg.ID))
+	return store.Store.DeleteActor(ctx, arg)
+}
+
+func (store *CachedStore) MergeActorsTx(ctx context.Context, arg MergeTxParams) (Actor, error) {
+	defer store.invalidate(tagActors, tagCast, actorTag(arg.SurvivorID), actorTag(arg.DuplicateID))
+	return store.Store.MergeActorsTx(ctx, arg)
+}
+
+func (store *CachedStore) ReassignActorMovies(ctx context.Context, arg ReassignActorMoviesParams) error {
+	defer store.invalidate(tagCast)
+	return store.Store.ReassignActorMovies(ctx, arg)
+}
+
+func (store *CachedStore) DeleteActorMovies(ctx context.Context, actorID int32) error {
+	defer store.invalidate(tagCast)
+	return store.Store.DeleteActorMovies(ctx, actorID)
+}
+
+func (store *CachedStore) CreateActorRedirect(ctx context.Context, arg CreateActorRedirectParams) error {
+	defer store.invalidate(actorTag(arg.OldID))
+	return store.Store.CreateActorRedirect(ctx, arg)
+}
+
+func (store *CachedStore) RepointActorRedirects(ctx context.Context, arg RepointActorRedirectsParams) error {
+	defer store.invalidate(actorTag(arg.DuplicateID))
+	return store.Store.RepointActorRedirects(ctx, arg)
+}
+
+var _ Store = (*CachedStore)(nil)
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCatalogStore keeps the movies and the catalog version in memory, its ExecTx runs the function on itself.
+type fakeCatalogStore struct {
+	Store
+
+	mu        sync.Mutex
+	version   int64
+	movies    map[int32]Movie
+	stateErr  error
+	movieLoad atomic.Int32
+	// closed to let the loads of GetMovie return, when set
+	release chan struct{}
+}
+
+func newFakeCatalogStore() *fakeCatalogStore {
+	return &fakeCatalogStore{
+		version: 1,
+		movies:  map[int32]Movie{1: {ID: 1, Name: "Alien", Version: 1}},
+	}
+}
+
+func (store *fakeCatalogStore) ExecTx(ctx context.Context, fn func(Store) error) error {
+	return fn(store)
+}
+
+func (store *fakeCatalogStore) GetCatalogState(ctx context.Context) (CatalogState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return CatalogState{ID: true, Version: store.version}, store.stateErr
+}
+
+func (store *fakeCatalogStore) GetMovie(ctx context.Context, id int32) (Movie, error) {
+	store.movieLoad.Add(1)
+	if store.release != nil {
+		<-store.release
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	movie, ok := store.movies[id]
+	if !ok {
+		return Movie{}, sql.ErrNoRows
+	}
+	return movie, nil
+}
+
+func (store *fakeCatalogStore) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	movie := store.movies[arg.ID]
+	movie.Name = arg.Name.String
+	movie.Version++
+	store.movies[arg.ID] = movie
+	return movie, nil
+}
+
+// write changes a movie the way another server would, bumping the catalog version.
+func (store *fakeCatalogStore) write(movie Movie) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.movies[movie.ID] = movie
+	store.version++
+}
+
+func TestCachedStoreCachesReads(t *testing.T) {
+	fake := newFakeCatalogStore()
+	store := NewCachedStore(fake, 10, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		movie, err := store.GetMovie(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, "Alien", movie.Name)
+	}
+	require.Equal(t, int32(1), fake.movieLoad.Load())
+	stats := store.Stats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+	require.Equal(t, 1, stats.Entries)
+
+	// the errors are not cached
+	_, err := store.GetMovie(ctx, 2)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = store.GetMovie(ctx, 2)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Equal(t, int32(3), fake.movieLoad.Load())
+}
+
+func TestCachedStoreSeesTheWritesOfOtherServers(t *testing.T) {
+	fake := newFakeCatalogStore()
+	store := NewCachedStore(fake, 10, 0)
+	ctx := context.Background()
+
+	_, err := store.GetMovie(ctx, 1)
+	require.NoError(t, err)
+
+	fake.write(Movie{ID: 1, Name: "Aliens", Version: 2})
+	movie, err := store.GetMovie(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "Aliens", movie.Name)
+	require.Equal(t, int32(2), fake.movieLoad.Load())
+
+	// without the catalog version the reads go to the store
+	fake.stateErr = errors.New("connection refused")
+	_, err = store.GetMovie(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), fake.movieLoad.Load())
+}
+
+func TestCachedStoreInvalidatesOnCommit(t *testing.T) {
+	fake := newFakeCatalogStore()
+	store := NewCachedStore(fake, 10, 0)
+	ctx := context.Background()
+
+	_, err := store.GetMovie(ctx, 1)
+	require.NoError(t, err)
+
+	err = store.ExecTx(ctx, func(tx Store) error {
+		_, err := tx.UpdateMovie(ctx, UpdateMovieParams{ID: 1, Name: sql.NullString{String: "Aliens", Valid: true}})
+		require.NoError(t, err)
+
+		// the reads of the transaction go to the store
+		movie, err := tx.GetMovie(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, "Aliens", movie.Name)
+
+		// the other reads are served the committed movie until the commit
+		movie, err = store.GetMovie(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, "Alien", movie.Name)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), fake.movieLoad.Load())
+
+	movie, err := store.GetMovie(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "Aliens", movie.Name)
+	require.Equal(t, int32(3), fake.movieLoad.Load())
+
+	// a transaction rolled back leaves the cache alone
+	errRollback := errors.New("rollback")
+	err = store.ExecTx(ctx, func(tx Store) error {
+		_, err := tx.UpdateMovie(ctx, UpdateMovieParams{ID: 1, Name: sql.NullString{String: "Alien 3", Valid: true}})
+		require.NoError(t, err)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	_, err = store.GetMovie(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), fake.movieLoad.Load())
+}
+
+func TestCachedStoreCoalescesMisses(t *testing.T) {
+	fake := newFakeCatalogStore()
+	fake.release = make(chan struct{})
+	store := NewCachedStore(fake, 10, 0)
+	ctx := context.Background()
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			movie, err := store.GetMovie(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "Alien", movie.Name)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return store.Stats().Misses == callers
+	}, time.Second, time.Millisecond)
+	// the callers wait on the single load once they counted their miss
+	time.Sleep(10 * time.Millisecond)
+	close(fake.release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), fake.movieLoad.Load())
+	require.Equal(t, uint64(callers-1), store.Stats().Coalesced)
+}
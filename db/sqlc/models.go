@@ -5,7 +5,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Actor struct {
@@ -13,6 +17,69 @@ type Actor struct {
 	Name     string    `json:"name"`
 	Gender   string    `json:"gender"`
 	Birthday time.Time `json:"birthday"`
+	Version  int32     `json:"version"`
+}
+
+type ActorRedirect struct {
+	OldID    int32     `json:"old_id"`
+	ActorID  int32     `json:"actor_id"`
+	MergedAt time.Time `json:"merged_at"`
+}
+
+type ActorRevision struct {
+	ActorID   int32           `json:"actor_id"`
+	Revision  int32           `json:"revision"`
+	Data      json.RawMessage `json:"data"`
+	Username  string          `json:"username"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID         int32        `json:"id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	KeyHash    []byte       `json:"key_hash"`
+	Scopes     []string     `json:"scopes"`
+	CreatedBy  string       `json:"created_by"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID        int64           `json:"id"`
+	Username  string          `json:"username"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  sql.NullInt32   `json:"entity_id"`
+	Diff      json.RawMessage `json:"diff"`
+	RequestID string          `json:"request_id"`
+	ClientIp  string          `json:"client_ip"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type CatalogState struct {
+	ID        bool      `json:"id"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	BumpedBy  int64     `json:"bumped_by"`
+}
+
+type LoginChallenge struct {
+	ChallengeHash []byte    `json:"challenge_hash"`
+	Username      string    `json:"username"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Key           string       `json:"key"`
+	Failures      int32        `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+	Pending       int32        `json:"pending"`
+	ReservedAt    time.Time    `json:"reserved_at"`
 }
 
 type Movie struct {
@@ -21,6 +88,7 @@ type Movie struct {
 	Description string    `json:"description"`
 	ReleaseDate time.Time `json:"release_date"`
 	Rating      string    `json:"rating"`
+	Version     int32     `json:"version"`
 }
 
 type MovieActor struct {
@@ -28,11 +96,142 @@ type MovieActor struct {
 	ActorID int32 `json:"actor_id"`
 }
 
+type MovieRedirect struct {
+	OldID    int32     `json:"old_id"`
+	MovieID  int32     `json:"movie_id"`
+	MergedAt time.Time `json:"merged_at"`
+}
+
+type MovieRevision struct {
+	MovieID   int32           `json:"movie_id"`
+	Revision  int32           `json:"revision"`
+	Data      json.RawMessage `json:"data"`
+	Username  string          `json:"username"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type OidcLogin struct {
+	StateHash    []byte         `json:"state_hash"`
+	CodeVerifier string         `json:"code_verifier"`
+	Nonce        string         `json:"nonce"`
+	Username     sql.NullString `json:"username"`
+	ExpiresAt    time.Time      `json:"expires_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	CodeHash  []byte       `json:"code_hash"`
+	Username  string       `json:"username"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RefreshToken struct {
+	TokenHash []byte       `json:"token_hash"`
+	SessionID uuid.UUID    `json:"session_id"`
+	RotatedAt sql.NullTime `json:"rotated_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type Role struct {
+	ID          int32     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RolePermission struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+}
+
+type SecurityEvent struct {
+	ID        int64          `json:"id"`
+	Event     string         `json:"event"`
+	Username  sql.NullString `json:"username"`
+	ClientIp  string         `json:"client_ip"`
+	Details   string         `json:"details"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Username   string    `json:"username"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIp   string    `json:"client_ip"`
+	IsRevoked  bool      `json:"is_revoked"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+type SigningKey struct {
+	Kid        string    `json:"kid"`
+	Algorithm  string    `json:"algorithm"`
+	PrivateKey []byte    `json:"private_key"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 type User struct {
-	ID                int32     `json:"id"`
-	Username          string    `json:"username"`
-	HashedPassword    string    `json:"hashed_password"`
-	PasswordChangedAt time.Time `json:"password_changed_at"`
-	Role              string    `json:"role"`
-	CreatedAt         time.Time `json:"created_at"`
+	ID                    int32          `json:"id"`
+	Username              string         `json:"username"`
+	HashedPassword        string         `json:"hashed_password"`
+	PasswordChangedAt     time.Time      `json:"password_changed_at"`
+	Role                  string         `json:"role"`
+	CreatedAt             time.Time      `json:"created_at"`
+	TokensValidAfter      time.Time      `json:"tokens_valid_after"`
+	DisabledAt            sql.NullTime   `json:"disabled_at"`
+	PasswordResetRequired bool           `json:"password_reset_required"`
+	Email                 sql.NullString `json:"email"`
+	EmailVerifiedAt       sql.NullTime   `json:"email_verified_at"`
+}
+
+type UserIdentity struct {
+	ID          int32          `json:"id"`
+	Issuer      string         `json:"issuer"`
+	Subject     string         `json:"subject"`
+	Username    string         `json:"username"`
+	Email       sql.NullString `json:"email"`
+	LastLoginAt sql.NullTime   `json:"last_login_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+type UserToken struct {
+	TokenHash []byte       `json:"token_hash"`
+	Username  string       `json:"username"`
+	Purpose   string       `json:"purpose"`
+	Email     string       `json:"email"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type UserTotpSecret struct {
+	Username    string       `json:"username"`
+	Secret      []byte       `json:"secret"`
+	ConfirmedAt sql.NullTime `json:"confirmed_at"`
+	LastStep    int64        `json:"last_step"`
+	CreatedAt   time.Time    `json:"created_at"`
 }
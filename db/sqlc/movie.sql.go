@@ -9,6 +9,8 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const createMovie = `-- name: CreateMovie :one
@@ -18,7 +20,7 @@ INSERT INTO movies (
   release_date,
   rating
 ) VALUES 
-  ($1, $2, $3, $4) RETURNING id, name, description, release_date, rating
+  ($1, $2, $3, $4) RETURNING id, name, description, release_date, rating, version
 `
 
 type CreateMovieParams struct {
@@ -42,22 +44,83 @@ func (q *Queries) CreateMovie(ctx context.Context, arg CreateMovieParams) (Movie
 		&i.Description,
 		&i.ReleaseDate,
 		&i.Rating,
+		&i.Version,
 	)
 	return i, err
 }
 
-const deleteMovie = `-- name: DeleteMovie :exec
+const createMovieRedirect = `-- name: CreateMovieRedirect :exec
+INSERT INTO movie_redirects (
+  old_id,
+  movie_id
+) VALUES
+  ($1, $2)
+`
+
+type CreateMovieRedirectParams struct {
+	OldID   int32 `json:"old_id"`
+	MovieID int32 `json:"movie_id"`
+}
+
+func (q *Queries) CreateMovieRedirect(ctx context.Context, arg CreateMovieRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, createMovieRedirect, arg.OldID, arg.MovieID)
+	return err
+}
+
+const deleteMovie = `-- name: DeleteMovie :execrows
 DELETE FROM movies
 WHERE id = $1
+  AND ($2::int IS NULL OR version = $2)
 `
 
-func (q *Queries) DeleteMovie(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteMovie, id)
+type DeleteMovieParams struct {
+	ID              int32         `json:"id"`
+	ExpectedVersion sql.NullInt32 `json:"expected_version"`
+}
+
+func (q *Queries) DeleteMovie(ctx context.Context, arg DeleteMovieParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMovie, arg.ID, arg.ExpectedVersion)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteMovieActors = `-- name: DeleteMovieActors :exec
+DELETE FROM movie_actors
+WHERE movie_id = $1
+`
+
+func (q *Queries) DeleteMovieActors(ctx context.Context, movieID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteMovieActors, movieID)
 	return err
 }
 
+const getMovie = `-- name: GetMovie :one
+SELECT id, name, description, release_date, rating, version FROM movies
+WHERE id = COALESCE(
+  (SELECT r.movie_id FROM movie_redirects r WHERE r.old_id = $1),
+  $1
+)
+LIMIT 1
+`
+
+func (q *Queries) GetMovie(ctx context.Context, id int32) (Movie, error) {
+	row := q.db.QueryRowContext(ctx, getMovie, id)
+	var i Movie
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.ReleaseDate,
+		&i.Rating,
+		&i.Version,
+	)
+	return i, err
+}
+
 const getMoviesByActorFragment = `-- name: GetMoviesByActorFragment :many
-SELECT m.id, m.name, m.description, m.release_date, m.rating
+SELECT m.id, m.name, m.description, m.release_date, m.rating, m.version
 FROM movies m
 JOIN movie_actors ma ON m.id = ma.movie_id
 JOIN actors a ON ma.actor_id = a.id
@@ -79,6 +142,7 @@ func (q *Queries) GetMoviesByActorFragment(ctx context.Context, dollar_1 sql.Nul
 			&i.Description,
 			&i.ReleaseDate,
 			&i.Rating,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -94,7 +158,7 @@ func (q *Queries) GetMoviesByActorFragment(ctx context.Context, dollar_1 sql.Nul
 }
 
 const getMoviesByNameFragment = `-- name: GetMoviesByNameFragment :many
-SELECT id, name, description, release_date, rating
+SELECT id, name, description, release_date, rating, version
 FROM movies
 WHERE name LIKE '%' || $1 || '%'
 `
@@ -114,6 +178,7 @@ func (q *Queries) GetMoviesByNameFragment(ctx context.Context, dollar_1 sql.Null
 			&i.Description,
 			&i.ReleaseDate,
 			&i.Rating,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -129,7 +194,7 @@ func (q *Queries) GetMoviesByNameFragment(ctx context.Context, dollar_1 sql.Null
 }
 
 const getMoviesByReleaseDate = `-- name: GetMoviesByReleaseDate :many
-SELECT id, name, description, release_date, rating
+SELECT id, name, description, release_date, rating, version
 FROM movies
 ORDER BY release_date DESC
 `
@@ -149,6 +214,7 @@ func (q *Queries) GetMoviesByReleaseDate(ctx context.Context) ([]Movie, error) {
 			&i.Description,
 			&i.ReleaseDate,
 			&i.Rating,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -164,7 +230,7 @@ func (q *Queries) GetMoviesByReleaseDate(ctx context.Context) ([]Movie, error) {
 }
 
 const getMoviesSortedByName = `-- name: GetMoviesSortedByName :many
-SELECT id, name, description, release_date, rating
+SELECT id, name, description, release_date, rating, version
 FROM movies
 ORDER BY name
 `
@@ -184,6 +250,7 @@ func (q *Queries) GetMoviesSortedByName(ctx context.Context) ([]Movie, error) {
 			&i.Description,
 			&i.ReleaseDate,
 			&i.Rating,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -199,7 +266,7 @@ func (q *Queries) GetMoviesSortedByName(ctx context.Context) ([]Movie, error) {
 }
 
 const getMoviesSortedByRating = `-- name: GetMoviesSortedByRating :many
-SELECT id, name, description, release_date, rating
+SELECT id, name, description, release_date, rating, version
 FROM movies
 ORDER BY rating DESC
 `
@@ -219,6 +286,153 @@ func (q *Queries) GetMoviesSortedByRating(ctx context.Context) ([]Movie, error)
 			&i.Description,
 			&i.ReleaseDate,
 			&i.Rating,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMovieCast = `-- name: ListMovieCast :many
+SELECT ma.movie_id, a.id, a.name, a.gender, a.birthday, a.version
+FROM movie_actors ma
+JOIN actors a ON a.id = ma.actor_id
+WHERE ma.movie_id = ANY($1::int[])
+ORDER BY ma.movie_id, a.name, a.id
+`
+
+type ListMovieCastRow struct {
+	MovieID  int32     `json:"movie_id"`
+	ID       int32     `json:"id"`
+	Name     string    `json:"name"`
+	Gender   string    `json:"gender"`
+	Birthday time.Time `json:"birthday"`
+	Version  int32     `json:"version"`
+}
+
+func (q *Queries) ListMovieCast(ctx context.Context, movieIds []int32) ([]ListMovieCastRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMovieCast, pq.Array(movieIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMovieCastRow{}
+	for rows.Next() {
+		var i ListMovieCastRow
+		if err := rows.Scan(
+			&i.MovieID,
+			&i.ID,
+			&i.Name,
+			&i.Gender,
+			&i.Birthday,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMovies = `-- name: ListMovies :many
+SELECT id, name, description, release_date, rating, version FROM movies
+WHERE ($1::text IS NULL OR name ILIKE '%' || $1 || '%')
+  AND ($2::date IS NULL OR release_date >= $2)
+  AND ($3::date IS NULL OR release_date <= $3)
+  AND ($4::numeric IS NULL OR rating >= $4)
+ORDER BY
+  CASE WHEN $5::text = 'rating' THEN rating END DESC,
+  CASE WHEN $5::text = 'name' THEN name END,
+  CASE WHEN $5::text = 'release_date' THEN release_date END DESC,
+  id
+LIMIT $6
+OFFSET $7
+`
+
+type ListMoviesParams struct {
+	Name           sql.NullString `json:"name"`
+	ReleasedAfter  sql.NullTime   `json:"released_after"`
+	ReleasedBefore sql.NullTime   `json:"released_before"`
+	MinRating      sql.NullString `json:"min_rating"`
+	SortBy         string         `json:"sort_by"`
+	PageLimit      int32          `json:"page_limit"`
+	PageOffset     int32          `json:"page_offset"`
+}
+
+func (q *Queries) ListMovies(ctx context.Context, arg ListMoviesParams) ([]Movie, error) {
+	rows, err := q.db.QueryContext(ctx, listMovies,
+		arg.Name,
+		arg.ReleasedAfter,
+		arg.ReleasedBefore,
+		arg.MinRating,
+		arg.SortBy,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Movie{}
+	for rows.Next() {
+		var i Movie
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.ReleaseDate,
+			&i.Rating,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMoviesByReleaseYear = `-- name: ListMoviesByReleaseYear :many
+SELECT id, name, description, release_date, rating, version FROM movies
+WHERE EXTRACT(YEAR FROM release_date) = EXTRACT(YEAR FROM $1::date)
+ORDER BY id
+`
+
+func (q *Queries) ListMoviesByReleaseYear(ctx context.Context, releaseDate time.Time) ([]Movie, error) {
+	rows, err := q.db.QueryContext(ctx, listMoviesByReleaseYear, releaseDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Movie{}
+	for rows.Next() {
+		var i Movie
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.ReleaseDate,
+			&i.Rating,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -233,31 +447,130 @@ func (q *Queries) GetMoviesSortedByRating(ctx context.Context) ([]Movie, error)
 	return items, nil
 }
 
+const lockMovie = `-- name: LockMovie :one
+SELECT id, name, description, release_date, rating, version FROM movies
+WHERE id = COALESCE(
+  (SELECT r.movie_id FROM movie_redirects r WHERE r.old_id = $1),
+  $1
+)
+LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) LockMovie(ctx context.Context, id int32) (Movie, error) {
+	row := q.db.QueryRowContext(ctx, lockMovie, id)
+	var i Movie
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.ReleaseDate,
+		&i.Rating,
+		&i.Version,
+	)
+	return i, err
+}
+
+const lockMovies = `-- name: LockMovies :many
+SELECT id, name, description, release_date, rating, version FROM movies
+WHERE id = ANY($1::int[])
+ORDER BY id
+FOR UPDATE
+`
+
+func (q *Queries) LockMovies(ctx context.Context, ids []int32) ([]Movie, error) {
+	rows, err := q.db.QueryContext(ctx, lockMovies, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Movie{}
+	for rows.Next() {
+		var i Movie
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.ReleaseDate,
+			&i.Rating,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reassignMovieActors = `-- name: ReassignMovieActors :exec
+INSERT INTO movie_actors (movie_id, actor_id)
+SELECT $1::int, ma.actor_id
+FROM movie_actors ma
+WHERE ma.movie_id = $2
+ON CONFLICT DO NOTHING
+`
+
+type ReassignMovieActorsParams struct {
+	SurvivorID  int32 `json:"survivor_id"`
+	DuplicateID int32 `json:"duplicate_id"`
+}
+
+func (q *Queries) ReassignMovieActors(ctx context.Context, arg ReassignMovieActorsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignMovieActors, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
+const repointMovieRedirects = `-- name: RepointMovieRedirects :exec
+UPDATE movie_redirects
+SET movie_id = $1
+WHERE movie_id = $2
+`
+
+type RepointMovieRedirectsParams struct {
+	SurvivorID  int32 `json:"survivor_id"`
+	DuplicateID int32 `json:"duplicate_id"`
+}
+
+func (q *Queries) RepointMovieRedirects(ctx context.Context, arg RepointMovieRedirectsParams) error {
+	_, err := q.db.ExecContext(ctx, repointMovieRedirects, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
 const updateMovie = `-- name: UpdateMovie :one
 UPDATE movies
-SET name = $2,
-  description = $3,
-  rating = $4,
-  release_date = $5
-WHERE id = $1
-RETURNING id, name, description, release_date, rating
+SET name = COALESCE($1, name),
+  description = COALESCE($2, description),
+  rating = COALESCE($3, rating),
+  release_date = COALESCE($4, release_date),
+  version = version + 1
+WHERE id = $5
+  AND ($6::int IS NULL OR version = $6)
+RETURNING id, name, description, release_date, rating, version
 `
 
 type UpdateMovieParams struct {
-	ID          int32     `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Rating      string    `json:"rating"`
-	ReleaseDate time.Time `json:"release_date"`
+	Name            sql.NullString `json:"name"`
+	Description     sql.NullString `json:"description"`
+	Rating          sql.NullString `json:"rating"`
+	ReleaseDate     sql.NullTime   `json:"release_date"`
+	ID              int32          `json:"id"`
+	ExpectedVersion sql.NullInt32  `json:"expected_version"`
 }
 
 func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie, error) {
 	row := q.db.QueryRowContext(ctx, updateMovie,
-		arg.ID,
 		arg.Name,
 		arg.Description,
 		arg.Rating,
 		arg.ReleaseDate,
+		arg.ID,
+		arg.ExpectedVersion,
 	)
 	var i Movie
 	err := row.Scan(
@@ -266,6 +579,7 @@ func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie
 		&i.Description,
 		&i.ReleaseDate,
 		&i.Rating,
+		&i.Version,
 	)
 	return i, err
 }
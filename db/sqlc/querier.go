@@ -7,23 +7,135 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	AddRolePermissions(ctx context.Context, arg AddRolePermissionsParams) error
+	ConfirmTOTPSecret(ctx context.Context, arg ConfirmTOTPSecretParams) (UserTotpSecret, error)
+	CountRecoveryCodes(ctx context.Context, username string) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateActor(ctx context.Context, arg CreateActorParams) (Actor, error)
+	CreateActorRedirect(ctx context.Context, arg CreateActorRedirectParams) error
+	CreateActorRevision(ctx context.Context, arg CreateActorRevisionParams) (ActorRevision, error)
+	CreateAuditEntry(ctx context.Context, arg CreateAuditEntryParams) (AuditLog, error)
+	CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) error
 	CreateMovie(ctx context.Context, arg CreateMovieParams) (Movie, error)
+	CreateMovieRedirect(ctx context.Context, arg CreateMovieRedirectParams) error
+	CreateMovieRevision(ctx context.Context, arg CreateMovieRevisionParams) (MovieRevision, error)
+	CreateOIDCLogin(ctx context.Context, arg CreateOIDCLoginParams) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error
+	CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error)
+	CreateSecurityEvent(ctx context.Context, arg CreateSecurityEventParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateSigningKey(ctx context.Context, arg CreateSigningKeyParams) error
+	CreateTOTPSecret(ctx context.Context, arg CreateTOTPSecretParams) (UserTotpSecret, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteActor(ctx context.Context, id int32) error
-	DeleteMovie(ctx context.Context, id int32) error
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	CreateUserToken(ctx context.Context, arg CreateUserTokenParams) error
+	DeleteActor(ctx context.Context, arg DeleteActorParams) (int64, error)
+	DeleteActorMovies(ctx context.Context, actorID int32) error
+	DeleteExpiredLoginChallenges(ctx context.Context, expiresAt time.Time) error
+	DeleteExpiredOIDCLogins(ctx context.Context, expiresAt time.Time) error
+	DeleteExpiredRevokedTokens(ctx context.Context) error
+	DeleteExpiredSigningKeys(ctx context.Context) error
+	DeleteIdleRateLimitBuckets(ctx context.Context, updatedAt time.Time) error
+	DeleteLoginChallenge(ctx context.Context, challengeHash []byte) (int64, error)
+	DeleteLoginThrottle(ctx context.Context, key string) error
+	DeleteMovie(ctx context.Context, arg DeleteMovieParams) (int64, error)
+	DeleteMovieActors(ctx context.Context, movieID int32) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteRole(ctx context.Context, name string) (int64, error)
+	DeleteRolePermissions(ctx context.Context, role string) error
+	DeleteStaleLoginThrottles(ctx context.Context, lastFailureAt time.Time) error
+	DeleteTOTPSecret(ctx context.Context, username string) (int64, error)
+	DeleteUserIdentity(ctx context.Context, arg DeleteUserIdentityParams) (int64, error)
+	DeleteUserTokens(ctx context.Context, arg DeleteUserTokensParams) error
+	GetAPIKey(ctx context.Context, id int32) (ApiKey, error)
+	GetActor(ctx context.Context, id int32) (Actor, error)
 	GetActorMoviesList(ctx context.Context) ([]GetActorMoviesListRow, error)
+	GetActorRevision(ctx context.Context, arg GetActorRevisionParams) (ActorRevision, error)
+	GetCatalogState(ctx context.Context) (CatalogState, error)
+	GetLoginChallenge(ctx context.Context, arg GetLoginChallengeParams) (LoginChallenge, error)
+	GetMovie(ctx context.Context, id int32) (Movie, error)
+	GetMovieRevision(ctx context.Context, arg GetMovieRevisionParams) (MovieRevision, error)
 	GetMoviesByActorFragment(ctx context.Context, dollar_1 sql.NullString) ([]Movie, error)
 	GetMoviesByNameFragment(ctx context.Context, dollar_1 sql.NullString) ([]Movie, error)
 	GetMoviesByReleaseDate(ctx context.Context) ([]Movie, error)
 	GetMoviesSortedByName(ctx context.Context) ([]Movie, error)
 	GetMoviesSortedByRating(ctx context.Context) ([]Movie, error)
+	GetRefreshToken(ctx context.Context, tokenHash []byte) (RefreshToken, error)
+	GetRole(ctx context.Context, name string) (Role, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetTOTPSecret(ctx context.Context, username string) (UserTotpSecret, error)
+	// issuers walks from the key up to the keys that issued it, named "apikey:<name>" in created_by, and ends at its user.
+	// The key is usable while none of them is revoked or expired and that user is not disabled, it grants no more than the
+	// role of that user.
+	GetUsableAPIKey(ctx context.Context, arg GetUsableAPIKeyParams) (GetUsableAPIKeyRow, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error)
+	GetUserTokenCutoff(ctx context.Context, username string) (GetUserTokenCutoffRow, error)
+	ListAPIKeys(ctx context.Context) ([]ApiKey, error)
+	ListActiveSessions(ctx context.Context, username string) ([]Session, error)
+	ListActorFilmography(ctx context.Context, actorIds []int32) ([]ListActorFilmographyRow, error)
+	ListActorRevisions(ctx context.Context, actorID int32) ([]ActorRevision, error)
+	ListActors(ctx context.Context, arg ListActorsParams) ([]Actor, error)
+	ListActorsByBirthday(ctx context.Context, birthday time.Time) ([]Actor, error)
+	ListAuditEntries(ctx context.Context, arg ListAuditEntriesParams) ([]AuditLog, error)
+	ListLoginThrottles(ctx context.Context, keys []string) ([]LoginThrottle, error)
+	ListMovieCast(ctx context.Context, movieIds []int32) ([]ListMovieCastRow, error)
+	ListMovieRevisions(ctx context.Context, movieID int32) ([]MovieRevision, error)
+	ListMovies(ctx context.Context, arg ListMoviesParams) ([]Movie, error)
+	ListMoviesByReleaseYear(ctx context.Context, releaseDate time.Time) ([]Movie, error)
+	ListPermissions(ctx context.Context) ([]Permission, error)
+	ListRevokedTokens(ctx context.Context) ([]RevokedToken, error)
+	ListRolePermissions(ctx context.Context) ([]RolePermission, error)
+	ListRoles(ctx context.Context) ([]Role, error)
+	ListSecurityEvents(ctx context.Context, arg ListSecurityEventsParams) ([]SecurityEvent, error)
+	ListSigningKeys(ctx context.Context, algorithm string) ([]SigningKey, error)
+	ListUserIdentities(ctx context.Context, username string) ([]UserIdentity, error)
+	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	LockActor(ctx context.Context, id int32) (Actor, error)
+	LockActors(ctx context.Context, ids []int32) ([]Actor, error)
+	LockLogin(ctx context.Context, arg LockLoginParams) error
+	LockMovie(ctx context.Context, id int32) (Movie, error)
+	LockMovies(ctx context.Context, ids []int32) ([]Movie, error)
+	// held until the end of the transaction by the server creating the next key of the algorithm
+	LockSigningKeys(ctx context.Context, algorithm string) error
+	ReassignActorMovies(ctx context.Context, arg ReassignActorMoviesParams) error
+	ReassignMovieActors(ctx context.Context, arg ReassignMovieActorsParams) error
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	ReleaseLoginAttempts(ctx context.Context, keys []string) error
+	RepointActorRedirects(ctx context.Context, arg RepointActorRedirectsParams) error
+	RepointMovieRedirects(ctx context.Context, arg RepointMovieRedirectsParams) error
+	RequirePasswordReset(ctx context.Context, username string) (User, error)
+	ReserveLoginAttempt(ctx context.Context, arg ReserveLoginAttemptParams) (LoginThrottle, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (Session, error)
+	RevokeUserSessions(ctx context.Context, username string) error
+	RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) (int64, error)
+	RotateRefreshToken(ctx context.Context, tokenHash []byte) (int64, error)
+	SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (User, error)
+	TakeOIDCLogin(ctx context.Context, arg TakeOIDCLoginParams) (OidcLogin, error)
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error)
+	TouchAPIKey(ctx context.Context, arg TouchAPIKeyParams) error
+	TouchSession(ctx context.Context, arg TouchSessionParams) (Session, error)
 	UpdateActor(ctx context.Context, arg UpdateActorParams) (Actor, error)
 	UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie, error)
+	UpdateRole(ctx context.Context, arg UpdateRoleParams) (Role, error)
+	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error)
+	UpdateUserIdentityLogin(ctx context.Context, arg UpdateUserIdentityLoginParams) (UserIdentity, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
+	UseUserToken(ctx context.Context, arg UseUserTokenParams) (UserToken, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
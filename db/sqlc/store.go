@@ -1,14 +1,36 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 )
 
 type Store interface {
 	Querier
+	ExecTx(ctx context.Context, fn func(Store) error) error
+	MergeActorsTx(ctx context.Context, arg MergeTxParams) (Actor, error)
+	MergeMoviesTx(ctx context.Context, arg MergeTxParams) (Movie, error)
+	CreateMovieTx(ctx context.Context, arg CreateMovieTxParams) (Movie, error)
+	UpdateMovieTx(ctx context.Context, arg UpdateMovieTxParams) (Movie, error)
+	RestoreMovieRevisionTx(ctx context.Context, arg RestoreRevisionTxParams) (Movie, error)
+	CreateActorTx(ctx context.Context, arg CreateActorTxParams) (Actor, error)
+	UpdateActorTx(ctx context.Context, arg UpdateActorTxParams) (Actor, error)
+	RestoreActorRevisionTx(ctx context.Context, arg RestoreRevisionTxParams) (Actor, error)
+	CreateSessionTx(ctx context.Context, arg CreateSessionTxParams) (Session, error)
+	RenewSessionTx(ctx context.Context, arg RenewSessionTxParams) (Session, error)
+	RevokeUserTokensTx(ctx context.Context, arg RevokeUserTokensParams) error
+	CreateRoleTx(ctx context.Context, arg RoleTxParams) (Role, error)
+	UpdateRoleTx(ctx context.Context, arg RoleTxParams) (Role, error)
+	CreateUserTokenTx(ctx context.Context, arg CreateUserTokenParams) error
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (UserTotpSecret, error)
+	ReplaceRecoveryCodesTx(ctx context.Context, arg ReplaceRecoveryCodesTxParams) error
 }
 type SQLStore struct {
 	db *sql.DB
+	tx *sql.Tx
 	*Queries
 }
 
@@ -18,3 +40,31 @@ func NewStore(db *sql.DB) Store {
 		Queries: New(db),
 	}
 }
+
+// ExecTx executes a function within a database transaction, with a store whose queries and Tx methods all run
+// in it, so that several writes are committed together or not at all.
+func (store *SQLStore) ExecTx(ctx context.Context, fn func(Store) error) error {
+	if store.tx != nil {
+		return fn(store)
+	}
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(&SQLStore{db: store.db, tx: tx, Queries: New(tx)})
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// execTx executes a function within a database transaction, or within the transaction of the store
+// when it was given by ExecTx.
+func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+	return store.ExecTx(ctx, func(tx Store) error {
+		return fn(tx.(*SQLStore).Queries)
+	})
+}
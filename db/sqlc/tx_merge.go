@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrMergeSameRecord = errors.New("cannot merge a record into itself")
+
+// MergeTxParams contains the input parameters of a merge transaction.
+type MergeTxParams struct {
+	SurvivorID  int32 `json:"survivor_id"`
+	DuplicateID int32 `json:"duplicate_id"`
+}
+
+// MergeActorsTx moves every relation of the duplicate actor to the survivor, deletes the duplicate
+// and leaves a redirect so the duplicate's ID keeps resolving to the survivor.
+// Relations added to actors in the future must be reassigned here as well.
+func (store *SQLStore) MergeActorsTx(ctx context.Context, arg MergeTxParams) (Actor, error) {
+	var survivor Actor
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		var duplicate Actor
+		survivor, duplicate, err = lockActorsForMerge(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+		err = q.ReassignActorMovies(ctx, ReassignActorMoviesParams{
+			SurvivorID:  survivor.ID,
+			DuplicateID: duplicate.ID,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.DeleteActorMovies(ctx, duplicate.ID)
+		if err != nil {
+			return err
+		}
+		err = q.RepointActorRedirects(ctx, RepointActorRedirectsParams{
+			SurvivorID:  survivor.ID,
+			DuplicateID: duplicate.ID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.DeleteActor(ctx, DeleteActorParams{ID: duplicate.ID})
+		if err != nil {
+			return err
+		}
+		return q.CreateActorRedirect(ctx, CreateActorRedirectParams{
+			OldID:   duplicate.ID,
+			ActorID: survivor.ID,
+		})
+	})
+	return survivor, err
+}
+
+// MergeMoviesTx moves every relation of the duplicate movie to the survivor, deletes the duplicate
+// and leaves a redirect so the duplicate's ID keeps resolving to the survivor.
+// Relations added to movies in the future must be reassigned here as well.
+func (store *SQLStore) MergeMoviesTx(ctx context.Context, arg MergeTxParams) (Movie, error) {
+	var survivor Movie
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		var duplicate Movie
+		survivor, duplicate, err = lockMoviesForMerge(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+		err = q.ReassignMovieActors(ctx, ReassignMovieActorsParams{
+			SurvivorID:  survivor.ID,
+			DuplicateID: duplicate.ID,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.DeleteMovieActors(ctx, duplicate.ID)
+		if err != nil {
+			return err
+		}
+		err = q.RepointMovieRedirects(ctx, RepointMovieRedirectsParams{
+			SurvivorID:  survivor.ID,
+			DuplicateID: duplicate.ID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.DeleteMovie(ctx, DeleteMovieParams{ID: duplicate.ID})
+		if err != nil {
+			return err
+		}
+		return q.CreateMovieRedirect(ctx, CreateMovieRedirectParams{
+			OldID:   duplicate.ID,
+			MovieID: survivor.ID,
+		})
+	})
+	return survivor, err
+}
+
+// lockActorsForMerge resolves the survivor and the duplicate actor and locks both rows, in the order of their IDs
+// so that two merges of the same pair cannot deadlock. A row merged away by a concurrent merge while waiting for
+// its lock is reported as not found.
+func lockActorsForMerge(ctx context.Context, q *Queries, arg MergeTxParams) (Actor, Actor, error) {
+	survivor, err := q.GetActor(ctx, arg.SurvivorID)
+	if err != nil {
+		return Actor{}, Actor{}, err
+	}
+	duplicate, err := q.GetActor(ctx, arg.DuplicateID)
+	if err != nil {
+		return Actor{}, Actor{}, err
+	}
+	if survivor.ID == duplicate.ID {
+		return Actor{}, Actor{}, ErrMergeSameRecord
+	}
+	locked, err := q.LockActors(ctx, []int32{survivor.ID, duplicate.ID})
+	if err != nil {
+		return Actor{}, Actor{}, err
+	}
+	if len(locked) != 2 {
+		return Actor{}, Actor{}, sql.ErrNoRows
+	}
+	if locked[0].ID == survivor.ID {
+		return locked[0], locked[1], nil
+	}
+	return locked[1], locked[0], nil
+}
+
+// lockMoviesForMerge resolves the survivor and the duplicate movie and locks both rows, like lockActorsForMerge.
+func lockMoviesForMerge(ctx context.Context, q *Queries, arg MergeTxParams) (Movie, Movie, error) {
+	survivor, err := q.GetMovie(ctx, arg.SurvivorID)
+	if err != nil {
+		return Movie{}, Movie{}, err
+	}
+	duplicate, err := q.GetMovie(ctx, arg.DuplicateID)
+	if err != nil {
+		return Movie{}, Movie{}, err
+	}
+	if survivor.ID == duplicate.ID {
+		return Movie{}, Movie{}, ErrMergeSameRecord
+	}
+	locked, err := q.LockMovies(ctx, []int32{survivor.ID, duplicate.ID})
+	if err != nil {
+		return Movie{}, Movie{}, err
+	}
+	if len(locked) != 2 {
+		return Movie{}, Movie{}, sql.ErrNoRows
+	}
+	if locked[0].ID == survivor.ID {
+		return locked[0], locked[1], nil
+	}
+	return locked[1], locked[0], nil
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// CreateMovieTxParams contains the input parameters of the create movie transaction.
+type CreateMovieTxParams struct {
+	CreateMovieParams
+	Username string
+}
+
+// UpdateMovieTxParams contains the input parameters of the update movie transaction.
+type UpdateMovieTxParams struct {
+	UpdateMovieParams
+	Username string
+}
+
+// CreateActorTxParams contains the input parameters of the create actor transaction.
+type CreateActorTxParams struct {
+	CreateActorParams
+	Username string
+}
+
+// UpdateActorTxParams contains the input parameters of the update actor transaction.
+type UpdateActorTxParams struct {
+	UpdateActorParams
+	Username string
+}
+
+// RestoreRevisionTxParams contains the input parameters of the restore revision transactions.
+type RestoreRevisionTxParams struct {
+	ID              int32
+	Revision        int32
+	ExpectedVersion sql.NullInt32
+	Username        string
+}
+
+// CreateMovieTx creates a movie and records it as its first revision.
+func (store *SQLStore) CreateMovieTx(ctx context.Context, arg CreateMovieTxParams) (Movie, error) {
+	var movie Movie
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		movie, err = q.CreateMovie(ctx, arg.CreateMovieParams)
+		if err != nil {
+			return err
+		}
+		return recordMovieRevision(ctx, q, movie, arg.Username)
+	})
+	return movie, err
+}
+
+// UpdateMovieTx updates a movie and records the result as a new revision.
+func (store *SQLStore) UpdateMovieTx(ctx context.Context, arg UpdateMovieTxParams) (Movie, error) {
+	var movie Movie
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		movie, err = q.UpdateMovie(ctx, arg.UpdateMovieParams)
+		if err != nil {
+			return err
+		}
+		return recordMovieRevision(ctx, q, movie, arg.Username)
+	})
+	return movie, err
+}
+
+// RestoreMovieRevisionTx brings a movie back to the state of an earlier revision, recorded as a new revision.
+func (store *SQLStore) RestoreMovieRevisionTx(ctx context.Context, arg RestoreRevisionTxParams) (Movie, error) {
+	var movie Movie
+	err := store.execTx(ctx, func(q *Queries) error {
+		revision, err := q.GetMovieRevision(ctx, GetMovieRevisionParams{
+			MovieID:  arg.ID,
+			Revision: arg.Revision,
+		})
+		if err != nil {
+			return err
+		}
+		var data Movie
+		err = json.Unmarshal(revision.Data, &data)
+		if err != nil {
+			return err
+		}
+		movie, err = q.UpdateMovie(ctx, UpdateMovieParams{
+			ID:              arg.ID,
+			Name:            sql.NullString{String: data.Name, Valid: true},
+			Description:     sql.NullString{String: data.Description, Valid: true},
+			Rating:          sql.NullString{String: data.Rating, Valid: true},
+			ReleaseDate:     sql.NullTime{Time: data.ReleaseDate, Valid: true},
+			ExpectedVersion: arg.ExpectedVersion,
+		})
+		if err != nil {
+			return err
+		}
+		return recordMovieRevision(ctx, q, movie, arg.Username)
+	})
+	return movie, err
+}
+
+// CreateActorTx creates an actor and records it as its first revision.
+func (store *SQLStore) CreateActorTx(ctx context.Context, arg CreateActorTxParams) (Actor, error) {
+	var actor Actor
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		actor, err = q.CreateActor(ctx, arg.CreateActorParams)
+		if err != nil {
+			return err
+		}
+		return recordActorRevision(ctx, q, actor, arg.Username)
+	})
+	return actor, err
+}
+
+// UpdateActorTx updates an actor and records the result as a new revision.
+func (store *SQLStore) UpdateActorTx(ctx context.Context, arg UpdateActorTxParams) (Actor, error) {
+	var actor Actor
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		actor, err = q.UpdateActor(ctx, arg.UpdateActorParams)
+		if err != nil {
+			return err
+		}
+		return recordActorRevision(ctx, q, actor, arg.Username)
+	})
+	return actor, err
+}
+
+// RestoreActorRevisionTx brings an actor back to the state of an earlier revision, recorded as a new revision.
+func (store *SQLStore) RestoreActorRevisionTx(ctx context.Context, arg RestoreRevisionTxParams) (Actor, error) {
+	var actor Actor
+	err := store.execTx(ctx, func(q *Queries) error {
+		revision, err := q.GetActorRevision(ctx, GetActorRevisionParams{
+			ActorID:  arg.ID,
+			Revision: arg.Revision,
+		})
+		if err != nil {
+			return err
+		}
+		var data Actor
+		err = json.Unmarshal(revision.Data, &data)
+		if err != nil {
+			return err
+		}
+		actor, err = q.UpdateActor(ctx, UpdateActorParams{
+			ID:              arg.ID,
+			Name:            sql.NullString{String: data.Name, Valid: true},
+			Gender:          sql.NullString{String: data.Gender, Valid: true},
+			Birthday:        sql.NullTime{Time: data.Birthday, Valid: true},
+			ExpectedVersion: arg.ExpectedVersion,
+		})
+		if err != nil {
+			return err
+		}
+		return recordActorRevision(ctx, q, actor, arg.Username)
+	})
+	return actor, err
+}
+
+func recordMovieRevision(ctx context.Context, q *Queries, movie Movie, username string) error {
+	data, err := json.Marshal(movie)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateMovieRevision(ctx, CreateMovieRevisionParams{
+		MovieID:  movie.ID,
+		Data:     data,
+		Username: username,
+	})
+	return err
+}
+
+func recordActorRevision(ctx context.Context, q *Queries, actor Actor, username string) error {
+	data, err := json.Marshal(actor)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateActorRevision(ctx, CreateActorRevisionParams{
+		ActorID:  actor.ID,
+		Data:     data,
+		Username: username,
+	})
+	return err
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedResult answers the next statement, which must contain match, with the rows or the error.
+type scriptedResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+// scriptedStatement is a statement run against the scripted database, with its arguments.
+type scriptedStatement struct {
+	query string
+	args  []driver.Value
+}
+
+// scriptedDB is a database answering the statements of a test in order, recording them and the end of the transaction,
+// so that the Tx methods of SQLStore run without Postgres.
+type scriptedDB struct {
+	t          *testing.T
+	script     []scriptedResult
+	statements []scriptedStatement
+	committed  bool
+	rolledBack bool
+}
+
+func newScriptedStore(t *testing.T, script ...scriptedResult) (Store, *scriptedDB) {
+	scripted := &scriptedDB{t: t, script: script}
+	conn := sql.OpenDB(scripted)
+	t.Cleanup(func() { conn.Close() })
+	return NewStore(conn), scripted
+}
+
+func (scripted *scriptedDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return scripted, nil
+}
+
+func (scripted *scriptedDB) Driver() driver.Driver {
+	return nil
+}
+
+func (scripted *scriptedDB) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not scripted")
+}
+
+func (scripted *scriptedDB) Close() error {
+	return nil
+}
+
+func (scripted *scriptedDB) Begin() (driver.Tx, error) {
+	return scripted, nil
+}
+
+func (scripted *scriptedDB) Commit() error {
+	scripted.committed = true
+	return nil
+}
+
+func (scripted *scriptedDB) Rollback() error {
+	scripted.rolledBack = true
+	return nil
+}
+
+func (scripted *scriptedDB) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := scripted.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptedRows{columns: result.columns, rows: result.rows}, nil
+}
+
+func (scripted *scriptedDB) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := scripted.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(result.rows)), nil
+}
+
+// next records a statement and returns its scripted result.
+func (scripted *scriptedDB) next(query string, args []driver.NamedValue) (scriptedResult, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	scripted.statements = append(scripted.statements, scriptedStatement{query: query, args: values})
+	if len(scripted.script) == 0 {
+		return scriptedResult{}, fmt.Errorf("unscripted statement %q", query)
+	}
+	result := scripted.script[0]
+	scripted.script = scripted.script[1:]
+	require.Contains(scripted.t, query, result.match)
+	return result, result.err
+}
+
+type scriptedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (rows *scriptedRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *scriptedRows) Close() error {
+	return nil
+}
+
+func (rows *scriptedRows) Next(dest []driver.Value) error {
+	if len(rows.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.rows[0])
+	rows.rows = rows.rows[1:]
+	return nil
+}
+
+var (
+	movieColumns    = []string{"id", "name", "description", "release_date", "rating", "version"}
+	revisionColumns = []string{"movie_id", "revision", "data", "username", "created_at"}
+)
+
+func movieRow(movie Movie) []driver.Value {
+	return []driver.Value{int64(movie.ID), movie.Name, movie.Description, movie.ReleaseDate, movie.Rating, int64(movie.Version)}
+}
+
+func revisionRow(t *testing.T, movie Movie, revision int32, username string) []driver.Value {
+	data, err := json.Marshal(movie)
+	require.NoError(t, err)
+	return []driver.Value{int64(movie.ID), int64(revision), data, username, time.Now()}
+}
+
+// recordedRevision returns the movie and the username of the revision recorded by a statement.
+func recordedRevision(t *testing.T, statement scriptedStatement) (Movie, string) {
+	require.True(t, strings.HasPrefix(statement.query, "-- name: CreateMovieRevision"))
+	var movie Movie
+	require.NoError(t, json.Unmarshal(statement.args[1].([]byte), &movie))
+	require.Equal(t, int64(movie.ID), statement.args[0])
+	return movie, statement.args[2].(string)
+}
+
+func TestUpdateMovieTxRecordsRevision(t *testing.T) {
+	updated := Movie{ID: 3, Name: "Alien", Description: "In space", ReleaseDate: time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC), Rating: "8.5", Version: 2}
+	store, scripted := newScriptedStore(t,
+		scriptedResult{match: "UPDATE movies", columns: movieColumns, rows: [][]driver.Value{movieRow(updated)}},
+		scriptedResult{match: "INSERT INTO movie_revisions", columns: revisionColumns, rows: [][]driver.Value{revisionRow(t, updated, 2, "vk-admin")}},
+	)
+
+	movie, err := store.UpdateMovieTx(context.Background(), UpdateMovieTxParams{
+		UpdateMovieParams: UpdateMovieParams{ID: 3, Rating: sql.NullString{String: "8.5", Valid: true}},
+		Username:          "vk-admin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, updated, movie)
+	require.Len(t, scripted.statements, 2)
+	recorded, username := recordedRevision(t, scripted.statements[1])
+	require.Equal(t, updated, recorded)
+	require.Equal(t, "vk-admin", username)
+	require.True(t, scripted.committed)
+}
+
+func TestUpdateMovieTxRollsBackWithoutRevision(t *testing.T) {
+	errInsert := errors.New("cannot insert")
+	store, scripted := newScriptedStore(t,
+		scriptedResult{match: "UPDATE movies", columns: movieColumns, rows: [][]driver.Value{movieRow(Movie{ID: 3, Version: 2})}},
+		scriptedResult{match: "INSERT INTO movie_revisions", err: errInsert},
+	)
+
+	_, err := store.UpdateMovieTx(context.Background(), UpdateMovieTxParams{UpdateMovieParams: UpdateMovieParams{ID: 3}, Username: "vk-admin"})
+	require.ErrorIs(t, err, errInsert)
+	require.True(t, scripted.rolledBack)
+	require.False(t, scripted.committed)
+}
+
+func TestRestoreMovieRevisionTx(t *testing.T) {
+	releaseDate := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+	old := Movie{ID: 3, Name: "Alien", Description: "In space", ReleaseDate: releaseDate, Rating: "8.4", Version: 1}
+	restored := old
+	restored.Version = 5
+	store, scripted := newScriptedStore(t,
+		scriptedResult{match: "FROM movie_revisions", columns: revisionColumns, rows: [][]driver.Value{revisionRow(t, old, 1, "vk-editor")}},
+		scriptedResult{match: "UPDATE movies", columns: movieColumns, rows: [][]driver.Value{movieRow(restored)}},
+		scriptedResult{match: "INSERT INTO movie_revisions", columns: revisionColumns, rows: [][]driver.Value{revisionRow(t, restored, 5, "vk-admin")}},
+	)
+
+	movie, err := store.RestoreMovieRevisionTx(context.Background(), RestoreRevisionTxParams{
+		ID:              3,
+		Revision:        1,
+		ExpectedVersion: sql.NullInt32{Int32: 4, Valid: true},
+		Username:        "vk-admin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, restored, movie)
+
+	// every field of the revision is written back, on the version the client saw
+	update := scripted.statements[1]
+	require.Equal(t, []driver.Value{"Alien", "In space", "8.4", releaseDate, int64(3), int64(4)}, update.args)
+	recorded, username := recordedRevision(t, scripted.statements[2])
+	require.Equal(t, restored, recorded)
+	require.Equal(t, "vk-admin", username)
+	require.True(t, scripted.committed)
+}
+
+func TestRestoreMovieRevisionTxUnknownRevision(t *testing.T) {
+	store, scripted := newScriptedStore(t,
+		scriptedResult{match: "FROM movie_revisions", columns: revisionColumns},
+	)
+
+	_, err := store.RestoreMovieRevisionTx(context.Background(), RestoreRevisionTxParams{ID: 3, Revision: 9, Username: "vk-admin"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Len(t, scripted.statements, 1)
+	require.True(t, scripted.rolledBack)
+}
@@ -0,0 +1,55 @@
+package db
+
+import "context"
+
+// RoleTxParams contains the input parameters of the create and update role transactions.
+type RoleTxParams struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// CreateRoleTx creates a role with its permissions.
+func (store *SQLStore) CreateRoleTx(ctx context.Context, arg RoleTxParams) (Role, error) {
+	var role Role
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		role, err = q.CreateRole(ctx, CreateRoleParams{
+			Name:        arg.Name,
+			Description: arg.Description,
+		})
+		if err != nil {
+			return err
+		}
+		return q.AddRolePermissions(ctx, AddRolePermissionsParams{
+			Role:        role.Name,
+			Permissions: arg.Permissions,
+		})
+	})
+	return role, err
+}
+
+// UpdateRoleTx updates the description of a role and replaces its permissions.
+// It returns sql.ErrNoRows for unknown roles.
+func (store *SQLStore) UpdateRoleTx(ctx context.Context, arg RoleTxParams) (Role, error) {
+	var role Role
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		role, err = q.UpdateRole(ctx, UpdateRoleParams{
+			Name:        arg.Name,
+			Description: arg.Description,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.DeleteRolePermissions(ctx, role.Name)
+		if err != nil {
+			return err
+		}
+		return q.AddRolePermissions(ctx, AddRolePermissionsParams{
+			Role:        role.Name,
+			Permissions: arg.Permissions,
+		})
+	})
+	return role, err
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+
+// CreateSessionTxParams contains the input parameters of the create session transaction.
+type CreateSessionTxParams struct {
+	CreateSessionParams
+	RefreshTokenHash []byte
+}
+
+// RenewSessionTxParams contains the input parameters of the renew session transaction.
+type RenewSessionTxParams struct {
+	SessionID   uuid.UUID
+	UserAgent   string
+	ClientIp    string
+	RotatedHash []byte
+	RefreshHash []byte
+}
+
+// CreateSessionTx creates a session and its first refresh token.
+func (store *SQLStore) CreateSessionTx(ctx context.Context, arg CreateSessionTxParams) (Session, error) {
+	var session Session
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		session, err = q.CreateSession(ctx, arg.CreateSessionParams)
+		if err != nil {
+			return err
+		}
+		return q.CreateRefreshToken(ctx, CreateRefreshTokenParams{
+			TokenHash: arg.RefreshTokenHash,
+			SessionID: session.ID,
+		})
+	})
+	return session, err
+}
+
+// RenewSessionTx rotates the refresh token of a session, the rotated token cannot be used again.
+// It returns ErrRefreshTokenReused when the rotated token was rotated already, by a concurrent renewal or a replay.
+func (store *SQLStore) RenewSessionTx(ctx context.Context, arg RenewSessionTxParams) (Session, error) {
+	var session Session
+	err := store.execTx(ctx, func(q *Queries) error {
+		rows, err := q.RotateRefreshToken(ctx, arg.RotatedHash)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrRefreshTokenReused
+		}
+		err = q.CreateRefreshToken(ctx, CreateRefreshTokenParams{
+			TokenHash: arg.RefreshHash,
+			SessionID: arg.SessionID,
+		})
+		if err != nil {
+			return err
+		}
+		session, err = q.TouchSession(ctx, TouchSessionParams{
+			ID:        arg.SessionID,
+			UserAgent: arg.UserAgent,
+			ClientIp:  arg.ClientIp,
+		})
+		return err
+	})
+	return session, err
+}
+
+// RevokeUserTokensTx rejects the access tokens of a user issued before arg.TokensValidAfter and revokes every
+// session of the user, so that no refresh token of the user can be renewed. It returns sql.ErrNoRows for unknown users.
+func (store *SQLStore) RevokeUserTokensTx(ctx context.Context, arg RevokeUserTokensParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		rows, err := q.RevokeUserTokens(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+		return q.RevokeUserSessions(ctx, arg.Username)
+	})
+}
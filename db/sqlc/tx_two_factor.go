@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ConfirmTOTPTxParams contains the input parameters of the confirm TOTP transaction.
+type ConfirmTOTPTxParams struct {
+	Username string
+	// the time step of the code confirming the secret, it cannot be used again
+	Step               int64
+	ConfirmedAt        time.Time
+	RecoveryCodeHashes [][]byte
+}
+
+// ReplaceRecoveryCodesTxParams contains the input parameters of the replace recovery codes transaction.
+type ReplaceRecoveryCodesTxParams struct {
+	Username   string
+	CodeHashes [][]byte
+}
+
+// ConfirmTOTPTx enables the TOTP secret enrolled by a user, along with its recovery codes.
+// It returns sql.ErrNoRows when the user has no secret waiting for its confirmation.
+func (store *SQLStore) ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (UserTotpSecret, error) {
+	var secret UserTotpSecret
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		secret, err = q.ConfirmTOTPSecret(ctx, ConfirmTOTPSecretParams{
+			Username:    arg.Username,
+			ConfirmedAt: sql.NullTime{Time: arg.ConfirmedAt, Valid: true},
+			LastStep:    arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+		return createRecoveryCodes(ctx, q, arg.Username, arg.RecoveryCodeHashes)
+	})
+	return secret, err
+}
+
+// ReplaceRecoveryCodesTx replaces the recovery codes of a user, the codes left unused stop working.
+func (store *SQLStore) ReplaceRecoveryCodesTx(ctx context.Context, arg ReplaceRecoveryCodesTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+		return createRecoveryCodes(ctx, q, arg.Username, arg.CodeHashes)
+	})
+}
+
+func createRecoveryCodes(ctx context.Context, q *Queries, username string, codeHashes [][]byte) error {
+	for _, codeHash := range codeHashes {
+		err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+			CodeHash: codeHash,
+			Username: username,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// The purposes of the single-use tokens sent by email.
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+)
+
+var ErrInvalidUserToken = errors.New("the token is invalid, has expired or has already been used")
+
+// VerifyEmailTxParams contains the input parameters of the verify email transaction.
+type VerifyEmailTxParams struct {
+	TokenHash  []byte
+	VerifiedAt time.Time
+}
+
+// ResetPasswordTxParams contains the input parameters of the reset password transaction.
+type ResetPasswordTxParams struct {
+	TokenHash      []byte
+	HashedPassword string
+	ChangedAt      time.Time
+}
+
+// CreateUserTokenTx stores a token sent by email, the tokens of the same user and purpose not used yet stop working.
+func (store *SQLStore) CreateUserTokenTx(ctx context.Context, arg CreateUserTokenParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.DeleteUserTokens(ctx, DeleteUserTokensParams{
+			Username: arg.Username,
+			Purpose:  arg.Purpose,
+		})
+		if err != nil {
+			return err
+		}
+		return q.CreateUserToken(ctx, arg)
+	})
+}
+
+// VerifyEmailTx uses a verification token to verify the email of its user.
+// It returns ErrInvalidUserToken when the token cannot be used, or when the email of the user changed since it was sent.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error) {
+	var user User
+	err := store.execTx(ctx, func(q *Queries) error {
+		userToken, err := q.UseUserToken(ctx, UseUserTokenParams{
+			UsedAt:    arg.VerifiedAt,
+			TokenHash: arg.TokenHash,
+			Purpose:   UserTokenVerifyEmail,
+		})
+		if err != nil {
+			return userTokenError(err)
+		}
+		user, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username:        userToken.Username,
+			Email:           sql.NullString{String: userToken.Email, Valid: true},
+			EmailVerifiedAt: sql.NullTime{Time: arg.VerifiedAt, Valid: true},
+		})
+		return userTokenError(err)
+	})
+	return user, err
+}
+
+// ResetPasswordTx uses a reset token to replace the password of its user, which also completes a forced password reset.
+// The other reset tokens of the user stop working. It returns ErrInvalidUserToken when the token cannot be used.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+	err := store.execTx(ctx, func(q *Queries) error {
+		userToken, err := q.UseUserToken(ctx, UseUserTokenParams{
+			UsedAt:    arg.ChangedAt,
+			TokenHash: arg.TokenHash,
+			Purpose:   UserTokenResetPassword,
+		})
+		if err != nil {
+			return userTokenError(err)
+		}
+		user, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:          userToken.Username,
+			HashedPassword:    arg.HashedPassword,
+			PasswordChangedAt: arg.ChangedAt,
+		})
+		if err != nil {
+			return err
+		}
+		return q.DeleteUserTokens(ctx, DeleteUserTokensParams{
+			Username: userToken.Username,
+			Purpose:  UserTokenResetPassword,
+		})
+	})
+	return user, err
+}
+
+// userTokenError reports the tokens matching no row as invalid.
+func userTokenError(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrInvalidUserToken
+	}
+	return err
+}
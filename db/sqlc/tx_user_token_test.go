@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	userTokenColumns = []string{"token_hash", "username", "purpose", "email", "expires_at", "used_at", "created_at"}
+	userColumns      = []string{"id", "username", "hashed_password", "password_changed_at", "role", "created_at",
+		"tokens_valid_after", "disabled_at", "password_reset_required", "email", "email_verified_at"}
+)
+
+func userRow(user User) []driver.Value {
+	return []driver.Value{int64(user.ID), user.Username, user.HashedPassword, user.PasswordChangedAt, user.Role, user.CreatedAt,
+		user.TokensValidAfter, nil, user.PasswordResetRequired, nil, nil}
+}
+
+func TestCreateUserTokenTx(t *testing.T) {
+	store, scripted := newScriptedStore(t,
+		scriptedResult{match: "DELETE FROM user_tokens"},
+		scriptedResult{match: "INSERT INTO user_tokens"},
+	)
+	arg := CreateUserTokenParams{
+		TokenHash: []byte("hash"),
+		Username:  "alice",
+		Purpose:   UserTokenResetPassword,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	require.NoError(t, store.CreateUserTokenTx(context.Background(), arg))
+	// the tokens of the same user and purpose not used yet are deleted first
+	require.Equal(t, []driver.Value{"alice", UserTokenResetPassword}, scripted.statements[0].args)
+	require.Equal(t, []byte("hash"), scripted.statements[1].args[0])
+	require.True(t, scripted.committed)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	changedAt := time.Now()
+	user := User{ID: 9, Username: "alice", HashedPassword: "new-hash", PasswordChangedAt: changedAt, Role: "client", CreatedAt: changedAt}
+	store, scripted := newScriptedStore(t,
+		scriptedResult{
+			match:   "UPDATE user_tokens",
+			columns: userTokenColumns,
+			rows:    [][]driver.Value{{[]byte("hash"), "alice", UserTokenResetPassword, "", changedAt.Add(time.Hour), changedAt, changedAt}},
+		},
+		scriptedResult{match: "UPDATE users", columns: userColumns, rows: [][]driver.Value{userRow(user)}},
+		scriptedResult{match: "DELETE FROM user_tokens"},
+	)
+
+	got, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      []byte("hash"),
+		HashedPassword: "new-hash",
+		ChangedAt:      changedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Username)
+	require.False(t, got.PasswordResetRequired)
+
+	// the token is used for its purpose only, then the password of its user is replaced
+	require.Equal(t, []driver.Value{changedAt, []byte("hash"), UserTokenResetPassword}, scripted.statements[0].args)
+	require.Contains(t, scripted.statements[1].args, "alice")
+	require.Contains(t, scripted.statements[1].args, "new-hash")
+	require.Equal(t, []driver.Value{"alice", UserTokenResetPassword}, scripted.statements[2].args)
+	require.True(t, scripted.committed)
+}
+
+func TestResetPasswordTxInvalidToken(t *testing.T) {
+	store, scripted := newScriptedStore(t,
+		scriptedResult{match: "UPDATE user_tokens", columns: userTokenColumns},
+	)
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{TokenHash: []byte("used"), HashedPassword: "new-hash", ChangedAt: time.Now()})
+	require.ErrorIs(t, err, ErrInvalidUserToken)
+	require.Len(t, scripted.statements, 1)
+	require.True(t, scripted.rolledBack)
+}
+
+func TestResetPasswordTxStoreError(t *testing.T) {
+	errConn := errors.New("connection refused")
+	store, scripted := newScriptedStore(t,
+		scriptedResult{match: "UPDATE user_tokens", err: errConn},
+	)
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{TokenHash: []byte("hash"), ChangedAt: time.Now()})
+	require.ErrorIs(t, err, errConn)
+	require.True(t, scripted.rolledBack)
+}
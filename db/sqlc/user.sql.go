@@ -7,23 +7,27 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
   username,
-  hashed_password
+  hashed_password,
+  email
 ) VALUES 
-  ($1, $2) RETURNING id, username, hashed_password, password_changed_at, role, created_at
+  ($1, $2, $3) RETURNING id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at
 `
 
 type CreateUserParams struct {
-	Username       string `json:"username"`
-	HashedPassword string `json:"hashed_password"`
+	Username       string         `json:"username"`
+	HashedPassword string         `json:"hashed_password"`
+	Email          sql.NullString `json:"email"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Username, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Username, arg.HashedPassword, arg.Email)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +36,17 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PasswordChangedAt,
 		&i.Role,
 		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, username, hashed_password, password_changed_at, role, created_at FROM users
+SELECT id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at FROM users
 WHERE username = $1
 LIMIT 1
 `
@@ -52,6 +61,284 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.PasswordChangedAt,
 		&i.Role,
 		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at FROM users
+WHERE lower(email) = lower($1)
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.Role,
+		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at FROM users
+WHERE ($1::text IS NULL OR username ILIKE '%' || $1 || '%')
+  AND ($2::text IS NULL OR role = $2)
+  AND ($3::boolean IS NULL OR (disabled_at IS NOT NULL) = $3)
+ORDER BY username
+LIMIT $4
+OFFSET $5
+`
+
+type ListUsersParams struct {
+	Username   sql.NullString `json:"username"`
+	Role       sql.NullString `json:"role"`
+	Disabled   sql.NullBool   `json:"disabled"`
+	PageLimit  int32          `json:"page_limit"`
+	PageOffset int32          `json:"page_offset"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.Username,
+		arg.Role,
+		arg.Disabled,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.HashedPassword,
+			&i.PasswordChangedAt,
+			&i.Role,
+			&i.CreatedAt,
+			&i.TokensValidAfter,
+			&i.DisabledAt,
+			&i.PasswordResetRequired,
+			&i.Email,
+			&i.EmailVerifiedAt,
+			&i.Email,
+			&i.EmailVerifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requirePasswordReset = `-- name: RequirePasswordReset :one
+UPDATE users
+SET password_reset_required = true
+WHERE username = $1
+RETURNING id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at
+`
+
+func (q *Queries) RequirePasswordReset(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, requirePasswordReset, username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.Role,
+		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :one
+UPDATE users
+SET disabled_at = $2
+WHERE username = $1
+RETURNING id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at
+`
+
+type SetUserDisabledParams struct {
+	Username   string       `json:"username"`
+	DisabledAt sql.NullTime `json:"disabled_at"`
+}
+
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserDisabled, arg.Username, arg.DisabledAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.Role,
+		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2,
+    email_verified_at = NULL
+WHERE username = $1
+RETURNING id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at
+`
+
+type UpdateUserEmailParams struct {
+	Username string         `json:"username"`
+	Email    sql.NullString `json:"email"`
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserEmail, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.Role,
+		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2,
+    password_changed_at = $3,
+    password_reset_required = false
+WHERE username = $1
+RETURNING id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at
+`
+
+type UpdateUserPasswordParams struct {
+	Username          string    `json:"username"`
+	HashedPassword    string    `json:"hashed_password"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.Username, arg.HashedPassword, arg.PasswordChangedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.Role,
+		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at
+`
+
+type UpdateUserRoleParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Username, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.Role,
+		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = $3
+WHERE username = $1
+  AND email = $2
+RETURNING id, username, hashed_password, password_changed_at, role, created_at, tokens_valid_after, disabled_at, password_reset_required, email, email_verified_at
+`
+
+type VerifyUserEmailParams struct {
+	Username        string         `json:"username"`
+	Email           sql.NullString `json:"email"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Username, arg.Email, arg.EmailVerifiedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedPassword,
+		&i.PasswordChangedAt,
+		&i.Role,
+		&i.CreatedAt,
+		&i.TokensValidAfter,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.Email,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,50 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	db "vk-film/db/sqlc"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const requestIDHeader = "x-request-id"
+
+// recordAudit appends an entry to the audit log for a mutation made by the authenticated user, like the HTTP handlers do,
+// with the store of the transaction applying the mutation.
+func recordAudit(ctx context.Context, store db.Store, action string, entity string, entityID int32, before, after interface{}) error {
+	return db.RecordAudit(ctx, store, db.RecordAuditParams{
+		Username:  authPayload(ctx).Username,
+		Action:    action,
+		Entity:    entity,
+		EntityID:  entityID,
+		Before:    before,
+		After:     after,
+		RequestID: requestID(ctx),
+		ClientIP:  clientIP(ctx),
+	})
+}
+
+// requestID returns the request ID sent by the client in the metadata, or a new one.
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 && len(values[0]) > 0 && len(values[0]) <= 64 {
+			return values[0]
+		}
+	}
+	return uuid.NewString()
+}
+
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
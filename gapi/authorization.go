@@ -0,0 +1,114 @@
+package gapi
+
+import (
+	"context"
+	"strings"
+	"vk-film/authz"
+	"vk-film/pb"
+	"vk-film/token"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationHeader     = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+type authorizationPayloadKey struct{}
+
+// publicServices are the services callable without a token.
+var publicServices = []string{
+	pb.AuthService_ServiceDesc.ServiceName,
+	"grpc.reflection.v1.ServerReflection",
+	"grpc.reflection.v1alpha.ServerReflection",
+}
+
+// UnaryAuthInterceptor verifies the bearer token of the unary calls, like authMiddleware does for HTTP.
+func (server *Server) UnaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := server.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamAuthInterceptor verifies the bearer token of the streaming calls.
+func (server *Server) StreamAuthInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := server.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream overrides the context of a stream with the one carrying the token payload.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *authenticatedStream) Context() context.Context {
+	return stream.ctx
+}
+
+// authenticate stores the payload of the token sent in the authorization metadata in the context.
+func (server *Server) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	for _, service := range publicServices {
+		if strings.HasPrefix(fullMethod, "/"+service+"/") {
+			return ctx, nil
+		}
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization header is not provided")
+	}
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization header format")
+	}
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization type %s", authorizationType)
+	}
+	payload, err := server.tokenMaker.VerifyToken(fields[1])
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid access token: %v", err)
+	}
+	err = server.revocations.Check(ctx, payload)
+	if err != nil {
+		if err == token.ErrRevokedToken {
+			return nil, status.Error(codes.Unauthenticated, "access token has been revoked")
+		}
+		if err == token.ErrUserDisabled {
+			return nil, status.Error(codes.PermissionDenied, "user has been disabled")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to check access token: %v", err)
+	}
+	return context.WithValue(ctx, authorizationPayloadKey{}, payload), nil
+}
+
+// authPayload returns the payload of the token the call was authenticated with.
+func authPayload(ctx context.Context) *token.Payload {
+	return ctx.Value(authorizationPayloadKey{}).(*token.Payload)
+}
+
+// authorize is the gRPC counterpart of requirePermission, it returns the payload of a user whose role grants a permission.
+func (server *Server) authorize(ctx context.Context, permission string) (*token.Payload, error) {
+	payload := authPayload(ctx)
+	err := server.authorizer.Authorize(ctx, payload.Role, permission)
+	if err != nil {
+		if err == authz.ErrPermissionDenied {
+			return nil, status.Errorf(codes.PermissionDenied, "the role of the user does not grant the %s permission", permission)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to check permissions: %v", err)
+	}
+	return payload, nil
+}
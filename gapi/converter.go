@@ -0,0 +1,54 @@
+package gapi
+
+import (
+	db "vk-film/db/sqlc"
+	"vk-film/pb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func convertMovie(movie db.Movie) *pb.Movie {
+	return &pb.Movie{
+		Id:          movie.ID,
+		Name:        movie.Name,
+		Description: movie.Description,
+		ReleaseDate: timestamppb.New(movie.ReleaseDate),
+		Rating:      movie.Rating,
+		Version:     movie.Version,
+	}
+}
+
+func convertMovies(movies []db.Movie) []*pb.Movie {
+	converted := make([]*pb.Movie, 0, len(movies))
+	for _, movie := range movies {
+		converted = append(converted, convertMovie(movie))
+	}
+	return converted
+}
+
+func convertActor(actor db.Actor) *pb.Actor {
+	return &pb.Actor{
+		Id:       actor.ID,
+		Name:     actor.Name,
+		Gender:   actor.Gender,
+		Birthday: timestamppb.New(actor.Birthday),
+		Version:  actor.Version,
+	}
+}
+
+func convertActors(actors []db.Actor) []*pb.Actor {
+	converted := make([]*pb.Actor, 0, len(actors))
+	for _, actor := range actors {
+		converted = append(converted, convertActor(actor))
+	}
+	return converted
+}
+
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:          user.Username,
+		Role:              user.Role,
+		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
+		CreatedAt:         timestamppb.New(user.CreatedAt),
+	}
+}
@@ -0,0 +1,59 @@
+package gapi
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errExpectedVersionRequired = status.Error(codes.FailedPrecondition, "expected_version is required to modify this resource")
+	errVersionMismatch         = status.Error(codes.Aborted, "the resource has been modified since it was read, fetch it again")
+)
+
+// expectedVersion is the gRPC counterpart of the If-Match handling of the HTTP API.
+// The returned version is invalid when the client accepts any version of the resource.
+func (server *Server) expectedVersion(expected *int32, current int32) (sql.NullInt32, error) {
+	if expected == nil {
+		if server.config.RequireIfMatch {
+			return sql.NullInt32{}, errExpectedVersionRequired
+		}
+		return sql.NullInt32{}, nil
+	}
+	if *expected != current {
+		return sql.NullInt32{}, errVersionMismatch
+	}
+	return sql.NullInt32{Int32: *expected, Valid: true}, nil
+}
+
+// storeError converts an error returned by the store to a gRPC status, the statuses are returned as they are.
+// Like the problems of the HTTP API, the statuses describe the Postgres errors by their constraint only
+// and the internal errors are logged rather than returned.
+func storeError(err error, message string) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return status.Errorf(codes.NotFound, "%s: not found", message)
+	}
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return status.Errorf(codes.AlreadyExists, "%s: a record with the same key already exists (%s)", message, pqErr.Constraint)
+		case "check_violation":
+			return status.Errorf(codes.InvalidArgument, "%s: the record violates the %s constraint", message, pqErr.Constraint)
+		case "not_null_violation":
+			return status.Errorf(codes.InvalidArgument, "%s: the %s is required", message, pqErr.Column)
+		case "string_data_right_truncation", "invalid_datetime_format", "invalid_text_representation", "numeric_value_out_of_range":
+			return status.Errorf(codes.InvalidArgument, "%s: a value is invalid or out of range", message)
+		case "foreign_key_violation":
+			return status.Errorf(codes.FailedPrecondition, "%s: the record references, or is referenced by, another record (%s)", message, pqErr.Constraint)
+		}
+	}
+	log.Printf("%s: %v", message, err)
+	return status.Errorf(codes.Internal, "%s: the request could not be processed, try again later", message)
+}
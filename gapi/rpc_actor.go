@@ -0,0 +1,207 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/pb"
+	"vk-film/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateActor creates an actor, like POST /actor/create.
+func (server *Server) CreateActor(ctx context.Context, req *pb.CreateActorRequest) (*pb.CreateActorResponse, error) {
+	payload, err := server.authorize(ctx, authz.ActorsWrite)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetName() == "" || req.GetGender() == "" || req.GetBirthday() == nil {
+		return nil, status.Error(codes.InvalidArgument, "name, gender and birthday are required")
+	}
+	if err := validateActor(&req.Name, &req.Gender, req.Birthday); err != nil {
+		return nil, err
+	}
+	arg := db.CreateActorParams{
+		Name:     req.GetName(),
+		Gender:   req.GetGender(),
+		Birthday: req.GetBirthday().AsTime(),
+	}
+	candidates, err := server.store.ListActorsByBirthday(ctx, arg.Birthday)
+	if err != nil {
+		return nil, storeError(err, "failed to look for duplicates")
+	}
+	var actor db.Actor
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		var err error
+		actor, err = store.CreateActorTx(ctx, db.CreateActorTxParams{
+			CreateActorParams: arg,
+			Username:          payload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityActor, actor.ID, nil, actor)
+	})
+	if err != nil {
+		return nil, storeError(err, "failed to create actor")
+	}
+	duplicates := []db.Actor{}
+	for _, candidate := range candidates {
+		if util.IsLikelyDuplicateName(arg.Name, candidate.Name) {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+	rsp := &pb.CreateActorResponse{
+		Actor:              convertActor(actor),
+		PossibleDuplicates: convertActors(duplicates),
+	}
+	return rsp, nil
+}
+
+// GetActor returns an actor, like GET /actor.
+func (server *Server) GetActor(ctx context.Context, req *pb.GetActorRequest) (*pb.GetActorResponse, error) {
+	actor, err := server.store.GetActor(ctx, req.GetId())
+	if err != nil {
+		return nil, storeError(err, "failed to get actor")
+	}
+	return &pb.GetActorResponse{Actor: convertActor(actor)}, nil
+}
+
+// UpdateActor changes the fields set in the request, like PATCH /actor/update.
+func (server *Server) UpdateActor(ctx context.Context, req *pb.UpdateActorRequest) (*pb.UpdateActorResponse, error) {
+	payload, err := server.authorize(ctx, authz.ActorsWrite)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateActor(req.Name, req.Gender, req.Birthday); err != nil {
+		return nil, err
+	}
+	var actor db.Actor
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockActor(ctx, req.GetId())
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(req.ExpectedVersion, before.Version)
+		if err != nil {
+			return err
+		}
+		arg := db.UpdateActorParams{
+			ID:              before.ID,
+			Name:            sql.NullString{String: req.GetName(), Valid: req.Name != nil},
+			Gender:          sql.NullString{String: req.GetGender(), Valid: req.Gender != nil},
+			Birthday:        sql.NullTime{Time: req.GetBirthday().AsTime(), Valid: req.Birthday != nil},
+			ExpectedVersion: expected,
+		}
+		if !arg.Name.Valid && !arg.Gender.Valid && !arg.Birthday.Valid {
+			actor = before
+			return nil
+		}
+		actor, err = store.UpdateActorTx(ctx, db.UpdateActorTxParams{
+			UpdateActorParams: arg,
+			Username:          payload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityActor, actor.ID, before, actor)
+	})
+	if err != nil {
+		return nil, storeError(err, "failed to update actor")
+	}
+	return &pb.UpdateActorResponse{Actor: convertActor(actor)}, nil
+}
+
+// DeleteActor deletes an actor, like DELETE /actor/delete.
+func (server *Server) DeleteActor(ctx context.Context, req *pb.DeleteActorRequest) (*pb.DeleteActorResponse, error) {
+	if _, err := server.authorize(ctx, authz.ActorsDelete); err != nil {
+		return nil, err
+	}
+	var before db.Actor
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		var err error
+		before, err = store.LockActor(ctx, req.GetId())
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(req.ExpectedVersion, before.Version)
+		if err != nil {
+			return err
+		}
+		deleted, err := store.DeleteActor(ctx, db.DeleteActorParams{
+			ID:              before.ID,
+			ExpectedVersion: expected,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errVersionMismatch
+		}
+		return recordAudit(ctx, store, db.AuditActionDelete, db.AuditEntityActor, before.ID, before, nil)
+	})
+	if err != nil {
+		return nil, storeError(err, "failed to delete actor")
+	}
+	return &pb.DeleteActorResponse{Id: before.ID}, nil
+}
+
+// MergeActors merges a duplicate actor into a survivor, like POST /actor/merge.
+func (server *Server) MergeActors(ctx context.Context, req *pb.MergeActorsRequest) (*pb.MergeActorsResponse, error) {
+	if _, err := server.authorize(ctx, authz.ActorsDelete); err != nil {
+		return nil, err
+	}
+	var actor db.Actor
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		duplicate, err := store.GetActor(ctx, req.GetDuplicateId())
+		if err != nil {
+			return err
+		}
+		actor, err = store.MergeActorsTx(ctx, db.MergeTxParams{
+			SurvivorID:  req.GetSurvivorId(),
+			DuplicateID: req.GetDuplicateId(),
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionMerge, db.AuditEntityActor, duplicate.ID, duplicate, map[string]int32{"merged_into": actor.ID})
+	})
+	if err != nil {
+		if err == db.ErrMergeSameRecord {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, storeError(err, "failed to merge actors")
+	}
+	return &pb.MergeActorsResponse{Actor: convertActor(actor)}, nil
+}
+
+// ListActorsWithMovies streams every actor with the movies they played in, like GET /actors-movies.
+func (server *Server) ListActorsWithMovies(req *pb.ListActorsWithMoviesRequest, stream pb.CatalogService_ListActorsWithMoviesServer) error {
+	rows, err := server.store.GetActorMoviesList(stream.Context())
+	if err != nil {
+		return storeError(err, "failed to list actors")
+	}
+	// rows are ordered by actor, an actor is complete when the next one starts
+	var current *pb.ActorWithMovies
+	for _, row := range rows {
+		if current != nil && current.Id != row.ActorID {
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+			current = nil
+		}
+		if current == nil {
+			current = &pb.ActorWithMovies{Id: row.ActorID, Name: row.ActorName}
+		}
+		if row.MovieID.Valid {
+			current.Movies = append(current.Movies, &pb.ActorMovie{Id: row.MovieID.Int32, Name: row.MovieName.String})
+		}
+	}
+	if current != nil {
+		return stream.Send(current)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/lockout"
+	"vk-film/pb"
+	"vk-film/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// CreateUser registers a new client user, like POST /users.
+func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if req.GetUsername() == "" || len(req.GetUsername()) > 50 || len(req.GetPassword()) < 6 {
+		return nil, status.Error(codes.InvalidArgument, "username is required, with at most 50 characters, and password must have at least 6 characters")
+	}
+	hashedPassword, err := util.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %v", err)
+	}
+	arg := db.CreateUserParams{
+		Username:       req.GetUsername(),
+		HashedPassword: hashedPassword,
+	}
+	user, err := server.store.CreateUser(ctx, arg)
+	if err != nil {
+		return nil, storeError(err, "failed to create user")
+	}
+	rsp := &pb.CreateUserResponse{
+		User: convertUser(user),
+	}
+	return rsp, nil
+}
+
+// LoginUser issues an access token, like POST /users/login. The users with two-factor authentication, or whose role
+// requires it, log in with the HTTP API, which asks for their TOTP code.
+func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if req.GetUsername() == "" || len(req.GetUsername()) > 50 || len(req.GetPassword()) < 6 {
+		return nil, status.Error(codes.InvalidArgument, "username is required, with at most 50 characters, and password must have at least 6 characters")
+	}
+	user, err := server.checkCredentials(ctx, req.GetUsername(), req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt.Valid {
+		return nil, status.Error(codes.PermissionDenied, "user has been disabled")
+	}
+	if user.PasswordResetRequired {
+		return nil, status.Error(codes.PermissionDenied, "the password of the user must be reset with POST /v1/users/password/reset before logging in")
+	}
+	// the failed logins are kept for the second factor, so that the password does not reset them while the codes are guessed
+	secret, err := server.store.GetTOTPSecret(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, status.Errorf(codes.Internal, "failed to find two-factor authentication: %v", err)
+	}
+	if secret.ConfirmedAt.Valid || (server.config.TOTPRequiredForAdministrators && user.Role == authz.RoleAdministrator) {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is required, log in with POST /v1/users/login")
+	}
+	err = server.loginGuard.Succeed(ctx, user.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to record login: %v", err)
+	}
+	accessToken, err := server.tokenMaker.CreateToken(
+		user.Username,
+		user.Role,
+		server.config.AccessTokenDuration,
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create access token: %v", err)
+	}
+	rsp := &pb.LoginUserResponse{
+		AccessToken: accessToken,
+		User:        convertUser(user),
+	}
+	return rsp, nil
+}
+
+// checkCredentials checks the password of a user, throttled by the login guard like the HTTP logins.
+// The unknown users are answered like the wrong passwords. The failed logins are forgotten by the caller.
+func (server *Server) checkCredentials(ctx context.Context, username string, password string) (db.User, error) {
+	clientIP := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		clientIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(clientIP); err == nil {
+			clientIP = host
+		}
+	}
+	err := server.loginGuard.Reserve(ctx, username, clientIP)
+	if err != nil {
+		var throttled *lockout.ThrottledError
+		if errors.As(err, &throttled) {
+			return db.User{}, status.Error(codes.ResourceExhausted, throttled.Error())
+		}
+		return db.User{}, status.Errorf(codes.Internal, "failed to check failed logins: %v", err)
+	}
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil && err != sql.ErrNoRows {
+		if err := server.loginGuard.Release(ctx, username, clientIP); err != nil {
+			log.Printf("cannot release the login of %q: %v", username, err)
+		}
+		return db.User{}, status.Errorf(codes.Internal, "failed to find user: %v", err)
+	}
+	if err == sql.ErrNoRows {
+		err = util.CheckUnknownUserPassword(password)
+	} else {
+		err = util.CheckPassword(password, user.HashedPassword)
+	}
+	if err != nil {
+		err = server.loginGuard.Fail(ctx, username, clientIP)
+		if err != nil {
+			return db.User{}, status.Errorf(codes.Internal, "failed to record failed login: %v", err)
+		}
+		return db.User{}, status.Error(codes.Unauthenticated, "incorrect username or password")
+	}
+	err = server.loginGuard.Release(ctx, username, clientIP)
+	if err != nil {
+		return db.User{}, status.Errorf(codes.Internal, "failed to record login: %v", err)
+	}
+	return user, nil
+}
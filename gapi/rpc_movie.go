@@ -0,0 +1,212 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/pb"
+	"vk-film/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateMovie creates a movie, like POST /movie/create.
+func (server *Server) CreateMovie(ctx context.Context, req *pb.CreateMovieRequest) (*pb.CreateMovieResponse, error) {
+	payload, err := server.authorize(ctx, authz.MoviesWrite)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetName() == "" || req.GetDescription() == "" || req.GetRating() == "" || req.GetReleaseDate() == nil {
+		return nil, status.Error(codes.InvalidArgument, "name, description, release_date and rating are required")
+	}
+	if err := validateMovie(&req.Name, &req.Description, &req.Rating); err != nil {
+		return nil, err
+	}
+	arg := db.CreateMovieParams{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		ReleaseDate: req.GetReleaseDate().AsTime(),
+		Rating:      req.GetRating(),
+	}
+	candidates, err := server.store.ListMoviesByReleaseYear(ctx, arg.ReleaseDate)
+	if err != nil {
+		return nil, storeError(err, "failed to look for duplicates")
+	}
+	var movie db.Movie
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		var err error
+		movie, err = store.CreateMovieTx(ctx, db.CreateMovieTxParams{
+			CreateMovieParams: arg,
+			Username:          payload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionCreate, db.AuditEntityMovie, movie.ID, nil, movie)
+	})
+	if err != nil {
+		return nil, storeError(err, "failed to create movie")
+	}
+	duplicates := []db.Movie{}
+	for _, candidate := range candidates {
+		if util.IsLikelyDuplicateName(arg.Name, candidate.Name) {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+	rsp := &pb.CreateMovieResponse{
+		Movie:              convertMovie(movie),
+		PossibleDuplicates: convertMovies(duplicates),
+	}
+	return rsp, nil
+}
+
+// GetMovie returns a movie, like GET /movie.
+func (server *Server) GetMovie(ctx context.Context, req *pb.GetMovieRequest) (*pb.GetMovieResponse, error) {
+	movie, err := server.store.GetMovie(ctx, req.GetId())
+	if err != nil {
+		return nil, storeError(err, "failed to get movie")
+	}
+	return &pb.GetMovieResponse{Movie: convertMovie(movie)}, nil
+}
+
+// UpdateMovie changes the fields set in the request, like PATCH /movie/update.
+func (server *Server) UpdateMovie(ctx context.Context, req *pb.UpdateMovieRequest) (*pb.UpdateMovieResponse, error) {
+	payload, err := server.authorize(ctx, authz.MoviesWrite)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMovie(req.Name, req.Description, req.Rating); err != nil {
+		return nil, err
+	}
+	var movie db.Movie
+	err = server.store.ExecTx(ctx, func(store db.Store) error {
+		before, err := store.LockMovie(ctx, req.GetId())
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(req.ExpectedVersion, before.Version)
+		if err != nil {
+			return err
+		}
+		arg := db.UpdateMovieParams{
+			ID:              before.ID,
+			Name:            sql.NullString{String: req.GetName(), Valid: req.Name != nil},
+			Description:     sql.NullString{String: req.GetDescription(), Valid: req.Description != nil},
+			Rating:          sql.NullString{String: req.GetRating(), Valid: req.Rating != nil},
+			ReleaseDate:     sql.NullTime{Time: req.GetReleaseDate().AsTime(), Valid: req.ReleaseDate != nil},
+			ExpectedVersion: expected,
+		}
+		if !arg.Name.Valid && !arg.Description.Valid && !arg.Rating.Valid && !arg.ReleaseDate.Valid {
+			movie = before
+			return nil
+		}
+		movie, err = store.UpdateMovieTx(ctx, db.UpdateMovieTxParams{
+			UpdateMovieParams: arg,
+			Username:          payload.Username,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionUpdate, db.AuditEntityMovie, movie.ID, before, movie)
+	})
+	if err != nil {
+		return nil, storeError(err, "failed to update movie")
+	}
+	return &pb.UpdateMovieResponse{Movie: convertMovie(movie)}, nil
+}
+
+// DeleteMovie deletes a movie, like DELETE /movie/delete.
+func (server *Server) DeleteMovie(ctx context.Context, req *pb.DeleteMovieRequest) (*pb.DeleteMovieResponse, error) {
+	if _, err := server.authorize(ctx, authz.MoviesDelete); err != nil {
+		return nil, err
+	}
+	var before db.Movie
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		var err error
+		before, err = store.LockMovie(ctx, req.GetId())
+		if err != nil {
+			return err
+		}
+		expected, err := server.expectedVersion(req.ExpectedVersion, before.Version)
+		if err != nil {
+			return err
+		}
+		deleted, err := store.DeleteMovie(ctx, db.DeleteMovieParams{
+			ID:              before.ID,
+			ExpectedVersion: expected,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errVersionMismatch
+		}
+		return recordAudit(ctx, store, db.AuditActionDelete, db.AuditEntityMovie, before.ID, before, nil)
+	})
+	if err != nil {
+		return nil, storeError(err, "failed to delete movie")
+	}
+	return &pb.DeleteMovieResponse{Id: before.ID}, nil
+}
+
+// MergeMovies merges a duplicate movie into a survivor, like POST /movie/merge.
+func (server *Server) MergeMovies(ctx context.Context, req *pb.MergeMoviesRequest) (*pb.MergeMoviesResponse, error) {
+	if _, err := server.authorize(ctx, authz.MoviesDelete); err != nil {
+		return nil, err
+	}
+	var movie db.Movie
+	err := server.store.ExecTx(ctx, func(store db.Store) error {
+		duplicate, err := store.GetMovie(ctx, req.GetDuplicateId())
+		if err != nil {
+			return err
+		}
+		movie, err = store.MergeMoviesTx(ctx, db.MergeTxParams{
+			SurvivorID:  req.GetSurvivorId(),
+			DuplicateID: req.GetDuplicateId(),
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, store, db.AuditActionMerge, db.AuditEntityMovie, duplicate.ID, duplicate, map[string]int32{"merged_into": movie.ID})
+	})
+	if err != nil {
+		if err == db.ErrMergeSameRecord {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, storeError(err, "failed to merge movies")
+	}
+	return &pb.MergeMoviesResponse{Movie: convertMovie(movie)}, nil
+}
+
+// ListMovies streams the movies of the catalog, like the GET /movies routes.
+func (server *Server) ListMovies(req *pb.ListMoviesRequest, stream pb.CatalogService_ListMoviesServer) error {
+	ctx := stream.Context()
+	if req.GetNameFragment() != "" && req.GetActorFragment() != "" {
+		return status.Error(codes.InvalidArgument, "name_fragment and actor_fragment cannot be combined")
+	}
+	var movies []db.Movie
+	var err error
+	switch {
+	case req.GetNameFragment() != "":
+		movies, err = server.store.GetMoviesByNameFragment(ctx, sql.NullString{String: req.GetNameFragment(), Valid: true})
+	case req.GetActorFragment() != "":
+		movies, err = server.store.GetMoviesByActorFragment(ctx, sql.NullString{String: req.GetActorFragment(), Valid: true})
+	case req.GetSort() == pb.MovieSort_MOVIE_SORT_NAME:
+		movies, err = server.store.GetMoviesSortedByName(ctx)
+	case req.GetSort() == pb.MovieSort_MOVIE_SORT_RELEASE_DATE:
+		movies, err = server.store.GetMoviesByReleaseDate(ctx)
+	default:
+		movies, err = server.store.GetMoviesSortedByRating(ctx)
+	}
+	if err != nil {
+		return storeError(err, "failed to list movies")
+	}
+	for _, movie := range movies {
+		if err := stream.Send(convertMovie(movie)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package gapi
+
+import (
+	"vk-film/authz"
+	db "vk-film/db/sqlc"
+	"vk-film/lockout"
+	"vk-film/pb"
+	"vk-film/token"
+	"vk-film/util"
+)
+
+// Server serves the gRPC requests of the catalog and auth services.
+type Server struct {
+	pb.UnimplementedCatalogServiceServer
+	pb.UnimplementedAuthServiceServer
+	config      util.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	revocations *token.RevocationList
+	authorizer  *authz.Authorizer
+	loginGuard  *lockout.Guard
+}
+
+// NewServer creates a new gRPC server sharing the token maker, the revocation list, the authorizer and the login guard
+// of the HTTP server, so that both see the same keys, revocations, permissions and login throttles.
+func NewServer(config util.Config, store db.Store, tokenMaker token.Maker, revocations *token.RevocationList, authorizer *authz.Authorizer, loginGuard *lockout.Guard) *Server {
+	return &Server{
+		config:      config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: revocations,
+		authorizer:  authorizer,
+		loginGuard:  loginGuard,
+	}
+}
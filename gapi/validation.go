@@ -0,0 +1,40 @@
+package gapi
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+	"vk-film/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// validateMovie checks the fields of a movie against the rules of the HTTP API, the fields left nil are not checked.
+func validateMovie(name, description, rating *string) error {
+	if name != nil && (*name == "" || utf8.RuneCountInString(*name) > util.MaxMovieNameLength) {
+		return status.Errorf(codes.InvalidArgument, "name must have from 1 to %d characters", util.MaxMovieNameLength)
+	}
+	if description != nil && utf8.RuneCountInString(*description) > util.MaxMovieDescriptionLength {
+		return status.Errorf(codes.InvalidArgument, "description must be at most %d characters", util.MaxMovieDescriptionLength)
+	}
+	if rating != nil && !util.IsValidRating(*rating) {
+		return status.Error(codes.InvalidArgument, "rating must be a number from 0 to 10 with at most one decimal")
+	}
+	return nil
+}
+
+// validateActor checks the fields of an actor against the rules of the HTTP API, the fields left nil are not checked.
+func validateActor(name, gender *string, birthday *timestamppb.Timestamp) error {
+	if name != nil && (*name == "" || utf8.RuneCountInString(*name) > util.MaxActorNameLength) {
+		return status.Errorf(codes.InvalidArgument, "name must have from 1 to %d characters", util.MaxActorNameLength)
+	}
+	if gender != nil && !util.IsSupportedGender(*gender) {
+		return status.Errorf(codes.InvalidArgument, "gender must be one of %s", strings.Join(util.Genders, ", "))
+	}
+	if birthday != nil && birthday.AsTime().After(time.Now()) {
+		return status.Error(codes.InvalidArgument, "birthday must not be in the future")
+	}
+	return nil
+}
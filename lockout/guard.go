@@ -0,0 +1,255 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	db "vk-film/db/sqlc"
+)
+
+// The events recorded in the security log.
+const (
+	EventAccountLocked = "account_locked"
+	EventIPLocked      = "ip_locked"
+)
+
+// ThrottledError rejects a login attempted too soon after the failed ones, or while the account or the client IP is locked.
+type ThrottledError struct {
+	// the time to wait before the next attempt
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (err *ThrottledError) Error() string {
+	if err.Locked {
+		return fmt.Sprintf("too many failed logins, locked for %s", err.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many failed logins, try again in %s", err.RetryAfter.Round(time.Second))
+}
+
+// Config sets the limits of a Guard.
+type Config struct {
+	// the failures of an account within Window locking it
+	MaxFailures int
+	// the failures from a client IP within Window locking it, on any account
+	MaxIPFailures int
+	// the failures older than Window are forgotten
+	Window time.Duration
+	// the wait after a failure, doubled by every next failure up to MaxDelay
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// how long an account or a client IP stays locked
+	LockoutDuration time.Duration
+}
+
+// Guard protects the password checks from brute force. It tracks the failed logins of every account and of every
+// client IP in the store, so that the limits hold across the servers, makes the next attempt wait longer after every
+// failure and locks the account or the client IP once its failures reach the limit, recording the lockout in the security log.
+// The unknown usernames are tracked like the existing ones, so that the throttling does not reveal which users exist.
+type Guard struct {
+	store  db.Store
+	config Config
+
+	mu        sync.Mutex
+	cleanedAt time.Time
+}
+
+// NewGuard creates a guard enforcing the limits of config.
+func NewGuard(store db.Store, config Config) (*Guard, error) {
+	if config.MaxFailures <= 0 || config.MaxIPFailures <= 0 {
+		return nil, errors.New("the maximum numbers of failed logins must be positive")
+	}
+	if config.Window <= 0 || config.LockoutDuration <= 0 {
+		return nil, errors.New("the failure window and the lockout duration must be positive")
+	}
+	if config.Delay < 0 || config.MaxDelay < config.Delay {
+		return nil, errors.New("the login delay must not be negative nor exceed the maximum delay")
+	}
+	return &Guard{store: store, config: config}, nil
+}
+
+// Reserve counts a login of username from clientIP as pending while its password is checked, so that the concurrent
+// attempts cannot all pass the limits checked before any of them failed. It returns a *ThrottledError when the login
+// must wait. The caller then reports the outcome of the check: Release when it passed, or Fail when it did not.
+// The reservations are kept apart from the failures, a login that passes leaves the failures as they were.
+func (guard *Guard) Reserve(ctx context.Context, username string, clientIP string) error {
+	err := guard.check(ctx, username, clientIP)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	windowStart := now.Add(-guard.config.Window)
+	var throttled *ThrottledError
+	for _, limit := range guard.limits(username, clientIP) {
+		throttle, err := guard.store.ReserveLoginAttempt(ctx, db.ReserveLoginAttemptParams{
+			Key:         limit.key,
+			ReservedAt:  now,
+			WindowStart: windowStart,
+		})
+		if err != nil {
+			return err
+		}
+		failures := throttle.Failures
+		if throttle.LastFailureAt.Before(windowStart) {
+			failures = 0
+		}
+		// the attempts in flight reached the limit already, this one is not checked
+		if attempts := failures + throttle.Pending; int(attempts) > limit.maxFailures {
+			throttled = &ThrottledError{RetryAfter: guard.delay(attempts)}
+			if throttled.RetryAfter < time.Second {
+				throttled.RetryAfter = time.Second
+			}
+		}
+	}
+	if throttled != nil {
+		err = guard.Release(ctx, username, clientIP)
+		if err != nil {
+			return err
+		}
+		return throttled
+	}
+	return nil
+}
+
+// check returns a *ThrottledError when a login of username from clientIP must wait after the failed ones.
+func (guard *Guard) check(ctx context.Context, username string, clientIP string) error {
+	throttles, err := guard.store.ListLoginThrottles(ctx, []string{accountKey(username), ipKey(clientIP)})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	throttled := &ThrottledError{}
+	for _, throttle := range throttles {
+		if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(now) {
+			wait := throttle.LockedUntil.Time.Sub(now)
+			if !throttled.Locked || wait > throttled.RetryAfter {
+				throttled.RetryAfter, throttled.Locked = wait, true
+			}
+			continue
+		}
+		// the pending attempts leave no failure to wait after
+		if throttled.Locked || throttle.Failures == 0 || throttle.LastFailureAt.Before(now.Add(-guard.config.Window)) {
+			continue
+		}
+		wait := throttle.LastFailureAt.Add(guard.delay(throttle.Failures)).Sub(now)
+		if wait > throttled.RetryAfter {
+			throttled.RetryAfter = wait
+		}
+	}
+	if throttled.RetryAfter > 0 {
+		return throttled
+	}
+	return nil
+}
+
+// Release uncounts a login reserved by Reserve whose check passed.
+func (guard *Guard) Release(ctx context.Context, username string, clientIP string) error {
+	return guard.store.ReleaseLoginAttempts(ctx, []string{accountKey(username), ipKey(clientIP)})
+}
+
+// Fail confirms a login reserved by Reserve as failed, locking the account or the client IP that reached its limit.
+// A lock starts the count of the failures over, so that the attempts after it get the full limit again.
+func (guard *Guard) Fail(ctx context.Context, username string, clientIP string) error {
+	now := time.Now()
+	for _, limit := range guard.limits(username, clientIP) {
+		throttle, err := guard.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Key:         limit.key,
+			FailedAt:    now,
+			WindowStart: now.Add(-guard.config.Window),
+		})
+		if err != nil {
+			return err
+		}
+		if int(throttle.Failures) < limit.maxFailures {
+			continue
+		}
+		// the concurrent failures lock it once
+		if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(now) {
+			continue
+		}
+		lockedUntil := now.Add(guard.config.LockoutDuration)
+		err = guard.store.LockLogin(ctx, db.LockLoginParams{
+			Key:         limit.key,
+			LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		details := fmt.Sprintf("%d failed logins within %s, locked until %s", throttle.Failures, guard.config.Window, lockedUntil.Format(time.RFC3339))
+		log.Printf("security: %s, username %q, client IP %s: %s", limit.event, username, clientIP, details)
+		err = guard.store.CreateSecurityEvent(ctx, db.CreateSecurityEventParams{
+			Event:    limit.event,
+			Username: sql.NullString{String: username, Valid: username != ""},
+			ClientIp: clientIP,
+			Details:  details,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	guard.cleanup(ctx, now)
+	return nil
+}
+
+// limit is the maximum number of failures of an account or a client IP, and the event recorded when it is locked.
+type limit struct {
+	key         string
+	maxFailures int
+	event       string
+}
+
+func (guard *Guard) limits(username string, clientIP string) []limit {
+	return []limit{
+		{accountKey(username), guard.config.MaxFailures, EventAccountLocked},
+		{ipKey(clientIP), guard.config.MaxIPFailures, EventIPLocked},
+	}
+}
+
+// Succeed forgets the failed logins of an account after a successful one. The failures of the client IP are kept,
+// a valid password of one account does not vouch for the attempts made on the other ones.
+func (guard *Guard) Succeed(ctx context.Context, username string) error {
+	return guard.store.DeleteLoginThrottle(ctx, accountKey(username))
+}
+
+// delay returns the wait after the failures of an account or a client IP.
+func (guard *Guard) delay(failures int32) time.Duration {
+	delay := guard.config.Delay
+	for i := int32(1); i < failures && delay < guard.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > guard.config.MaxDelay {
+		return guard.config.MaxDelay
+	}
+	return delay
+}
+
+// cleanup deletes the failures and the reservations older than the window, at most once per window.
+func (guard *Guard) cleanup(ctx context.Context, now time.Time) {
+	guard.mu.Lock()
+	due := now.Sub(guard.cleanedAt) >= guard.config.Window
+	if due {
+		guard.cleanedAt = now
+	}
+	guard.mu.Unlock()
+	if !due {
+		return
+	}
+	err := guard.store.DeleteStaleLoginThrottles(ctx, now.Add(-guard.config.Window))
+	if err != nil {
+		log.Printf("cannot delete the stale failed logins: %v", err)
+	}
+}
+
+// accountKey is the key of the failures of an account, the usernames differing only by case share it.
+func accountKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipKey(clientIP string) string {
+	return "ip:" + clientIP
+}
@@ -0,0 +1,283 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+// throttleStore keeps the login throttles and the security events in memory like the queries do,
+// the other methods of the store are not used by the guard.
+type throttleStore struct {
+	db.Store
+	mu        sync.Mutex
+	throttles map[string]db.LoginThrottle
+	events    []db.CreateSecurityEventParams
+}
+
+func newThrottleStore() *throttleStore {
+	return &throttleStore{throttles: map[string]db.LoginThrottle{}}
+}
+
+func (store *throttleStore) ListLoginThrottles(ctx context.Context, keys []string) ([]db.LoginThrottle, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	throttles := []db.LoginThrottle{}
+	for _, key := range keys {
+		if throttle, ok := store.throttles[key]; ok {
+			throttles = append(throttles, throttle)
+		}
+	}
+	return throttles, nil
+}
+
+func (store *throttleStore) ReserveLoginAttempt(ctx context.Context, arg db.ReserveLoginAttemptParams) (db.LoginThrottle, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	throttle, ok := store.throttles[arg.Key]
+	if !ok {
+		throttle = db.LoginThrottle{Key: arg.Key, LastFailureAt: arg.ReservedAt}
+	} else if throttle.ReservedAt.Before(arg.WindowStart) {
+		throttle.Pending = 0
+	}
+	throttle.Pending++
+	throttle.ReservedAt = arg.ReservedAt
+	store.throttles[arg.Key] = throttle
+	return throttle, nil
+}
+
+func (store *throttleStore) RecordLoginFailure(ctx context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	throttle, ok := store.throttles[arg.Key]
+	if !ok {
+		throttle = db.LoginThrottle{Key: arg.Key}
+	} else if throttle.LastFailureAt.Before(arg.WindowStart) {
+		throttle.Failures = 0
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = arg.FailedAt
+	if throttle.Pending > 0 {
+		throttle.Pending--
+	}
+	store.throttles[arg.Key] = throttle
+	return throttle, nil
+}
+
+func (store *throttleStore) ReleaseLoginAttempts(ctx context.Context, keys []string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, key := range keys {
+		if throttle, ok := store.throttles[key]; ok && throttle.Pending > 0 {
+			throttle.Pending--
+			store.throttles[key] = throttle
+		}
+	}
+	return nil
+}
+
+func (store *throttleStore) LockLogin(ctx context.Context, arg db.LockLoginParams) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if throttle, ok := store.throttles[arg.Key]; ok {
+		throttle.LockedUntil = arg.LockedUntil
+		throttle.Failures = 0
+		store.throttles[arg.Key] = throttle
+	}
+	return nil
+}
+
+func (store *throttleStore) DeleteLoginThrottle(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.throttles, key)
+	return nil
+}
+
+func (store *throttleStore) DeleteStaleLoginThrottles(ctx context.Context, lastFailureAt time.Time) error {
+	return nil
+}
+
+func (store *throttleStore) CreateSecurityEvent(ctx context.Context, arg db.CreateSecurityEventParams) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.events = append(store.events, arg)
+	return nil
+}
+
+// shift moves the stored times back, as if d had passed.
+func (store *throttleStore) shift(d time.Duration) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for key, throttle := range store.throttles {
+		throttle.LastFailureAt = throttle.LastFailureAt.Add(-d)
+		throttle.ReservedAt = throttle.ReservedAt.Add(-d)
+		if throttle.LockedUntil.Valid {
+			throttle.LockedUntil.Time = throttle.LockedUntil.Time.Add(-d)
+		}
+		store.throttles[key] = throttle
+	}
+}
+
+func (store *throttleStore) throttle(key string) db.LoginThrottle {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.throttles[key]
+}
+
+var testConfig = Config{
+	MaxFailures:     3,
+	MaxIPFailures:   5,
+	Window:          time.Hour,
+	Delay:           time.Second,
+	MaxDelay:        4 * time.Second,
+	LockoutDuration: 15 * time.Minute,
+}
+
+func newTestGuard(t *testing.T) (*Guard, *throttleStore) {
+	store := newThrottleStore()
+	guard, err := NewGuard(store, testConfig)
+	require.NoError(t, err)
+	return guard, store
+}
+
+// failLogin reserves a login and reports it failed, waiting out the delay of the previous failures first.
+func failLogin(t *testing.T, guard *Guard, store *throttleStore, username string, clientIP string) {
+	store.shift(testConfig.MaxDelay)
+	require.NoError(t, guard.Reserve(context.Background(), username, clientIP))
+	require.NoError(t, guard.Fail(context.Background(), username, clientIP))
+}
+
+func TestNewGuardRejectsInvalidConfig(t *testing.T) {
+	store := newThrottleStore()
+	for _, config := range []Config{
+		{MaxFailures: 0, MaxIPFailures: 5, Window: time.Hour, LockoutDuration: time.Minute},
+		{MaxFailures: 3, MaxIPFailures: 5, Window: 0, LockoutDuration: time.Minute},
+		{MaxFailures: 3, MaxIPFailures: 5, Window: time.Hour, LockoutDuration: time.Minute, Delay: 2 * time.Second, MaxDelay: time.Second},
+	} {
+		_, err := NewGuard(store, config)
+		require.Error(t, err)
+	}
+}
+
+func TestGuardDelay(t *testing.T) {
+	guard, _ := newTestGuard(t)
+	for failures, delay := range map[int32]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		9: 4 * time.Second,
+	} {
+		require.Equal(t, delay, guard.delay(failures), "delay after %d failures", failures)
+	}
+}
+
+func TestGuardReleaseKeepsFailureWindow(t *testing.T) {
+	guard, store := newTestGuard(t)
+	ctx := context.Background()
+	failLogin(t, guard, store, "alice", "10.0.0.1")
+	failedAt := store.throttle(ipKey("10.0.0.1")).LastFailureAt
+
+	store.shift(testConfig.MaxDelay)
+	require.NoError(t, guard.Reserve(ctx, "alice", "10.0.0.1"))
+	require.Equal(t, int32(1), store.throttle(accountKey("alice")).Pending)
+	require.NoError(t, guard.Release(ctx, "alice", "10.0.0.1"))
+
+	throttle := store.throttle(ipKey("10.0.0.1"))
+	require.Zero(t, throttle.Pending)
+	require.Equal(t, int32(1), throttle.Failures)
+	require.Equal(t, failedAt.Add(-testConfig.MaxDelay), throttle.LastFailureAt)
+}
+
+func TestGuardFailDelaysNextAttempt(t *testing.T) {
+	guard, store := newTestGuard(t)
+	ctx := context.Background()
+	require.NoError(t, guard.Reserve(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, guard.Fail(ctx, "alice", "10.0.0.1"))
+
+	err := guard.Reserve(ctx, "alice", "10.0.0.1")
+	var throttled *ThrottledError
+	require.ErrorAs(t, err, &throttled)
+	require.False(t, throttled.Locked)
+	require.InDelta(t, testConfig.Delay, throttled.RetryAfter, float64(100*time.Millisecond))
+
+	store.shift(testConfig.Delay)
+	require.NoError(t, guard.Reserve(ctx, "alice", "10.0.0.1"))
+}
+
+func TestGuardLocksAccount(t *testing.T) {
+	guard, store := newTestGuard(t)
+	ctx := context.Background()
+	for i := 0; i < testConfig.MaxFailures; i++ {
+		failLogin(t, guard, store, "alice", "10.0.0.1")
+	}
+	require.Len(t, store.events, 1)
+	require.Equal(t, EventAccountLocked, store.events[0].Event)
+	require.Equal(t, "alice", store.events[0].Username.String)
+
+	// another IP is locked out of the account too
+	store.shift(testConfig.MaxDelay)
+	err := guard.Reserve(ctx, "Alice", "10.0.0.2")
+	var throttled *ThrottledError
+	require.ErrorAs(t, err, &throttled)
+	require.True(t, throttled.Locked)
+	require.InDelta(t, testConfig.LockoutDuration-testConfig.MaxDelay, throttled.RetryAfter, float64(time.Second))
+
+	// the lock expires with a fresh count
+	store.shift(testConfig.LockoutDuration)
+	require.NoError(t, guard.Reserve(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, guard.Fail(ctx, "alice", "10.0.0.1"))
+	require.Len(t, store.events, 1)
+}
+
+func TestGuardLocksClientIP(t *testing.T) {
+	guard, store := newTestGuard(t)
+	usernames := []string{"alice", "bob", "carol", "dave", "erin"}
+	for _, username := range usernames {
+		failLogin(t, guard, store, username, "10.0.0.1")
+	}
+	require.Len(t, store.events, 1)
+	require.Equal(t, EventIPLocked, store.events[0].Event)
+
+	err := guard.Reserve(context.Background(), "frank", "10.0.0.1")
+	var throttled *ThrottledError
+	require.ErrorAs(t, err, &throttled)
+	require.True(t, throttled.Locked)
+}
+
+func TestGuardSucceedForgetsAccountFailures(t *testing.T) {
+	guard, store := newTestGuard(t)
+	ctx := context.Background()
+	failLogin(t, guard, store, "alice", "10.0.0.1")
+	store.shift(testConfig.MaxDelay)
+	require.NoError(t, guard.Reserve(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, guard.Release(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, guard.Succeed(ctx, "alice"))
+
+	_, ok := store.throttles[accountKey("alice")]
+	require.False(t, ok)
+	require.Equal(t, int32(1), store.throttle(ipKey("10.0.0.1")).Failures)
+}
+
+// TestGuardReserveLimitsConcurrentAttempts checks that the attempts in flight count against the limit before any fails.
+func TestGuardReserveLimitsConcurrentAttempts(t *testing.T) {
+	guard, store := newTestGuard(t)
+	ctx := context.Background()
+	for i := 0; i < testConfig.MaxFailures; i++ {
+		require.NoError(t, guard.Reserve(ctx, "alice", "10.0.0.1"))
+	}
+	err := guard.Reserve(ctx, "alice", "10.0.0.1")
+	var throttled *ThrottledError
+	require.ErrorAs(t, err, &throttled)
+	require.False(t, throttled.Locked)
+	require.Equal(t, int32(testConfig.MaxFailures), store.throttle(accountKey("alice")).Pending)
+
+	// a reservation left by a crashed server expires with the window
+	store.shift(testConfig.Window + time.Second)
+	require.NoError(t, guard.Reserve(ctx, "alice", "10.0.0.1"))
+	require.Equal(t, int32(1), store.throttle(accountKey("alice")).Pending)
+}
@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// The mailers selectable with MAILER.
+const (
+	TypeSMTP   = "smtp"
+	TypeOutbox = "outbox"
+)
+
+var errHeaderInjection = errors.New("mail header values cannot contain line breaks")
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures the mailer created by NewMailer.
+type Config struct {
+	Type string
+	// the From header of every email
+	From string
+	// used by the SMTP mailer
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// used by the outbox mailer
+	OutboxDir string
+}
+
+// NewMailer creates the mailer of config.Type.
+func NewMailer(config Config) (Mailer, error) {
+	switch config.Type {
+	case TypeSMTP:
+		return NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.From)
+	case TypeOutbox:
+		return NewOutboxMailer(config.OutboxDir, config.From)
+	default:
+		return nil, fmt.Errorf("unsupported mailer type %q", config.Type)
+	}
+}
+
+// format renders a message as an RFC 5322 email, the subject is encoded for the non-ASCII characters.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
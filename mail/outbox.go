@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OutboxMailer writes every email to a file of a directory instead of sending it, for the local development and the tests.
+// The files are named by the time they were written, so that listing the directory lists the emails in order.
+type OutboxMailer struct {
+	dir  string
+	from string
+}
+
+// NewOutboxMailer creates a mailer writing the emails to dir, which is created if needed.
+func NewOutboxMailer(dir string, from string) (*OutboxMailer, error) {
+	if dir == "" {
+		return nil, errors.New("the outbox directory is required")
+	}
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxMailer{dir: dir, from: from}, nil
+}
+
+// Send writes a message to a new .eml file of the outbox.
+func (mailer *OutboxMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(mailer.from, msg, now)
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	path := filepath.Join(mailer.dir, name)
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return err
+	}
+	log.Printf("email %q to %s written to %s", msg.Subject, msg.To, path)
+	return nil
+}
@@ -0,0 +1,102 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer sends the emails through an SMTP server, upgrading the connection with STARTTLS when the server offers it.
+type SMTPMailer struct {
+	host     string
+	addr     string
+	auth     smtp.Auth
+	from     string
+	envelope string
+}
+
+// NewSMTPMailer creates a mailer sending from the from address through the SMTP server at host:port.
+// Without a username the emails are sent without authentication.
+func NewSMTPMailer(host string, port int, username string, password string, from string) (*SMTPMailer, error) {
+	if host == "" {
+		return nil, errors.New("the SMTP host is required")
+	}
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, err
+	}
+	mailer := &SMTPMailer{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		from:     from,
+		envelope: address.Address,
+	}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return mailer, nil
+}
+
+// Send delivers a message, giving up when ctx is done.
+func (mailer *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	data, err := format(mailer.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", mailer.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, mailer.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: mailer.host})
+		if err != nil {
+			return err
+		}
+	}
+	if mailer.auth != nil {
+		err = client.Auth(mailer.auth)
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(mailer.envelope)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(to.Address)
+	if err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
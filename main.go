@@ -1,12 +1,17 @@
-// swagger:meta
 package main
 
 import (
 	"database/sql"
 	"log"
+	"net"
 	"vk-film/api"
 	db "vk-film/db/sqlc"
+	"vk-film/gapi"
+	"vk-film/pb"
 	"vk-film/util"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
@@ -19,18 +24,43 @@ func main() {
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
-	store := db.NewStore(conn)
-	runGinServer(config, store)
-
-}
-
-func runGinServer(config util.Config, store db.Store) {
+	var store db.Store = db.NewStore(conn)
+	if config.CacheSize > 0 {
+		store = db.NewCachedStore(store, config.CacheSize, config.CacheTTL)
+	}
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
-	err = server.Start(config.HTTPServerAddress)
+	go runGinServer(config, server)
+	runGrpcServer(config, store, server.Auth())
+
+}
+
+func runGinServer(config util.Config, server *api.Server) {
+	err := server.Start(config.HTTPServerAddress)
 	if err != nil {
 		log.Fatal("cannot start server:", err)
 	}
 }
+
+func runGrpcServer(config util.Config, store db.Store, auth api.Auth) {
+	server := gapi.NewServer(config, store, auth.TokenMaker, auth.Revocations, auth.Authorizer, auth.LoginGuard)
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(server.UnaryAuthInterceptor),
+		grpc.StreamInterceptor(server.StreamAuthInterceptor),
+	)
+	pb.RegisterCatalogServiceServer(grpcServer, server)
+	pb.RegisterAuthServiceServer(grpcServer, server)
+	reflection.Register(grpcServer)
+
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
+	if err != nil {
+		log.Fatal("cannot create listener:", err)
+	}
+	log.Printf("start gRPC server at %s", listener.Addr().String())
+	err = grpcServer.Serve(listener)
+	if err != nil {
+		log.Fatal("cannot start gRPC server:", err)
+	}
+}
@@ -0,0 +1,346 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// discoveryTTL is how long the configuration of the provider is used before it is fetched again
+	discoveryTTL = time.Hour
+	// keysTTL is how long the signing keys of the provider are used before they are fetched again,
+	// an ID token signed with an unknown key fetches them right away, at most every keysMinInterval
+	keysTTL         = time.Hour
+	keysMinInterval = time.Minute
+	// maxResponseSize bounds the responses read from the provider
+	maxResponseSize = 1 << 20
+)
+
+// The signing algorithms accepted for the ID tokens.
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Error is an error response of the provider, to the authorization request or to the token request.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (err *Error) Error() string {
+	if err.Description != "" {
+		return fmt.Sprintf("identity provider error %s: %s", err.Code, err.Description)
+	}
+	return fmt.Sprintf("identity provider error %s", err.Code)
+}
+
+// Config sets the client registered with an OpenID Connect provider.
+type Config struct {
+	// the issuer URL, its /.well-known/openid-configuration describes the provider
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// the URL the provider redirects the browser to with the authorization code
+	RedirectURL string
+	Scopes      []string
+	// the claim of the ID token listing the groups of the user
+	GroupsClaim string
+}
+
+// Identity is the user authenticated by the provider, read from the claims of a verified ID token.
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Groups            []string
+}
+
+// Provider signs the users in with an OpenID Connect provider, by the authorization code flow with PKCE.
+// The configuration and the signing keys of the provider are fetched on first use and cached,
+// so that the server starts while the provider is unreachable.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu           sync.Mutex
+	discovery    discovery
+	discoveredAt time.Time
+	keys         map[string]crypto.PublicKey
+	keysLoadedAt time.Time
+}
+
+// discovery is the part of the provider configuration the client uses.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider creates the client of the provider of config.
+func NewProvider(config Config) (*Provider, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("the issuer, the client ID and the redirect URL of the OpenID Connect provider are required")
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid"}
+	}
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Issuer returns the issuer URL of the provider, which scopes the subjects of its users.
+func (provider *Provider) Issuer() string {
+	return provider.config.Issuer
+}
+
+// NewCodeVerifier generates the PKCE code verifier of a login, kept by the server until the code is exchanged.
+func NewCodeVerifier() (string, error) {
+	return randomString()
+}
+
+// NewNonce generates the nonce of a login, which the ID token must carry back.
+func NewNonce() (string, error) {
+	return randomString()
+}
+
+// CodeChallenge derives the S256 PKCE challenge sent with the authorization request from a code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+// AuthCodeURL returns the URL of the provider the browser is sent to, to sign in.
+func (provider *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	discovery, err := provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.config.ClientID},
+		"redirect_uri":          {provider.config.RedirectURL},
+		"scope":                 {strings.Join(provider.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange exchanges an authorization code, with the code verifier of its login, for an ID token
+// and returns the identity it carries once verified: signed by the provider, issued for this client and carrying the nonce.
+func (provider *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (Identity, error) {
+	discovery, err := provider.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {provider.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {provider.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if provider.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(provider.config.ClientID), url.QueryEscape(provider.config.ClientSecret))
+	}
+	var rsp struct {
+		IDToken string `json:"id_token"`
+		Error
+	}
+	status, err := provider.do(req, &rsp)
+	if err != nil {
+		return Identity{}, err
+	}
+	if rsp.Code != "" {
+		return Identity{}, &rsp.Error
+	}
+	if status != http.StatusOK || rsp.IDToken == "" {
+		return Identity{}, fmt.Errorf("unexpected token response of the identity provider, status %d", status)
+	}
+	return provider.verify(ctx, discovery, rsp.IDToken, nonce)
+}
+
+// verify checks an ID token and reads the identity it carries.
+func (provider *Provider) verify(ctx context.Context, discovery discovery, idToken string, nonce string) (Identity, error) {
+	parser := jwt.Parser{ValidMethods: idTokenAlgorithms}
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(idToken, claims, func(jwToken *jwt.Token) (interface{}, error) {
+		kid, _ := jwToken.Header["kid"].(string)
+		return provider.key(ctx, discovery, kid)
+	})
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if !claims.VerifyIssuer(discovery.Issuer, true) {
+		return Identity{}, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+	if !claims.VerifyAudience(provider.config.ClientID, true) {
+		return Identity{}, fmt.Errorf("%w: not issued for this client", ErrInvalidIDToken)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != provider.config.ClientID {
+		return Identity{}, fmt.Errorf("%w: authorized for another client", ErrInvalidIDToken)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return Identity{}, fmt.Errorf("%w: no expiry", ErrInvalidIDToken)
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return Identity{}, fmt.Errorf("%w: unexpected nonce", ErrInvalidIDToken)
+	}
+	identity := Identity{Issuer: provider.config.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	identity.Email, _ = claims["email"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		// some providers send the boolean claims as strings
+		identity.EmailVerified = verified == "true"
+	}
+	identity.PreferredUsername, _ = claims["preferred_username"].(string)
+	if provider.config.GroupsClaim != "" {
+		switch groups := claims[provider.config.GroupsClaim].(type) {
+		case []interface{}:
+			for _, group := range groups {
+				if name, ok := group.(string); ok {
+					identity.Groups = append(identity.Groups, name)
+				}
+			}
+		case string:
+			identity.Groups = []string{groups}
+		}
+	}
+	return identity, nil
+}
+
+// discover returns the configuration of the provider, fetching it when it is older than discoveryTTL.
+func (provider *Provider) discover(ctx context.Context) (discovery, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if !provider.discoveredAt.IsZero() && time.Since(provider.discoveredAt) < discoveryTTL {
+		return provider.discovery, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return discovery{}, err
+	}
+	var fetched discovery
+	status, err := provider.do(req, &fetched)
+	if err != nil {
+		return discovery{}, err
+	}
+	if status != http.StatusOK {
+		return discovery{}, fmt.Errorf("cannot discover the identity provider, status %d", status)
+	}
+	if strings.TrimSuffix(fetched.Issuer, "/") != provider.config.Issuer {
+		return discovery{}, fmt.Errorf("the identity provider names issuer %q instead of %q", fetched.Issuer, provider.config.Issuer)
+	}
+	if fetched.AuthorizationEndpoint == "" || fetched.TokenEndpoint == "" || fetched.JWKSURI == "" {
+		return discovery{}, errors.New("the identity provider configuration lacks an endpoint")
+	}
+	provider.discovery, provider.discoveredAt = fetched, time.Now()
+	return fetched, nil
+}
+
+// key returns a signing key of the provider by ID, fetching the keys when the ID is unknown or they are older than keysTTL.
+func (provider *Provider) key(ctx context.Context, discovery discovery, kid string) (crypto.PublicKey, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	key, ok := provider.keys[kid]
+	age := time.Since(provider.keysLoadedAt)
+	if (ok && age < keysTTL) || (!ok && provider.keys != nil && age < keysMinInterval) {
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	status, err := provider.do(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch the keys of the identity provider, status %d", status)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// the keys of unsupported types are skipped, the tokens they sign are rejected
+		if publicKey, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = publicKey
+		}
+	}
+	provider.keys, provider.keysLoadedAt = keys, time.Now()
+	key, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// do sends a request to the provider and decodes its JSON response, whatever its status.
+func (provider *Provider) do(req *http.Request, v interface{}) (int, error) {
+	rsp, err := provider.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(rsp.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return rsp.StatusCode, fmt.Errorf("invalid response of the identity provider, status %d: %v", rsp.StatusCode, err)
+	}
+	return rsp.StatusCode, nil
+}
@@ -0,0 +1,190 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID    = "vk-film"
+	testRedirectURL = "http://localhost:8080/v1/users/oidc/callback"
+	testCode        = "test-code"
+)
+
+// mockIssuer is a local OpenID Connect provider issuing an ID token for a single authorization code.
+type mockIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// the challenge and the nonce of the authorization request, checked by the token request
+	challenge string
+	nonce     string
+	// claims overrides the claims of the ID token
+	claims jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": "test-key",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != testCode || CodeChallenge(r.FormValue("code_verifier")) != issuer.challenge ||
+			r.FormValue("redirect_uri") != testRedirectURL || r.FormValue("client_id") != testClientID {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":            issuer.URL,
+			"aud":            testClientID,
+			"sub":            "248289761001",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          issuer.nonce,
+			"email":          "vk-user@example.com",
+			"email_verified": true,
+			"groups":         []string{"vk-editors"},
+		}
+		for name, value := range issuer.claims {
+			claims[name] = value
+		}
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		idToken.Header["kid"] = "test-key"
+		signed, err := idToken.SignedString(key)
+		require.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// authorize plays the browser signing in: it checks the authorization URL and keeps the challenge and the nonce it carries.
+func (issuer *mockIssuer) authorize(t *testing.T, authorizationURL string) {
+	parsed, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	require.Equal(t, issuer.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	require.Equal(t, "code", query.Get("response_type"))
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.Equal(t, testRedirectURL, query.Get("redirect_uri"))
+	issuer.challenge = query.Get("code_challenge")
+	issuer.nonce = query.Get("nonce")
+}
+
+func newTestProvider(t *testing.T, issuer *mockIssuer) *Provider {
+	provider, err := NewProvider(Config{
+		Issuer:      issuer.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+		Scopes:      []string{"openid", "email", "groups"},
+		GroupsClaim: "groups",
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func startLogin(t *testing.T, provider *Provider, issuer *mockIssuer) (verifier string, nonce string) {
+	verifier, err := NewCodeVerifier()
+	require.NoError(t, err)
+	nonce, err = NewNonce()
+	require.NoError(t, err)
+	authorizationURL, err := provider.AuthCodeURL(context.Background(), "test-state", nonce, CodeChallenge(verifier))
+	require.NoError(t, err)
+	issuer.authorize(t, authorizationURL)
+	return verifier, nonce
+}
+
+func TestProviderLogin(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer)
+	verifier, nonce := startLogin(t, provider, issuer)
+
+	identity, err := provider.Exchange(context.Background(), testCode, verifier, nonce)
+	require.NoError(t, err)
+	require.Equal(t, issuer.URL, identity.Issuer)
+	require.Equal(t, "248289761001", identity.Subject)
+	require.Equal(t, "vk-user@example.com", identity.Email)
+	require.True(t, identity.EmailVerified)
+	require.Equal(t, []string{"vk-editors"}, identity.Groups)
+}
+
+func TestProviderRejectsWrongVerifier(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer)
+	_, nonce := startLogin(t, provider, issuer)
+	otherVerifier, err := NewCodeVerifier()
+	require.NoError(t, err)
+
+	_, err = provider.Exchange(context.Background(), testCode, otherVerifier, nonce)
+	var providerErr *Error
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, "invalid_grant", providerErr.Code)
+}
+
+func TestProviderRejectsInvalidIDToken(t *testing.T) {
+	for name, claims := range map[string]jwt.MapClaims{
+		"wrong nonce":    {"nonce": "other-nonce"},
+		"wrong audience": {"aud": "other-client"},
+		"wrong issuer":   {"iss": "https://id.example.com"},
+		"expired":        {"exp": time.Now().Add(-time.Minute).Unix()},
+		"no subject":     {"sub": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			issuer.claims = claims
+			provider := newTestProvider(t, issuer)
+			verifier, nonce := startLogin(t, provider, issuer)
+
+			_, err := provider.Exchange(context.Background(), testCode, verifier, nonce)
+			require.ErrorIs(t, err, ErrInvalidIDToken)
+		})
+	}
+}
+
+func TestGroupRoles(t *testing.T) {
+	mapping, err := ParseGroupRoles("vk-admins=administrator, vk-editors=editor")
+	require.NoError(t, err)
+	require.Equal(t, []GroupRole{{"vk-admins", "administrator"}, {"vk-editors", "editor"}}, mapping)
+
+	role, ok := RoleForGroups(mapping, []string{"vk-editors", "vk-admins"})
+	require.True(t, ok)
+	require.Equal(t, "administrator", role)
+
+	_, ok = RoleForGroups(mapping, []string{"other"})
+	require.False(t, ok)
+
+	_, err = ParseGroupRoles("vk-admins")
+	require.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupRole maps a group of the provider to a role.
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// ParseGroupRoles parses the mapping of the groups to the roles, written "<group>=<role>,...", such as
+// "vk-admins=administrator,vk-editors=editor". The first group of the user in the list gives the role.
+func ParseGroupRoles(value string) ([]GroupRole, error) {
+	var mapping []GroupRole
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(entry, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid group role %q, expected <group>=<role>", entry)
+		}
+		mapping = append(mapping, GroupRole{Group: group, Role: role})
+	}
+	return mapping, nil
+}
+
+// RoleForGroups returns the role of the first group of the mapping the user is in, false when there is none.
+func RoleForGroups(mapping []GroupRole, groups []string) (string, bool) {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+	for _, entry := range mapping {
+		if member[entry.Group] {
+			return entry.Role, true
+		}
+	}
+	return "", false
+}
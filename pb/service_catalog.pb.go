@@ -0,0 +1,168 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: service_catalog.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+var File_service_catalog_proto protoreflect.FileDescriptor
+
+var file_service_catalog_proto_rawDesc = []byte{
+	0x0a, 0x15, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x63, 0x61, 0x74, 0x61, 0x6c, 0x6f,
+	0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x0b, 0x61, 0x63, 0x74,
+	0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0b, 0x6d, 0x6f, 0x76, 0x69, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0f, 0x72, 0x70, 0x63, 0x5f, 0x61, 0x63, 0x74, 0x6f, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0f, 0x72, 0x70, 0x63, 0x5f, 0x6d, 0x6f, 0x76, 0x69,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x32, 0x98, 0x06, 0x0a, 0x0e, 0x43, 0x61, 0x74, 0x61,
+	0x6c, 0x6f, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x40, 0x0a, 0x0b, 0x43, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x12, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x43,
+	0x72, 0x65, 0x61, 0x74, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x4d, 0x6f, 0x76,
+	0x69, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x37, 0x0a, 0x08,
+	0x47, 0x65, 0x74, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x12, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x47, 0x65,
+	0x74, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e,
+	0x70, 0x62, 0x2e, 0x47, 0x65, 0x74, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x0b, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4d,
+	0x6f, 0x76, 0x69, 0x65, 0x12, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x4d, 0x6f, 0x76, 0x69, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70,
+	0x62, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x0b, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x12, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17,
+	0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x0b, 0x4d, 0x65, 0x72,
+	0x67, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x73, 0x12, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x4d, 0x65,
+	0x72, 0x67, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x4d, 0x6f, 0x76, 0x69, 0x65,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x32, 0x0a, 0x0a, 0x4c,
+	0x69, 0x73, 0x74, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x73, 0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x4c,
+	0x69, 0x73, 0x74, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x09, 0x2e, 0x70, 0x62, 0x2e, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12,
+	0x40, 0x0a, 0x0b, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x16,
+	0x2e, 0x70, 0x62, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x00, 0x12, 0x37, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x13, 0x2e,
+	0x70, 0x62, 0x2e, 0x47, 0x65, 0x74, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x47, 0x65, 0x74, 0x41, 0x63, 0x74, 0x6f, 0x72,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x0b, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x55,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x41, 0x63, 0x74,
+	0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x0b,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x16, 0x2e, 0x70, 0x62,
+	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x41,
+	0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40,
+	0x0a, 0x0b, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x16, 0x2e,
+	0x70, 0x62, 0x2e, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x4d, 0x65, 0x72, 0x67, 0x65,
+	0x41, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x12, 0x50, 0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x57, 0x69,
+	0x74, 0x68, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x73, 0x12, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x57, 0x69, 0x74, 0x68, 0x4d, 0x6f, 0x76, 0x69,
+	0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x41,
+	0x63, 0x74, 0x6f, 0x72, 0x57, 0x69, 0x74, 0x68, 0x4d, 0x6f, 0x76, 0x69, 0x65, 0x73, 0x22, 0x00,
+	0x30, 0x01, 0x42, 0x0c, 0x5a, 0x0a, 0x76, 0x6b, 0x2d, 0x66, 0x69, 0x6c, 0x6d, 0x2f, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var file_service_catalog_proto_goTypes = []any{
+	(*CreateMovieRequest)(nil),          // 0: pb.CreateMovieRequest
+	(*GetMovieRequest)(nil),             // 1: pb.GetMovieRequest
+	(*UpdateMovieRequest)(nil),          // 2: pb.UpdateMovieRequest
+	(*DeleteMovieRequest)(nil),          // 3: pb.DeleteMovieRequest
+	(*MergeMoviesRequest)(nil),          // 4: pb.MergeMoviesRequest
+	(*ListMoviesRequest)(nil),           // 5: pb.ListMoviesRequest
+	(*CreateActorRequest)(nil),          // 6: pb.CreateActorRequest
+	(*GetActorRequest)(nil),             // 7: pb.GetActorRequest
+	(*UpdateActorRequest)(nil),          // 8: pb.UpdateActorRequest
+	(*DeleteActorRequest)(nil),          // 9: pb.DeleteActorRequest
+	(*MergeActorsRequest)(nil),          // 10: pb.MergeActorsRequest
+	(*ListActorsWithMoviesRequest)(nil), // 11: pb.ListActorsWithMoviesRequest
+	(*CreateMovieResponse)(nil),         // 12: pb.CreateMovieResponse
+	(*GetMovieResponse)(nil),            // 13: pb.GetMovieResponse
+	(*UpdateMovieResponse)(nil),         // 14: pb.UpdateMovieResponse
+	(*DeleteMovieResponse)(nil),         // 15: pb.DeleteMovieResponse
+	(*MergeMoviesResponse)(nil),         // 16: pb.MergeMoviesResponse
+	(*Movie)(nil),                       // 17: pb.Movie
+	(*CreateActorResponse)(nil),         // 18: pb.CreateActorResponse
+	(*GetActorResponse)(nil),            // 19: pb.GetActorResponse
+	(*UpdateActorResponse)(nil),         // 20: pb.UpdateActorResponse
+	(*DeleteActorResponse)(nil),         // 21: pb.DeleteActorResponse
+	(*MergeActorsResponse)(nil),         // 22: pb.MergeActorsResponse
+	(*ActorWithMovies)(nil),             // 23: pb.ActorWithMovies
+}
+var file_service_catalog_proto_depIdxs = []int32{
+	0,  // 0: pb.CatalogService.CreateMovie:input_type -> pb.CreateMovieRequest
+	1,  // 1: pb.CatalogService.GetMovie:input_type -> pb.GetMovieRequest
+	2,  // 2: pb.CatalogService.UpdateMovie:input_type -> pb.UpdateMovieRequest
+	3,  // 3: pb.CatalogService.DeleteMovie:input_type -> pb.DeleteMovieRequest
+	4,  // 4: pb.CatalogService.MergeMovies:input_type -> pb.MergeMoviesRequest
+	5,  // 5: pb.CatalogService.ListMovies:input_type -> pb.ListMoviesRequest
+	6,  // 6: pb.CatalogService.CreateActor:input_type -> pb.CreateActorRequest
+	7,  // 7: pb.CatalogService.GetActor:input_type -> pb.GetActorRequest
+	8,  // 8: pb.CatalogService.UpdateActor:input_type -> pb.UpdateActorRequest
+	9,  // 9: pb.CatalogService.DeleteActor:input_type -> pb.DeleteActorRequest
+	10, // 10: pb.CatalogService.MergeActors:input_type -> pb.MergeActorsRequest
+	11, // 11: pb.CatalogService.ListActorsWithMovies:input_type -> pb.ListActorsWithMoviesRequest
+	12, // 12: pb.CatalogService.CreateMovie:output_type -> pb.CreateMovieResponse
+	13, // 13: pb.CatalogService.GetMovie:output_type -> pb.GetMovieResponse
+	14, // 14: pb.CatalogService.UpdateMovie:output_type -> pb.UpdateMovieResponse
+	15, // 15: pb.CatalogService.DeleteMovie:output_type -> pb.DeleteMovieResponse
+	16, // 16: pb.CatalogService.MergeMovies:output_type -> pb.MergeMoviesResponse
+	17, // 17: pb.CatalogService.ListMovies:output_type -> pb.Movie
+	18, // 18: pb.CatalogService.CreateActor:output_type -> pb.CreateActorResponse
+	19, // 19: pb.CatalogService.GetActor:output_type -> pb.GetActorResponse
+	20, // 20: pb.CatalogService.UpdateActor:output_type -> pb.UpdateActorResponse
+	21, // 21: pb.CatalogService.DeleteActor:output_type -> pb.DeleteActorResponse
+	22, // 22: pb.CatalogService.MergeActors:output_type -> pb.MergeActorsResponse
+	23, // 23: pb.CatalogService.ListActorsWithMovies:output_type -> pb.ActorWithMovies
+	12, // [12:24] is the sub-list for method output_type
+	0,  // [0:12] is the sub-list for method input_type
+	0,  // [0:0] is the sub-list for extension type_name
+	0,  // [0:0] is the sub-list for extension extendee
+	0,  // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_service_catalog_proto_init() }
+func file_service_catalog_proto_init() {
+	if File_service_catalog_proto != nil {
+		return
+	}
+	file_actor_proto_init()
+	file_movie_proto_init()
+	file_rpc_actor_proto_init()
+	file_rpc_movie_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_service_catalog_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_service_catalog_proto_goTypes,
+		DependencyIndexes: file_service_catalog_proto_depIdxs,
+	}.Build()
+	File_service_catalog_proto = out.File
+	file_service_catalog_proto_rawDesc = nil
+	file_service_catalog_proto_goTypes = nil
+	file_service_catalog_proto_depIdxs = nil
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	db "vk-film/db/sqlc"
+)
+
+// The backends selectable with RATE_LIMIT_BACKEND.
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+	BackendRedis    = "redis"
+)
+
+// Limit is a token bucket: it holds up to Requests tokens, refilled at Requests per Period, and every request takes one.
+// A client can burst Requests requests at once, then make Requests per Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses a limit written "<requests>/<period>", such as "100/1m". An empty limit disables the rate limiting.
+func ParseLimit(value string) (Limit, error) {
+	if value == "" {
+		return Limit{}, nil
+	}
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<period>", value)
+	}
+	var limit Limit
+	var err error
+	limit.Requests, err = strconv.Atoi(requests)
+	if err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid number of requests in rate limit %q", value)
+	}
+	limit.Period, err = time.ParseDuration(period)
+	if err != nil || limit.Period <= 0 {
+		return Limit{}, fmt.Errorf("invalid period in rate limit %q", value)
+	}
+	return limit, nil
+}
+
+// Enabled reports whether the limit limits anything.
+func (limit Limit) Enabled() bool {
+	return limit.Requests > 0
+}
+
+// capacity is the number of tokens of a full bucket.
+func (limit Limit) capacity() float64 {
+	return float64(limit.Requests)
+}
+
+// rate is the number of tokens refilled per second.
+func (limit Limit) rate() float64 {
+	return float64(limit.Requests) / limit.Period.Seconds()
+}
+
+// Result is the state of a bucket after a request took a token from it, or failed to.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// the requests that can be made right away
+	Remaining int
+	// the time before the next request is allowed, zero for the allowed requests
+	RetryAfter time.Duration
+	// the time before the bucket is full again
+	ResetAfter time.Duration
+}
+
+// newResult computes the result of a request from the tokens left in its bucket.
+func newResult(limit Limit, allowed bool, tokens float64) Result {
+	result := Result{
+		Allowed:    allowed,
+		Limit:      limit.Requests,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: seconds((limit.capacity() - tokens) / limit.rate()),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+	return result
+}
+
+func seconds(value float64) time.Duration {
+	if value <= 0 {
+		return 0
+	}
+	return time.Duration(value * float64(time.Second))
+}
+
+// Backend keeps the token buckets. Take must take a token from the bucket of a key atomically,
+// so that the concurrent requests sharing the backend never take more tokens than the bucket holds.
+type Backend interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Config selects the backend keeping the token buckets.
+type Config struct {
+	Backend       string
+	RedisAddress  string
+	RedisPassword string
+	RedisDB       int
+}
+
+// NewBackend creates the backend selected by config, the postgres backend keeps the buckets in the store.
+func NewBackend(config Config, store db.Store) (Backend, error) {
+	switch config.Backend {
+	case BackendMemory, "":
+		return NewMemoryBackend(), nil
+	case BackendPostgres:
+		return NewPostgresBackend(store), nil
+	case BackendRedis:
+		return NewRedisBackend(config.RedisAddress, config.RedisPassword, config.RedisDB)
+	}
+	return nil, fmt.Errorf("unsupported rate limit backend %q", config.Backend)
+}
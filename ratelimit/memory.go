@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps the token buckets in memory, the limits hold for one instance of the server.
+type MemoryBackend struct {
+	// now is the clock of the buckets, replaced by the tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// the time the bucket is full again, after which it is dropped
+	fullAt time.Time
+}
+
+// NewMemoryBackend creates an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take takes a token from the bucket of a key.
+func (backend *MemoryBackend) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := backend.now()
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	backend.sweep(now)
+	b, ok := backend.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.capacity(), updatedAt: now}
+		backend.buckets[key] = b
+	}
+	elapsed := math.Max(now.Sub(b.updatedAt).Seconds(), 0)
+	b.tokens = math.Min(limit.capacity(), b.tokens+elapsed*limit.rate())
+	b.updatedAt = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	result := newResult(limit, allowed, b.tokens)
+	b.fullAt = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+// sweep drops the full buckets, which are the same as no bucket, at most once a minute.
+func (backend *MemoryBackend) sweep(now time.Time) {
+	if now.Sub(backend.sweptAt) < time.Minute {
+		return
+	}
+	backend.sweptAt = now
+	for key, b := range backend.buckets {
+		if !now.Before(b.fullAt) {
+			delete(backend.buckets, key)
+		}
+	}
+}
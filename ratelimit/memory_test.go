@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("100/1m")
+	require.NoError(t, err)
+	require.Equal(t, Limit{Requests: 100, Period: time.Minute}, limit)
+	require.True(t, limit.Enabled())
+
+	limit, err = ParseLimit("")
+	require.NoError(t, err)
+	require.False(t, limit.Enabled())
+
+	for _, value := range []string{"100", "0/1m", "x/1m", "100/0s", "100/x"} {
+		_, err = ParseLimit(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestMemoryBackend(t *testing.T) {
+	now := time.Now()
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+	ctx := context.Background()
+
+	// a full bucket lets the client burst its limit
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := backend.Take(ctx, "user:vk-user", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, remaining, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+
+	result, err := backend.Take(ctx, "user:vk-user", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.ResetAfter)
+
+	// the other keys have their own bucket
+	result, err = backend.Take(ctx, "ip:127.0.0.1", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// a token is refilled every second
+	now = now.Add(time.Second)
+	result, err = backend.Take(ctx, "user:vk-user", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+
+	// the bucket never holds more than its capacity
+	now = now.Add(time.Hour)
+	result, err = backend.Take(ctx, "user:vk-user", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 2, result.Remaining)
+	require.Equal(t, time.Second, result.ResetAfter)
+}
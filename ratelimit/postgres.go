@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	db "vk-film/db/sqlc"
+)
+
+// idleBucketTTL is how long the Postgres buckets are kept after their last request.
+// It must exceed the longest period of the limits, a bucket dropped before it is full lets a client burst again.
+const idleBucketTTL = 24 * time.Hour
+
+// PostgresBackend keeps the token buckets in the store, so that the limits hold across the servers sharing it.
+// Every request takes its token with a single upsert.
+type PostgresBackend struct {
+	store db.Store
+
+	mu        sync.Mutex
+	cleanedAt time.Time
+}
+
+// NewPostgresBackend creates a backend keeping the buckets in the store.
+func NewPostgresBackend(store db.Store) *PostgresBackend {
+	return &PostgresBackend{store: store}
+}
+
+// Take takes a token from the bucket of a key.
+func (backend *PostgresBackend) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+	bucket, err := backend.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:      key,
+		Capacity: limit.capacity(),
+		Now:      now,
+		Rate:     limit.rate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	backend.cleanup(ctx, now)
+	return newResult(limit, bucket.Allowed, bucket.Tokens), nil
+}
+
+// cleanup deletes the idle buckets, at most once an hour.
+func (backend *PostgresBackend) cleanup(ctx context.Context, now time.Time) {
+	backend.mu.Lock()
+	due := now.Sub(backend.cleanedAt) >= time.Hour
+	if due {
+		backend.cleanedAt = now
+	}
+	backend.mu.Unlock()
+	if !due {
+		return
+	}
+	err := backend.store.DeleteIdleRateLimitBuckets(ctx, now.Add(-idleBucketTTL))
+	if err != nil {
+		log.Printf("cannot delete the idle rate limit buckets: %v", err)
+	}
+}
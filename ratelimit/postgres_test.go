@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+	"vk-film/util"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore connects to the database of app.env, skipping the test when it is unreachable.
+func newTestStore(t *testing.T) db.Store {
+	config, err := util.LoadConfig("..")
+	require.NoError(t, err)
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := conn.PingContext(ctx); err != nil {
+		t.Skipf("cannot connect to the database: %v", err)
+	}
+	return db.NewStore(conn)
+}
+
+func TestPostgresBackend(t *testing.T) {
+	backend := NewPostgresBackend(newTestStore(t))
+	limit := Limit{Requests: 3, Period: time.Hour}
+	ctx := context.Background()
+	key := fmt.Sprintf("user:vk-user-%d", time.Now().UnixNano())
+
+	// a full bucket lets the client burst its limit
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := backend.Take(ctx, key, limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, remaining, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+
+	result, err := backend.Take(ctx, key, limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.InDelta(t, 20*time.Minute, result.RetryAfter, float64(time.Second))
+
+	// the other keys have their own bucket
+	result, err = backend.Take(ctx, key+"-other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
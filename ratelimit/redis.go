@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript takes a token from the bucket of KEYS[1], a hash of its tokens and the time it was updated, in milliseconds.
+// It runs atomically on the server. The bucket expires once it is full again, a missing bucket being a full one.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated_at')
+local tokens = tonumber(bucket[1]) or capacity
+local updated_at = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(now - updated_at, 0) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated_at', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// redisTimeout bounds the calls made without a deadline in their context.
+const redisTimeout = time.Second
+
+// RedisBackend keeps the token buckets in Redis, so that the limits hold across the servers sharing it.
+// Every request takes its token with a single script call.
+type RedisBackend struct {
+	client *redis.Client
+	// now is the clock of the buckets, replaced by the tests
+	now func() time.Time
+}
+
+// NewRedisBackend creates a backend connecting to the server at addr, authenticating with password when it is set.
+func NewRedisBackend(addr string, password string, database int) (*RedisBackend, error) {
+	if addr == "" {
+		return nil, errors.New("the Redis address is required")
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     password,
+		DB:           database,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})
+	return &RedisBackend{client: client, now: time.Now}, nil
+}
+
+// Take takes a token from the bucket of a key.
+func (backend *RedisBackend) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	// the script is sent by its hash, and in full the first time the server has not cached it
+	values, err := takeScript.Run(ctx, backend.client, []string{key},
+		strconv.FormatFloat(limit.capacity(), 'f', -1, 64),
+		strconv.FormatFloat(limit.rate(), 'f', -1, 64),
+		backend.now().UnixMilli(),
+	).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply of the rate limit script: %v", values)
+	}
+	allowed, _ := values[0].(int64)
+	rawTokens, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(rawTokens, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected tokens in the reply of the rate limit script: %v", err)
+	}
+	return newResult(limit, allowed == 1, tokens), nil
+}
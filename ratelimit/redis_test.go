@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisBackend(t *testing.T) {
+	server := miniredis.RunT(t)
+	backend, err := NewRedisBackend(server.Addr(), "", 0)
+	require.NoError(t, err)
+	now := time.Now()
+	backend.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+	ctx := context.Background()
+
+	// a full bucket lets the client burst its limit
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := backend.Take(ctx, "user:vk-user", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, remaining, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+
+	result, err := backend.Take(ctx, "user:vk-user", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.ResetAfter)
+
+	// the bucket expires a second after it is full again
+	require.Equal(t, 4*time.Second, server.TTL("user:vk-user"))
+
+	// the other keys have their own bucket
+	result, err = backend.Take(ctx, "ip:127.0.0.1", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// a token is refilled every second
+	now = now.Add(time.Second)
+	result, err = backend.Take(ctx, "user:vk-user", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+
+	// the bucket never holds more than its capacity
+	now = now.Add(time.Hour)
+	result, err = backend.Take(ctx, "user:vk-user", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 2, result.Remaining)
+	require.Equal(t, time.Second, result.ResetAfter)
+
+	// an expired bucket is a full one
+	server.FastForward(time.Hour)
+	result, err = backend.Take(ctx, "user:vk-user", limit)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Remaining)
+}
+
+func TestRedisBackendUnreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	backend, err := NewRedisBackend(server.Addr(), "", 0)
+	require.NoError(t, err)
+	server.Close()
+
+	_, err = backend.Take(context.Background(), "user:vk-user", Limit{Requests: 3, Period: time.Second})
+	require.Error(t, err)
+
+	_, err = NewRedisBackend("", "", 0)
+	require.Error(t, err)
+}
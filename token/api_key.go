@@ -0,0 +1,98 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	db "vk-film/db/sqlc"
+)
+
+const (
+	// apiKeyScheme starts every API key, so that a leaked key is recognized as one
+	apiKeyScheme = "vkf"
+	// apiKeyIDSize is the size of the random ID shown in the prefix of a key
+	apiKeyIDSize = 4
+	// apiKeyUseResolution is how stale the recorded last use of a key gets before it is written again,
+	// so that a busy key does not write its row on every request
+	apiKeyUseResolution = time.Minute
+	// APIKeyPrincipal prefixes the name of an API key where a username is expected, such as in the audit log
+	APIKeyPrincipal = "apikey:"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+
+// NewAPIKey generates an API key written "vkf_<id>_<secret>", the prefix "vkf_<id>" shown to tell the keys apart,
+// and the hash to store in place of the key.
+func NewAPIKey() (key string, prefix string, hash []byte, err error) {
+	id := make([]byte, apiKeyIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", nil, err
+	}
+	secret := make([]byte, opaqueTokenSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", nil, err
+	}
+	prefix = apiKeyScheme + "_" + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashOpaqueToken(key), nil
+}
+
+// APIKeyVerifier authenticates the service-to-service clients by their API key.
+// Every key is looked up in the store, so that a revoked key is rejected right away on every server,
+// and the time it was last used is recorded, to the minute.
+type APIKeyVerifier struct {
+	store db.Store
+}
+
+// NewAPIKeyVerifier creates a verifier of the API keys kept in the store.
+func NewAPIKeyVerifier(store db.Store) *APIKeyVerifier {
+	return &APIKeyVerifier{store: store}
+}
+
+// Verify returns the payload of an API key, which carries the scopes of the key and the role of the user who issued it,
+// or ErrInvalidAPIKey if the key is unknown, expired or revoked, or was issued by a disabled user or through such a key.
+func (verifier *APIKeyVerifier) Verify(ctx context.Context, key string) (*Payload, error) {
+	if !strings.HasPrefix(key, apiKeyScheme+"_") {
+		return nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	apiKey, err := verifier.store.GetUsableAPIKey(ctx, db.GetUsableAPIKeyParams{
+		KeyHash: HashOpaqueToken(key),
+		Now:     sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	staleBefore := now.Add(-apiKeyUseResolution)
+	if !apiKey.LastUsedAt.Valid || apiKey.LastUsedAt.Time.Before(staleBefore) {
+		err = verifier.store.TouchAPIKey(ctx, db.TouchAPIKeyParams{
+			UsedAt:      sql.NullTime{Time: now, Valid: true},
+			ID:          apiKey.ID,
+			StaleBefore: sql.NullTime{Time: staleBefore, Valid: true},
+		})
+		if err != nil {
+			log.Printf("cannot record the use of API key %d: %v", apiKey.ID, err)
+		}
+	}
+	scopes := apiKey.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &Payload{
+		Username:  APIKeyPrincipal + apiKey.Name,
+		Role:      apiKey.IssuerRole,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiresAt.Time,
+		APIKeyID:  apiKey.ID,
+		Scopes:    scopes,
+	}, nil
+}
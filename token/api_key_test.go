@@ -0,0 +1,99 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+// apiKeyStore keeps one usable API key, the other methods of the store are not used by the verifier.
+type apiKeyStore struct {
+	db.Store
+	key     db.GetUsableAPIKeyRow
+	touches int
+}
+
+func (store *apiKeyStore) GetUsableAPIKey(ctx context.Context, arg db.GetUsableAPIKeyParams) (db.GetUsableAPIKeyRow, error) {
+	if string(arg.KeyHash) != string(store.key.KeyHash) {
+		return db.GetUsableAPIKeyRow{}, sql.ErrNoRows
+	}
+	return store.key, nil
+}
+
+func (store *apiKeyStore) TouchAPIKey(ctx context.Context, arg db.TouchAPIKeyParams) error {
+	if store.key.LastUsedAt.Valid && !store.key.LastUsedAt.Time.Before(arg.StaleBefore.Time) {
+		return nil
+	}
+	store.key.LastUsedAt = arg.UsedAt
+	store.touches++
+	return nil
+}
+
+func newAPIKeyStore(t *testing.T) (*apiKeyStore, string) {
+	key, prefix, hash, err := NewAPIKey()
+	require.NoError(t, err)
+	return &apiKeyStore{key: db.GetUsableAPIKeyRow{
+		ID:         7,
+		Name:       "billing",
+		Prefix:     prefix,
+		KeyHash:    hash,
+		Scopes:     []string{"movies:write"},
+		CreatedBy:  "alice",
+		CreatedAt:  time.Now(),
+		IssuerRole: "editor",
+	}}, key
+}
+
+func TestNewAPIKey(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(prefix, "vkf_"))
+	require.Len(t, prefix, len("vkf_")+2*apiKeyIDSize)
+	require.True(t, strings.HasPrefix(key, prefix+"_"))
+	require.Equal(t, HashOpaqueToken(key), hash)
+
+	otherKey, otherPrefix, _, err := NewAPIKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key, otherKey)
+	require.NotEqual(t, prefix, otherPrefix)
+}
+
+func TestAPIKeyVerifierVerify(t *testing.T) {
+	store, key := newAPIKeyStore(t)
+	verifier := NewAPIKeyVerifier(store)
+
+	payload, err := verifier.Verify(context.Background(), key)
+	require.NoError(t, err)
+	require.True(t, payload.IsAPIKey())
+	require.Equal(t, int32(7), payload.APIKeyID)
+	require.Equal(t, APIKeyPrincipal+"billing", payload.Username)
+	require.Equal(t, "editor", payload.Role)
+	require.Equal(t, []string{"movies:write"}, payload.Scopes)
+
+	_, err = verifier.Verify(context.Background(), key+"x")
+	require.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = verifier.Verify(context.Background(), "not-a-key")
+	require.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyVerifierRecordsUseOnceAMinute(t *testing.T) {
+	store, key := newAPIKeyStore(t)
+	verifier := NewAPIKeyVerifier(store)
+
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(context.Background(), key)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, store.touches)
+
+	store.key.LastUsedAt.Time = store.key.LastUsedAt.Time.Add(-apiKeyUseResolution - time.Second)
+	_, err := verifier.Verify(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, 2, store.touches)
+	require.WithinDuration(t, time.Now(), store.key.LastUsedAt.Time, time.Second)
+}
@@ -0,0 +1,101 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// AsymmetricJWTMaker signs the tokens with the current key of a key ring, named by the kid header,
+// so that other services verify them with the published public keys only.
+type AsymmetricJWTMaker struct {
+	keys     *KeyRing
+	method   jwt.SigningMethod
+	issuer   string
+	audience string
+}
+
+// registeredClaims carries the payload with the registered claims the other services check.
+type registeredClaims struct {
+	*Payload
+	jwt.StandardClaims
+}
+
+func (claims registeredClaims) Valid() error {
+	return claims.Payload.Valid()
+}
+
+func NewAsymmetricJWTMaker(keys *KeyRing, issuer string, audience string) (Maker, error) {
+	if issuer == "" || audience == "" {
+		return nil, errors.New("the issuer and the audience of the tokens are required")
+	}
+	return &AsymmetricJWTMaker{
+		keys:     keys,
+		method:   jwt.GetSigningMethod(keys.algorithm),
+		issuer:   issuer,
+		audience: audience,
+	}, nil
+}
+
+func (maker *AsymmetricJWTMaker) CreateToken(
+	username string,
+	role string,
+	duration time.Duration,
+) (string, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", err
+	}
+	key, err := maker.keys.signingKey(context.Background())
+	if err != nil {
+		return "", err
+	}
+	if payload.ExpiredAt.After(key.expiresAt) {
+		return "", fmt.Errorf("token duration %s exceeds the key overlap %s", duration, maker.keys.overlap)
+	}
+	claims := registeredClaims{
+		Payload: payload,
+		StandardClaims: jwt.StandardClaims{
+			Id:        payload.ID.String(),
+			Subject:   payload.Username,
+			Issuer:    maker.issuer,
+			Audience:  maker.audience,
+			IssuedAt:  payload.IssuedAt.Unix(),
+			ExpiresAt: payload.ExpiredAt.Unix(),
+		},
+	}
+	jwToken := jwt.NewWithClaims(maker.method, claims)
+	jwToken.Header["kid"] = key.id
+	return jwToken.SignedString(key.privateKey)
+}
+
+func (maker *AsymmetricJWTMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(jwToken *jwt.Token) (interface{}, error) {
+		if jwToken.Method.Alg() != maker.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		kid, _ := jwToken.Header["kid"].(string)
+		return maker.keys.publicKey(context.Background(), kid)
+	}
+	claims := &registeredClaims{Payload: &Payload{}}
+	_, err := jwt.ParseWithClaims(token, claims, keyFunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(maker.issuer, true) || !claims.VerifyAudience(maker.audience, true) {
+		return nil, ErrInvalidToken
+	}
+	return claims.Payload, nil
+}
+
+// KeySet returns the public keys verifying the tokens.
+func (maker *AsymmetricJWTMaker) KeySet(ctx context.Context) (JSONWebKeySet, error) {
+	return maker.keys.KeySet(ctx)
+}
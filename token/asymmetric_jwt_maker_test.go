@@ -0,0 +1,277 @@
+package token
+
+import (
+	"context"
+	"crypto/x509"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKeyEncryptionKey = "98765432109876543210987654321098"
+
+// keyStore keeps the signing keys in memory, the other methods of the store are not used by the key ring.
+// Its transactions run one at a time, like those holding the lock of the signing keys.
+type keyStore struct {
+	db.Store
+	txMu sync.Mutex
+	mu   sync.Mutex
+	keys []db.SigningKey
+}
+
+func (store *keyStore) ExecTx(ctx context.Context, fn func(db.Store) error) error {
+	store.txMu.Lock()
+	defer store.txMu.Unlock()
+	return fn(store)
+}
+
+func (store *keyStore) LockSigningKeys(ctx context.Context, algorithm string) error {
+	return nil
+}
+
+func (store *keyStore) CreateSigningKey(ctx context.Context, arg db.CreateSigningKeyParams) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, key := range store.keys {
+		if key.Algorithm == arg.Algorithm && key.NotBefore.Equal(arg.NotBefore) {
+			return nil
+		}
+	}
+	store.keys = append(store.keys, db.SigningKey{
+		Kid:        arg.Kid,
+		Algorithm:  arg.Algorithm,
+		PrivateKey: arg.PrivateKey,
+		NotBefore:  arg.NotBefore,
+		NotAfter:   arg.NotAfter,
+		ExpiresAt:  arg.ExpiresAt,
+		CreatedAt:  time.Now().UTC(),
+	})
+	return nil
+}
+
+func (store *keyStore) ListSigningKeys(ctx context.Context, algorithm string) ([]db.SigningKey, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	keys := []db.SigningKey{}
+	for _, key := range store.keys {
+		if key.Algorithm == algorithm && key.ExpiresAt.After(time.Now()) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].NotBefore.Before(keys[j].NotBefore) })
+	return keys, nil
+}
+
+func (store *keyStore) DeleteExpiredSigningKeys(ctx context.Context) error {
+	return nil
+}
+
+// shift moves the schedule of the stored keys back in time, as if d had passed.
+func (store *keyStore) shift(d time.Duration) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for i := range store.keys {
+		store.keys[i].NotBefore = store.keys[i].NotBefore.Add(-d)
+		store.keys[i].NotAfter = store.keys[i].NotAfter.Add(-d)
+		store.keys[i].ExpiresAt = store.keys[i].ExpiresAt.Add(-d)
+	}
+}
+
+func newAsymmetricJWTMaker(t *testing.T, store db.Store, tokenType string) Maker {
+	maker, err := NewMaker(MakerConfig{
+		Type:                tokenType,
+		Issuer:              "http://localhost:8080",
+		Audience:            "vk-film",
+		Store:               store,
+		KeyEncryptionKey:    testKeyEncryptionKey,
+		KeyRotationInterval: 24 * time.Hour,
+		KeyOverlap:          time.Hour,
+		// every call syncs the keys from the store
+		KeySyncInterval: time.Nanosecond,
+	})
+	require.NoError(t, err)
+	return maker
+}
+
+func TestAsymmetricJWTMaker(t *testing.T) {
+	for _, tokenType := range []string{TypeJWTRS256, TypeJWTEdDSA} {
+		t.Run(tokenType, func(t *testing.T) {
+			maker := newAsymmetricJWTMaker(t, &keyStore{}, tokenType)
+
+			issuedAt := time.Now()
+			expiredAt := issuedAt.Add(time.Minute)
+			token, err := maker.CreateToken("vk-user", "client", time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.NotZero(t, payload.ID)
+			require.Equal(t, "vk-user", payload.Username)
+			require.Equal(t, "client", payload.Role)
+			require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+			require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+
+			// the registered claims are readable by the services verifying the token with the key set
+			claims := &jwt.StandardClaims{}
+			jwToken, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+			require.NoError(t, err)
+			require.Equal(t, "http://localhost:8080", claims.Issuer)
+			require.Equal(t, "vk-film", claims.Audience)
+			require.Equal(t, "vk-user", claims.Subject)
+			require.Equal(t, payload.ID.String(), claims.Id)
+
+			keys, err := maker.(KeySetMaker).KeySet(context.Background())
+			require.NoError(t, err)
+			require.Len(t, keys.Keys, 1)
+			require.Equal(t, jwToken.Header["kid"], keys.Keys[0].KeyID)
+			require.Equal(t, jwToken.Method.Alg(), keys.Keys[0].Algorithm)
+		})
+	}
+}
+
+func TestExpiredAsymmetricJWTToken(t *testing.T) {
+	maker := newAsymmetricJWTMaker(t, &keyStore{}, TypeJWTEdDSA)
+
+	token, err := maker.CreateToken("vk-user", "client", -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrExpiredToken)
+	require.Nil(t, payload)
+}
+
+func TestTamperedAsymmetricJWTToken(t *testing.T) {
+	maker := newAsymmetricJWTMaker(t, &keyStore{}, TypeJWTEdDSA)
+
+	token, err := maker.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+	other, err := maker.CreateToken("vk-admin", "administrator", time.Minute)
+	require.NoError(t, err)
+	otherParts := strings.Split(other, ".")
+
+	// the claims of another token are paired with the signature of the first one
+	payload, err := maker.VerifyToken(strings.Join([]string{parts[0], otherParts[1], parts[2]}, "."))
+	require.ErrorIs(t, err, ErrInvalidToken)
+	require.Nil(t, payload)
+}
+
+func TestAsymmetricJWTTokenOfAnotherKeyRing(t *testing.T) {
+	maker := newAsymmetricJWTMaker(t, &keyStore{}, TypeJWTEdDSA)
+	other := newAsymmetricJWTMaker(t, &keyStore{}, TypeJWTEdDSA)
+
+	token, err := other.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	require.Nil(t, payload)
+}
+
+func TestAsymmetricJWTTokenOfAnotherAudience(t *testing.T) {
+	store := &keyStore{}
+	maker := newAsymmetricJWTMaker(t, store, TypeJWTEdDSA)
+	keys, err := NewKeyRing(store, AlgorithmEdDSA, testKeyEncryptionKey, 24*time.Hour, time.Hour, time.Nanosecond)
+	require.NoError(t, err)
+	other, err := NewAsymmetricJWTMaker(keys, "http://localhost:8080", "another-service")
+	require.NoError(t, err)
+
+	token, err := other.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	require.Nil(t, payload)
+}
+
+func TestAsymmetricJWTKeyRotation(t *testing.T) {
+	store := &keyStore{}
+	maker := newAsymmetricJWTMaker(t, store, TypeJWTEdDSA)
+
+	oldToken, err := maker.CreateToken("vk-user", "client", time.Hour)
+	require.NoError(t, err)
+
+	// within the overlap before the first key retires, the next key is published but does not sign yet
+	store.shift(23*time.Hour + 30*time.Minute)
+	keys, err := maker.(KeySetMaker).KeySet(context.Background())
+	require.NoError(t, err)
+	require.Len(t, keys.Keys, 2)
+	token, err := maker.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, kid(t, oldToken), kid(t, token))
+
+	// once the first key retires, the next one signs and the tokens of the first one are still verified
+	store.shift(time.Hour)
+	newToken, err := maker.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+	require.NotEqual(t, kid(t, oldToken), kid(t, newToken))
+	_, err = maker.VerifyToken(newToken)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	// a token outliving the publication of its key is not signed
+	_, err = maker.CreateToken("vk-user", "client", 48*time.Hour)
+	require.Error(t, err)
+}
+
+func TestNewKeyRingInvalidSchedule(t *testing.T) {
+	_, err := NewKeyRing(&keyStore{}, "HS256", testKeyEncryptionKey, 24*time.Hour, time.Hour, time.Minute)
+	require.Error(t, err)
+	_, err = NewKeyRing(&keyStore{}, AlgorithmRS256, testKeyEncryptionKey, time.Hour, time.Hour, time.Minute)
+	require.Error(t, err)
+	_, err = NewKeyRing(&keyStore{}, AlgorithmRS256, testKeyEncryptionKey, 24*time.Hour, time.Hour, time.Hour)
+	require.Error(t, err)
+	_, err = NewKeyRing(&keyStore{}, AlgorithmRS256, "too-short", 24*time.Hour, time.Hour, time.Minute)
+	require.Error(t, err)
+}
+
+func TestKeyRingEncryptsPrivateKeys(t *testing.T) {
+	store := &keyStore{}
+	maker := newAsymmetricJWTMaker(t, store, TypeJWTEdDSA)
+	token, err := maker.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+
+	require.Len(t, store.keys, 1)
+	_, err = x509.ParsePKCS8PrivateKey(store.keys[0].PrivateKey)
+	require.Error(t, err)
+
+	// a key ring with another encryption key cannot read the stored keys
+	keys, err := NewKeyRing(store, AlgorithmEdDSA, "01234567890123456789012345678901", 24*time.Hour, time.Hour, time.Nanosecond)
+	require.NoError(t, err)
+	other, err := NewAsymmetricJWTMaker(keys, "http://localhost:8080", "vk-film")
+	require.NoError(t, err)
+	_, err = other.VerifyToken(token)
+	require.Error(t, err)
+}
+
+func kid(t *testing.T, token string) string {
+	jwToken, _, err := new(jwt.Parser).ParseUnverified(token, &jwt.StandardClaims{})
+	require.NoError(t, err)
+	return jwToken.Header["kid"].(string)
+}
+
+// TestKeyRingsStartingTogether checks that the servers starting on an empty store create a single key.
+func TestKeyRingsStartingTogether(t *testing.T) {
+	store := &keyStore{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ring, err := NewKeyRing(store, AlgorithmEdDSA, testKeyEncryptionKey, 24*time.Hour, time.Hour, time.Nanosecond)
+			assert.NoError(t, err)
+			_, err = ring.KeySet(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Len(t, store.keys, 1)
+}
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+const testSymmetricKey = "12345678901234567890123456789012"
+
+func TestJWTMaker(t *testing.T) {
+	maker, err := NewJWTMaker(testSymmetricKey)
+	require.NoError(t, err)
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(time.Minute)
+	token, err := maker.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotZero(t, payload.ID)
+	require.Equal(t, "vk-user", payload.Username)
+	require.Equal(t, "client", payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestJWTMakerShortKey(t *testing.T) {
+	_, err := NewJWTMaker("too-short")
+	require.Error(t, err)
+}
+
+func TestExpiredJWTToken(t *testing.T) {
+	maker, err := NewJWTMaker(testSymmetricKey)
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken("vk-user", "client", -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrExpiredToken)
+	require.Nil(t, payload)
+}
+
+func TestTamperedJWTToken(t *testing.T) {
+	maker, err := NewJWTMaker(testSymmetricKey)
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+
+	// the claims are rewritten to grant the administrator role, the signature is kept
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	claims = []byte(strings.Replace(string(claims), `"role":"client"`, `"role":"administrator"`, 1))
+	parts[1] = base64.RawURLEncoding.EncodeToString(claims)
+
+	payload, err := maker.VerifyToken(strings.Join(parts, "."))
+	require.ErrorIs(t, err, ErrInvalidToken)
+	require.Nil(t, payload)
+}
+
+func TestJWTTokenOfAnotherKey(t *testing.T) {
+	maker, err := NewJWTMaker(testSymmetricKey)
+	require.NoError(t, err)
+	other, err := NewJWTMaker(strings.Repeat("x", minSecretKeySize))
+	require.NoError(t, err)
+
+	token, err := other.CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	require.Nil(t, payload)
+}
+
+func TestJWTTokenAlgNone(t *testing.T) {
+	maker, err := NewJWTMaker(testSymmetricKey)
+	require.NoError(t, err)
+
+	payload, err := NewPayload("vk-user", "administrator", time.Minute)
+	require.NoError(t, err)
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
+	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	require.Nil(t, payload)
+}
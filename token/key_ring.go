@@ -0,0 +1,362 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+	db "vk-film/db/sqlc"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeySize = 2048
+
+	// the AES-256 key encrypting the private keys in the store
+	keyEncryptionKeySize = 32
+)
+
+// KeyRing holds the keys signing the access tokens, shared by the servers through the store.
+// Every key signs for rotationInterval. The next key is published overlap before it starts signing,
+// so that the verifiers caching the key set know it before the first token it signs,
+// and a retired key stays published overlap after it stops signing, so that its tokens can be verified until they expire.
+// The keys are cached in memory and synced from the store every syncInterval, the sync also rotates them when they are due.
+// The private keys are stored encrypted with AES-GCM.
+type KeyRing struct {
+	store            db.Store
+	algorithm        string
+	aead             cipher.AEAD
+	rotationInterval time.Duration
+	overlap          time.Duration
+	syncInterval     time.Duration
+
+	// syncMu is held by the sync reading and rotating the keys, without blocking the callers using the keys loaded before
+	syncMu sync.Mutex
+	mu     sync.Mutex
+	// the published keys, oldest first
+	keys     []signingKey
+	syncedAt time.Time
+}
+
+type signingKey struct {
+	id         string
+	privateKey crypto.Signer
+	notBefore  time.Time
+	notAfter   time.Time
+	expiresAt  time.Time
+}
+
+// JSONWebKey is the public part of a signing key, as described in RFC 7517.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// the modulus and the exponent of the RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// the curve and the public key of the Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet lists the keys verifying the access tokens.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewKeyRing creates a key ring of RS256 or EdDSA keys, whose private keys are stored encrypted with encryptionKey.
+// The overlap must be at least the lifetime of the access tokens, and the sync interval shorter than the overlap,
+// so that every server picks up a key before it starts signing.
+func NewKeyRing(store db.Store, algorithm string, encryptionKey string, rotationInterval, overlap, syncInterval time.Duration) (*KeyRing, error) {
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if len(encryptionKey) != keyEncryptionKeySize {
+		return nil, fmt.Errorf("invalid key encryption key size: must be exactly %d characters", keyEncryptionKeySize)
+	}
+	if overlap <= 0 || rotationInterval <= overlap {
+		return nil, fmt.Errorf("invalid key rotation: the interval %s must be longer than the overlap %s", rotationInterval, overlap)
+	}
+	if syncInterval >= overlap {
+		return nil, fmt.Errorf("invalid key sync interval %s: must be shorter than the overlap %s", syncInterval, overlap)
+	}
+	block, err := aes.NewCipher([]byte(encryptionKey))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyRing{
+		store:            store,
+		algorithm:        algorithm,
+		aead:             aead,
+		rotationInterval: rotationInterval,
+		overlap:          overlap,
+		syncInterval:     syncInterval,
+	}, nil
+}
+
+// KeySet returns the public keys of the published signing keys.
+func (ring *KeyRing) KeySet(ctx context.Context) (JSONWebKeySet, error) {
+	keys, err := ring.published(ctx)
+	if err != nil {
+		return JSONWebKeySet{}, err
+	}
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := ring.jsonWebKey(key.privateKey.Public())
+		if err != nil {
+			return JSONWebKeySet{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// signingKey returns the key signing the tokens now, the newest one that has started signing.
+func (ring *KeyRing) signingKey(ctx context.Context) (signingKey, error) {
+	keys, err := ring.published(ctx)
+	if err != nil {
+		return signingKey{}, err
+	}
+	now := time.Now()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !keys[i].notBefore.After(now) {
+			return keys[i], nil
+		}
+	}
+	return signingKey{}, fmt.Errorf("no %s key signs the tokens", ring.algorithm)
+}
+
+// publicKey returns the public key of a published signing key, or ErrInvalidToken for unknown keys.
+func (ring *KeyRing) publicKey(ctx context.Context, id string) (crypto.PublicKey, error) {
+	keys, err := ring.published(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.id == id {
+			return key.privateKey.Public(), nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// published returns the published keys, syncing them from the store when they are older than syncInterval.
+// The sync reads the store and creates the keys outside of mu, the callers arriving meanwhile use the keys loaded before,
+// or wait for the sync when there are none yet. When the store cannot be read, the keys loaded before keep being used
+// until the next sync.
+func (ring *KeyRing) published(ctx context.Context) ([]signingKey, error) {
+	keys, fresh := ring.cached()
+	if fresh {
+		return keys, nil
+	}
+	if len(keys) == 0 {
+		ring.syncMu.Lock()
+	} else if !ring.syncMu.TryLock() {
+		return keys, nil
+	}
+	defer ring.syncMu.Unlock()
+	// another sync may have completed while waiting
+	if keys, fresh = ring.cached(); fresh {
+		return keys, nil
+	}
+	loaded, err := ring.load(ctx)
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	if err != nil {
+		if len(ring.keys) == 0 {
+			return nil, err
+		}
+		log.Printf("cannot sync the %s signing keys: %v", ring.algorithm, err)
+	} else {
+		ring.keys = loaded
+	}
+	ring.syncedAt = time.Now()
+	return ring.keys, nil
+}
+
+// cached returns the keys loaded by the last sync and whether they are younger than syncInterval.
+func (ring *KeyRing) cached() ([]signingKey, bool) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	return ring.keys, time.Since(ring.syncedAt) < ring.syncInterval
+}
+
+// load reads the published keys from the store, creating the next key first when it is due.
+func (ring *KeyRing) load(ctx context.Context) ([]signingKey, error) {
+	err := ring.store.DeleteExpiredSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ring.store.ListSigningKeys(ctx, ring.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if _, due := ring.nextNotBefore(rows, time.Now()); due {
+		rows, err = ring.rotate(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	keys := make([]signingKey, 0, len(rows))
+	for _, row := range rows {
+		der, err := ring.decrypt(row.Kid, row.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt signing key %s: %v", row.Kid, err)
+		}
+		privateKey, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse signing key %s: %v", row.Kid, err)
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("cannot parse signing key %s: unsupported key type %T", row.Kid, privateKey)
+		}
+		keys = append(keys, signingKey{
+			id:         row.Kid,
+			privateKey: signer,
+			notBefore:  row.NotBefore,
+			notAfter:   row.NotAfter,
+			expiresAt:  row.ExpiresAt,
+		})
+	}
+	return keys, nil
+}
+
+// nextNotBefore returns when the next key starts signing, and whether it is due: once the newest key stops signing
+// within the overlap, or right away when no key signs.
+func (ring *KeyRing) nextNotBefore(rows []db.SigningKey, now time.Time) (time.Time, bool) {
+	switch {
+	case len(rows) == 0 || !rows[len(rows)-1].NotAfter.After(now):
+		return now, true
+	case !rows[len(rows)-1].NotAfter.After(now.Add(ring.overlap)):
+		return rows[len(rows)-1].NotAfter, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// rotate creates the next key if it is still due once the other servers are kept out by a lock, so that the servers
+// starting together do not each create a key, and returns the published keys.
+func (ring *KeyRing) rotate(ctx context.Context) ([]db.SigningKey, error) {
+	var rows []db.SigningKey
+	err := ring.store.ExecTx(ctx, func(store db.Store) error {
+		err := store.LockSigningKeys(ctx, ring.algorithm)
+		if err != nil {
+			return err
+		}
+		rows, err = store.ListSigningKeys(ctx, ring.algorithm)
+		if err != nil {
+			return err
+		}
+		notBefore, due := ring.nextNotBefore(rows, time.Now())
+		if !due {
+			return nil
+		}
+		err = ring.createKey(ctx, store, notBefore)
+		if err != nil {
+			return err
+		}
+		rows, err = store.ListSigningKeys(ctx, ring.algorithm)
+		return err
+	})
+	return rows, err
+}
+
+// createKey creates a key signing from notBefore for the rotation interval.
+func (ring *KeyRing) createKey(ctx context.Context, store db.Store, notBefore time.Time) error {
+	privateKey, err := ring.generateKey()
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	jwk, err := ring.jsonWebKey(privateKey.Public())
+	if err != nil {
+		return err
+	}
+	encrypted, err := ring.encrypt(jwk.KeyID, der)
+	if err != nil {
+		return err
+	}
+	notAfter := notBefore.Add(ring.rotationInterval)
+	return store.CreateSigningKey(ctx, db.CreateSigningKeyParams{
+		Kid:        jwk.KeyID,
+		Algorithm:  ring.algorithm,
+		PrivateKey: encrypted,
+		NotBefore:  notBefore,
+		NotAfter:   notAfter,
+		ExpiresAt:  notAfter.Add(ring.overlap),
+	})
+}
+
+// encrypt seals a private key with a random nonce prepended, the key ID is authenticated with it
+// so that the private key of a row cannot be moved to another one.
+func (ring *KeyRing) encrypt(kid string, der []byte) ([]byte, error) {
+	nonce := make([]byte, ring.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return ring.aead.Seal(nonce, nonce, der, []byte(kid)), nil
+}
+
+// decrypt opens a private key sealed by encrypt.
+func (ring *KeyRing) decrypt(kid string, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < ring.aead.NonceSize() {
+		return nil, errors.New("the encrypted key is too short")
+	}
+	nonce, sealed := encrypted[:ring.aead.NonceSize()], encrypted[ring.aead.NonceSize():]
+	return ring.aead.Open(nil, nonce, sealed, []byte(kid))
+}
+
+func (ring *KeyRing) generateKey() (crypto.Signer, error) {
+	if ring.algorithm == AlgorithmRS256 {
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	return privateKey, err
+}
+
+// jsonWebKey describes a public key, its key ID is its RFC 7638 thumbprint.
+func (ring *KeyRing) jsonWebKey(publicKey crypto.PublicKey) (JSONWebKey, error) {
+	jwk := JSONWebKey{Use: "sig", Algorithm: ring.algorithm}
+	var thumbprint string
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		thumbprint = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		thumbprint = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, jwk.X)
+	default:
+		return JSONWebKey{}, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+	sum := sha256.Sum256([]byte(thumbprint))
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return jwk, nil
+}
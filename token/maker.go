@@ -1,8 +1,69 @@
 package token
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"time"
+	db "vk-film/db/sqlc"
+)
+
+const (
+	TypeJWT          = "jwt"
+	TypeJWTRS256     = "jwt.rs256"
+	TypeJWTEdDSA     = "jwt.eddsa"
+	TypePasetoLocal  = "paseto.v4.local"
+	TypePasetoPublic = "paseto.v4.public"
+)
 
 type Maker interface {
 	CreateToken(username string, role string, duration time.Duration) (string, error)
 	VerifyToken(token string) (*Payload, error)
 }
+
+// KeySetMaker is a maker whose tokens are verified with published public keys.
+type KeySetMaker interface {
+	Maker
+	KeySet(ctx context.Context) (JSONWebKeySet, error)
+}
+
+// MakerConfig configures the maker created by NewMaker.
+type MakerConfig struct {
+	// The token type, TypeJWT when empty.
+	Type string
+	// The key of the JWT and v4.local makers.
+	SymmetricKey string
+	// The key of the v4.public maker, a hex-encoded Ed25519 seed.
+	AsymmetricKey string
+	// The iss and aud claims of the RS256 and EdDSA JWTs.
+	Issuer   string
+	Audience string
+	// The store of the RS256 and EdDSA signing keys, the key encrypting them in the store, and the schedule of their rotation.
+	Store               db.Store
+	KeyEncryptionKey    string
+	KeyRotationInterval time.Duration
+	KeyOverlap          time.Duration
+	KeySyncInterval     time.Duration
+}
+
+// NewMaker creates the maker of a token type.
+func NewMaker(config MakerConfig) (Maker, error) {
+	switch config.Type {
+	case TypeJWT, "":
+		return NewJWTMaker(config.SymmetricKey)
+	case TypeJWTRS256, TypeJWTEdDSA:
+		algorithm := AlgorithmRS256
+		if config.Type == TypeJWTEdDSA {
+			algorithm = AlgorithmEdDSA
+		}
+		keys, err := NewKeyRing(config.Store, algorithm, config.KeyEncryptionKey, config.KeyRotationInterval, config.KeyOverlap, config.KeySyncInterval)
+		if err != nil {
+			return nil, err
+		}
+		return NewAsymmetricJWTMaker(keys, config.Issuer, config.Audience)
+	case TypePasetoLocal:
+		return NewPasetoLocalMaker(config.SymmetricKey)
+	case TypePasetoPublic:
+		return NewPasetoPublicMaker(config.AsymmetricKey)
+	}
+	return nil, fmt.Errorf("unsupported token type %q", config.Type)
+}
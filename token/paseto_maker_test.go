@@ -0,0 +1,125 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSeed      = "6b90a98dee0220a5a4cc6e6d5c9dd1e1226a3b6ac292af17b167651fae9ec6fa"
+	testOtherSeed = "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+)
+
+// newPasetoMakers creates a v4.local and a v4.public maker, by purpose.
+func newPasetoMakers(t *testing.T, symmetricKey string, seed string) map[string]Maker {
+	local, err := NewPasetoLocalMaker(symmetricKey)
+	require.NoError(t, err)
+	public, err := NewPasetoPublicMaker(seed)
+	require.NoError(t, err)
+	return map[string]Maker{"local": local, "public": public}
+}
+
+func TestPasetoMaker(t *testing.T) {
+	for purpose, maker := range newPasetoMakers(t, testSymmetricKey, testSeed) {
+		t.Run(purpose, func(t *testing.T) {
+			issuedAt := time.Now()
+			expiredAt := issuedAt.Add(time.Minute)
+			token, err := maker.CreateToken("vk-user", "client", time.Minute)
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(token, "v4."+purpose+"."))
+
+			payload, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.NotZero(t, payload.ID)
+			require.Equal(t, "vk-user", payload.Username)
+			require.Equal(t, "client", payload.Role)
+			require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+			require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+		})
+	}
+}
+
+func TestPasetoMakerInvalidKey(t *testing.T) {
+	_, err := NewPasetoLocalMaker("too-short")
+	require.Error(t, err)
+	_, err = NewPasetoPublicMaker("not-hex")
+	require.Error(t, err)
+}
+
+func TestExpiredPasetoToken(t *testing.T) {
+	for purpose, maker := range newPasetoMakers(t, testSymmetricKey, testSeed) {
+		t.Run(purpose, func(t *testing.T) {
+			token, err := maker.CreateToken("vk-user", "client", -time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			require.ErrorIs(t, err, ErrExpiredToken)
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestTamperedPasetoToken(t *testing.T) {
+	for purpose, maker := range newPasetoMakers(t, testSymmetricKey, testSeed) {
+		t.Run(purpose, func(t *testing.T) {
+			token, err := maker.CreateToken("vk-user", "client", time.Minute)
+			require.NoError(t, err)
+
+			// a character in the middle of the encrypted or signed part is changed
+			i := len(token) / 2
+			replacement := "A"
+			if token[i] == 'A' {
+				replacement = "B"
+			}
+			tampered := token[:i] + replacement + token[i+1:]
+
+			payload, err := maker.VerifyToken(tampered)
+			require.ErrorIs(t, err, ErrInvalidToken)
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestPasetoTokenOfAnotherKey(t *testing.T) {
+	makers := newPasetoMakers(t, testSymmetricKey, testSeed)
+	others := newPasetoMakers(t, strings.Repeat("x", minSecretKeySize), testOtherSeed)
+	for purpose, maker := range makers {
+		t.Run(purpose, func(t *testing.T) {
+			token, err := others[purpose].CreateToken("vk-user", "client", time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			require.ErrorIs(t, err, ErrInvalidToken)
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestPasetoTokenOfAnotherPurpose(t *testing.T) {
+	makers := newPasetoMakers(t, testSymmetricKey, testSeed)
+	local, err := makers["local"].CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+	public, err := makers["public"].CreateToken("vk-user", "client", time.Minute)
+	require.NoError(t, err)
+
+	_, err = makers["public"].VerifyToken(local)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	_, err = makers["local"].VerifyToken(public)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewMaker(t *testing.T) {
+	for _, tokenType := range []string{TypeJWT, TypePasetoLocal, TypePasetoPublic} {
+		maker, err := NewMaker(MakerConfig{Type: tokenType, SymmetricKey: testSymmetricKey, AsymmetricKey: testSeed})
+		require.NoError(t, err)
+		token, err := maker.CreateToken("vk-user", "client", time.Minute)
+		require.NoError(t, err)
+		_, err = maker.VerifyToken(token)
+		require.NoError(t, err)
+	}
+	_, err := NewMaker(MakerConfig{Type: "paseto.v1.local", SymmetricKey: testSymmetricKey, AsymmetricKey: testSeed})
+	require.Error(t, err)
+}
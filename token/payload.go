@@ -18,6 +18,10 @@ type Payload struct {
 	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// the API key authenticating the request and the permissions it grants, set by APIKeyVerifier and never part of
+	// a signed token. The Role of an API key is the role of the user who issued it, which bounds its scopes.
+	APIKeyID int32    `json:"-"`
+	Scopes   []string `json:"-"`
 }
 
 func NewPayload(username string, role string, duration time.Duration) (*Payload, error) {
@@ -41,3 +45,8 @@ func (payload *Payload) Valid() error {
 	}
 	return nil
 }
+
+// IsAPIKey reports whether the payload authenticates an API key rather than a user.
+func (payload *Payload) IsAPIKey() bool {
+	return payload.APIKeyID != 0
+}
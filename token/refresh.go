@@ -0,0 +1,36 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+const opaqueTokenSize = 32
+
+// NewRefreshToken generates an opaque refresh token and the hash to store in its place.
+// Refresh tokens are not signed tokens, so they can never be used as access tokens.
+func NewRefreshToken() (string, []byte, error) {
+	return NewOpaqueToken()
+}
+
+// HashRefreshToken returns the hash under which a refresh token is stored.
+func HashRefreshToken(token string) []byte {
+	return HashOpaqueToken(token)
+}
+
+// NewOpaqueToken generates a random token, such as the single-use tokens sent by email, and the hash to store in its place.
+func NewOpaqueToken() (string, []byte, error) {
+	secret := make([]byte, opaqueTokenSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken returns the hash under which an opaque token is stored.
+func HashOpaqueToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
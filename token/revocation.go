@@ -0,0 +1,188 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRevokedToken = errors.New("token has been revoked")
+	ErrUserDisabled = errors.New("user has been disabled")
+)
+
+// RevocationList rejects the access tokens revoked before they expire: the ones revoked one by one, at logout,
+// and the ones issued before the password of their user changed or before all the tokens of the user were revoked.
+// It also rejects the tokens of the disabled users.
+// The revocations are kept in memory, so that checking a token does not query the store on every request,
+// and they are synced from the store every syncInterval, to pick up the revocations made by the other servers.
+type RevocationList struct {
+	store        db.Store
+	syncInterval time.Duration
+
+	mu sync.Mutex
+	// the IDs of the revoked tokens, until the tokens expire
+	tokens   map[uuid.UUID]time.Time
+	syncedAt time.Time
+	// the time before which the tokens of a user are rejected, by username
+	cutoffs map[string]tokenCutoff
+}
+
+type tokenCutoff struct {
+	at       time.Time
+	disabled bool
+	loadedAt time.Time
+}
+
+// NewRevocationList creates a revocation list syncing from the store at most every syncInterval.
+func NewRevocationList(store db.Store, syncInterval time.Duration) *RevocationList {
+	return &RevocationList{
+		store:        store,
+		syncInterval: syncInterval,
+		tokens:       make(map[uuid.UUID]time.Time),
+		cutoffs:      make(map[string]tokenCutoff),
+	}
+}
+
+// Check returns ErrRevokedToken if the token of a payload has been revoked, or ErrUserDisabled if its user has been disabled.
+func (list *RevocationList) Check(ctx context.Context, payload *Payload) error {
+	err := list.sync(ctx)
+	if err != nil {
+		return err
+	}
+	cutoff, err := list.cutoff(ctx, payload.Username)
+	if err != nil {
+		return err
+	}
+	if cutoff.disabled {
+		return ErrUserDisabled
+	}
+	list.mu.Lock()
+	_, revoked := list.tokens[payload.ID]
+	list.mu.Unlock()
+	if revoked || payload.IssuedAt.Before(cutoff.at) {
+		return ErrRevokedToken
+	}
+	return nil
+}
+
+// Revoke rejects the token of a payload until it expires.
+func (list *RevocationList) Revoke(ctx context.Context, payload *Payload) error {
+	err := list.store.CreateRevokedToken(ctx, db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		return err
+	}
+	list.mu.Lock()
+	list.tokens[payload.ID] = payload.ExpiredAt
+	list.mu.Unlock()
+	return nil
+}
+
+// RevokeUser rejects every token of a user issued until now and revokes the sessions of the user.
+// It returns the time before which the tokens are rejected, or sql.ErrNoRows for unknown users.
+func (list *RevocationList) RevokeUser(ctx context.Context, username string) (time.Time, error) {
+	now := time.Now()
+	err := list.store.RevokeUserTokensTx(ctx, db.RevokeUserTokensParams{
+		Username:         username,
+		TokensValidAfter: now,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	list.mu.Lock()
+	if cutoff, ok := list.cutoffs[username]; !ok || cutoff.at.Before(now) {
+		list.cutoffs[username] = tokenCutoff{at: now, disabled: cutoff.disabled, loadedAt: now}
+	}
+	list.mu.Unlock()
+	return now, nil
+}
+
+// ForgetUser makes the next check reload the state of a user, after the user was disabled or enabled through this server.
+func (list *RevocationList) ForgetUser(username string) {
+	list.mu.Lock()
+	delete(list.cutoffs, username)
+	list.mu.Unlock()
+}
+
+// sync reloads the revoked tokens from the store when they are older than syncInterval.
+// A single request reloads them, the concurrent ones keep using the tokens loaded before.
+func (list *RevocationList) sync(ctx context.Context) error {
+	list.mu.Lock()
+	syncedAt := list.syncedAt
+	fresh := time.Since(syncedAt) < list.syncInterval
+	if !fresh {
+		list.syncedAt = time.Now()
+	}
+	list.mu.Unlock()
+	if fresh {
+		return nil
+	}
+	revoked, err := list.loadRevokedTokens(ctx)
+	if err != nil {
+		list.mu.Lock()
+		list.syncedAt = syncedAt
+		list.mu.Unlock()
+		return err
+	}
+	tokens := make(map[uuid.UUID]time.Time, len(revoked))
+	for _, token := range revoked {
+		tokens[token.ID] = token.ExpiresAt
+	}
+	now := time.Now()
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	// the tokens revoked here while the store was read are kept
+	for id, expiresAt := range list.tokens {
+		if _, ok := tokens[id]; !ok && expiresAt.After(now) {
+			tokens[id] = expiresAt
+		}
+	}
+	list.tokens = tokens
+	for username, cutoff := range list.cutoffs {
+		if now.Sub(cutoff.loadedAt) >= list.syncInterval {
+			delete(list.cutoffs, username)
+		}
+	}
+	return nil
+}
+
+func (list *RevocationList) loadRevokedTokens(ctx context.Context) ([]db.RevokedToken, error) {
+	err := list.store.DeleteExpiredRevokedTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return list.store.ListRevokedTokens(ctx)
+}
+
+// cutoff returns the time before which the tokens of a user are rejected and whether the user is disabled,
+// loading them when they are not cached.
+func (list *RevocationList) cutoff(ctx context.Context, username string) (tokenCutoff, error) {
+	list.mu.Lock()
+	cutoff, ok := list.cutoffs[username]
+	list.mu.Unlock()
+	if ok && time.Since(cutoff.loadedAt) < list.syncInterval {
+		return cutoff, nil
+	}
+	row, err := list.store.GetUserTokenCutoff(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// the user has been deleted
+			return tokenCutoff{}, ErrRevokedToken
+		}
+		return tokenCutoff{}, err
+	}
+	cutoff = tokenCutoff{at: row.Cutoff, disabled: row.Disabled, loadedAt: time.Now()}
+	list.mu.Lock()
+	list.cutoffs[username] = cutoff
+	list.mu.Unlock()
+	return cutoff, nil
+}
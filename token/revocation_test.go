@@ -0,0 +1,186 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	db "vk-film/db/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+// revocationStore keeps the revoked tokens and the token cutoffs of the users, and counts the reads of the store.
+type revocationStore struct {
+	db.Store
+	mu          sync.Mutex
+	tokens      []db.RevokedToken
+	cutoffs     map[string]db.GetUserTokenCutoffRow
+	listErr     error
+	syncs       int
+	cutoffReads int
+}
+
+func newRevocationStore(usernames ...string) *revocationStore {
+	store := &revocationStore{cutoffs: make(map[string]db.GetUserTokenCutoffRow)}
+	for _, username := range usernames {
+		store.cutoffs[username] = db.GetUserTokenCutoffRow{Cutoff: time.Now().Add(-time.Hour)}
+	}
+	return store
+}
+
+func (store *revocationStore) DeleteExpiredRevokedTokens(ctx context.Context) error {
+	return nil
+}
+
+func (store *revocationStore) ListRevokedTokens(ctx context.Context) ([]db.RevokedToken, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.syncs++
+	if store.listErr != nil {
+		return nil, store.listErr
+	}
+	return append([]db.RevokedToken(nil), store.tokens...), nil
+}
+
+func (store *revocationStore) CreateRevokedToken(ctx context.Context, arg db.CreateRevokedTokenParams) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.tokens = append(store.tokens, db.RevokedToken{ID: arg.ID, Username: arg.Username, ExpiresAt: arg.ExpiresAt})
+	return nil
+}
+
+func (store *revocationStore) GetUserTokenCutoff(ctx context.Context, username string) (db.GetUserTokenCutoffRow, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.cutoffReads++
+	row, ok := store.cutoffs[username]
+	if !ok {
+		return db.GetUserTokenCutoffRow{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+func (store *revocationStore) RevokeUserTokensTx(ctx context.Context, arg db.RevokeUserTokensParams) error {
+	store.setCutoff(arg.Username, arg.TokensValidAfter, false)
+	return nil
+}
+
+func (store *revocationStore) setCutoff(username string, cutoff time.Time, disabled bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.cutoffs[username] = db.GetUserTokenCutoffRow{Cutoff: cutoff, Disabled: disabled}
+}
+
+func newTestPayload(t *testing.T, username string) *Payload {
+	payload, err := NewPayload(username, "viewer", time.Minute)
+	require.NoError(t, err)
+	return payload
+}
+
+func TestRevocationListCheck(t *testing.T) {
+	store := newRevocationStore("alice")
+	list := NewRevocationList(store, time.Hour)
+	payload := newTestPayload(t, "alice")
+
+	require.NoError(t, list.Check(context.Background(), payload))
+	require.NoError(t, list.Check(context.Background(), payload))
+	// the revoked tokens and the cutoff are read once per sync interval
+	require.Equal(t, 1, store.syncs)
+	require.Equal(t, 1, store.cutoffReads)
+}
+
+func TestRevocationListRevoke(t *testing.T) {
+	store := newRevocationStore("alice")
+	list := NewRevocationList(store, time.Hour)
+	payload := newTestPayload(t, "alice")
+
+	require.NoError(t, list.Revoke(context.Background(), payload))
+	require.ErrorIs(t, list.Check(context.Background(), payload), ErrRevokedToken)
+	require.NoError(t, list.Check(context.Background(), newTestPayload(t, "alice")))
+	require.Len(t, store.tokens, 1)
+	require.Equal(t, payload.ID, store.tokens[0].ID)
+}
+
+func TestRevocationListRevokeUser(t *testing.T) {
+	store := newRevocationStore("alice")
+	list := NewRevocationList(store, time.Hour)
+	before := newTestPayload(t, "alice")
+	require.NoError(t, list.Check(context.Background(), before))
+
+	cutoff, err := list.RevokeUser(context.Background(), "alice")
+	require.NoError(t, err)
+	// the cached cutoff is replaced without waiting for the next sync
+	require.ErrorIs(t, list.Check(context.Background(), before), ErrRevokedToken)
+	after := newTestPayload(t, "alice")
+	require.False(t, after.IssuedAt.Before(cutoff))
+	require.NoError(t, list.Check(context.Background(), after))
+	require.Equal(t, 1, store.cutoffReads)
+}
+
+func TestRevocationListDisabledUser(t *testing.T) {
+	store := newRevocationStore("alice")
+	store.setCutoff("alice", time.Now().Add(-time.Hour), true)
+	list := NewRevocationList(store, time.Hour)
+
+	require.ErrorIs(t, list.Check(context.Background(), newTestPayload(t, "alice")), ErrUserDisabled)
+}
+
+func TestRevocationListDeletedUser(t *testing.T) {
+	list := NewRevocationList(newRevocationStore(), time.Hour)
+
+	require.ErrorIs(t, list.Check(context.Background(), newTestPayload(t, "alice")), ErrRevokedToken)
+}
+
+func TestRevocationListCachesCutoff(t *testing.T) {
+	store := newRevocationStore("alice")
+	list := NewRevocationList(store, time.Hour)
+	payload := newTestPayload(t, "alice")
+	require.NoError(t, list.Check(context.Background(), payload))
+
+	// a change made by another server is not seen until the cached cutoff expires
+	store.setCutoff("alice", time.Now().Add(-time.Hour), true)
+	require.NoError(t, list.Check(context.Background(), payload))
+
+	// a change made through this server is seen at once
+	list.ForgetUser("alice")
+	require.ErrorIs(t, list.Check(context.Background(), payload), ErrUserDisabled)
+	require.Equal(t, 2, store.cutoffReads)
+}
+
+func TestRevocationListSync(t *testing.T) {
+	syncInterval := 20 * time.Millisecond
+	store := newRevocationStore("alice")
+	list := NewRevocationList(store, syncInterval)
+	payload := newTestPayload(t, "alice")
+	require.NoError(t, list.Check(context.Background(), payload))
+
+	// another server revokes the token
+	require.NoError(t, store.CreateRevokedToken(context.Background(), db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  "alice",
+		ExpiresAt: payload.ExpiredAt,
+	}))
+	require.NoError(t, list.Check(context.Background(), payload))
+
+	time.Sleep(syncInterval)
+	require.ErrorIs(t, list.Check(context.Background(), payload), ErrRevokedToken)
+	require.Equal(t, 2, store.syncs)
+	require.Equal(t, 2, store.cutoffReads)
+}
+
+func TestRevocationListSyncError(t *testing.T) {
+	store := newRevocationStore("alice")
+	store.listErr = errors.New("connection refused")
+	list := NewRevocationList(store, time.Hour)
+	payload := newTestPayload(t, "alice")
+
+	require.ErrorIs(t, list.Check(context.Background(), payload), store.listErr)
+
+	// a failed sync is retried by the next check
+	store.listErr = nil
+	require.NoError(t, list.Check(context.Background(), payload))
+	require.Equal(t, 2, store.syncs)
+}
@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the SHA-1 secret of the test vectors of RFC 6238, appendix B
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// the 8 digit codes of RFC 6238 truncated to their last 6 digits
+	for unix, code := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		require.Equal(t, code, Code(rfcSecret, Step(time.Unix(unix, 0))), "time %d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	step, ok := Validate(rfcSecret, "050471", now)
+	require.True(t, ok)
+	require.Equal(t, current, step)
+
+	step, ok = Validate(rfcSecret, Code(rfcSecret, current-1), now)
+	require.True(t, ok)
+	require.Equal(t, current-1, step)
+
+	step, ok = Validate(rfcSecret, "050 471", now)
+	require.True(t, ok)
+	require.Equal(t, current, step)
+
+	_, ok = Validate(rfcSecret, Code(rfcSecret, current-2), now)
+	require.False(t, ok)
+	_, ok = Validate(rfcSecret, Code(rfcSecret, current+2), now)
+	require.False(t, ok)
+	_, ok = Validate(rfcSecret, "", now)
+	require.False(t, ok)
+	_, ok = Validate(rfcSecret, "50471", now)
+	require.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, secretSize)
+
+	uri, err := url.Parse(URI("vk-film", "vk-admin", secret))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/vk-film:vk-admin", uri.Path)
+	require.Equal(t, EncodeSecret(secret), uri.Query().Get("secret"))
+	require.Equal(t, "vk-film", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		require.Regexp(t, `^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`, code)
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+
+	require.Equal(t, "ABCD2345EFGH6723", NormalizeRecoveryCode("abcd-2345 efgh-6723"))
+	require.Equal(t, NormalizeRecoveryCode(codes[0]), NormalizeRecoveryCode(codes[0][:9]+codes[0][10:]))
+}
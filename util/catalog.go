@@ -0,0 +1,38 @@
+package util
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// The lengths of the text columns of the catalog.
+const (
+	MaxMovieNameLength        = 150
+	MaxMovieDescriptionLength = 1000
+	MaxActorNameLength        = 255
+)
+
+// Genders are the genders an actor can have.
+var Genders = []string{"male", "female", "other"}
+
+// ratingPattern matches the values of the DECIMAL(3, 1) rating column, the range is checked separately.
+var ratingPattern = regexp.MustCompile(`^\d{1,2}(\.\d)?$`)
+
+// IsValidRating reports whether a rating fits the rating column, from 0 to 10 with at most one decimal.
+func IsValidRating(value string) bool {
+	if !ratingPattern.MatchString(value) {
+		return false
+	}
+	rating, err := strconv.ParseFloat(value, 64)
+	return err == nil && rating <= 10
+}
+
+// IsSupportedGender reports whether an actor can have a gender.
+func IsSupportedGender(gender string) bool {
+	for _, supported := range Genders {
+		if gender == supported {
+			return true
+		}
+	}
+	return false
+}
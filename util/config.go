@@ -7,11 +7,70 @@ import (
 )
 
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	HTTPServerAddress   string        `mapstructure:"HTTP_SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	Environment                    string        `mapstructure:"ENVIRONMENT"`
+	DBDriver                       string        `mapstructure:"DB_DRIVER"`
+	DBSource                       string        `mapstructure:"DB_SOURCE"`
+	HTTPServerAddress              string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	TrustedProxies                 string        `mapstructure:"TRUSTED_PROXIES"`
+	GRPCServerAddress              string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TokenType                      string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey              string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenAsymmetricKey             string        `mapstructure:"TOKEN_ASYMMETRIC_KEY"`
+	TokenIssuer                    string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience                  string        `mapstructure:"TOKEN_AUDIENCE"`
+	TokenKeyRotationInterval       time.Duration `mapstructure:"TOKEN_KEY_ROTATION_INTERVAL"`
+	TokenKeyOverlap                time.Duration `mapstructure:"TOKEN_KEY_OVERLAP"`
+	TokenKeySyncInterval           time.Duration `mapstructure:"TOKEN_KEY_SYNC_INTERVAL"`
+	TokenKeyEncryptionKey          string        `mapstructure:"TOKEN_KEY_ENCRYPTION_KEY"`
+	AccessTokenDuration            time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration           time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	RevocationSyncInterval         time.Duration `mapstructure:"REVOCATION_SYNC_INTERVAL"`
+	PermissionSyncInterval         time.Duration `mapstructure:"PERMISSION_SYNC_INTERVAL"`
+	RequireIfMatch                 bool          `mapstructure:"REQUIRE_IF_MATCH"`
+	MoviesCacheControl             string        `mapstructure:"MOVIES_CACHE_CONTROL"`
+	ActorsCacheControl             string        `mapstructure:"ACTORS_CACHE_CONTROL"`
+	CacheSize                      int           `mapstructure:"CACHE_SIZE"`
+	CacheTTL                       time.Duration `mapstructure:"CACHE_TTL"`
+	GraphQLMaxDepth                int           `mapstructure:"GRAPHQL_MAX_DEPTH"`
+	GraphQLMaxComplexity           int           `mapstructure:"GRAPHQL_MAX_COMPLEXITY"`
+	LegacyAPIDeprecatedAt          string        `mapstructure:"LEGACY_API_DEPRECATED_AT"`
+	LegacyAPISunset                string        `mapstructure:"LEGACY_API_SUNSET"`
+	OpenAPIValidation              bool          `mapstructure:"OPENAPI_VALIDATION"`
+	Mailer                         string        `mapstructure:"MAILER"`
+	MailFrom                       string        `mapstructure:"MAIL_FROM"`
+	SMTPHost                       string        `mapstructure:"SMTP_HOST"`
+	SMTPPort                       int           `mapstructure:"SMTP_PORT"`
+	SMTPUsername                   string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword                   string        `mapstructure:"SMTP_PASSWORD"`
+	MailOutboxDir                  string        `mapstructure:"MAIL_OUTBOX_DIR"`
+	EmailVerificationURL           string        `mapstructure:"EMAIL_VERIFICATION_URL"`
+	EmailVerificationTokenDuration time.Duration `mapstructure:"EMAIL_VERIFICATION_TOKEN_DURATION"`
+	PasswordResetURL               string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetTokenDuration     time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+	LoginMaxFailures               int           `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginIPMaxFailures             int           `mapstructure:"LOGIN_IP_MAX_FAILURES"`
+	LoginFailureWindow             time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	LoginDelay                     time.Duration `mapstructure:"LOGIN_DELAY"`
+	LoginMaxDelay                  time.Duration `mapstructure:"LOGIN_MAX_DELAY"`
+	LoginLockoutDuration           time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	RateLimitBackend               string        `mapstructure:"RATE_LIMIT_BACKEND"`
+	RedisAddress                   string        `mapstructure:"REDIS_ADDRESS"`
+	RedisPassword                  string        `mapstructure:"REDIS_PASSWORD"`
+	RedisDB                        int           `mapstructure:"REDIS_DB"`
+	RateLimitAuth                  string        `mapstructure:"RATE_LIMIT_AUTH"`
+	RateLimitCatalog               string        `mapstructure:"RATE_LIMIT_CATALOG"`
+	RateLimitDefault               string        `mapstructure:"RATE_LIMIT_DEFAULT"`
+	OIDCIssuer                     string        `mapstructure:"OIDC_ISSUER"`
+	OIDCClientID                   string        `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret               string        `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL                string        `mapstructure:"OIDC_REDIRECT_URL"`
+	OIDCScopes                     string        `mapstructure:"OIDC_SCOPES"`
+	OIDCGroupsClaim                string        `mapstructure:"OIDC_GROUPS_CLAIM"`
+	OIDCGroupRoles                 string        `mapstructure:"OIDC_GROUP_ROLES"`
+	OIDCLoginDuration              time.Duration `mapstructure:"OIDC_LOGIN_DURATION"`
+	TOTPIssuer                     string        `mapstructure:"TOTP_ISSUER"`
+	TOTPRequiredForAdministrators  bool          `mapstructure:"TOTP_REQUIRED_FOR_ADMINISTRATORS"`
+	LoginChallengeDuration         time.Duration `mapstructure:"LOGIN_CHALLENGE_DURATION"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
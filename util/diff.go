@@ -0,0 +1,51 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// FieldChange holds the value of a field before and after a change.
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// JSONDiff compares the JSON representations of two values field by field and returns the fields that differ.
+// A nil value is treated as an empty object, so diffing against nil lists every field of the other value.
+func JSONDiff(before, after interface{}) (map[string]FieldChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+	diff := map[string]FieldChange{}
+	for key, beforeValue := range beforeFields {
+		afterValue, ok := afterFields[key]
+		if !ok || !reflect.DeepEqual(beforeValue, afterValue) {
+			diff[key] = FieldChange{Before: beforeValue, After: afterValue}
+		}
+	}
+	for key, afterValue := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			diff[key] = FieldChange{Before: nil, After: afterValue}
+		}
+	}
+	return diff, nil
+}
+
+func jsonFields(value interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if value == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,3 +18,18 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(password string, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+var (
+	unknownUserHashOnce sync.Once
+	unknownUserHash     []byte
+)
+
+// CheckUnknownUserPassword fails like CheckPassword with a wrong password, after as long a check,
+// so that the time of a login does not reveal whether its user exists.
+func CheckUnknownUserPassword(password string) error {
+	unknownUserHashOnce.Do(func() {
+		unknownUserHash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(unknownUserHash, []byte(password))
+	return bcrypt.ErrMismatchedHashAndPassword
+}
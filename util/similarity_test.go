@@ -0,0 +1,57 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"accents", "Pénélope Cruz", "cruz penelope"},
+		{"word order", "Reeves, Keanu", "keanu reeves"},
+		{"case and spaces", "  keanu   REEVES ", "keanu reeves"},
+		{"punctuation", "Catherine Zeta-Jones", "catherine jones zeta"},
+		{"digits", "Blade Runner 2049", "2049 blade runner"},
+		{"empty", " - ", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, NormalizeName(tc.input))
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	testCases := []struct {
+		name      string
+		a, b      string
+		expected  float64
+		duplicate bool
+	}{
+		{"same", "Keanu Reeves", "Keanu Reeves", 1, true},
+		{"accents", "Pénélope Cruz", "Penelope Cruz", 1, true},
+		{"word order", "Reeves, Keanu", "keanu REEVES", 1, true},
+		{"one typo", "Keanu Reeves", "Keanu Reves", 1 - 1.0/12, true},
+		{"two typos", "Keanu Reeves", "Kenu Reves", 1 - 2.0/12, false},
+		// 3 edits in 20 runes are exactly at the threshold
+		{"above threshold", "Catherine Zeta-Jones", "Catherina Zeta-Jonas", 0.90, true},
+		{"at threshold", "Catherine Zeta-Jones", "Catharina Zeta-Jonas", 0.85, true},
+		{"below threshold", "Catherine Zeta-Jones", "Catharina Zita-Jonas", 0.80, false},
+		{"different", "Keanu Reeves", "Tom Hanks", 1 - 10.0/12, false},
+		{"empty", "", "Tom Hanks", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.InDelta(t, tc.expected, NameSimilarity(tc.a, tc.b), 1e-9)
+			require.InDelta(t, tc.expected, NameSimilarity(tc.b, tc.a), 1e-9)
+			require.Equal(t, tc.duplicate, IsLikelyDuplicateName(tc.a, tc.b))
+		})
+	}
+}